@@ -2,17 +2,33 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/kushturner/finances/internal/account"
 	"github.com/kushturner/finances/internal/csvparser"
 	"github.com/kushturner/finances/internal/db"
+	"github.com/kushturner/finances/internal/importjob"
 	"github.com/kushturner/finances/internal/server"
 	"github.com/kushturner/finances/internal/transaction"
 	"github.com/kushturner/finances/migrations"
 )
 
+const (
+	// importWorkers is how many uploads are imported in the background at
+	// once, and importQueueSize how many more can wait their turn.
+	importWorkers   = 2
+	importQueueSize = 16
+	// shutdownTimeout is how long requests in flight are given to finish
+	// once the server is told to stop.
+	shutdownTimeout = 30 * time.Second
+)
+
 func main() {
 	connStr := os.Getenv("FINANCES_DATABASE_URL")
 	if connStr == "" {
@@ -24,21 +40,58 @@ func main() {
 	}
 	log.Println("Migrations completed")
 
-	conn, err := db.Connect()
+	pool, err := db.Connect()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer conn.Close(context.Background())
+	defer pool.Close()
 	log.Println("Connected to database")
 
-	querier := db.New(conn)
-	transactionService := transaction.NewService(querier)
+	store := db.NewStore(pool)
+	transactionService := transaction.NewService(store)
+	accountService := account.NewService(store)
 	parserService := csvparser.NewService()
+	if dir := os.Getenv("FINANCES_PARSER_PROFILES_DIR"); dir != "" {
+		parserService, err = csvparser.NewServiceWithProfileDir(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Loaded parser profiles from %s", dir)
+	}
+
+	// ctx is cancelled on SIGINT or SIGTERM, which stops the import
+	// workers as well as the server.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	importJobService := importjob.NewService(store, importWorkers, importQueueSize)
+	if err := importJobService.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	r := server.NewRouter(transactionService, parserService, accountService, importJobService)
+	srv := &http.Server{Addr: ":8080", Handler: r}
 
-	r := server.NewRouter(transactionService, parserService)
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("Starting server on :8080")
+		serveErr <- srv.ListenAndServe()
+	}()
 
-	log.Println("Starting server on :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
+	select {
+	case err := <-serveErr:
 		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Shutting down server: %v", err)
 	}
+	// No job can be submitted once the server has shut down, so the
+	// workers can be drained.
+	importJobService.Wait()
+	log.Println("Import workers stopped")
 }
@@ -0,0 +1,42 @@
+package account
+
+import (
+	"time"
+
+	"github.com/Rhymond/go-money"
+)
+
+const (
+	TypeCurrent = "current"
+	TypeCredit  = "credit"
+	TypeSavings = "savings"
+	TypeCash    = "cash"
+)
+
+type Account struct {
+	ID          int32
+	Institution string
+	Name        string
+	// MaskedNumber only ever holds the last few digits of the account or
+	// card number, e.g. "****12345".
+	MaskedNumber   *string
+	Type           string
+	OpeningBalance *money.Money
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Hint is what an uploaded statement says about the account it belongs to.
+// Only Institution is required; the rest is filled in when the format
+// carries it.
+type Hint struct {
+	Institution  string
+	Name         string
+	MaskedNumber string
+	Type         string
+	Currency     string
+	// OpeningBalance is the balance before the statement's first
+	// transaction, in minor units of Currency. An account created from the
+	// hint opens with it, so its first statement reconciles.
+	OpeningBalance int64
+}
@@ -0,0 +1,303 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxHistoryDays bounds the balance history series so a stray date range
+// cannot produce an enormous response.
+const MaxHistoryDays = 3660
+
+const (
+	// DiscrepancyOpeningBalance means the first statement balance does not
+	// follow from the account's opening balance, usually because history
+	// before the first import is missing or the opening balance is unset.
+	DiscrepancyOpeningBalance = "opening_balance"
+	// DiscrepancyGap means a statement balance does not follow from the
+	// previous one, so rows are missing or an amount differs.
+	DiscrepancyGap = "gap"
+)
+
+type Discrepancy struct {
+	Kind          string
+	TransactionID int32
+	Date          time.Time
+	// Expected is the balance our stored amounts imply; Statement is what
+	// the bank reported. Difference is Statement minus Expected, so a
+	// positive difference points at missing money in.
+	Expected   *money.Money
+	Statement  *money.Money
+	Difference *money.Money
+}
+
+// StatementBalanceCheck compares the closing balance reported by the most
+// recent import with the balance computed up to that import's date.
+type StatementBalanceCheck struct {
+	ImportID   int32
+	Date       time.Time
+	Statement  *money.Money
+	Computed   *money.Money
+	Difference *money.Money
+}
+
+type Reconciliation struct {
+	AccountID       int32
+	OpeningBalance  *money.Money
+	ComputedBalance *money.Money
+	// CheckedRows counts transactions that carried a statement balance.
+	CheckedRows      int
+	Discrepancies    []Discrepancy
+	StatementBalance *StatementBalanceCheck
+}
+
+func (r Reconciliation) Reconciled() bool {
+	if len(r.Discrepancies) > 0 {
+		return false
+	}
+	return r.StatementBalance == nil || r.StatementBalance.Difference.IsZero()
+}
+
+type DailyBalance struct {
+	Date    time.Time
+	Balance *money.Money
+	// StatementBalance is the last balance the bank reported that day, if
+	// any.
+	StatementBalance *money.Money
+}
+
+// ledgerEntry is one transaction as it affects the balance, after
+// ordering.
+type ledgerEntry struct {
+	id             int32
+	date           time.Time
+	amount         int64
+	runningBalance *int64
+}
+
+// ledgerDay is the net effect of a day's transactions.
+type ledgerDay struct {
+	date             time.Time
+	closing          int64
+	statementClosing *int64
+}
+
+func (s *service) Reconcile(ctx context.Context, id int32) (Reconciliation, error) {
+	a, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	entries, err := s.ledger(ctx, a)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+
+	currency := a.OpeningBalance.Currency().Code
+	rec := Reconciliation{AccountID: a.ID, OpeningBalance: a.OpeningBalance}
+
+	balance := a.OpeningBalance.Amount()
+	anchored := false
+	for _, e := range entries {
+		balance += e.amount
+		if e.runningBalance == nil {
+			continue
+		}
+		rec.CheckedRows++
+		if *e.runningBalance != balance {
+			kind := DiscrepancyGap
+			if !anchored {
+				kind = DiscrepancyOpeningBalance
+			}
+			rec.Discrepancies = append(rec.Discrepancies, Discrepancy{
+				Kind:          kind,
+				TransactionID: e.id,
+				Date:          e.date,
+				Expected:      money.New(balance, currency),
+				Statement:     money.New(*e.runningBalance, currency),
+				Difference:    money.New(*e.runningBalance-balance, currency),
+			})
+			// Re-anchor on the bank's figure so one gap is reported once
+			// rather than on every row after it.
+			balance = *e.runningBalance
+		}
+		anchored = true
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.amount
+	}
+	rec.ComputedBalance = money.New(a.OpeningBalance.Amount()+total, currency)
+
+	rec.StatementBalance, err = s.statementBalanceCheck(ctx, a, entries)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	return rec, nil
+}
+
+func (s *service) BalanceHistory(ctx context.Context, id int32, from, to *time.Time) ([]DailyBalance, error) {
+	if from != nil && to != nil && from.After(*to) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrValidation)
+	}
+
+	a, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.ledger(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	days := summariseDays(entries, a.OpeningBalance.Amount())
+	if len(days) == 0 && (from == nil || to == nil) {
+		return []DailyBalance{}, nil
+	}
+
+	start, end := from, to
+	if start == nil {
+		start = &days[0].date
+	}
+	if end == nil {
+		end = &days[len(days)-1].date
+	}
+	if end.Sub(*start) > MaxHistoryDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: balance history covers at most %d days", ErrValidation, MaxHistoryDays)
+	}
+
+	currency := a.OpeningBalance.Currency().Code
+	balance := a.OpeningBalance.Amount()
+	series := make([]DailyBalance, 0, int(end.Sub(*start).Hours()/24)+1)
+	next := 0
+	for day := *start; !day.After(*end); day = day.AddDate(0, 0, 1) {
+		var statement *money.Money
+		for next < len(days) && !days[next].date.After(day) {
+			balance = days[next].closing
+			if days[next].date.Equal(day) && days[next].statementClosing != nil {
+				statement = money.New(*days[next].statementClosing, currency)
+			}
+			next++
+		}
+		series = append(series, DailyBalance{
+			Date:             day,
+			Balance:          money.New(balance, currency),
+			StatementBalance: statement,
+		})
+	}
+	return series, nil
+}
+
+// ledger loads the account's transactions in the order they hit the
+// balance. Rows in another currency than the account's cannot be summed and
+// are left out.
+func (s *service) ledger(ctx context.Context, a Account) ([]ledgerEntry, error) {
+	rows, err := s.store.ListAccountLedger(ctx, pgtype.Int4{Int32: a.ID, Valid: true})
+	if err != nil {
+		return nil, wrapQueryError(err)
+	}
+
+	currency := a.OpeningBalance.Currency().Code
+	var entries []ledgerEntry
+	for _, row := range rows {
+		if row.Currency != currency {
+			continue
+		}
+		e := ledgerEntry{id: row.ID, date: row.Date.Time, amount: row.Amount}
+		if row.RunningBalance.Valid {
+			e.runningBalance = &row.RunningBalance.Int64
+		}
+		entries = append(entries, e)
+	}
+	return orderByBalance(entries, a.OpeningBalance.Amount()), nil
+}
+
+// orderByBalance fixes the order of transactions within each day. Rows come
+// back by date and insertion order, but statements disagree on whether the
+// newest row is first or last, so within a day the statement balances are
+// followed instead: the next row is the one whose reported balance follows
+// from the balance so far. Rows that fit nowhere keep insertion order.
+func orderByBalance(entries []ledgerEntry, opening int64) []ledgerEntry {
+	ordered := make([]ledgerEntry, 0, len(entries))
+	balance := opening
+	for start := 0; start < len(entries); {
+		end := start
+		for end < len(entries) && entries[end].date.Equal(entries[start].date) {
+			end++
+		}
+
+		remaining := append([]ledgerEntry(nil), entries[start:end]...)
+		for len(remaining) > 0 {
+			next := 0
+			for i, e := range remaining {
+				if e.runningBalance != nil && *e.runningBalance == balance+e.amount {
+					next = i
+					break
+				}
+			}
+			e := remaining[next]
+			remaining = append(remaining[:next], remaining[next+1:]...)
+			ordered = append(ordered, e)
+
+			balance += e.amount
+			if e.runningBalance != nil {
+				balance = *e.runningBalance
+			}
+		}
+		start = end
+	}
+	return ordered
+}
+
+func summariseDays(entries []ledgerEntry, opening int64) []ledgerDay {
+	var days []ledgerDay
+	balance := opening
+	for _, e := range entries {
+		balance += e.amount
+		if len(days) == 0 || !days[len(days)-1].date.Equal(e.date) {
+			days = append(days, ledgerDay{date: e.date})
+		}
+		day := &days[len(days)-1]
+		day.closing = balance
+		if e.runningBalance != nil {
+			day.statementClosing = e.runningBalance
+		}
+	}
+	return days
+}
+
+func (s *service) statementBalanceCheck(ctx context.Context, a Account, entries []ledgerEntry) (*StatementBalanceCheck, error) {
+	batch, err := s.store.GetLatestImportBalance(ctx, pgtype.Int4{Int32: a.ID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrapQueryError(err)
+	}
+	if !batch.BalanceDate.Valid {
+		return nil, nil
+	}
+
+	computed := a.OpeningBalance.Amount()
+	for _, e := range entries {
+		if e.date.After(batch.BalanceDate.Time) {
+			break
+		}
+		computed += e.amount
+	}
+
+	currency := a.OpeningBalance.Currency().Code
+	return &StatementBalanceCheck{
+		ImportID:   batch.ID,
+		Date:       batch.BalanceDate.Time,
+		Statement:  money.New(batch.ClosingBalance.Int64, currency),
+		Computed:   money.New(computed, currency),
+		Difference: money.New(batch.ClosingBalance.Int64-computed, currency),
+	}, nil
+}
@@ -0,0 +1,198 @@
+package account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func ledgerRow(id int32, d int, amount int64, balance *int64) db.ListAccountLedgerRow {
+	row := db.ListAccountLedgerRow{
+		ID:       id,
+		Date:     pgtype.Date{Time: day(d), Valid: true},
+		Amount:   amount,
+		Currency: "GBP",
+	}
+	if balance != nil {
+		row.RunningBalance = pgtype.Int8{Int64: *balance, Valid: true}
+	}
+	return row
+}
+
+func bal(v int64) *int64 {
+	return &v
+}
+
+func storeWithLedger(opening int64, rows ...db.ListAccountLedgerRow) *mockStore {
+	return &mockStore{
+		getAccountFunc: func(ctx context.Context, id int32) (db.Account, error) {
+			return db.Account{ID: id, Institution: "Nationwide", Currency: "GBP", OpeningBalance: opening}, nil
+		},
+		ledger: rows,
+	}
+}
+
+func TestOrderByBalance_FollowsStatementWithinDay(t *testing.T) {
+	// Inserted newest first, as Nationwide exports them.
+	entries := []ledgerEntry{
+		{id: 1, date: day(15), amount: -300, runningBalance: bal(9200)},
+		{id: 2, date: day(15), amount: -500, runningBalance: bal(9500)},
+		{id: 3, date: day(16), amount: 100, runningBalance: bal(9300)},
+	}
+
+	ordered := orderByBalance(entries, 10000)
+
+	var ids []int32
+	for _, e := range ordered {
+		ids = append(ids, e.id)
+	}
+	assert.Equal(t, []int32{2, 1, 3}, ids)
+}
+
+func TestService_Reconcile_Consistent(t *testing.T) {
+	store := storeWithLedger(10000,
+		ledgerRow(2, 15, -300, bal(9200)),
+		ledgerRow(1, 15, -500, bal(9500)),
+		ledgerRow(3, 16, 2000, bal(11200)),
+	)
+
+	rec, err := NewService(store).Reconcile(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.True(t, rec.Reconciled())
+	assert.Equal(t, 3, rec.CheckedRows)
+	assert.Empty(t, rec.Discrepancies)
+	assert.Equal(t, int64(11200), rec.ComputedBalance.Amount())
+}
+
+func TestService_Reconcile_FlagsMissingRows(t *testing.T) {
+	store := storeWithLedger(10000,
+		ledgerRow(1, 15, -500, bal(9500)),
+		// A £20 debit between these two rows was never imported.
+		ledgerRow(2, 17, -100, bal(9380)),
+		ledgerRow(3, 18, -80, bal(9300)),
+	)
+
+	rec, err := NewService(store).Reconcile(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.False(t, rec.Reconciled())
+	assert.Len(t, rec.Discrepancies, 1)
+	d := rec.Discrepancies[0]
+	assert.Equal(t, DiscrepancyGap, d.Kind)
+	assert.Equal(t, int32(2), d.TransactionID)
+	assert.Equal(t, int64(9400), d.Expected.Amount())
+	assert.Equal(t, int64(9380), d.Statement.Amount())
+	assert.Equal(t, int64(-20), d.Difference.Amount())
+}
+
+func TestService_Reconcile_FlagsOpeningBalance(t *testing.T) {
+	store := storeWithLedger(0,
+		ledgerRow(1, 15, -500, bal(9500)),
+	)
+
+	rec, err := NewService(store).Reconcile(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, rec.Discrepancies, 1)
+	assert.Equal(t, DiscrepancyOpeningBalance, rec.Discrepancies[0].Kind)
+	assert.Equal(t, int64(10000), rec.Discrepancies[0].Difference.Amount())
+}
+
+func TestService_Reconcile_ChecksStatementClosingBalance(t *testing.T) {
+	store := storeWithLedger(10000,
+		ledgerRow(1, 15, -500, nil),
+		ledgerRow(2, 20, -100, nil),
+	)
+	store.latestImport = &db.ImportBatch{
+		ID:             6,
+		ClosingBalance: pgtype.Int8{Int64: 9400, Valid: true},
+		BalanceDate:    pgtype.Date{Time: day(15), Valid: true},
+	}
+
+	rec, err := NewService(store).Reconcile(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, rec.CheckedRows)
+	assert.Equal(t, int32(6), rec.StatementBalance.ImportID)
+	assert.Equal(t, day(15), rec.StatementBalance.Date)
+	assert.Equal(t, int64(9400), rec.StatementBalance.Statement.Amount())
+	assert.Equal(t, int64(9500), rec.StatementBalance.Computed.Amount())
+	assert.Equal(t, int64(-100), rec.StatementBalance.Difference.Amount())
+	assert.False(t, rec.Reconciled())
+}
+
+func TestService_Reconcile_IgnoresOtherCurrencies(t *testing.T) {
+	foreign := ledgerRow(2, 16, -9999, nil)
+	foreign.Currency = "EUR"
+	store := storeWithLedger(1000, ledgerRow(1, 15, -100, bal(900)), foreign)
+
+	rec, err := NewService(store).Reconcile(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(900), rec.ComputedBalance.Amount())
+}
+
+func TestService_BalanceHistory_CarriesBalanceForward(t *testing.T) {
+	store := storeWithLedger(10000,
+		ledgerRow(1, 15, -500, bal(9500)),
+		ledgerRow(2, 17, -100, nil),
+	)
+
+	history, err := NewService(store).BalanceHistory(context.Background(), 1, nil, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, day(15), history[0].Date)
+	assert.Equal(t, int64(9500), history[0].Balance.Amount())
+	assert.Equal(t, int64(9500), history[0].StatementBalance.Amount())
+	assert.Equal(t, int64(9500), history[1].Balance.Amount())
+	assert.Nil(t, history[1].StatementBalance)
+	assert.Equal(t, int64(9400), history[2].Balance.Amount())
+}
+
+func TestService_BalanceHistory_ExplicitRange(t *testing.T) {
+	store := storeWithLedger(10000,
+		ledgerRow(1, 15, -500, nil),
+		ledgerRow(2, 17, -100, nil),
+	)
+	from, to := day(14), day(16)
+
+	history, err := NewService(store).BalanceHistory(context.Background(), 1, &from, &to)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, int64(10000), history[0].Balance.Amount())
+	assert.Equal(t, int64(9500), history[2].Balance.Amount())
+}
+
+func TestService_BalanceHistory_NoTransactions(t *testing.T) {
+	history, err := NewService(storeWithLedger(0)).BalanceHistory(context.Background(), 1, nil, nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestService_BalanceHistory_InvalidRange(t *testing.T) {
+	from, to := day(16), day(14)
+
+	_, err := NewService(storeWithLedger(0)).BalanceHistory(context.Background(), 1, &from, &to)
+
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestService_BalanceHistory_RangeTooLong(t *testing.T) {
+	from, to := day(1), day(1).AddDate(20, 0, 0)
+
+	_, err := NewService(storeWithLedger(0)).BalanceHistory(context.Background(), 1, &from, &to)
+
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,11 @@
+package account
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("account not found")
+	ErrValidation      = errors.New("validation failure")
+	ErrDatabaseFailure = errors.New("database failure")
+	ErrInUse           = errors.New("account in use")
+	ErrAmbiguous       = errors.New("ambiguous account")
+)
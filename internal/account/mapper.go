@@ -0,0 +1,55 @@
+package account
+
+import (
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+)
+
+func AccountFromDB(a db.Account) Account {
+	var maskedNumber *string
+	if a.MaskedNumber.Valid {
+		maskedNumber = &a.MaskedNumber.String
+	}
+
+	return Account{
+		ID:             a.ID,
+		Institution:    a.Institution,
+		Name:           a.Name,
+		MaskedNumber:   maskedNumber,
+		Type:           a.AccountType,
+		OpeningBalance: money.New(a.OpeningBalance, a.Currency),
+		CreatedAt:      a.CreatedAt.Time,
+		UpdatedAt:      a.UpdatedAt.Time,
+	}
+}
+
+func AccountToDB(a Account) db.CreateAccountParams {
+	return db.CreateAccountParams{
+		Institution:    a.Institution,
+		Name:           a.Name,
+		MaskedNumber:   maskedNumberToDB(a.MaskedNumber),
+		AccountType:    a.Type,
+		Currency:       a.OpeningBalance.Currency().Code,
+		OpeningBalance: a.OpeningBalance.Amount(),
+	}
+}
+
+func AccountToUpdateDB(a Account) db.UpdateAccountParams {
+	return db.UpdateAccountParams{
+		ID:             a.ID,
+		Institution:    a.Institution,
+		Name:           a.Name,
+		MaskedNumber:   maskedNumberToDB(a.MaskedNumber),
+		AccountType:    a.Type,
+		Currency:       a.OpeningBalance.Currency().Code,
+		OpeningBalance: a.OpeningBalance.Amount(),
+	}
+}
+
+func maskedNumberToDB(s *string) pgtype.Text {
+	if s == nil || *s == "" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
@@ -0,0 +1,292 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kushturner/finances/internal/db"
+)
+
+// foreignKeyViolation is the Postgres error code raised when deleting an
+// account that transactions still point at.
+const foreignKeyViolation = "23503"
+
+type Service interface {
+	ListAccounts(ctx context.Context) ([]Account, error)
+	GetAccount(ctx context.Context, id int32) (Account, error)
+	CreateAccount(ctx context.Context, a Account) (Account, error)
+	UpdateAccount(ctx context.Context, a Account) (Account, error)
+	DeleteAccount(ctx context.Context, id int32) error
+	// ResolveAccount picks the account an upload belongs to. An explicit id
+	// wins; otherwise the hint is matched by number, or else by name,
+	// against existing accounts at the same institution in the same
+	// currency, creating one if none fits.
+	ResolveAccount(ctx context.Context, id *int32, hint Hint) (Account, error)
+	// MatchAccount is ResolveAccount without writing anything. An account
+	// it would create is returned unsaved, with a zero ID.
+	MatchAccount(ctx context.Context, id *int32, hint Hint) (Account, error)
+	// Reconcile replays the account's stored amounts against the balances
+	// its statements reported.
+	Reconcile(ctx context.Context, id int32) (Reconciliation, error)
+	// BalanceHistory returns the end-of-day balance for every day from
+	// from to to, defaulting to the span of the account's transactions.
+	BalanceHistory(ctx context.Context, id int32, from, to *time.Time) ([]DailyBalance, error)
+	// InTx returns the service running its queries with q, so that an
+	// account it resolves is written in q's transaction, and rolled back
+	// with it.
+	InTx(q db.Querier) Service
+}
+
+type service struct {
+	store db.Store
+}
+
+func NewService(store db.Store) Service {
+	return &service{
+		store: store,
+	}
+}
+
+func (s *service) InTx(q db.Querier) Service {
+	return NewService(db.InTx(q))
+}
+
+func (s *service) ListAccounts(ctx context.Context) ([]Account, error) {
+	dbAccounts, err := s.store.ListAccounts(ctx)
+	if err != nil {
+		return nil, wrapQueryError(err)
+	}
+
+	accounts := make([]Account, 0, len(dbAccounts))
+	for _, a := range dbAccounts {
+		accounts = append(accounts, AccountFromDB(a))
+	}
+	return accounts, nil
+}
+
+func (s *service) GetAccount(ctx context.Context, id int32) (Account, error) {
+	dbAccount, err := s.store.GetAccount(ctx, id)
+	if err != nil {
+		return Account{}, wrapQueryError(err)
+	}
+
+	return AccountFromDB(dbAccount), nil
+}
+
+func (s *service) CreateAccount(ctx context.Context, a Account) (Account, error) {
+	a = normalise(a)
+	if err := a.Validate(); err != nil {
+		return Account{}, err
+	}
+
+	dbAccount, err := s.store.CreateAccount(ctx, AccountToDB(a))
+	if err != nil {
+		return Account{}, wrapQueryError(err)
+	}
+
+	return AccountFromDB(dbAccount), nil
+}
+
+func (s *service) UpdateAccount(ctx context.Context, a Account) (Account, error) {
+	a = normalise(a)
+	if err := a.Validate(); err != nil {
+		return Account{}, err
+	}
+
+	dbAccount, err := s.store.UpdateAccount(ctx, AccountToUpdateDB(a))
+	if err != nil {
+		return Account{}, wrapQueryError(err)
+	}
+
+	return AccountFromDB(dbAccount), nil
+}
+
+func (s *service) DeleteAccount(ctx context.Context, id int32) error {
+	rows, err := s.store.DeleteAccount(ctx, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return fmt.Errorf("%w: account %d still has transactions or imports", ErrInUse, id)
+		}
+		return wrapQueryError(err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *service) ResolveAccount(ctx context.Context, id *int32, hint Hint) (Account, error) {
+	match, err := s.matchAccount(ctx, id, hint)
+	if err != nil {
+		return Account{}, err
+	}
+	switch {
+	case match.create:
+		return s.CreateAccount(ctx, match.account)
+	case match.numbered:
+		return s.UpdateAccount(ctx, match.account)
+	}
+	return match.account, nil
+}
+
+func (s *service) MatchAccount(ctx context.Context, id *int32, hint Hint) (Account, error) {
+	match, err := s.matchAccount(ctx, id, hint)
+	if err != nil {
+		return Account{}, err
+	}
+	return match.account, nil
+}
+
+// accountMatch is the account an upload belongs to, and what has to be
+// written before it can be used.
+type accountMatch struct {
+	account Account
+	// create is set for a new account built from the hint.
+	create bool
+	// numbered is set for an existing account taking the hint's number.
+	numbered bool
+}
+
+func (s *service) matchAccount(ctx context.Context, id *int32, hint Hint) (accountMatch, error) {
+	if id != nil {
+		a, err := s.GetAccount(ctx, *id)
+		return accountMatch{account: a}, err
+	}
+
+	dbAccounts, err := s.store.ListAccountsByInstitution(ctx, hint.Institution)
+	if err != nil {
+		return accountMatch{}, wrapQueryError(err)
+	}
+	// A statement in one currency never belongs to an account in another,
+	// such as the other balances of a Wise or PayPal export.
+	var accounts []Account
+	for _, dbAccount := range dbAccounts {
+		if hint.Currency == "" || strings.EqualFold(dbAccount.Currency, hint.Currency) {
+			accounts = append(accounts, AccountFromDB(dbAccount))
+		}
+	}
+
+	maskedNumber := MaskNumber(hint.MaskedNumber)
+	if maskedNumber == "" {
+		return matchUnnumbered(accounts, hint)
+	}
+
+	var unnumbered []Account
+	for _, a := range accounts {
+		if a.MaskedNumber == nil {
+			unnumbered = append(unnumbered, a)
+			continue
+		}
+		if *a.MaskedNumber == maskedNumber {
+			return accountMatch{account: a}, nil
+		}
+	}
+
+	// An account created before its number was known, typically one
+	// backfilled from the bank name, takes the number on first sight so
+	// later statements keep landing on it. It has to pass the same name
+	// check as an unnumbered hint, or a statement for a new named account
+	// would number an unrelated one.
+	if candidates := byName(unnumbered, hint.Name); len(candidates) == 1 {
+		a := candidates[0]
+		a.MaskedNumber = &maskedNumber
+		return accountMatch{account: a, numbered: true}, nil
+	}
+
+	return accountMatch{account: accountFromHint(hint), create: true}, nil
+}
+
+// matchUnnumbered matches a hint without a number by name. An account
+// still named after its institution, as one created before names were
+// known is, takes any name.
+func matchUnnumbered(accounts []Account, hint Hint) (accountMatch, error) {
+	candidates := byName(accounts, hint.Name)
+	switch len(candidates) {
+	case 0:
+		return accountMatch{account: accountFromHint(hint), create: true}, nil
+	case 1:
+		return accountMatch{account: candidates[0]}, nil
+	default:
+		return accountMatch{}, fmt.Errorf("%w: %d accounts at %s, choose one with account_id",
+			ErrAmbiguous, len(candidates), hint.Institution)
+	}
+}
+
+// byName keeps the accounts a hint's name can belong to: those with that
+// name or, failing any, those still named after their institution. Every
+// account fits a hint without a name.
+func byName(accounts []Account, name string) []Account {
+	if name == "" {
+		return accounts
+	}
+	var named, unnamed []Account
+	for _, a := range accounts {
+		switch {
+		case strings.EqualFold(a.Name, name):
+			named = append(named, a)
+		case strings.EqualFold(a.Name, a.Institution):
+			unnamed = append(unnamed, a)
+		}
+	}
+	if len(named) == 0 {
+		return unnamed
+	}
+	return named
+}
+
+func accountFromHint(hint Hint) Account {
+	name := hint.Name
+	if name == "" {
+		name = hint.Institution
+	}
+	accountType := hint.Type
+	if accountType == "" {
+		accountType = TypeCurrent
+	}
+	currency := hint.Currency
+	if currency == "" {
+		currency = money.GBP
+	}
+
+	var maskedNumber *string
+	if hint.MaskedNumber != "" {
+		maskedNumber = &hint.MaskedNumber
+	}
+
+	return Account{
+		Institution:    hint.Institution,
+		Name:           name,
+		MaskedNumber:   maskedNumber,
+		Type:           accountType,
+		OpeningBalance: money.New(hint.OpeningBalance, currency),
+	}
+}
+
+func normalise(a Account) Account {
+	a.Institution = strings.TrimSpace(a.Institution)
+	a.Name = strings.TrimSpace(a.Name)
+	if a.MaskedNumber != nil {
+		masked := MaskNumber(*a.MaskedNumber)
+		if masked == "" {
+			a.MaskedNumber = nil
+		} else {
+			a.MaskedNumber = &masked
+		}
+	}
+	return a
+}
+
+func wrapQueryError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+}
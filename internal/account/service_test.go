@@ -0,0 +1,427 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockStore embeds db.Store so only the account queries need stubbing;
+// anything else panics, which is what we want if the service strays.
+type mockStore struct {
+	db.Store
+	accounts          []db.Account
+	err               error
+	getAccountFunc    func(ctx context.Context, id int32) (db.Account, error)
+	createAccountFunc func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error)
+	updateAccountFunc func(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error)
+	deleteAccountFunc func(ctx context.Context, id int32) (int64, error)
+	ledger            []db.ListAccountLedgerRow
+	latestImport      *db.ImportBatch
+}
+
+func (m *mockStore) ListAccountLedger(ctx context.Context, accountID pgtype.Int4) ([]db.ListAccountLedgerRow, error) {
+	return m.ledger, m.err
+}
+
+func (m *mockStore) GetLatestImportBalance(ctx context.Context, accountID pgtype.Int4) (db.ImportBatch, error) {
+	if m.latestImport == nil {
+		return db.ImportBatch{}, pgx.ErrNoRows
+	}
+	return *m.latestImport, nil
+}
+
+func (m *mockStore) ListAccounts(ctx context.Context) ([]db.Account, error) {
+	return m.accounts, m.err
+}
+
+func (m *mockStore) ListAccountsByInstitution(ctx context.Context, institution string) ([]db.Account, error) {
+	var matches []db.Account
+	for _, a := range m.accounts {
+		if a.Institution == institution {
+			matches = append(matches, a)
+		}
+	}
+	return matches, m.err
+}
+
+func (m *mockStore) GetAccount(ctx context.Context, id int32) (db.Account, error) {
+	if m.getAccountFunc != nil {
+		return m.getAccountFunc(ctx, id)
+	}
+	return db.Account{}, pgx.ErrNoRows
+}
+
+func (m *mockStore) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	if m.createAccountFunc != nil {
+		return m.createAccountFunc(ctx, arg)
+	}
+	return db.Account{
+		ID:             100,
+		Institution:    arg.Institution,
+		Name:           arg.Name,
+		MaskedNumber:   arg.MaskedNumber,
+		AccountType:    arg.AccountType,
+		Currency:       arg.Currency,
+		OpeningBalance: arg.OpeningBalance,
+	}, nil
+}
+
+func (m *mockStore) UpdateAccount(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+	if m.updateAccountFunc != nil {
+		return m.updateAccountFunc(ctx, arg)
+	}
+	return db.Account{
+		ID:             arg.ID,
+		Institution:    arg.Institution,
+		Name:           arg.Name,
+		MaskedNumber:   arg.MaskedNumber,
+		AccountType:    arg.AccountType,
+		Currency:       arg.Currency,
+		OpeningBalance: arg.OpeningBalance,
+	}, nil
+}
+
+func (m *mockStore) DeleteAccount(ctx context.Context, id int32) (int64, error) {
+	if m.deleteAccountFunc != nil {
+		return m.deleteAccountFunc(ctx, id)
+	}
+	return 1, nil
+}
+
+func TestService_CreateAccount_MasksNumber(t *testing.T) {
+	var created db.CreateAccountParams
+	store := &mockStore{
+		createAccountFunc: func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			created = arg
+			return db.Account{ID: 1, Institution: arg.Institution, Currency: arg.Currency}, nil
+		},
+	}
+	number := "12-34-56 12345678"
+
+	service := NewService(store)
+	_, err := service.CreateAccount(context.Background(), Account{
+		Institution:    " Nationwide ",
+		Name:           "FlexDirect",
+		MaskedNumber:   &number,
+		Type:           TypeCurrent,
+		OpeningBalance: money.New(10000, "GBP"),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Nationwide", created.Institution)
+	assert.Equal(t, pgtype.Text{String: "****5678", Valid: true}, created.MaskedNumber)
+	assert.Equal(t, int64(10000), created.OpeningBalance)
+}
+
+func TestService_CreateAccount_ValidationError(t *testing.T) {
+	service := NewService(&mockStore{})
+	_, err := service.CreateAccount(context.Background(), Account{
+		Institution:    "Nationwide",
+		Name:           "FlexDirect",
+		Type:           "brokerage",
+		OpeningBalance: money.New(0, "GBP"),
+	})
+
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestService_GetAccount_NotFound(t *testing.T) {
+	service := NewService(&mockStore{})
+	_, err := service.GetAccount(context.Background(), 5)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_DeleteAccount_InUse(t *testing.T) {
+	store := &mockStore{
+		deleteAccountFunc: func(ctx context.Context, id int32) (int64, error) {
+			return 0, &pgconn.PgError{Code: foreignKeyViolation}
+		},
+	}
+
+	err := NewService(store).DeleteAccount(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrInUse)
+}
+
+func TestService_DeleteAccount_NotFound(t *testing.T) {
+	store := &mockStore{
+		deleteAccountFunc: func(ctx context.Context, id int32) (int64, error) {
+			return 0, nil
+		},
+	}
+
+	err := NewService(store).DeleteAccount(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_ResolveAccount_ExplicitID(t *testing.T) {
+	store := &mockStore{
+		getAccountFunc: func(ctx context.Context, id int32) (db.Account, error) {
+			return db.Account{ID: id, Institution: "Nationwide", Currency: "GBP"}, nil
+		},
+	}
+	id := int32(7)
+
+	account, err := NewService(store).ResolveAccount(context.Background(), &id, Hint{Institution: "Nationwide"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), account.ID)
+}
+
+func TestService_ResolveAccount_ExplicitIDNotFound(t *testing.T) {
+	id := int32(7)
+
+	_, err := NewService(&mockStore{}).ResolveAccount(context.Background(), &id, Hint{Institution: "Nationwide"})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_ResolveAccount_MatchesMaskedNumber(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "Nationwide", Name: "Joint", MaskedNumber: pgtype.Text{String: "****99999", Valid: true}, Currency: "GBP"},
+			{ID: 2, Institution: "Nationwide", Name: "Debit", MaskedNumber: pgtype.Text{String: "****12345", Valid: true}, Currency: "GBP"},
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution:  "Nationwide",
+		Name:         "Debit",
+		MaskedNumber: "****12345",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), account.ID)
+}
+
+func TestService_ResolveAccount_ClaimsUnnumberedAccount(t *testing.T) {
+	var updated db.UpdateAccountParams
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "Nationwide", Name: "Nationwide", AccountType: TypeCurrent, Currency: "GBP"},
+		},
+		updateAccountFunc: func(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+			updated = arg
+			return db.Account{ID: arg.ID, MaskedNumber: arg.MaskedNumber, Currency: arg.Currency}, nil
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution:  "Nationwide",
+		MaskedNumber: "****12345",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), account.ID)
+	assert.Equal(t, pgtype.Text{String: "****12345", Valid: true}, updated.MaskedNumber)
+}
+
+func TestService_ResolveAccount_NumberedHintSkipsDifferentlyNamedAccount(t *testing.T) {
+	var created db.CreateAccountParams
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "Revolut", Name: "Savings", AccountType: TypeSavings, Currency: "GBP"},
+		},
+		updateAccountFunc: func(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+			t.Fatal("the savings account must not take the current account's number")
+			return db.Account{}, nil
+		},
+		createAccountFunc: func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			created = arg
+			return db.Account{ID: 2, Currency: arg.Currency}, nil
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution:  "Revolut",
+		Name:         "Current",
+		MaskedNumber: "****12345",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), account.ID)
+	assert.Equal(t, "Current", created.Name)
+	assert.Equal(t, pgtype.Text{String: "****12345", Valid: true}, created.MaskedNumber)
+}
+
+func TestService_ResolveAccount_CreatesAccountForNewNumber(t *testing.T) {
+	var created db.CreateAccountParams
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "Nationwide", MaskedNumber: pgtype.Text{String: "****99999", Valid: true}, Currency: "GBP"},
+		},
+		createAccountFunc: func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			created = arg
+			return db.Account{ID: 2, Currency: arg.Currency}, nil
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution:  "Nationwide",
+		Name:         "Debit",
+		MaskedNumber: "****12345",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), account.ID)
+	assert.Equal(t, db.CreateAccountParams{
+		Institution:  "Nationwide",
+		Name:         "Debit",
+		MaskedNumber: pgtype.Text{String: "****12345", Valid: true},
+		AccountType:  TypeCurrent,
+		Currency:     "GBP",
+	}, created)
+}
+
+func TestService_ResolveAccount_CreatesFirstAccountForInstitution(t *testing.T) {
+	account, err := NewService(&mockStore{}).ResolveAccount(context.Background(), nil, Hint{
+		Institution: "American Express",
+		Type:        TypeCredit,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "American Express", account.Name)
+	assert.Equal(t, TypeCredit, account.Type)
+	assert.Nil(t, account.MaskedNumber)
+}
+
+func TestService_ResolveAccount_NewAccountOpensWithStatementBalance(t *testing.T) {
+	var created db.CreateAccountParams
+	store := &mockStore{
+		createAccountFunc: func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			created = arg
+			return db.Account{ID: 1, OpeningBalance: arg.OpeningBalance, Currency: arg.Currency}, nil
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution:    "Monzo",
+		Currency:       "GBP",
+		OpeningBalance: 25000,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25000), created.OpeningBalance)
+	assert.Equal(t, int64(25000), account.OpeningBalance.Amount())
+}
+
+func TestService_ResolveAccount_SingleAccountWithoutNumber(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{{ID: 3, Institution: "American Express", Currency: "GBP"}},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{Institution: "American Express"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), account.ID)
+}
+
+func TestService_ResolveAccount_CreatesAccountForNewCurrency(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{{ID: 1, Institution: "Wise", Name: "Wise GBP", Currency: "GBP"}},
+		createAccountFunc: func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			return db.Account{ID: 2, Name: arg.Name, Currency: arg.Currency}, nil
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution: "Wise",
+		Name:        "Wise EUR",
+		Currency:    "EUR",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), account.ID)
+	assert.Equal(t, "EUR", account.OpeningBalance.Currency().Code)
+}
+
+func TestService_ResolveAccount_MatchesName(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "Revolut", Name: "Current GBP", Currency: "GBP"},
+			{ID: 2, Institution: "Revolut", Name: "Savings GBP", Currency: "GBP"},
+		},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution: "Revolut",
+		Name:        "Savings GBP",
+		Currency:    "GBP",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), account.ID)
+}
+
+func TestService_ResolveAccount_NamedHintTakesInstitutionNamedAccount(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{{ID: 1, Institution: "Revolut", Name: "Revolut", Currency: "GBP"}},
+	}
+
+	account, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{
+		Institution: "Revolut",
+		Name:        "Current GBP",
+		Currency:    "GBP",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), account.ID)
+}
+
+func TestService_ResolveAccount_Ambiguous(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "American Express", Currency: "GBP"},
+			{ID: 2, Institution: "American Express", Currency: "GBP"},
+		},
+	}
+
+	_, err := NewService(store).ResolveAccount(context.Background(), nil, Hint{Institution: "American Express"})
+
+	assert.ErrorIs(t, err, ErrAmbiguous)
+}
+
+func TestService_MatchAccount_WritesNothing(t *testing.T) {
+	store := &mockStore{
+		accounts: []db.Account{
+			{ID: 1, Institution: "Nationwide", Name: "Nationwide", AccountType: TypeCurrent, Currency: "GBP"},
+		},
+		createAccountFunc: func(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			t.Error("MatchAccount created an account")
+			return db.Account{}, nil
+		},
+		updateAccountFunc: func(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+			t.Error("MatchAccount updated an account")
+			return db.Account{}, nil
+		},
+	}
+	service := NewService(store)
+
+	claimed, err := service.MatchAccount(context.Background(), nil, Hint{Institution: "Nationwide", MaskedNumber: "****12345"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), claimed.ID)
+	assert.Equal(t, "****12345", *claimed.MaskedNumber)
+
+	created, err := service.MatchAccount(context.Background(), nil, Hint{Institution: "Monzo", Name: "Joint"})
+	assert.NoError(t, err)
+	assert.Zero(t, created.ID)
+	assert.Equal(t, "Joint", created.Name)
+}
+
+func TestService_ListAccounts_DatabaseError(t *testing.T) {
+	store := &mockStore{err: errors.New("connection refused")}
+
+	_, err := NewService(store).ListAccounts(context.Background())
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+}
@@ -0,0 +1,65 @@
+package account
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Rhymond/go-money"
+)
+
+const (
+	maxInstitutionLength  = 100
+	maxNameLength         = 100
+	maxMaskedNumberLength = 50
+)
+
+func (a Account) Validate() error {
+	if strings.TrimSpace(a.Institution) == "" {
+		return fmt.Errorf("%w: institution is required", ErrValidation)
+	}
+	if len(a.Institution) > maxInstitutionLength {
+		return fmt.Errorf("%w: institution must be at most %d characters", ErrValidation, maxInstitutionLength)
+	}
+	if strings.TrimSpace(a.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrValidation)
+	}
+	if len(a.Name) > maxNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrValidation, maxNameLength)
+	}
+	if a.MaskedNumber != nil && len(*a.MaskedNumber) > maxMaskedNumberLength {
+		return fmt.Errorf("%w: masked_number must be at most %d characters", ErrValidation, maxMaskedNumberLength)
+	}
+	switch a.Type {
+	case TypeCurrent, TypeCredit, TypeSavings, TypeCash:
+	default:
+		return fmt.Errorf("%w: type must be one of current, credit, savings or cash", ErrValidation)
+	}
+	if a.OpeningBalance == nil {
+		return fmt.Errorf("%w: opening balance is required", ErrValidation)
+	}
+	if money.GetCurrency(a.OpeningBalance.Currency().Code) == nil {
+		return fmt.Errorf("%w: unsupported currency %q", ErrValidation, a.OpeningBalance.Currency().Code)
+	}
+	return nil
+}
+
+// MaskNumber keeps only the last four digits of a full account or card
+// number so that it is never stored in the clear. Values that are already
+// masked are returned unchanged, apart from surrounding whitespace.
+func MaskNumber(number string) string {
+	number = strings.TrimSpace(number)
+	if strings.Contains(number, "*") {
+		return number
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+	if len(digits) <= 4 {
+		return number
+	}
+	return "****" + digits[len(digits)-4:]
+}
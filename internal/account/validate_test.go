@@ -0,0 +1,57 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/stretchr/testify/assert"
+)
+
+func validAccount() Account {
+	return Account{
+		Institution:    "Nationwide",
+		Name:           "FlexDirect",
+		Type:           TypeCurrent,
+		OpeningBalance: money.New(0, "GBP"),
+	}
+}
+
+func TestAccount_Validate(t *testing.T) {
+	long := string(make([]byte, 101))
+	tests := []struct {
+		name   string
+		modify func(a *Account)
+	}{
+		{"missing institution", func(a *Account) { a.Institution = " " }},
+		{"institution too long", func(a *Account) { a.Institution = long }},
+		{"missing name", func(a *Account) { a.Name = "" }},
+		{"name too long", func(a *Account) { a.Name = long }},
+		{"unknown type", func(a *Account) { a.Type = "brokerage" }},
+		{"missing opening balance", func(a *Account) { a.OpeningBalance = nil }},
+		{"unknown currency", func(a *Account) { a.OpeningBalance = money.New(0, "XXZ") }},
+	}
+
+	assert.NoError(t, validAccount().Validate())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := validAccount()
+			tt.modify(&a)
+			assert.ErrorIs(t, a.Validate(), ErrValidation)
+		})
+	}
+}
+
+func TestMaskNumber(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"****12345":         "****12345",
+		" Debit ****1 ":     "Debit ****1",
+		"1234":              "1234",
+		"12345678":          "****5678",
+		"12-34-56 12345678": "****5678",
+		"3782 822463 10005": "****0005",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, MaskNumber(input), input)
+	}
+}
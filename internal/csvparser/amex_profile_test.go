@@ -0,0 +1,22 @@
+package csvparser
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmexProfile_Parse_CapturesReference(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	parser := bundledParser("amex")
+	transactions, err := parser.Parse(file)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, transactions[0].ExternalID)
+	assert.Equal(t, "AT123456789", *transactions[0].ExternalID)
+	assert.Equal(t, "AT987654321", *transactions[1].ExternalID)
+}
@@ -0,0 +1,188 @@
+package csvparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Rhymond/go-money"
+)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+func parseAmount(amountStr string) (*money.Money, error) {
+	return parseAmountIn(amountStr, money.GBP)
+}
+
+// parseAmountIn converts a statement amount into minor units of currency
+// without going through floating point. It accepts currency symbols or the
+// currency code, thousands separators, a leading or trailing minus, a
+// leading plus, parentheses for negatives and CR/DR suffixes.
+func parseAmountIn(amountStr string, currencyCode string) (*money.Money, error) {
+	currency := money.GetCurrency(currencyCode)
+	if currency == nil {
+		return nil, fmt.Errorf("%w: unknown currency %q", ErrInvalidAmount, currencyCode)
+	}
+
+	minor, err := parseMinorUnits(amountStr, currency)
+	if err != nil {
+		return nil, err
+	}
+	return money.New(minor, currency.Code), nil
+}
+
+// parseAmountWithDecimal is parseAmountIn for statements that write
+// amounts with the given decimal separator rather than the currency's usual
+// one, such as "1.234,56". The other of '.' and ',' is then the thousands
+// separator.
+func parseAmountWithDecimal(amountStr string, currencyCode string, decimal string) (*money.Money, error) {
+	currency := money.GetCurrency(currencyCode)
+	if currency == nil {
+		return nil, fmt.Errorf("%w: unknown currency %q", ErrInvalidAmount, currencyCode)
+	}
+	if decimal != "" && decimal != currency.Decimal {
+		local := *currency
+		local.Decimal = decimal
+		local.Thousand = ","
+		if decimal == "," {
+			local.Thousand = "."
+		}
+		currency = &local
+	}
+
+	minor, err := parseMinorUnits(amountStr, currency)
+	if err != nil {
+		return nil, err
+	}
+	return money.New(minor, currency.Code), nil
+}
+
+func parseMinorUnits(amountStr string, currency *money.Currency) (int64, error) {
+	s := strings.TrimSpace(amountStr)
+	negative := false
+	signs := 0
+
+	upper := strings.ToUpper(s)
+	switch {
+	case strings.HasSuffix(upper, "DR"):
+		negative = true
+		signs++
+		s = strings.TrimSpace(s[:len(s)-2])
+	case strings.HasSuffix(upper, "CR"):
+		signs++
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+
+	if len(s) >= 3 && strings.EqualFold(s[:3], currency.Code) {
+		s = s[3:]
+	} else if len(s) >= 3 && strings.EqualFold(s[len(s)-3:], currency.Code) {
+		s = s[:len(s)-3]
+	}
+
+	// Drop currency symbols and spaces, keeping only the characters that
+	// carry meaning. Letters are rejected so that text is never read as 0.
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9', c == '-', c == '+', c == '(', c == ')',
+			strings.IndexByte(currency.Decimal, c) >= 0, strings.IndexByte(currency.Thousand, c) >= 0:
+			b.WriteByte(c)
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			return 0, fmt.Errorf("%w: unexpected character %q in %q", ErrInvalidAmount, c, amountStr)
+		}
+	}
+	s = b.String()
+
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		signs++
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		signs++
+		s = s[1:]
+	}
+	if strings.HasPrefix(s, "(") || strings.HasSuffix(s, ")") {
+		if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+			return 0, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidAmount, amountStr)
+		}
+		negative = true
+		signs++
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		negative = true
+		signs++
+		s = s[:len(s)-1]
+	}
+
+	if s == "" {
+		return 0, fmt.Errorf("empty amount string")
+	}
+	if signs > 1 {
+		return 0, fmt.Errorf("%w: conflicting sign markers in %q", ErrInvalidAmount, amountStr)
+	}
+	if strings.ContainsAny(s, "+-()") {
+		return 0, fmt.Errorf("%w: misplaced sign in %q", ErrInvalidAmount, amountStr)
+	}
+
+	whole, fraction, hasDecimal := strings.Cut(s, currency.Decimal)
+	if strings.Contains(fraction, currency.Decimal) {
+		return 0, fmt.Errorf("%w: multiple decimal separators in %q", ErrInvalidAmount, amountStr)
+	}
+	if hasDecimal && fraction == "" && whole == "" {
+		return 0, fmt.Errorf("%w: no digits in %q", ErrInvalidAmount, amountStr)
+	}
+
+	whole, err := stripThousands(whole, currency.Thousand)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s in %q", ErrInvalidAmount, err, amountStr)
+	}
+	if strings.Contains(fraction, currency.Thousand) {
+		return 0, fmt.Errorf("%w: thousands separator after decimal point in %q", ErrInvalidAmount, amountStr)
+	}
+
+	// Extra decimal places are only accepted when they are zeros, so
+	// "4.350" is fine for GBP but "4.355" would need rounding and is not.
+	if len(fraction) > currency.Fraction {
+		if strings.Trim(fraction[currency.Fraction:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q has more than %d decimal places for %s", ErrInvalidAmount, amountStr, currency.Fraction, currency.Code)
+		}
+		fraction = fraction[:currency.Fraction]
+	}
+	fraction += strings.Repeat("0", currency.Fraction-len(fraction))
+
+	digits := strings.TrimLeft(whole+fraction, "0")
+	if digits == "" {
+		return 0, nil
+	}
+	if negative {
+		digits = "-" + digits
+	}
+
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amountStr)
+	}
+	return minor, nil
+}
+
+// stripThousands removes grouping separators from the integer part of an
+// amount, insisting that they sit between groups of three digits.
+func stripThousands(whole string, separator string) (string, error) {
+	if !strings.Contains(whole, separator) {
+		return whole, nil
+	}
+
+	groups := strings.Split(whole, separator)
+	if len(groups[0]) == 0 || len(groups[0]) > 3 {
+		return "", errors.New("misplaced thousands separator")
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return "", errors.New("misplaced thousands separator")
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
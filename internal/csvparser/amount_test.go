@@ -0,0 +1,274 @@
+package csvparser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount_UTF8PoundSign(t *testing.T) {
+	result, err := parseAmount("£100.00")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000), result.Amount())
+}
+
+func TestParseAmount_ISO88591PoundSign(t *testing.T) {
+	result, err := parseAmount("\xa3100.00")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000), result.Amount())
+}
+
+func TestParseAmount_NegativeWithUTF8Pound(t *testing.T) {
+	result, err := parseAmount("-£100.00")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-10000), result.Amount())
+}
+
+func TestParseAmount_NegativeWithISO88591Pound(t *testing.T) {
+	result, err := parseAmount("-\xa3100.00")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-10000), result.Amount())
+}
+
+func TestParseAmount_WithCommas(t *testing.T) {
+	result, err := parseAmount("£1,234.56")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456), result.Amount())
+}
+
+func TestParseAmount_WithoutCurrencySymbol(t *testing.T) {
+	result, err := parseAmount("50.75")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5075), result.Amount())
+}
+
+func TestParseAmount_EmptyString(t *testing.T) {
+	_, err := parseAmount("")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "empty amount string")
+}
+
+func TestParseAmount_OnlyCurrencySymbol(t *testing.T) {
+	_, err := parseAmount("£")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "empty amount string")
+}
+
+func TestParseAmount_InvalidFormat(t *testing.T) {
+	_, err := parseAmount("abc")
+
+	assert.Error(t, err)
+}
+
+func TestParseAmount_MultipleDecimalPoints(t *testing.T) {
+	_, err := parseAmount("£10.50.25")
+
+	assert.Error(t, err)
+}
+
+func TestParseAmount_Whitespace(t *testing.T) {
+	result, err := parseAmount("  £100.00  ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000), result.Amount())
+}
+
+func TestParseAmount_SmallAmount(t *testing.T) {
+	result, err := parseAmount("£0.01")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Amount())
+}
+
+func TestParseAmount_Zero(t *testing.T) {
+	result, err := parseAmount("£0.00")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Amount())
+}
+
+func TestParseAmount_ExactForValuesThatFloatsTruncate(t *testing.T) {
+	cases := map[string]int64{
+		"4.35":  435,
+		"0.29":  29,
+		"1.15":  115,
+		"8.20":  820,
+		"19.99": 1999,
+	}
+
+	for input, expected := range cases {
+		result, err := parseAmount(input)
+
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, result.Amount(), input)
+	}
+}
+
+func TestParseAmount_SignConventions(t *testing.T) {
+	cases := map[string]int64{
+		"1,234.56-":  -123456,
+		"(12.00)":    -1200,
+		"(£12.00)":   -1200,
+		"+5.00":      500,
+		"12.00 DR":   -1200,
+		"12.00DR":    -1200,
+		"12.00 CR":   1200,
+		"12.00 cr":   1200,
+		"GBP 12.00":  1200,
+		"-12.00 GBP": -1200,
+		".5":         50,
+		"7":          700,
+		"4.350":      435,
+	}
+
+	for input, expected := range cases {
+		result, err := parseAmount(input)
+
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, result.Amount(), input)
+	}
+}
+
+func TestParseAmount_Errors(t *testing.T) {
+	cases := map[string]string{
+		"-(12.00)":          "conflicting sign markers",
+		"-12.00-":           "conflicting sign markers",
+		"(12.00) DR":        "conflicting sign markers",
+		"--12.00":           "misplaced sign",
+		"(12.00":            "unbalanced parentheses",
+		"12-00":             "misplaced sign",
+		"12,34.00":          "misplaced thousands separator",
+		"1234,567.00":       "misplaced thousands separator",
+		"12.3,4":            "thousands separator after decimal point",
+		"4.355":             "more than 2 decimal places for GBP",
+		"12.00 USD":         "unexpected character",
+		"99999999999999999": "out of range",
+	}
+
+	for input, message := range cases {
+		_, err := parseAmount(input)
+
+		assert.ErrorIs(t, err, ErrInvalidAmount, input)
+		assert.Contains(t, err.Error(), message, input)
+	}
+}
+
+func TestParseAmountIn_HonoursCurrencyFraction(t *testing.T) {
+	yen, err := parseAmountIn("¥1,500", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), yen.Amount())
+	assert.Equal(t, "JPY", yen.Currency().Code)
+
+	_, err = parseAmountIn("1,500.5", "JPY")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	dinar, err := parseAmountIn("12.345", "BHD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), dinar.Amount())
+}
+
+func TestParseAmountIn_UnknownCurrency(t *testing.T) {
+	_, err := parseAmountIn("1.00", "ZZZ")
+
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	assert.Contains(t, err.Error(), "unknown currency")
+}
+
+// formatMinorUnits renders an amount the way statements do, so the fuzz
+// tests can check that parsing it returns the original value.
+func formatMinorUnits(minor int64, fraction int, grouped bool, style int) string {
+	negative := minor < 0
+	if negative {
+		minor = -minor
+	}
+	digits := fmt.Sprintf("%0*d", fraction+1, minor)
+	whole, frac := digits[:len(digits)-fraction], digits[len(digits)-fraction:]
+
+	if grouped {
+		var groups []string
+		for len(whole) > 3 {
+			groups = append([]string{whole[len(whole)-3:]}, groups...)
+			whole = whole[:len(whole)-3]
+		}
+		whole = strings.Join(append([]string{whole}, groups...), ",")
+	}
+
+	s := whole
+	if fraction > 0 {
+		s += "." + frac
+	}
+	if !negative {
+		return "£" + s
+	}
+	switch style % 4 {
+	case 0:
+		return "-£" + s
+	case 1:
+		return s + "-"
+	case 2:
+		return "(" + s + ")"
+	default:
+		return s + " DR"
+	}
+}
+
+func FuzzParseAmount_RoundTrip(f *testing.F) {
+	f.Add(int64(435), true, 0)
+	f.Add(int64(-123456), true, 1)
+	f.Add(int64(-1200), false, 2)
+	f.Add(int64(0), false, 3)
+	f.Add(int64(1), true, 0)
+
+	f.Fuzz(func(t *testing.T, minor int64, grouped bool, style int) {
+		if minor == -minor && minor != 0 {
+			t.Skip("cannot negate math.MinInt64")
+		}
+		if style < 0 {
+			style = -style
+		}
+
+		input := formatMinorUnits(minor, 2, grouped, style)
+		result, err := parseAmount(input)
+
+		if err != nil {
+			t.Fatalf("parseAmount(%q): %v", input, err)
+		}
+		if result.Amount() != minor {
+			t.Fatalf("parseAmount(%q) = %d, want %d", input, result.Amount(), minor)
+		}
+	})
+}
+
+func FuzzParseAmount_ArbitraryInput(f *testing.F) {
+	for _, seed := range []string{"£1,234.56", "(12.00)", "1,234.56-", "12.00 CR", "\xa3100.00", "abc", "", "-", "1e5"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		result, err := parseAmount(input)
+		if err != nil {
+			return
+		}
+
+		// Anything accepted must survive a format and re-parse unchanged.
+		formatted := formatMinorUnits(result.Amount(), money.GetCurrency("GBP").Fraction, true, 0)
+		again, err := parseAmount(formatted)
+		if err != nil {
+			t.Fatalf("re-parsing %q (from %q): %v", formatted, input, err)
+		}
+		if again.Amount() != result.Amount() {
+			t.Fatalf("re-parsing %q (from %q) = %d, want %d", formatted, input, again.Amount(), result.Amount())
+		}
+	})
+}
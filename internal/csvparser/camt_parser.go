@@ -0,0 +1,456 @@
+package csvparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// camtInstitution is recorded when the statement does not name its bank.
+const camtInstitution = "camt"
+
+// maxDescriptionLength matches the transaction description limit, which
+// joined remittance lines and narratives can run past.
+const maxDescriptionLength = 500
+
+// CamtParser reads ISO 20022 bank-to-customer statements: camt.053
+// end-of-day statements and camt.052 intraday account reports, in any
+// schema version. A file can hold several statements, one per account or
+// currency. Amounts are unsigned in the file, with CdtDbtInd saying which
+// way the money moved.
+type CamtParser struct{}
+
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	ID       string        `xml:"Id"`
+	Account  camtAccount   `xml:"Acct"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+	Name     string `xml:"Nm"`
+	// Older schema versions name the servicer's BIC BIC, newer ones BICFI.
+	ServicerName  string `xml:"Svcr>FinInstnId>Nm"`
+	ServicerBIC   string `xml:"Svcr>FinInstnId>BIC"`
+	ServicerBICFI string `xml:"Svcr>FinInstnId>BICFI"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+// camtStatus is written as <Sts>BOOK</Sts> before camt.053.001.08 and as
+// <Sts><Cd>BOOK</Cd></Sts> from then on.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtEntry struct {
+	Ref            string          `xml:"NtryRef"`
+	Amount         camtAmount      `xml:"Amt"`
+	CdtDbtInd      string          `xml:"CdtDbtInd"`
+	Status         camtStatus      `xml:"Sts"`
+	BookingDate    camtDate        `xml:"BookgDt"`
+	ValueDate      camtDate        `xml:"ValDt"`
+	AcctSvcrRef    string          `xml:"AcctSvcrRef"`
+	Details        []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string          `xml:"AddtlNtryInf"`
+}
+
+type camtTxDetails struct {
+	AcctSvcrRef string `xml:"Refs>AcctSvcrRef"`
+	// Newer schema versions give the amount directly, older ones only in
+	// AmtDtls.
+	Amount         *camtAmount `xml:"Amt"`
+	TxAmount       *camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	CdtDbtInd      string      `xml:"CdtDbtInd"`
+	Debtor         camtParty   `xml:"RltdPties>Dbtr"`
+	Creditor       camtParty   `xml:"RltdPties>Cdtr"`
+	Unstructured   []string    `xml:"RmtInf>Ustrd"`
+	AdditionalInfo string      `xml:"AddtlTxInf"`
+}
+
+// camtParty is written as <Dbtr><Nm> before camt.053.001.08 and as
+// <Dbtr><Pty><Nm> from then on.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	return strings.TrimSpace(firstNonEmpty(p.Name, p.PartyName))
+}
+
+func (d camtTxDetails) amount() *camtAmount {
+	if d.Amount != nil {
+		return d.Amount
+	}
+	return d.TxAmount
+}
+
+func (p *CamtParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	return parseSingleStatement(p, r)
+}
+
+// ParseStatements reads every statement and report in the file, in file
+// order. Pending and informational entries are left out; only booked
+// entries have reached the balance.
+func (p *CamtParser) ParseStatements(r io.Reader) ([]Statement, error) {
+	var doc camtDocument
+	decoder := xml.NewDecoder(r)
+	// The service has already decoded the file to UTF-8, so whatever
+	// charset the declaration names is read as is.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing camt file: %w", err)
+	}
+
+	stmts := append(doc.Statements, doc.Reports...)
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("no camt statement or report found")
+	}
+
+	statements := make([]Statement, 0, len(stmts))
+	for i, stmt := range stmts {
+		statement, err := camtStatementFrom(stmt)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+func camtStatementFrom(stmt camtStatement) (Statement, error) {
+	currency := strings.ToUpper(strings.TrimSpace(stmt.Account.Currency))
+	if currency == "" && len(stmt.Entries) > 0 {
+		currency = strings.ToUpper(stmt.Entries[0].Amount.Currency)
+	}
+	if currency == "" {
+		currency = money.GBP
+	}
+	if money.GetCurrency(currency) == nil {
+		return Statement{}, fmt.Errorf("unknown currency %q", currency)
+	}
+
+	statement := Statement{
+		Institution: strings.TrimSpace(firstNonEmpty(stmt.Account.ServicerName, stmt.Account.ServicerBIC, stmt.Account.ServicerBICFI)),
+		Account: AccountDetails{
+			Name:         strings.TrimSpace(stmt.Account.Name),
+			MaskedNumber: account.MaskNumber(strings.TrimSpace(firstNonEmpty(stmt.Account.IBAN, stmt.Account.Other))),
+		},
+	}
+	if statement.Institution == "" {
+		statement.Institution = camtInstitution
+	}
+
+	if err := camtBalances(&statement, stmt.Balances, currency); err != nil {
+		return Statement{}, err
+	}
+
+	for i, entry := range stmt.Entries {
+		if !entry.booked() {
+			continue
+		}
+		transactions, err := camtTransactions(entry, currency)
+		if err != nil {
+			return Statement{}, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		for j := range transactions {
+			transactions[j].Bank = statement.Institution
+		}
+		statement.Transactions = append(statement.Transactions, transactions...)
+	}
+	return statement, nil
+}
+
+// camtBalances picks the statement's balances by type code. A camt.053
+// reports opening and closing booked balances (OPBD, CLBD); a camt.052
+// report often has only interim ones (ITBD), of which the last is the
+// latest. PRCD, the previous day's closing balance, stands in for a
+// missing opening balance.
+func camtBalances(statement *Statement, balances []camtBalance, currency string) error {
+	var opening, closing, available *camtBalance
+	for i := range balances {
+		bal := &balances[i]
+		switch strings.ToUpper(strings.TrimSpace(bal.Code)) {
+		case "OPBD":
+			opening = bal
+		case "PRCD":
+			if opening == nil {
+				opening = bal
+			}
+		case "CLBD":
+			closing = bal
+		case "ITBD":
+			if closing == nil || strings.EqualFold(closing.Code, "ITBD") {
+				closing = bal
+			}
+		case "CLAV":
+			available = bal
+		case "ITAV":
+			if available == nil || strings.EqualFold(available.Code, "ITAV") {
+				available = bal
+			}
+		}
+	}
+
+	var err error
+	if opening != nil {
+		if statement.OpeningBalance, statement.OpeningBalanceDate, err = opening.parse(currency); err != nil {
+			return fmt.Errorf("parsing opening balance: %w", err)
+		}
+	}
+	if closing != nil {
+		if statement.ClosingBalance, statement.BalanceDate, err = closing.parse(currency); err != nil {
+			return fmt.Errorf("parsing closing balance: %w", err)
+		}
+	}
+	if available != nil {
+		if statement.AvailableBalance, _, err = available.parse(currency); err != nil {
+			return fmt.Errorf("parsing available balance: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b camtBalance) parse(currency string) (*money.Money, time.Time, error) {
+	amount, err := camtMoney(b.Amount, b.CdtDbtInd, currency)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var date time.Time
+	if b.Date.Date != "" || b.Date.DateTime != "" {
+		if date, err = b.Date.parse(); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+	return amount, date, nil
+}
+
+func (e camtEntry) booked() bool {
+	status := strings.ToUpper(strings.TrimSpace(firstNonEmpty(e.Status.Code, e.Status.Text)))
+	return status == "" || status == "BOOK"
+}
+
+// camtTransactions converts a booked entry. A batch entry, such as a payroll
+// run booked as one debit, lists each payment in its own TxDtls; those are
+// expanded into one transaction per payment when every payment has an
+// amount and they add up to the entry.
+func camtTransactions(entry camtEntry, currency string) ([]transaction.Transaction, error) {
+	date, err := entry.date()
+	if err != nil {
+		return nil, err
+	}
+	amount, err := camtMoney(entry.Amount, entry.CdtDbtInd, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.isBatch(amount.Currency().Code) {
+		transactions := make([]transaction.Transaction, 0, len(entry.Details))
+		var total int64
+		for i, details := range entry.Details {
+			txAmount, err := camtMoney(*details.amount(), firstNonEmpty(details.CdtDbtInd, entry.CdtDbtInd), currency)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+			}
+			total += txAmount.Amount()
+			tx, err := camtTransaction(entry, details, date, txAmount, fmt.Sprintf("/%d", i+1))
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+			}
+			transactions = append(transactions, tx)
+		}
+		if total != amount.Amount() {
+			return nil, fmt.Errorf("transactions add up to %d but the entry is %d", total, amount.Amount())
+		}
+		return transactions, nil
+	}
+
+	var details camtTxDetails
+	if len(entry.Details) > 0 {
+		details = entry.Details[0]
+	}
+	tx, err := camtTransaction(entry, details, date, amount, "")
+	if err != nil {
+		return nil, err
+	}
+	return []transaction.Transaction{tx}, nil
+}
+
+// isBatch reports whether the entry lists several payments, each with an
+// amount in the entry's currency. Details in another currency describe a
+// conversion rather than separate payments.
+func (e camtEntry) isBatch(currency string) bool {
+	if len(e.Details) < 2 {
+		return false
+	}
+	for _, details := range e.Details {
+		amt := details.amount()
+		if amt == nil || (amt.Currency != "" && !strings.EqualFold(amt.Currency, currency)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e camtEntry) date() (time.Time, error) {
+	d := e.BookingDate
+	if d.Date == "" && d.DateTime == "" {
+		d = e.ValueDate
+	}
+	if d.Date == "" && d.DateTime == "" {
+		return time.Time{}, fmt.Errorf("missing booking date")
+	}
+	date, err := d.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date, nil
+}
+
+// camtTransaction builds one transaction. The description names the
+// counterparty, the creditor of a debit or the debtor of a credit, followed
+// by the remittance information. The external ID is the bank's own
+// reference where there is one: end-to-end IDs are chosen by the payer and
+// are reused by some, such as employers paying every salary under one ID,
+// so they are only used when the bank gives no reference. refSuffix keeps
+// entry-level references unique across a batch.
+func camtTransaction(entry camtEntry, details camtTxDetails, date time.Time, amount *money.Money, refSuffix string) (transaction.Transaction, error) {
+	counterparty := details.Creditor.name()
+	if amount.Amount() > 0 {
+		counterparty = details.Debtor.name()
+	}
+
+	var remittance []string
+	for _, line := range details.Unstructured {
+		if line = strings.TrimSpace(line); line != "" {
+			remittance = append(remittance, line)
+		}
+	}
+	info := firstNonEmpty(strings.Join(remittance, " "), strings.TrimSpace(details.AdditionalInfo), strings.TrimSpace(entry.AdditionalInfo))
+
+	description := counterparty
+	switch {
+	case description == "":
+		description = info
+	case info != "" && info != counterparty:
+		description += " - " + info
+	}
+	if description == "" {
+		return transaction.Transaction{}, fmt.Errorf("entry has no counterparty, remittance information or description")
+	}
+
+	// Only the bank's reference identifies the booking. The end-to-end ID
+	// is chosen by the payer and can repeat across payments, so it would
+	// merge distinct rows.
+	entryRef := strings.TrimSpace(entry.AcctSvcrRef)
+	if entryRef != "" {
+		entryRef += refSuffix
+	}
+	var externalID *string
+	if ref := firstNonEmpty(strings.TrimSpace(details.AcctSvcrRef), entryRef); ref != "" {
+		externalID = &ref
+	}
+
+	return transaction.Transaction{
+		Date:        date,
+		Description: truncateUTF8(description, maxDescriptionLength),
+		Amount:      amount,
+		ExternalID:  externalID,
+	}, nil
+}
+
+// camtMoney signs an unsigned camt amount by its credit/debit indicator.
+// The amount's own Ccy attribute takes precedence over the account's.
+func camtMoney(amt camtAmount, cdtDbtInd string, currency string) (*money.Money, error) {
+	if amt.Currency != "" {
+		currency = strings.ToUpper(amt.Currency)
+	}
+	if money.GetCurrency(currency) == nil {
+		return nil, fmt.Errorf("unknown currency %q", currency)
+	}
+	value := strings.TrimSpace(amt.Value)
+	amount, err := parseAmountWithDecimal(value, currency, ".")
+	if err != nil {
+		return nil, fmt.Errorf("parsing amount '%s': %w", value, err)
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(cdtDbtInd)) {
+	case "CRDT":
+		return amount, nil
+	case "DBIT":
+		return negate(amount), nil
+	default:
+		return nil, fmt.Errorf("credit/debit indicator must be CRDT or DBIT, got %q", cdtDbtInd)
+	}
+}
+
+// parse reads an ISO date, or the date part of an ISO datetime, which is
+// already the bank's local booking date.
+func (d camtDate) parse() (time.Time, error) {
+	value := strings.TrimSpace(firstNonEmpty(d.Date, d.DateTime))
+	if len(value) > len("2006-01-02") {
+		value = value[:len("2006-01-02")]
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing date '%s': %w", value, err)
+	}
+	return date, nil
+}
+
+// truncateUTF8 shortens s to at most max bytes without splitting a rune.
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSpace(s)
+}
+
+func (p *CamtParser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+// Detect looks for the camt.052 or camt.053 namespace or message element.
+func (p *CamtParser) Detect(sample []byte) bool {
+	return bytes.Contains(sample, []byte("xsd:camt.052")) || bytes.Contains(sample, []byte("xsd:camt.053")) ||
+		bytes.Contains(sample, []byte("BkToCstmrStmt>")) || bytes.Contains(sample, []byte("BkToCstmrAcctRpt>"))
+}
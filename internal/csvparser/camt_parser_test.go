@@ -0,0 +1,172 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCamtParser_ParseStatements_Camt053(t *testing.T) {
+	file, err := os.Open("testdata/camt053_sample.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := (&CamtParser{}).ParseStatements(file)
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+
+	gbp := statements[0]
+	assert.Equal(t, "Test Business Bank", gbp.Institution)
+	assert.Equal(t, AccountDetails{Name: "Business Current", MaskedNumber: "****6819"}, gbp.Account)
+	assert.Equal(t, int64(100000), gbp.OpeningBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), gbp.OpeningBalanceDate)
+	assert.Equal(t, int64(269950), gbp.ClosingBalance.Amount())
+	assert.Equal(t, int64(259950), gbp.AvailableBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), gbp.BalanceDate)
+	// The pending entry is left out and the payroll batch is expanded.
+	assert.Len(t, gbp.Transactions, 4)
+
+	debit := gbp.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), debit.Date)
+	assert.Equal(t, "ACME SUPPLIES LTD - INVOICE 1001", debit.Description)
+	assert.Equal(t, int64(-5050), debit.Amount.Amount())
+	assert.Equal(t, "GBP", debit.Amount.Currency().Code)
+	assert.Equal(t, "BANKREF001", *debit.ExternalID)
+	assert.Equal(t, "Test Business Bank", debit.Bank)
+
+	credit := gbp.Transactions[1]
+	assert.Equal(t, "BIG CUSTOMER PLC - PAYMENT FOR ORDER 7788", credit.Description)
+	assert.Equal(t, int64(250000), credit.Amount.Amount())
+	// Its only reference is the payer's end-to-end ID, which is no key.
+	assert.Nil(t, credit.ExternalID)
+
+	assert.Equal(t, "A EMPLOYEE - SALARY JAN", gbp.Transactions[2].Description)
+	assert.Equal(t, int64(-50000), gbp.Transactions[2].Amount.Amount())
+	assert.Equal(t, "BATCH42/1", *gbp.Transactions[2].ExternalID)
+	assert.Equal(t, "B EMPLOYEE - SALARY JAN", gbp.Transactions[3].Description)
+	assert.Equal(t, int64(-25000), gbp.Transactions[3].Amount.Amount())
+	assert.Equal(t, "BATCH42/2", *gbp.Transactions[3].ExternalID)
+
+	eur := statements[1]
+	assert.Equal(t, AccountDetails{Name: "Euro Account", MaskedNumber: "****3000"}, eur.Account)
+	assert.Equal(t, int64(-1000), eur.OpeningBalance.Amount())
+	assert.Equal(t, "EUR", eur.OpeningBalance.Currency().Code)
+	assert.Equal(t, time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), eur.OpeningBalanceDate)
+	assert.Equal(t, int64(-2275), eur.ClosingBalance.Amount())
+	assert.Nil(t, eur.AvailableBalance)
+	assert.Len(t, eur.Transactions, 1)
+	assert.Equal(t, "ACCOUNT FEE", eur.Transactions[0].Description)
+	assert.Equal(t, "EUR", eur.Transactions[0].Amount.Currency().Code)
+}
+
+func TestCamtParser_ParseStatements_Camt052(t *testing.T) {
+	file, err := os.Open("testdata/camt052_sample.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := (&CamtParser{}).ParseStatements(file)
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 1)
+
+	report := statements[0]
+	assert.Equal(t, "TESTGB2L", report.Institution)
+	assert.Equal(t, AccountDetails{MaskedNumber: "****5678"}, report.Account)
+	assert.Nil(t, report.OpeningBalance)
+	// The latest interim balance is the closing balance.
+	assert.Equal(t, int64(37500), report.ClosingBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC), report.BalanceDate)
+	assert.Len(t, report.Transactions, 1)
+	assert.Equal(t, "COFFEE ROASTERS", report.Transactions[0].Description)
+	assert.Equal(t, int64(-2500), report.Transactions[0].Amount.Amount())
+	assert.Equal(t, "TX-555", *report.Transactions[0].ExternalID)
+}
+
+func TestCamtParser_Parse_MultipleStatements(t *testing.T) {
+	file, err := os.Open("testdata/camt053_sample.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = (&CamtParser{}).Parse(file)
+
+	assert.ErrorContains(t, err, "contains 2 statements")
+}
+
+func TestCamtParser_ParseStatements_Errors(t *testing.T) {
+	entry := func(inner string) string {
+		return `<Document><BkToCstmrStmt><Stmt><Acct><Ccy>GBP</Ccy></Acct><Ntry>` + inner + `</Ntry></Stmt></BkToCstmrStmt></Document>`
+	}
+	tests := []struct {
+		name   string
+		camt   string
+		errMsg string
+	}{
+		{"not XML", "Date,Description,Amount\n", "parsing camt file"},
+		{"no statement", "<Document><BkToCstmrDbtCdtNtfctn/></Document>", "no camt statement or report found"},
+		{"unknown currency", "<Document><BkToCstmrStmt><Stmt><Acct><Ccy>XYZ</Ccy></Acct></Stmt></BkToCstmrStmt></Document>", "unknown currency"},
+		{"bad amount", entry(`<Amt>abc</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2026-01-15</Dt></BookgDt><AddtlNtryInf>X</AddtlNtryInf>`), "statement 1: entry 1: parsing amount 'abc'"},
+		{"missing indicator", entry(`<Amt>1.00</Amt><BookgDt><Dt>2026-01-15</Dt></BookgDt><AddtlNtryInf>X</AddtlNtryInf>`), "CRDT or DBIT"},
+		{"missing date", entry(`<Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><AddtlNtryInf>X</AddtlNtryInf>`), "missing booking date"},
+		{"bad date", entry(`<Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>15/01/2026</Dt></BookgDt><AddtlNtryInf>X</AddtlNtryInf>`), "parsing date '15/01/2026'"},
+		{"no description", entry(`<Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2026-01-15</Dt></BookgDt>`), "no counterparty"},
+		{"batch does not add up", entry(`<Amt>3.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2026-01-15</Dt></BookgDt><AddtlNtryInf>X</AddtlNtryInf>
+			<NtryDtls><TxDtls><Amt>1.00</Amt></TxDtls><TxDtls><Amt>1.00</Amt></TxDtls></NtryDtls>`), "transactions add up to -200 but the entry is -300"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&CamtParser{}).ParseStatements(strings.NewReader(tt.camt))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestCamtParser_ParseStatements_TruncatesLongDescriptions(t *testing.T) {
+	ustrd := "<Ustrd>" + strings.Repeat("é", 140) + "</Ustrd>"
+	camt := `<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy="GBP">1.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+		<BookgDt><Dt>2026-01-15</Dt></BookgDt><NtryDtls><TxDtls><RmtInf>` + ustrd + ustrd + `</RmtInf></TxDtls></NtryDtls></Ntry></Stmt></BkToCstmrStmt></Document>`
+
+	statements, err := (&CamtParser{}).ParseStatements(strings.NewReader(camt))
+
+	assert.NoError(t, err)
+	description := statements[0].Transactions[0].Description
+	assert.LessOrEqual(t, len(description), maxDescriptionLength)
+	assert.True(t, strings.HasPrefix(description, "éé"))
+	assert.True(t, strings.HasSuffix(description, "é"))
+}
+
+func TestCamtParser_Detect(t *testing.T) {
+	parser := &CamtParser{}
+
+	assert.True(t, parser.Detect([]byte(`<?xml version="1.0"?><Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`)))
+	assert.True(t, parser.Detect([]byte(`<Document><BkToCstmrAcctRpt><GrpHdr>`)))
+	assert.False(t, parser.Detect([]byte("<?xml version=\"1.0\"?>\n<?OFX OFXHEADER=\"200\"?>\n<OFX>\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount\n")))
+}
+
+func TestService_ParseStatements_DetectsCamt(t *testing.T) {
+	file, err := os.Open("testdata/camt053_sample.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := NewService().ParseStatements(file, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+	assert.Equal(t, "camt", statements[0].Format)
+	assert.Equal(t, "camt", statements[1].Format)
+}
+
+func TestService_ParseStatement_RejectsMultipleStatements(t *testing.T) {
+	file, err := os.Open("testdata/camt053_sample.xml")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = NewService().ParseStatement(file, "camt")
+
+	assert.ErrorContains(t, err, "file contains 2 statements")
+}
@@ -0,0 +1,53 @@
+package csvparser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+)
+
+// sampleRows parses as many complete CSV rows as it can from the start of a
+// file. The sample is usually cut mid-row, so reading stops at the first error.
+func sampleRows(sample []byte, limit int, comma rune) [][]string {
+	reader := csv.NewReader(bytes.NewReader(sample))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	for len(rows) < limit {
+		row, err := reader.Read()
+		if err != nil {
+			break
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func hasColumns(row []string, columns ...string) bool {
+	for _, column := range columns {
+		if findColumnIndex(row, column) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// findColumnIndex matches header names case-insensitively, ignoring
+// surrounding whitespace.
+func findColumnIndex(headers []string, columnName string) int {
+	for i, header := range headers {
+		if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(columnName)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func firstField(row []string) string {
+	if len(row) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(row[0])
+}
@@ -0,0 +1,204 @@
+package csvparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var ErrEncoding = errors.New("unsupported character encoding")
+
+// encodings are the character encodings an upload can be read in, by the
+// names it can choose them with. Names are matched ignoring case, spaces,
+// hyphens and underscores. UTF-8 and UTF-16 skip a byte order mark.
+var encodings = map[string]encoding.Encoding{
+	"utf8":        unicode.UTF8BOM,
+	"utf16":       unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf16le":     unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf16be":     unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"windows1252": charmap.Windows1252,
+	"cp1252":      charmap.Windows1252,
+	"1252":        charmap.Windows1252,
+	"iso88591":    charmap.ISO8859_1,
+	"latin1":      charmap.ISO8859_1,
+}
+
+var encodingNameReplacer = strings.NewReplacer("-", "", "_", "", " ", "")
+
+var (
+	// ofxCharset is the CHARSET line of an OFX 1.x header.
+	ofxCharset = regexp.MustCompile(`(?m)^\s*CHARSET:\s*(\S+)`)
+	// xmlEncoding is the encoding of an XML declaration, as OFX 2.x and
+	// CAMT files start with.
+	xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*\sencoding=["']([^"']+)["']`)
+)
+
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	enc, ok := encodings[encodingNameReplacer.Replace(strings.ToLower(strings.TrimSpace(name)))]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrEncoding, name)
+	}
+	return enc, nil
+}
+
+// textDecoder returns the decoder that turns an upload starting with sample
+// into UTF-8: the named encoding when one is given, and otherwise the one
+// detectEncoding finds.
+func textDecoder(sample []byte, name string) (transform.Transformer, error) {
+	if name == "" {
+		return detectEncoding(sample), nil
+	}
+	enc, err := lookupEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder(), nil
+}
+
+// detectEncoding works out how a text upload is encoded from its start. A
+// byte order mark is trusted, and UTF-16 without one is spotted from the
+// zero bytes beside its ASCII characters. Otherwise the bytes decide: text
+// that is not valid UTF-8 was written in a legacy encoding, which is the
+// one the file declares or else Windows-1252, as Excel and most UK banks
+// write on Windows. Text that is valid UTF-8 is read as UTF-8, even when
+// the file declares a legacy charset, since exports often declare one
+// they no longer use. Only ASCII text is read as the charset it declares.
+func detectEncoding(sample []byte) transform.Transformer {
+	switch {
+	case bytes.HasPrefix(sample, []byte("\xef\xbb\xbf")):
+		return unicode.UTF8BOM.NewDecoder()
+	case bytes.HasPrefix(sample, []byte("\xff\xfe")), bytes.HasPrefix(sample, []byte("\xfe\xff")):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+	}
+	if enc := utf16WithoutBOM(sample); enc != nil {
+		return enc.NewDecoder()
+	}
+
+	declared := declaredEncoding(sample)
+	switch {
+	case !validUTF8Sample(sample):
+		if declared == nil {
+			declared = charmap.Windows1252
+		}
+		return declared.NewDecoder()
+	case isASCII(sample) && declared != nil:
+		return declared.NewDecoder()
+	}
+	return utf8Fallback{}
+}
+
+// utf16WithoutBOM recognises UTF-16 text without a byte order mark, which
+// writes ASCII characters with a zero byte after them in little-endian
+// order and before them in big-endian order.
+func utf16WithoutBOM(sample []byte) encoding.Encoding {
+	n := min(len(sample), 512) &^ 1
+	if n < 4 {
+		return nil
+	}
+	var even, odd int
+	for i := 0; i < n; i += 2 {
+		if sample[i] == 0 {
+			even++
+		}
+		if sample[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case even == 0 && odd > pairs*3/4:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case odd == 0 && even > pairs*3/4:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	return nil
+}
+
+// declaredEncoding is the legacy charset an OFX 1.x header or an XML
+// declaration names, or nil. Files declaring UTF-8 or UTF-16 are judged
+// from their bytes instead. ISO-8859-1 is read as Windows-1252, which
+// differs only in using the control characters ISO-8859-1 leaves unused
+// for punctuation such as "€" and curly quotes.
+func declaredEncoding(sample []byte) encoding.Encoding {
+	var name string
+	if m := xmlEncoding.FindSubmatch(sample); m != nil {
+		name = string(m[1])
+	} else if m := ofxCharset.FindSubmatch(sample); m != nil {
+		name = string(m[1])
+	}
+	if name == "" {
+		return nil
+	}
+	enc, err := lookupEncoding(name)
+	if err != nil {
+		return nil
+	}
+	if _, legacy := enc.(*charmap.Charmap); !legacy {
+		return nil
+	}
+	if enc == charmap.ISO8859_1 {
+		return charmap.Windows1252
+	}
+	return enc
+}
+
+// validUTF8Sample is utf8.Valid for a sample that may stop part way
+// through a character.
+func validUTF8Sample(sample []byte) bool {
+	for i := len(sample) - 1; i >= 0 && i >= len(sample)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(sample[i]) {
+			if !utf8.FullRune(sample[i:]) {
+				sample = sample[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(sample)
+}
+
+func isASCII(sample []byte) bool {
+	for _, b := range sample {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// utf8Fallback reads UTF-8, taking any byte that is not part of a valid
+// UTF-8 sequence as Windows-1252. Detection only sees the start of a file,
+// so a file that turns out not to be UTF-8 further in, or that joins
+// exports written in different encodings, is still read without mangling
+// its characters.
+type utf8Fallback struct{ transform.NopResetter }
+
+func (utf8Fallback) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	var buf [utf8.UTFMax]byte
+	for nSrc < len(src) {
+		char := src[nSrc:]
+		r, size := utf8.DecodeRune(char)
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(char) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			size = 1
+			char = buf[:utf8.EncodeRune(buf[:], charmap.Windows1252.DecodeByte(src[nSrc]))]
+		} else {
+			char = char[:size]
+		}
+		if nDst+len(char) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], char)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
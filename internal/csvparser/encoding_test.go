@@ -0,0 +1,155 @@
+package csvparser
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// amexCafeCSV is the Amex sample with a description that needs more than
+// ASCII, in UTF-8.
+func amexCafeCSV(t *testing.T) string {
+	data, err := os.ReadFile("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	return strings.Replace(string(data), "TEST COFFEE SHOP LONDON,MR TEST", "CAFÉ NÉRO £5 OFF,MR TEST", 1)
+}
+
+func encode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	assert.NoError(t, err)
+	return data
+}
+
+func TestService_ParseStatementsWithOptions_DetectsEncoding(t *testing.T) {
+	text := amexCafeCSV(t)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-8", []byte(text)},
+		{"UTF-8 with BOM", append([]byte("\xef\xbb\xbf"), text...)},
+		{"Windows-1252", encode(t, charmap.Windows1252, text)},
+		{"UTF-16LE with BOM", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), text)},
+		{"UTF-16BE with BOM", encode(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), text)},
+		{"UTF-16LE without BOM", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), text)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewService().ParseStatementsWithOptions(bytes.NewReader(tt.data), ParseOptions{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "amex", result.Statements[0].Format)
+			assert.Equal(t, "CAFÉ NÉRO £5 OFF", result.Statements[0].Transactions[2].Description)
+		})
+	}
+}
+
+func TestService_ParseStatementsWithOptions_EncodingOverride(t *testing.T) {
+	data := encode(t, charmap.Windows1252, amexCafeCSV(t))
+
+	// Read as UTF-8, each Windows-1252 byte becomes the replacement
+	// character.
+	result, err := NewService().ParseStatementsWithOptions(bytes.NewReader(data), ParseOptions{Encoding: "UTF-8"})
+	assert.NoError(t, err)
+	assert.Equal(t, "CAF� N�RO �5 OFF", result.Statements[0].Transactions[2].Description)
+
+	result, err = NewService().ParseStatementsWithOptions(bytes.NewReader(data), ParseOptions{BankType: "amex", Encoding: "latin1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "CAFÉ NÉRO £5 OFF", result.Statements[0].Transactions[2].Description)
+
+	_, err = NewService().ParseStatementsWithOptions(bytes.NewReader(data), ParseOptions{Encoding: "ebcdic"})
+	assert.ErrorIs(t, err, ErrEncoding)
+	assert.ErrorContains(t, err, "'ebcdic'")
+}
+
+func TestService_ParseStatementsWithOptions_EncodingForSpreadsheet(t *testing.T) {
+	_, err := NewService().ParseStatementsWithOptions(bytes.NewReader(amexWorkbook(t)), ParseOptions{Encoding: "utf-8"})
+
+	assert.ErrorContains(t, err, "an encoding can only be chosen for a text upload")
+}
+
+func TestService_ParseStatementsWithOptions_OFXCharset(t *testing.T) {
+	data, err := os.ReadFile("testdata/ofx1_sample.ofx")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		// The header declares CHARSET:1252.
+		{"as declared", bytes.Replace(data, []byte("FISH &amp; CHIPS"), []byte("CAF\xc9 \x80"), 1)},
+		// A file declaring 1252 but written in UTF-8 is read as UTF-8.
+		{"UTF-8 despite the declaration", bytes.Replace(data, []byte("FISH &amp; CHIPS"), []byte("CAFÉ €"), 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := NewService().ParseStatement(bytes.NewReader(tt.data), "")
+
+			assert.NoError(t, err)
+			assert.Contains(t, descriptions(statement.Transactions), "CAFÉ €")
+		})
+	}
+}
+
+func TestService_ParseStatementsWithOptions_CamtDeclaredCharset(t *testing.T) {
+	data, err := os.ReadFile("testdata/camt053_sample.xml")
+	assert.NoError(t, err)
+	text := strings.Replace(string(data), `encoding="UTF-8"`, `encoding="ISO-8859-1"`, 1)
+	text = strings.Replace(text, "ACME SUPPLIES LTD", "CAFÉ NÉRO £5 OFF", 1)
+
+	result, err := NewService().ParseStatementsWithOptions(bytes.NewReader(encode(t, charmap.ISO8859_1, text)), ParseOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "camt", result.Statements[0].Format)
+	assert.Equal(t, "CAFÉ NÉRO £5 OFF - INVOICE 1001", result.Statements[0].Transactions[0].Description)
+}
+
+func TestDetectEncoding_DeclaredCharsetForASCII(t *testing.T) {
+	sample := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?><Document>`)
+
+	assert.Equal(t, charmap.Windows1252.NewDecoder(), detectEncoding(sample))
+	assert.Equal(t, utf8Fallback{}, detectEncoding([]byte(`<?xml version="1.0" encoding="UTF-8"?>`)))
+	assert.Equal(t, utf8Fallback{}, detectEncoding([]byte("Date,Amount\n")))
+}
+
+func TestValidUTF8Sample(t *testing.T) {
+	assert.True(t, validUTF8Sample([]byte("caf\xc3\xa9")))
+	// The sample can end part way through a character.
+	assert.True(t, validUTF8Sample([]byte("caf\xc3")))
+	assert.True(t, validUTF8Sample([]byte("\xe2\x82")))
+	assert.False(t, validUTF8Sample([]byte("caf\xe9 au lait")))
+}
+
+func TestUTF8Fallback(t *testing.T) {
+	// UTF-8 "é", then Windows-1252 "é" and "€", then UTF-8 "€".
+	input := "caf\xc3\xa9 caf\xe9 \x80 \xe2\x82\xac"
+
+	decoded, err := io.ReadAll(transform.NewReader(iotest.OneByteReader(strings.NewReader(input)), utf8Fallback{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "café café € €", string(decoded))
+
+	// A sequence cut short by the end of the file is taken byte by byte.
+	result, _, err := transform.String(utf8Fallback{}, "caf\xe2\x82")
+	assert.NoError(t, err)
+	assert.Equal(t, "cafâ‚", result)
+}
+
+func descriptions(transactions []transaction.Transaction) []string {
+	var result []string
+	for _, tx := range transactions {
+		result = append(result, tx.Description)
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonzoProfile_ParseStatement(t *testing.T) {
+	file, err := os.Open("testdata/monzo_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := bundledParser("monzo").ParseStatement(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Monzo", statement.Institution)
+	assert.Equal(t, "current", statement.Account.Type)
+	assert.Len(t, statement.Transactions, 3)
+
+	coffee := statement.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), coffee.Date)
+	assert.Equal(t, "☕ Pret A Manger", coffee.Description)
+	assert.Equal(t, int64(-450), coffee.Amount.Amount())
+	assert.Equal(t, "tx_0000AbCdEf123", *coffee.ExternalID)
+	assert.Equal(t, "Eating out", *coffee.Category)
+	assert.Equal(t, "Team breakfast #work", *coffee.Notes)
+	assert.Equal(t, "1 Test Street, London, EC1A 1BB", *coffee.Address)
+	// A local amount in GBP only repeats the amount.
+	assert.Nil(t, coffee.LocalAmount)
+
+	abroad := statement.Transactions[1]
+	assert.Equal(t, "🍽️ Café Test", abroad.Description)
+	assert.Equal(t, int64(-2150), abroad.Amount.Amount())
+	assert.Equal(t, "GBP", abroad.Amount.Currency().Code)
+	assert.Equal(t, int64(-2500), abroad.LocalAmount.Amount())
+	assert.Equal(t, "EUR", abroad.LocalAmount.Currency().Code)
+	assert.Nil(t, abroad.Notes)
+	assert.Equal(t, "Rue de Test, Paris", *abroad.Address)
+
+	salary := statement.Transactions[2]
+	assert.Equal(t, "EMPLOYER LTD SALARY", salary.Description)
+	assert.Equal(t, int64(150000), salary.Amount.Amount())
+	assert.Nil(t, salary.Address)
+}
+
+func TestMonzoProfile_ParseStatement_UnknownLocalCurrency(t *testing.T) {
+	csv := "Transaction ID,Date,Name,Emoji,Amount,Currency,Local amount,Local currency,Description\n" +
+		"tx_1,15/01/2026,Shop,,-1.00,GBP,-1.00,XYZ,SHOP\n"
+
+	_, err := bundledParser("monzo").ParseStatement(strings.NewReader(csv))
+
+	assert.ErrorContains(t, err, "row 1: unknown local currency 'XYZ'")
+}
+
+func TestService_Parse_DetectsMonzo(t *testing.T) {
+	file, err := os.Open("testdata/monzo_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := NewService().ParseStatement(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "monzo", statement.Format)
+}
@@ -0,0 +1,366 @@
+package csvparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// mt940Institution is recorded when the account line carries no BIC.
+const mt940Institution = "MT940"
+
+// MT940Parser reads SWIFT MT940 customer statements. A file can hold
+// several statements, often one per day for the same account. Amounts are
+// unsigned, with a credit/debit mark on each statement line and balance.
+type MT940Parser struct{}
+
+var (
+	mt940TagPattern = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+	// :61: is value date, optional entry date, mark, optional funds code,
+	// amount, transaction type, then the customer reference with an
+	// optional //bank reference.
+	mt940LinePattern = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(R?[CD])([A-Z])?([0-9]+,[0-9]*)([NSF][A-Z0-9]{3})(.*)$`)
+	// The German structured :86: form: a three-digit business code, then
+	// ?NN subfields.
+	mt940StructuredPattern = regexp.MustCompile(`^[0-9]{3}\?`)
+	mt940BICPattern        = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+)
+
+// mt940Field is one tag and its value, with continuation lines kept as
+// separate lines.
+type mt940Field struct {
+	tag   string
+	lines []string
+	line  int
+}
+
+type mt940Entry struct {
+	line        mt940Field
+	information *mt940Field
+}
+
+func (p *MT940Parser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	return parseSingleStatement(p, r)
+}
+
+// ParseStatements reads every statement in the file, in file order. Each
+// starts at its :20: transaction reference.
+func (p *MT940Parser) ParseStatements(r io.Reader) ([]Statement, error) {
+	fields, err := readMT940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages [][]mt940Field
+	for _, field := range fields {
+		if field.tag == "20" || len(messages) == 0 {
+			messages = append(messages, nil)
+		}
+		messages[len(messages)-1] = append(messages[len(messages)-1], field)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no MT940 statement found")
+	}
+
+	statements := make([]Statement, 0, len(messages))
+	for i, message := range messages {
+		statement, err := mt940Statement(message)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// readMT940Fields splits a file into tagged fields, dropping the SWIFT
+// block headers and trailers some banks wrap each statement in.
+func readMT940Fields(r io.Reader) ([]mt940Field, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var fields []mt940Field
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || line == "-" || strings.HasPrefix(line, "-}") || strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		if m := mt940TagPattern.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], lines: []string{line[len(m[0]):]}, line: lineNum})
+			continue
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: expected a :20: tag", lineNum)
+		}
+		last := &fields[len(fields)-1]
+		last.lines = append(last.lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading MT940 file: %w", err)
+	}
+	return fields, nil
+}
+
+func mt940Statement(fields []mt940Field) (Statement, error) {
+	var (
+		statement Statement
+		currency  string
+		entries   []mt940Entry
+	)
+
+	for i := range fields {
+		field := fields[i]
+		value := field.lines[0]
+		switch field.tag {
+		case "25":
+			statement.Account, statement.Institution = mt940Account(value)
+		case "60F", "60M":
+			balance, date, err := mt940Balance(value)
+			if err != nil {
+				return Statement{}, fmt.Errorf("line %d: parsing opening balance: %w", field.line, err)
+			}
+			statement.OpeningBalance, statement.OpeningBalanceDate = balance, date
+			currency = balance.Currency().Code
+		case "61":
+			entries = append(entries, mt940Entry{line: field})
+		case "86":
+			// A :86: after the last statement line describes the whole
+			// statement rather than a transaction.
+			if len(entries) > 0 && entries[len(entries)-1].information == nil && i > 0 && fields[i-1].tag == "61" {
+				entries[len(entries)-1].information = &fields[i]
+			}
+		case "62F", "62M":
+			balance, date, err := mt940Balance(value)
+			if err != nil {
+				return Statement{}, fmt.Errorf("line %d: parsing closing balance: %w", field.line, err)
+			}
+			statement.ClosingBalance, statement.BalanceDate = balance, date
+		case "64":
+			balance, _, err := mt940Balance(value)
+			if err != nil {
+				return Statement{}, fmt.Errorf("line %d: parsing available balance: %w", field.line, err)
+			}
+			statement.AvailableBalance = balance
+		}
+	}
+
+	if currency == "" {
+		return Statement{}, fmt.Errorf("missing :60F: opening balance")
+	}
+	if statement.Institution == "" {
+		statement.Institution = mt940Institution
+	}
+
+	for _, entry := range entries {
+		tx, err := mt940Transaction(entry, currency)
+		if err != nil {
+			return Statement{}, fmt.Errorf("line %d: %w", entry.line.line, err)
+		}
+		tx.Bank = statement.Institution
+		statement.Transactions = append(statement.Transactions, tx)
+	}
+	return statement, nil
+}
+
+// mt940Account reads a :25: account identification, which is an IBAN, a
+// bare account number, or "BIC/account". The number is masked here so it
+// never leaves the parser.
+func mt940Account(value string) (AccountDetails, string) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	var institution string
+	if bic, number, ok := strings.Cut(value, "/"); ok && mt940BICPattern.MatchString(bic) {
+		institution, value = bic, number
+	}
+	// Some banks append the currency, as in "12345678/GBP".
+	if number, suffix, ok := strings.Cut(value, "/"); ok && money.GetCurrency(suffix) != nil {
+		value = number
+	}
+	return AccountDetails{MaskedNumber: account.MaskNumber(value)}, institution
+}
+
+// mt940Balance reads a balance such as "C260115GBP1000,00".
+func mt940Balance(value string) (*money.Money, time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 11 {
+		return nil, time.Time{}, fmt.Errorf("balance '%s' is too short", value)
+	}
+	mark, dateStr, currency, amountStr := value[:1], value[1:7], value[7:10], value[10:]
+
+	date, err := time.Parse("060102", dateStr)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parsing date '%s': %w", dateStr, err)
+	}
+	amount, err := parseAmountWithDecimal(amountStr, currency, ",")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parsing amount '%s': %w", amountStr, err)
+	}
+	switch mark {
+	case "C":
+	case "D":
+		amount = negate(amount)
+	default:
+		return nil, time.Time{}, fmt.Errorf("balance mark must be C or D, got %q", mark)
+	}
+	return amount, date, nil
+}
+
+// mt940Transaction converts a :61: statement line and its :86:
+// narrative. The date is the entry (booking) date where the line has one,
+// otherwise the value date. A reversal of a credit (RC) takes money out and
+// a reversal of a debit (RD) puts it back.
+func mt940Transaction(entry mt940Entry, currency string) (transaction.Transaction, error) {
+	value := entry.line.lines[0]
+	m := mt940LinePattern.FindStringSubmatch(value)
+	if m == nil {
+		return transaction.Transaction{}, fmt.Errorf("statement line '%s' is not in :61: format", value)
+	}
+	valueDateStr, entryDateStr, mark, amountStr, rest := m[1], m[2], m[3], m[5], m[7]
+
+	valueDate, err := time.Parse("060102", valueDateStr)
+	if err != nil {
+		return transaction.Transaction{}, fmt.Errorf("parsing date '%s': %w", valueDateStr, err)
+	}
+	date := valueDate
+	if entryDateStr != "" {
+		date, err = mt940EntryDate(valueDate, entryDateStr)
+		if err != nil {
+			return transaction.Transaction{}, err
+		}
+	}
+
+	amount, err := parseAmountWithDecimal(amountStr, currency, ",")
+	if err != nil {
+		return transaction.Transaction{}, fmt.Errorf("parsing amount '%s': %w", amountStr, err)
+	}
+	if mark == "D" || mark == "RC" {
+		amount = negate(amount)
+	}
+
+	customerRef, bankRef, _ := strings.Cut(rest, "//")
+	var supplementary string
+	if len(entry.line.lines) > 1 {
+		supplementary = strings.TrimSpace(strings.Join(entry.line.lines[1:], " "))
+	}
+
+	var narrative string
+	if entry.information != nil {
+		narrative = mt940Narrative(entry.information.lines)
+	}
+	description := firstNonEmpty(narrative, supplementary, mt940Reference(customerRef))
+	if description == "" {
+		return transaction.Transaction{}, fmt.Errorf("statement line has no narrative or reference")
+	}
+
+	// The bank's reference identifies the booking. The customer reference
+	// is whatever the payer chose and can repeat, so it is never used.
+	var externalID *string
+	if ref := mt940Reference(bankRef); ref != "" {
+		externalID = &ref
+	}
+
+	return transaction.Transaction{
+		Date:        date,
+		Description: truncateUTF8(description, maxDescriptionLength),
+		Amount:      amount,
+		ExternalID:  externalID,
+	}, nil
+}
+
+// mt940EntryDate places an MMDD entry date in the year that puts it nearest
+// the value date, so a value date of 31 December booked on 2 January lands
+// in the next year.
+func mt940EntryDate(valueDate time.Time, mmdd string) (time.Time, error) {
+	var nearest time.Time
+	for _, year := range []int{valueDate.Year() - 1, valueDate.Year(), valueDate.Year() + 1} {
+		date, err := time.Parse("20060102", fmt.Sprintf("%04d%s", year, mmdd))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parsing entry date '%s': %w", mmdd, err)
+		}
+		if nearest.IsZero() || absDuration(date.Sub(valueDate)) < absDuration(nearest.Sub(valueDate)) {
+			nearest = date
+		}
+	}
+	return nearest, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// mt940Reference trims a reference, treating NONREF as none.
+func mt940Reference(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "NONREF") {
+		return ""
+	}
+	return value
+}
+
+// mt940Narrative joins a :86: field's lines. The German structured form
+// is reduced to the counterparty name followed by the payment reference,
+// as for camt statements; any other narrative is kept as written.
+func mt940Narrative(lines []string) string {
+	joined := strings.Join(lines, "")
+	if !mt940StructuredPattern.MatchString(joined) {
+		return strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	}
+
+	subfields := make(map[string]string)
+	for _, part := range strings.Split(joined[3:], "?")[1:] {
+		if len(part) < 2 {
+			continue
+		}
+		subfields[part[:2]] += part[2:]
+	}
+
+	// ?20-?29 and ?60-?63 carry the payment reference in 27-character
+	// pieces that can split words, so they are joined without spaces.
+	var reference strings.Builder
+	for _, code := range []string{"20", "21", "22", "23", "24", "25", "26", "27", "28", "29", "60", "61", "62", "63"} {
+		reference.WriteString(subfields[code])
+	}
+	info := strings.TrimSpace(reference.String())
+	if info == "" {
+		info = strings.TrimSpace(subfields["00"])
+	}
+	counterparty := strings.TrimSpace(subfields["32"] + subfields["33"])
+
+	switch {
+	case counterparty == "":
+		return info
+	case info == "":
+		return counterparty
+	default:
+		return counterparty + " - " + info
+	}
+}
+
+func (p *MT940Parser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+var (
+	mt940DetectReference = regexp.MustCompile(`(?m)^:20:`)
+	mt940DetectAccount   = regexp.MustCompile(`(?m)^:25:`)
+	mt940DetectOpening   = regexp.MustCompile(`(?m)^:60[FM]:`)
+)
+
+// Detect looks for the :20:, :25: and :60F: tags that open every
+// statement.
+func (p *MT940Parser) Detect(sample []byte) bool {
+	return mt940DetectReference.Match(sample) && mt940DetectAccount.Match(sample) && mt940DetectOpening.Match(sample)
+}
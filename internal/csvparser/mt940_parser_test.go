@@ -0,0 +1,215 @@
+package csvparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenStatement is a Statement in a stable, readable form for golden
+// files.
+type goldenStatement struct {
+	Format             string              `json:"format"`
+	Institution        string              `json:"institution"`
+	AccountName        string              `json:"account_name,omitempty"`
+	MaskedNumber       string              `json:"masked_number,omitempty"`
+	AccountType        string              `json:"account_type,omitempty"`
+	OpeningBalance     string              `json:"opening_balance,omitempty"`
+	OpeningBalanceDate string              `json:"opening_balance_date,omitempty"`
+	ClosingBalance     string              `json:"closing_balance,omitempty"`
+	AvailableBalance   string              `json:"available_balance,omitempty"`
+	BalanceDate        string              `json:"balance_date,omitempty"`
+	Transactions       []goldenTransaction `json:"transactions"`
+}
+
+type goldenTransaction struct {
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Amount      string  `json:"amount"`
+	ExternalID  *string `json:"external_id,omitempty"`
+	Bank        string  `json:"bank"`
+}
+
+func toGolden(statements []Statement) []goldenStatement {
+	amount := func(m *money.Money) string {
+		if m == nil {
+			return ""
+		}
+		return formatQIFAmount(m) + " " + m.Currency().Code
+	}
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+
+	golden := make([]goldenStatement, 0, len(statements))
+	for _, s := range statements {
+		g := goldenStatement{
+			Format:             s.Format,
+			Institution:        s.Institution,
+			AccountName:        s.Account.Name,
+			MaskedNumber:       s.Account.MaskedNumber,
+			AccountType:        s.Account.Type,
+			OpeningBalance:     amount(s.OpeningBalance),
+			OpeningBalanceDate: date(s.OpeningBalanceDate),
+			ClosingBalance:     amount(s.ClosingBalance),
+			AvailableBalance:   amount(s.AvailableBalance),
+			BalanceDate:        date(s.BalanceDate),
+			Transactions:       []goldenTransaction{},
+		}
+		for _, tx := range s.Transactions {
+			g.Transactions = append(g.Transactions, goldenTransaction{
+				Date:        date(tx.Date),
+				Description: tx.Description,
+				Amount:      amount(tx.Amount),
+				ExternalID:  tx.ExternalID,
+				Bank:        tx.Bank,
+			})
+		}
+		golden = append(golden, g)
+	}
+	return golden
+}
+
+// TestMT940Parser_Golden parses each testdata/mt940/*.sta file and compares
+// the result with the .golden.json file beside it. Run with -update to
+// rewrite the golden files after an intended change.
+func TestMT940Parser_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/mt940/*.sta")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			file, err := os.Open(input)
+			assert.NoError(t, err)
+			defer file.Close()
+
+			statements, err := NewService().ParseStatements(file, "")
+			assert.NoError(t, err)
+
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			assert.NoError(t, encoder.Encode(toGolden(statements)))
+			got := buf.Bytes()
+
+			goldenPath := strings.TrimSuffix(input, ".sta") + ".golden.json"
+			if *updateGolden {
+				assert.NoError(t, os.WriteFile(goldenPath, got, 0o644))
+			}
+			want, err := os.ReadFile(goldenPath)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+func TestMT940Parser_Parse_MultipleStatements(t *testing.T) {
+	file, err := os.Open("testdata/mt940/multi.sta")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = (&MT940Parser{}).Parse(file)
+
+	assert.ErrorContains(t, err, "contains 2 statements")
+}
+
+func TestMT940Parser_ParseStatements_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		mt940  string
+		errMsg string
+	}{
+		{"empty", "", "no MT940 statement found"},
+		{"text before first tag", "hello\n:20:X\n", "line 1: expected a :20: tag"},
+		{"no opening balance", ":20:X\n:25:123\n:62F:C260115GBP1,00\n", "missing :60F: opening balance"},
+		{"bad balance mark", ":20:X\n:60F:X260115GBP1,00\n", "balance mark must be C or D"},
+		{"bad balance date", ":20:X\n:60F:C261315GBP1,00\n", "parsing date '261315'"},
+		{"unknown currency", ":20:X\n:60F:C260115XYZ1,00\n", "unknown currency"},
+		{"bad statement line", ":20:X\n:60F:C260115GBP1,00\n:61:garbage\n:86:X\n", "statement 1: line 3: statement line 'garbage' is not in :61: format"},
+		{"bad entry date", ":20:X\n:60F:C260115GBP1,00\n:61:2601151399D1,00NTRFREF\n", "parsing entry date '1399'"},
+		{"no description", ":20:X\n:60F:C260115GBP1,00\n:61:260115D1,00NTRFNONREF\n", "no narrative or reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&MT940Parser{}).ParseStatements(strings.NewReader(tt.mt940))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestMT940Parser_CustomerReferenceIsNotExternalID(t *testing.T) {
+	mt940 := ":20:STMT\n:25:123\n:60F:C260114GBP0,00\n" +
+		":61:260115C25,00NTRFPO-7788\n:86:FIRST PAYMENT\n" +
+		":61:260115C25,00NTRFPO-7788\n:86:SECOND PAYMENT\n" +
+		":62F:C260115GBP50,00\n-"
+
+	transactions, err := (&MT940Parser{}).Parse(strings.NewReader(mt940))
+
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	for _, tx := range transactions {
+		assert.Nil(t, tx.ExternalID)
+	}
+}
+
+func TestMT940EntryDate(t *testing.T) {
+	tests := []struct {
+		valueDate time.Time
+		mmdd      string
+		want      time.Time
+	}{
+		{time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), "0116", time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), "0102", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), "1231", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := mt940EntryDate(tt.valueDate, tt.mmdd)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestMT940Account(t *testing.T) {
+	tests := []struct {
+		value       string
+		want        AccountDetails
+		institution string
+	}{
+		{"GB29NWBK60161331926819", AccountDetails{MaskedNumber: "****6819"}, ""},
+		{"TESTDEFF/0532013000", AccountDetails{MaskedNumber: "****3000"}, "TESTDEFF"},
+		{"12345678/GBP", AccountDetails{MaskedNumber: "****5678"}, ""},
+		{"60-16-13 31926819", AccountDetails{MaskedNumber: "****6819"}, ""},
+	}
+	for _, tt := range tests {
+		got, institution := mt940Account(tt.value)
+
+		assert.Equal(t, tt.want, got, tt.value)
+		assert.Equal(t, tt.institution, institution, tt.value)
+	}
+}
+
+func TestMT940Parser_Detect(t *testing.T) {
+	parser := &MT940Parser{}
+
+	assert.True(t, parser.Detect([]byte(":20:STMT\r\n:25:123\r\n:28C:1\r\n:60F:C260115GBP1,00\r\n")))
+	assert.True(t, parser.Detect([]byte("{1:F01TESTGB2LAXXX0000000000}{4:\n:20:STMT\n:25:123\n:60M:C260115GBP1,00\n")))
+	assert.False(t, parser.Detect([]byte(":20:STMT\n:25:123\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount\n")))
+}
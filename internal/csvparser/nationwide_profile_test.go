@@ -0,0 +1,133 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNationwideProfile_Parse_ValidCSV(t *testing.T) {
+	file, err := os.Open("testdata/nationwide_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	parser := bundledParser("nationwide")
+	transactions, err := parser.Parse(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(transactions))
+
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), transactions[0].Date)
+	assert.Equal(t, "TEST PAYEE", transactions[0].Description)
+	assert.Equal(t, int64(-5000), transactions[0].Amount.Amount())
+	assert.Equal(t, "Nationwide", transactions[0].Bank)
+	assert.Nil(t, transactions[0].Category)
+
+	assert.Equal(t, "TEST MERCHANT LONDON GB APPLEPAY 1234", transactions[1].Description)
+	assert.Equal(t, int64(-1050), transactions[1].Amount.Amount())
+
+	assert.Equal(t, "TEST STANDING ORDER", transactions[2].Description)
+	assert.Equal(t, int64(-10000), transactions[2].Amount.Amount())
+
+	assert.Equal(t, "TEST UTILITY COMPANY", transactions[3].Description)
+	assert.Equal(t, int64(-7525), transactions[3].Amount.Amount())
+
+	assert.Equal(t, "SALARY PAYMENT", transactions[4].Description)
+	assert.Equal(t, int64(200000), transactions[4].Amount.Amount())
+}
+
+func TestNationwideProfile_Parse_MissingColumns(t *testing.T) {
+	csvData := `"Account Name:","Debit ****12345"
+"Account Balance:","£1234.56"
+"Available Balance: ","£1234.56"
+
+"Date","Description"
+"15 Jan 2026","TEST"
+`
+	parser := bundledParser("nationwide")
+	_, err := parser.Parse(strings.NewReader(csvData))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "required column")
+}
+
+func TestNationwideProfile_ParseStatement_ReadsAccount(t *testing.T) {
+	file, err := os.Open("testdata/nationwide_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	parser := bundledParser("nationwide")
+	statement, err := parser.ParseStatement(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Nationwide", statement.Institution)
+	assert.Equal(t, AccountDetails{Name: "Debit", MaskedNumber: "****12345"}, statement.Account)
+	assert.Len(t, statement.Transactions, 5)
+}
+
+func TestSplitAccountName(t *testing.T) {
+	tests := []struct {
+		value        string
+		name, masked string
+	}{
+		{"FlexDirect ****12345", "FlexDirect", "****12345"},
+		{"Loyalty Saver", "Loyalty Saver", ""},
+		{" Debit ****12345 ", "Debit", "****12345"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		name, masked := splitAccountName(tt.value)
+		assert.Equal(t, tt.name, name, tt.value)
+		assert.Equal(t, tt.masked, masked, tt.value)
+	}
+}
+
+func TestNationwideProfile_ParseStatement_ReadsBalances(t *testing.T) {
+	file, err := os.Open("testdata/nationwide_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	parser := bundledParser("nationwide")
+	statement, err := parser.ParseStatement(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456), statement.ClosingBalance.Amount())
+	assert.Equal(t, int64(123456), statement.AvailableBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), statement.BalanceDate)
+	assert.Equal(t, int64(118456), statement.Transactions[0].RunningBalance.Amount())
+	assert.Equal(t, int64(298831), statement.Transactions[4].RunningBalance.Amount())
+}
+
+func TestNationwideProfile_Parse_WithoutBalanceColumn(t *testing.T) {
+	csvData := `"Account Name:","Debit ****12345"
+"Account Balance:","unavailable"
+"Available Balance: ","£10.00"
+
+"Date","Transaction type","Description","Paid out","Paid in"
+"15 Jan 2026","Payment to","TEST PAYEE","£50.00",""
+`
+	parser := bundledParser("nationwide")
+	statement, err := parser.ParseStatement(strings.NewReader(csvData))
+
+	assert.NoError(t, err)
+	assert.Nil(t, statement.ClosingBalance)
+	assert.Equal(t, int64(1000), statement.AvailableBalance.Amount())
+	assert.Nil(t, statement.Transactions[0].RunningBalance)
+}
+
+func TestNationwideProfile_Parse_InvalidBalance(t *testing.T) {
+	csvData := `"Account Name:","Debit ****12345"
+"Account Balance:","£10.00"
+"Available Balance: ","£10.00"
+
+"Date","Transaction type","Description","Paid out","Paid in","Balance"
+"15 Jan 2026","Payment to","TEST PAYEE","£50.00","","abc"
+`
+	parser := bundledParser("nationwide")
+	_, err := parser.Parse(strings.NewReader(csvData))
+
+	assert.ErrorContains(t, err, "parsing balance")
+}
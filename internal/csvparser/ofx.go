@@ -0,0 +1,176 @@
+package csvparser
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ofxNode is an element of an OFX document. Leaf elements carry a value;
+// aggregates carry children.
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+func (n *ofxNode) child(name string) *ofxNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// path follows a chain of child elements, returning nil if any is missing.
+func (n *ofxNode) path(names ...string) *ofxNode {
+	for _, name := range names {
+		n = n.child(name)
+	}
+	return n
+}
+
+// text returns the value of the named child, or "" when it is missing.
+func (n *ofxNode) text(name string) string {
+	if c := n.child(name); c != nil {
+		return c.value
+	}
+	return ""
+}
+
+// findAll returns every descendant with the given name, in document order.
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	var found []*ofxNode
+	for _, c := range n.children {
+		if c.name == name {
+			found = append(found, c)
+		}
+		found = append(found, c.findAll(name)...)
+	}
+	return found
+}
+
+type ofxTokenKind int
+
+const (
+	ofxOpen ofxTokenKind = iota
+	ofxClose
+	ofxEmpty
+	ofxText
+)
+
+type ofxToken struct {
+	kind  ofxTokenKind
+	value string
+}
+
+// parseOFX builds a tree from an OFX document. OFX 1.x is SGML, where leaf
+// elements such as <TRNAMT>-1.00 are never closed; OFX 2.x is XML. Both are
+// handled by closing a leaf as soon as the next tag starts, and treating an
+// element with no value as an empty leaf unless the document closes that
+// element somewhere.
+func parseOFX(document string) (*ofxNode, error) {
+	tokens, err := tokenizeOFX(document)
+	if err != nil {
+		return nil, err
+	}
+
+	closed := make(map[string]bool)
+	for _, token := range tokens {
+		if token.kind == ofxClose {
+			closed[token.value] = true
+		}
+	}
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	closeLeaf := func() {
+		top := stack[len(stack)-1]
+		if top != root && len(top.children) == 0 && (top.value != "" || !closed[top.name]) {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for _, token := range tokens {
+		switch token.kind {
+		case ofxOpen, ofxEmpty:
+			closeLeaf()
+			node := &ofxNode{name: token.value}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			if token.kind == ofxOpen {
+				stack = append(stack, node)
+			}
+		case ofxText:
+			if top := stack[len(stack)-1]; top != root {
+				top.value += token.value
+			}
+		case ofxClose:
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == token.value {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+	return root, nil
+}
+
+// tokenizeOFX splits a document into tags and text, skipping the OFX 1.x
+// header, XML declarations, processing instructions and comments.
+func tokenizeOFX(document string) ([]ofxToken, error) {
+	var tokens []ofxToken
+	for i := 0; i < len(document); {
+		if document[i] != '<' {
+			end := strings.IndexByte(document[i:], '<')
+			if end == -1 {
+				end = len(document) - i
+			}
+			if text := strings.TrimSpace(document[i : i+end]); text != "" {
+				tokens = append(tokens, ofxToken{kind: ofxText, value: html.UnescapeString(text)})
+			}
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(document[i:], "<!--") {
+			end := strings.Index(document[i:], "-->")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + len("-->")
+			continue
+		}
+
+		end := strings.IndexByte(document[i:], '>')
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated tag at offset %d", i)
+		}
+		tag := strings.TrimSpace(document[i+1 : i+end])
+		i += end + 1
+
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+
+		kind := ofxOpen
+		if strings.HasPrefix(tag, "/") {
+			kind = ofxClose
+			tag = tag[1:]
+		} else if strings.HasSuffix(tag, "/") {
+			kind = ofxEmpty
+			tag = tag[:len(tag)-1]
+		}
+		fields := strings.Fields(tag)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty tag name at offset %d", i)
+		}
+		tokens = append(tokens, ofxToken{kind: kind, value: strings.ToUpper(fields[0])})
+	}
+	return tokens, nil
+}
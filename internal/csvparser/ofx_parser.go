@@ -0,0 +1,196 @@
+package csvparser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// ofxInstitution is recorded when the file does not name its bank.
+const ofxInstitution = "OFX"
+
+// OFXParser reads OFX 1.x (SGML) and 2.x (XML) statements, including
+// Quicken's QFX variant. Amounts are signed from the account holder's side,
+// so debits are negative for card accounts too.
+type OFXParser struct{}
+
+func (p *OFXParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	statement, err := p.ParseStatement(r)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Transactions, nil
+}
+
+// ParseStatement reads the one bank or card statement in the file. Its
+// LEDGERBAL becomes the closing balance and AVAILBAL the available balance.
+func (p *OFXParser) ParseStatement(r io.Reader) (Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Statement{}, fmt.Errorf("reading OFX file: %w", err)
+	}
+	root, err := parseOFX(string(data))
+	if err != nil {
+		return Statement{}, fmt.Errorf("parsing OFX file: %w", err)
+	}
+	ofx := root.child("OFX")
+	if ofx == nil {
+		return Statement{}, fmt.Errorf("no OFX element found")
+	}
+
+	statements := append(ofx.findAll("STMTRS"), ofx.findAll("CCSTMTRS")...)
+	if len(statements) == 0 {
+		return Statement{}, fmt.Errorf("no bank or card statement found in OFX file")
+	}
+	if len(statements) > 1 {
+		return Statement{}, fmt.Errorf("OFX file contains %d statements; upload one account at a time", len(statements))
+	}
+	stmt := statements[0]
+
+	currency := strings.ToUpper(stmt.text("CURDEF"))
+	if currency == "" {
+		currency = money.GBP
+	}
+	if money.GetCurrency(currency) == nil {
+		return Statement{}, fmt.Errorf("unknown currency %q", currency)
+	}
+
+	statement := Statement{
+		Institution: ofx.path("SIGNONMSGSRSV1", "SONRS", "FI").text("ORG"),
+		Account:     ofxAccount(stmt),
+	}
+	if statement.Institution == "" {
+		statement.Institution = ofxInstitution
+	}
+
+	if ledger := stmt.child("LEDGERBAL"); ledger != nil {
+		statement.ClosingBalance, err = ofxAmount(ledger.text("BALAMT"), currency)
+		if err != nil {
+			return Statement{}, fmt.Errorf("parsing ledger balance: %w", err)
+		}
+		if asOf := ledger.text("DTASOF"); asOf != "" {
+			statement.BalanceDate, err = parseOFXDate(asOf)
+			if err != nil {
+				return Statement{}, fmt.Errorf("parsing ledger balance date: %w", err)
+			}
+		}
+	}
+	if available := stmt.child("AVAILBAL"); available != nil {
+		statement.AvailableBalance, err = ofxAmount(available.text("BALAMT"), currency)
+		if err != nil {
+			return Statement{}, fmt.Errorf("parsing available balance: %w", err)
+		}
+	}
+
+	for i, trn := range stmt.child("BANKTRANLIST").findAll("STMTTRN") {
+		tx, err := ofxTransaction(trn, currency)
+		if err != nil {
+			return Statement{}, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		tx.Bank = statement.Institution
+		statement.Transactions = append(statement.Transactions, tx)
+	}
+
+	return statement, nil
+}
+
+func ofxTransaction(trn *ofxNode, currency string) (transaction.Transaction, error) {
+	posted := trn.text("DTPOSTED")
+	date, err := parseOFXDate(posted)
+	if err != nil {
+		return transaction.Transaction{}, fmt.Errorf("parsing date '%s': %w", posted, err)
+	}
+
+	// A CURRENCY aggregate means the amount is in that currency rather
+	// than the statement's. ORIGCURRENCY only describes a conversion
+	// already applied, so the amount stays in the statement currency.
+	if symbol := trn.child("CURRENCY").text("CURSYM"); symbol != "" {
+		currency = strings.ToUpper(symbol)
+	}
+	amount, err := ofxAmount(trn.text("TRNAMT"), currency)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	description := trn.text("NAME")
+	if description == "" {
+		description = trn.text("MEMO")
+	}
+
+	var externalID *string
+	if fitID := trn.text("FITID"); fitID != "" {
+		externalID = &fitID
+	}
+
+	return transaction.Transaction{
+		Date:        date,
+		Description: description,
+		Amount:      amount,
+		ExternalID:  externalID,
+	}, nil
+}
+
+// ofxAccount reads BANKACCTFROM or CCACCTFROM. The full account number is
+// masked here so it never leaves the parser.
+func ofxAccount(stmt *ofxNode) AccountDetails {
+	if from := stmt.child("CCACCTFROM"); from != nil {
+		return AccountDetails{MaskedNumber: account.MaskNumber(from.text("ACCTID")), Type: account.TypeCredit}
+	}
+
+	from := stmt.child("BANKACCTFROM")
+	if from == nil {
+		return AccountDetails{}
+	}
+	details := AccountDetails{MaskedNumber: account.MaskNumber(from.text("ACCTID"))}
+	switch strings.ToUpper(from.text("ACCTTYPE")) {
+	case "CHECKING":
+		details.Type = account.TypeCurrent
+	case "SAVINGS", "MONEYMRKT", "CD":
+		details.Type = account.TypeSavings
+	case "CREDITLINE":
+		details.Type = account.TypeCredit
+	}
+	return details
+}
+
+// ofxAmount parses an OFX amount, which uses a plain "." or, from some
+// European banks, "," as the decimal separator.
+func ofxAmount(value string, currency string) (*money.Money, error) {
+	decimal := "."
+	if strings.Contains(value, ",") && !strings.Contains(value, ".") {
+		decimal = ","
+	}
+	amount, err := parseAmountWithDecimal(value, currency, decimal)
+	if err != nil {
+		return nil, fmt.Errorf("parsing amount '%s': %w", value, err)
+	}
+	return amount, nil
+}
+
+// parseOFXDate reads the date from an OFX datetime such as
+// "20260115120000.000[-5:EST]". Only the date part is kept; it is already
+// the bank's local posting date.
+func parseOFXDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("date too short")
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+func (p *OFXParser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+// Detect looks for the OFX 1.x header or the root element, which OFX 2.x
+// files reach after an XML declaration.
+func (p *OFXParser) Detect(sample []byte) bool {
+	upper := bytes.ToUpper(bytes.TrimSpace(sample))
+	return bytes.HasPrefix(upper, []byte("OFXHEADER:")) || bytes.Contains(upper, []byte("<OFX>"))
+}
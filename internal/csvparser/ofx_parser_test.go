@@ -0,0 +1,123 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOFXParser_ParseStatement_SGML(t *testing.T) {
+	file, err := os.Open("testdata/ofx1_sample.ofx")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := (&OFXParser{}).ParseStatement(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Building Society", statement.Institution)
+	assert.Equal(t, AccountDetails{MaskedNumber: "****5678", Type: "current"}, statement.Account)
+	assert.Equal(t, int64(293950), statement.ClosingBalance.Amount())
+	assert.Equal(t, int64(283950), statement.AvailableBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC), statement.BalanceDate)
+	assert.Len(t, statement.Transactions, 3)
+
+	first := statement.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), first.Date)
+	assert.Equal(t, "TEST PAYEE", first.Description)
+	assert.Equal(t, int64(-5000), first.Amount.Amount())
+	assert.Equal(t, "GBP", first.Amount.Currency().Code)
+	assert.Equal(t, "202601150001", *first.ExternalID)
+	assert.Equal(t, "Test Building Society", first.Bank)
+
+	assert.Equal(t, "FISH & CHIPS", statement.Transactions[1].Description)
+	assert.Equal(t, "SALARY PAYMENT", statement.Transactions[2].Description)
+	assert.Equal(t, int64(200000), statement.Transactions[2].Amount.Amount())
+}
+
+func TestOFXParser_ParseStatement_XMLCreditCard(t *testing.T) {
+	file, err := os.Open("testdata/ofx2_sample.qfx")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := (&OFXParser{}).ParseStatement(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "OFX", statement.Institution)
+	assert.Equal(t, AccountDetails{MaskedNumber: "****1111", Type: "credit"}, statement.Account)
+	assert.Equal(t, int64(-12550), statement.ClosingBalance.Amount())
+	assert.Equal(t, "EUR", statement.ClosingBalance.Currency().Code)
+	assert.Nil(t, statement.AvailableBalance)
+	assert.Len(t, statement.Transactions, 3)
+
+	assert.Equal(t, int64(-2550), statement.Transactions[0].Amount.Amount())
+	assert.Equal(t, "EUR", statement.Transactions[0].Amount.Currency().Code)
+	assert.Equal(t, "AT123456789", *statement.Transactions[0].ExternalID)
+	assert.Equal(t, "USD", statement.Transactions[1].Amount.Currency().Code)
+	assert.Equal(t, int64(10000), statement.Transactions[2].Amount.Amount())
+}
+
+func TestOFXParser_ParseStatement_MultipleStatements(t *testing.T) {
+	ofx := `<OFX><BANKMSGSRSV1>
+<STMTTRNRS><STMTRS><CURDEF>GBP<BANKTRANLIST></BANKTRANLIST></STMTRS></STMTTRNRS>
+<STMTTRNRS><STMTRS><CURDEF>GBP<BANKTRANLIST></BANKTRANLIST></STMTRS></STMTTRNRS>
+</BANKMSGSRSV1></OFX>`
+
+	_, err := (&OFXParser{}).ParseStatement(strings.NewReader(ofx))
+
+	assert.ErrorContains(t, err, "contains 2 statements")
+}
+
+func TestOFXParser_ParseStatement_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ofx    string
+		errMsg string
+	}{
+		{"not OFX", "Date,Description,Amount\n", "no OFX element"},
+		{"no statement", "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>", "no bank or card statement"},
+		{"unterminated tag", "<OFX><STMTRS", "unterminated tag"},
+		{"bad amount", "<OFX><STMTRS><CURDEF>GBP<BANKTRANLIST><STMTTRN><DTPOSTED>20260115<TRNAMT>abc</STMTTRN></BANKTRANLIST></STMTRS></OFX>", "transaction 1: parsing amount 'abc'"},
+		{"bad date", "<OFX><STMTRS><CURDEF>GBP<BANKTRANLIST><STMTTRN><DTPOSTED>2026<TRNAMT>1.00</STMTTRN></BANKTRANLIST></STMTRS></OFX>", "transaction 1: parsing date '2026'"},
+		{"unknown currency", "<OFX><STMTRS><CURDEF>XYZ</STMTRS></OFX>", "unknown currency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&OFXParser{}).ParseStatement(strings.NewReader(tt.ofx))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestParseOFX_EmptySGMLLeaf(t *testing.T) {
+	root, err := parseOFX("<OFX><STMTTRN><MEMO><NAME>SHOP<TRNAMT>-1.00</STMTTRN></OFX>")
+
+	assert.NoError(t, err)
+	trn := root.path("OFX", "STMTTRN")
+	assert.Equal(t, "", trn.text("MEMO"))
+	assert.Equal(t, "SHOP", trn.text("NAME"))
+	assert.Equal(t, "-1.00", trn.text("TRNAMT"))
+}
+
+func TestOFXParser_Detect(t *testing.T) {
+	parser := &OFXParser{}
+
+	assert.True(t, parser.Detect([]byte("OFXHEADER:100\r\nDATA:OFXSGML\r\n")))
+	assert.True(t, parser.Detect([]byte("<?xml version=\"1.0\"?>\n<?OFX OFXHEADER=\"200\"?>\n<OFX>\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount,Card Member\n")))
+}
+
+func TestService_Parse_DetectsOFX(t *testing.T) {
+	file, err := os.Open("testdata/ofx1_sample.ofx")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := NewService().ParseStatement(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ofx", statement.Format)
+	assert.Len(t, statement.Transactions, 3)
+}
@@ -1,42 +1,528 @@
 package csvparser
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"golang.org/x/text/transform"
 
 	"github.com/kushturner/finances/internal/transaction"
 )
 
+// sampleSize is how much of an upload is inspected to detect its format.
+const sampleSize = 8 << 10
+
 type Service interface {
+	// Parse reads transactions from r. An empty bankType detects the
+	// format from the file contents. Amounts are always returned with
+	// outflows negative, whatever the source format uses.
 	Parse(r io.Reader, bankType string) ([]transaction.Transaction, error)
+	// ParseStatement is Parse plus whatever the file says about the account
+	// the transactions belong to. It fails for a file holding statements
+	// for more than one account.
+	ParseStatement(r io.Reader, bankType string) (Statement, error)
+	// ParseStatements is ParseStatement for formats that can hold several
+	// statements in one file, returning them in file order.
+	ParseStatements(r io.Reader, bankType string) ([]Statement, error)
+	// ParseStatementsWithOptions is ParseStatements with the choices an
+	// upload can make beyond the format.
+	ParseStatementsWithOptions(r io.Reader, options ParseOptions) (ParseResult, error)
+	// StreamStatement is ParseStatement for files too large to hold as a
+	// slice of transactions: a CSV export is read a row at a time as the
+	// stream's transactions are ranged over. It fails with
+	// ErrNotStreamable for spreadsheets and the formats read whole, which
+	// ParseStatementsWithOptions reads instead.
+	StreamStatement(r io.Reader, options ParseOptions) (*StatementStream, error)
+	SupportedFormats() []string
+	// Profiles returns the bank profiles in detection order.
+	Profiles() []Profile
+	// AddProfile registers a user profile, replacing an earlier user
+	// profile with the same name. Built-in profiles cannot be replaced.
+	AddProfile(profile Profile) error
+}
+
+// ParseOptions are the choices an upload can make about how its file is
+// read. The zero value detects the format and reads the first sheet of a
+// spreadsheet.
+type ParseOptions struct {
+	// BankType names the format; empty detects it from the file.
+	BankType string
+	// Sheet picks the sheet of an XLSX workbook by name or 1-based
+	// position.
+	Sheet string
+	// Encoding names the character encoding of a text file, such as
+	// "windows-1252"; empty detects it.
+	Encoding string
+	// Lenient leaves out the rows that cannot be read, reporting them in
+	// the result, rather than failing the file on the first. Only formats
+	// read row by row, such as CSV exports, can skip rows; asking for it
+	// with an OFX, QIF, camt or MT940 file fails with
+	// ErrLenientUnsupported.
+	Lenient bool
+}
+
+// ParseResult is a parsed upload: its statements in file order, and the
+// rows a lenient parse left out of them.
+type ParseResult struct {
+	Statements []Statement
+	Rejected   []RowError
 }
 
-type service struct{}
+// Statement is a parsed file. Institution is the bank name the
+// transactions are recorded under; Account is empty for formats that do not
+// identify the account. The balances are those the file reports as at
+// BalanceDate, and are nil when it reports none. OpeningBalance is the
+// balance as at OpeningBalanceDate, before the first transaction, for
+// formats that report one.
+type Statement struct {
+	Format             string
+	Institution        string
+	Account            AccountDetails
+	Transactions       []transaction.Transaction
+	ClosingBalance     *money.Money
+	AvailableBalance   *money.Money
+	BalanceDate        time.Time
+	OpeningBalance     *money.Money
+	OpeningBalanceDate time.Time
+}
 
+type AccountDetails struct {
+	Name         string
+	MaskedNumber string
+	// Type is one of the account package's account types, or empty when
+	// the format does not say.
+	Type string
+}
+
+type service struct {
+	mu      sync.RWMutex
+	parsers []registeredParser
+	// profileDir is where added profiles are saved; empty keeps them in
+	// memory only.
+	profileDir string
+}
+
+type registeredParser struct {
+	name   string
+	parser parser
+	// path is the file a user profile was loaded from or saved to.
+	path string
+}
+
+// NewService returns a service for the built-in formats: the bundled CSV
+// profiles, OFX, QIF, camt, MT940, Revolut, PayPal and Wise.
 func NewService() Service {
-	return &service{}
+	s := &service{}
+	for _, profile := range bundledProfiles() {
+		s.parsers = append(s.parsers, registeredParser{name: profile.Name, parser: &profileParser{profile: profile}})
+	}
+	s.parsers = append(s.parsers,
+		registeredParser{name: "ofx", parser: &OFXParser{}},
+		registeredParser{name: "qif", parser: &QIFParser{}},
+		registeredParser{name: "camt", parser: &CamtParser{}},
+		registeredParser{name: "mt940", parser: &MT940Parser{}},
+		registeredParser{name: "revolut", parser: &RevolutParser{}},
+		registeredParser{name: "paypal", parser: &PayPalParser{}},
+		registeredParser{name: "wise", parser: &WiseParser{}},
+	)
+	return s
+}
+
+// NewServiceWithProfileDir also loads the user profiles in dir, and saves
+// profiles added later to it so they survive a restart.
+func NewServiceWithProfileDir(dir string) (Service, error) {
+	files, err := loadProfileDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewService().(*service)
+	s.profileDir = dir
+	for _, file := range files {
+		if err := s.register(file.profile, file.path); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.path, err)
+		}
+	}
+	return s, nil
 }
 
 func (s *service) Parse(r io.Reader, bankType string) ([]transaction.Transaction, error) {
-	parser, err := getParser(bankType)
+	statement, err := s.ParseStatement(r, bankType)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Transactions, nil
+}
+
+func (s *service) ParseStatement(r io.Reader, bankType string) (Statement, error) {
+	statements, err := s.ParseStatements(r, bankType)
+	if err != nil {
+		return Statement{}, err
+	}
+	return singleStatement(statements)
+}
+
+// parseSingleStatement is Parse for a format whose files can hold several
+// statements: the transactions of a file holding one.
+func parseSingleStatement(p multiStatementParser, r io.Reader) ([]transaction.Transaction, error) {
+	statements, err := p.ParseStatements(r)
+	if err != nil {
+		return nil, err
+	}
+	statement, err := singleStatement(statements)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Transactions, nil
+}
+
+// singleStatement returns the only statement of a file, failing for a file
+// holding several.
+func singleStatement(statements []Statement) (Statement, error) {
+	if len(statements) > 1 {
+		return Statement{}, fmt.Errorf("file contains %d statements; upload one account at a time", len(statements))
+	}
+	return statements[0], nil
+}
+
+func (s *service) ParseStatements(r io.Reader, bankType string) ([]Statement, error) {
+	result, err := s.ParseStatementsWithOptions(r, ParseOptions{BankType: bankType})
 	if err != nil {
 		return nil, err
 	}
-	return parser.Parse(r)
+	return result.Statements, nil
+}
+
+func (s *service) ParseStatementsWithOptions(r io.Reader, options ParseOptions) (ParseResult, error) {
+	var report *rowReport
+	if options.Lenient {
+		report = &rowReport{}
+	}
+	statements, err := s.parse(r, options, report)
+	if err != nil {
+		return ParseResult{}, err
+	}
+	return ParseResult{Statements: statements, Rejected: report.rows()}, nil
+}
+
+// parse reads r as options say, reporting rows it leaves out to report,
+// which is nil for a strict parse.
+func (s *service) parse(r io.Reader, options ParseOptions, report *rowReport) ([]Statement, error) {
+	buffered, sample, err := peekSample(r)
+	if err != nil {
+		return nil, err
+	}
+	if isSpreadsheet(sample) {
+		if options.Encoding != "" {
+			return nil, fmt.Errorf("an encoding can only be chosen for a text upload")
+		}
+		return s.parseSpreadsheet(buffered, options, report)
+	}
+
+	parser, bankType, text, err := s.openText(buffered, sample, options)
+	if err != nil {
+		return nil, err
+	}
+	return parseWith(parser, bankType, text, report)
+}
+
+// peekSample buffers r and returns the start of it, which format
+// detection looks at.
+func peekSample(r io.Reader) (*bufio.Reader, []byte, error) {
+	buffered := bufio.NewReaderSize(r, sampleSize)
+	sample, err := buffered.Peek(sampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil, fmt.Errorf("reading file sample: %w", err)
+	}
+	return buffered, sample, nil
+}
+
+// openText decodes a text file to UTF-8 and picks the parser for it,
+// returning the decoded file for the parser to read.
+func (s *service) openText(buffered *bufio.Reader, sample []byte, options ParseOptions) (parser, string, io.Reader, error) {
+	if options.Sheet != "" {
+		return nil, "", nil, fmt.Errorf("a sheet can only be chosen for an XLSX upload")
+	}
+
+	// Parsers read UTF-8, so the file is decoded before the sample that
+	// detection sees is taken again.
+	decoder, err := textDecoder(sample, options.Encoding)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	buffered = bufio.NewReaderSize(transform.NewReader(buffered, decoder), sampleSize)
+	sample, err = buffered.Peek(sampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", nil, fmt.Errorf("decoding file: %w", err)
+	}
+
+	bankType := options.BankType
+	if bankType == "" {
+		bankType, err = s.detect(sample)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+	parser, err := s.getParser(bankType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return parser, bankType, buffered, nil
+}
+
+// parseSpreadsheet reads one sheet of an XLSX workbook as the delimited
+// text the chosen format's parser reads, with dates and numbers written
+// the way that format writes them.
+func (s *service) parseSpreadsheet(r io.Reader, options ParseOptions, report *rowReport) ([]Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading spreadsheet: %w", err)
+	}
+	book, err := readWorkbook(data)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := book.sheet(options.Sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	bankType := options.BankType
+	if bankType == "" {
+		bankType, err = s.detectSheet(sheet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parser, err := s.getParser(bankType)
+	if err != nil {
+		return nil, err
+	}
+	sp, ok := parser.(spreadsheetParser)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s files are not spreadsheets", ErrSpreadsheet, bankType)
+	}
+	return parseWith(parser, bankType, bytes.NewReader(sheet.csv(sp.cellFormat(), 0)), report)
+}
+
+// parseWith reads r with parser and normalises the result.
+func parseWith(parser parser, bankType string, r io.Reader, report *rowReport) ([]Statement, error) {
+	if _, ok := parser.(rowParser); !ok && report != nil {
+		return nil, fmt.Errorf("%w for %s files, which are read whole; use strict mode", ErrLenientUnsupported, bankType)
+	}
+
+	var statements []Statement
+	var err error
+	switch p := parser.(type) {
+	case rowParser:
+		statements, err = p.parseRows(r, report)
+	case multiStatementParser:
+		statements, err = p.ParseStatements(r)
+	case statementParser:
+		var statement Statement
+		statement, err = p.ParseStatement(r)
+		statements = []Statement{statement}
+	default:
+		var statement Statement
+		statement.Transactions, err = parser.Parse(r)
+		statements = []Statement{statement}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range statements {
+		finishStatement(&statements[i], parser, bankType)
+	}
+	return statements, nil
+}
+
+// finishStatement records the format a statement was read as and
+// normalises its signs.
+func finishStatement(statement *Statement, parser parser, bankType string) {
+	statement.Format = strings.ToLower(bankType)
+	if statement.Institution == "" && len(statement.Transactions) > 0 {
+		statement.Institution = statement.Transactions[0].Bank
+	}
+	normaliseSigns(statement, parser.SignConvention())
+}
+
+func (s *service) SupportedFormats() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.parsers))
+	for _, p := range s.parsers {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+func (s *service) Profiles() []Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var profiles []Profile
+	for _, p := range s.parsers {
+		if pp, ok := p.parser.(*profileParser); ok {
+			profiles = append(profiles, pp.profile)
+		}
+	}
+	return profiles
+}
+
+func (s *service) AddProfile(profile Profile) error {
+	profile.builtIn = false
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkReplaceable(profile.Name); err != nil {
+		return err
+	}
+
+	var path string
+	if s.profileDir != "" {
+		path = filepath.Join(s.profileDir, profile.Name+".yaml")
+		if existing := s.find(profile.Name); existing != nil && existing.path != "" {
+			path = existing.path
+		}
+		if err := writeProfileFile(path, profile); err != nil {
+			return fmt.Errorf("saving profile %s: %w", profile.Name, err)
+		}
+	}
+	return s.registerLocked(profile, path)
+}
+
+func (s *service) register(profile Profile, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.registerLocked(profile, path)
+}
+
+// registerLocked adds or replaces a user profile. s.mu must be held.
+func (s *service) registerLocked(profile Profile, path string) error {
+	if err := s.checkReplaceable(profile.Name); err != nil {
+		return err
+	}
+
+	registered := registeredParser{name: profile.Name, parser: &profileParser{profile: profile}, path: path}
+	if existing := s.find(profile.Name); existing != nil {
+		*existing = registered
+		return nil
+	}
+	s.parsers = append(s.parsers, registered)
+	return nil
+}
+
+func (s *service) checkReplaceable(name string) error {
+	existing := s.find(name)
+	if existing == nil {
+		return nil
+	}
+	if pp, ok := existing.parser.(*profileParser); ok && !pp.profile.builtIn {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is a built-in format", ErrProfileConflict, name)
+}
+
+func (s *service) find(name string) *registeredParser {
+	for i := range s.parsers {
+		if s.parsers[i].name == name {
+			return &s.parsers[i]
+		}
+	}
+	return nil
+}
+
+// detectSheet is detect for a spreadsheet, offering each format that can
+// read one the start of the sheet written out its own way.
+func (s *service) detectSheet(sheet sheet) (string, error) {
+	s.mu.RLock()
+	var candidates []string
+	for _, p := range s.parsers {
+		sp, ok := p.parser.(spreadsheetParser)
+		if ok && p.parser.Detect(sheet.csv(sp.cellFormat(), spreadsheetDetectRows)) {
+			candidates = append(candidates, p.name)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(candidates) != 1 {
+		return "", &DetectionError{Candidates: candidates, Supported: s.SupportedFormats()}
+	}
+	return candidates[0], nil
+}
+
+func (s *service) detect(sample []byte) (string, error) {
+	s.mu.RLock()
+	var candidates []string
+	for _, p := range s.parsers {
+		if p.parser.Detect(sample) {
+			candidates = append(candidates, p.name)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(candidates) != 1 {
+		return "", &DetectionError{Candidates: candidates, Supported: s.SupportedFormats()}
+	}
+	return candidates[0], nil
 }
 
 type parser interface {
 	Parse(r io.Reader) ([]transaction.Transaction, error)
+	// Detect reports whether the start of a file looks like this format.
+	Detect(sample []byte) bool
+	// SignConvention reports how Parse signs amounts, so the service can
+	// normalise them to OutflowNegative.
+	SignConvention() SignConvention
 }
 
-func getParser(bankType string) (parser, error) {
-	switch strings.ToLower(bankType) {
-	case "nationwide":
-		return &NationwideParser{}, nil
-	case "amex":
-		return &AmexParser{}, nil
-	default:
-		return nil, fmt.Errorf("unsupported bank type: %s", bankType)
+// statementParser is implemented by formats that identify the account in
+// the file as well as listing its transactions.
+type statementParser interface {
+	ParseStatement(r io.Reader) (Statement, error)
+}
+
+// multiStatementParser is implemented by formats whose files can hold
+// statements for several accounts. ParseStatements returns at least one.
+type multiStatementParser interface {
+	ParseStatements(r io.Reader) ([]Statement, error)
+}
+
+func (s *service) getParser(bankType string) (parser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.parsers {
+		if p.name == strings.ToLower(bankType) {
+			return p.parser, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported bank type: %s", bankType)
+}
+
+// DetectionError is returned when the format of a file cannot be determined
+// unambiguously. Candidates holds every format that matched, if any.
+type DetectionError struct {
+	Candidates []string
+	Supported  []string
+}
+
+func (e *DetectionError) Error() string {
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("could not detect file format, supported formats: %s", strings.Join(e.Supported, ", "))
 	}
+	return fmt.Sprintf("file matches multiple formats: %s", strings.Join(e.Candidates, ", "))
 }
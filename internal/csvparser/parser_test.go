@@ -1,9 +1,13 @@
 package csvparser
 
 import (
+	"errors"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/transaction"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,3 +19,149 @@ func TestService_Parse_InvalidBankType(t *testing.T) {
 	assert.Nil(t, transactions)
 	assert.Contains(t, err.Error(), "unsupported bank type")
 }
+
+func TestService_Parse_DetectsNationwide(t *testing.T) {
+	file, err := os.Open("testdata/nationwide_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	transactions, err := NewService().Parse(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(transactions))
+	assert.Equal(t, "Nationwide", transactions[0].Bank)
+}
+
+func TestService_Parse_DetectsAmex(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	transactions, err := NewService().Parse(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(transactions))
+	assert.Equal(t, "American Express", transactions[0].Bank)
+}
+
+func TestService_ParseStatement_FillsFormatAndInstitution(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := NewService().ParseStatement(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "amex", statement.Format)
+	assert.Equal(t, "American Express", statement.Institution)
+	assert.Equal(t, "credit", statement.Account.Type)
+	assert.Len(t, statement.Transactions, 4)
+}
+
+func TestService_Parse_BankOverride(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = NewService().Parse(file, "nationwide")
+
+	assert.Error(t, err)
+	var detectionErr *DetectionError
+	assert.False(t, errors.As(err, &detectionErr))
+}
+
+func TestService_Parse_UnknownFormat(t *testing.T) {
+	_, err := NewService().Parse(strings.NewReader("Col A,Col B\n1,2\n"), "")
+
+	var detectionErr *DetectionError
+	assert.True(t, errors.As(err, &detectionErr))
+	assert.Empty(t, detectionErr.Candidates)
+	assert.Equal(t, []string{"nationwide", "amex", "monzo", "ofx", "qif", "camt", "mt940", "revolut", "paypal", "wise"}, detectionErr.Supported)
+	assert.Contains(t, err.Error(), "supported formats: nationwide, amex, monzo, ofx, qif")
+}
+
+func TestService_Parse_AmbiguousFormat(t *testing.T) {
+	svc := &service{
+		parsers: []registeredParser{
+			{name: "amex", parser: bundledParser("amex")},
+			{name: "amex-copy", parser: bundledParser("amex")},
+		},
+	}
+
+	_, err := svc.Parse(strings.NewReader("Date,Description,Amount,Card Member\n"), "")
+
+	var detectionErr *DetectionError
+	assert.True(t, errors.As(err, &detectionErr))
+	assert.Equal(t, []string{"amex", "amex-copy"}, detectionErr.Candidates)
+	assert.Contains(t, err.Error(), "multiple formats")
+}
+
+func TestNationwideProfile_Detect(t *testing.T) {
+	parser := bundledParser("nationwide")
+
+	assert.True(t, parser.Detect([]byte("\"Account Name:\",\"Debit ****12345\"\n\"Account Balance:\",\"£1.00\"\n\n\"Date\",\"Transaction type\",\"Description\",\"Paid out\",\"Paid in\",\"Balance\"\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount\n")))
+}
+
+func TestAmexProfile_Detect(t *testing.T) {
+	parser := bundledParser("amex")
+
+	assert.True(t, parser.Detect([]byte("Date,Description,Amount,Extended Details\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount\n")))
+}
+
+func TestService_Parse_NormalisesAmexSigns(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	transactions, err := NewService().Parse(file, "amex")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-2550), transactions[0].Amount.Amount())
+	assert.Equal(t, int64(10000), transactions[1].Amount.Amount())
+	assert.Equal(t, "GBP", transactions[0].Amount.Currency().Code)
+}
+
+func TestService_Parse_LeavesNationwideSigns(t *testing.T) {
+	file, err := os.Open("testdata/nationwide_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	transactions, err := NewService().Parse(file, "nationwide")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-5000), transactions[0].Amount.Amount())
+	assert.Equal(t, int64(200000), transactions[4].Amount.Amount())
+}
+
+func TestAmexProfile_Parse_KeepsStatementSigns(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	transactions, err := bundledParser("amex").Parse(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2550), transactions[0].Amount.Amount())
+	assert.Equal(t, OutflowPositive, bundledParser("amex").SignConvention())
+}
+
+func TestNormaliseSigns_FlipsBalances(t *testing.T) {
+	statement := Statement{
+		Transactions: []transaction.Transaction{
+			{Amount: money.New(2550, "GBP"), RunningBalance: money.New(12550, "GBP")},
+			{Amount: money.New(-1000, "GBP")},
+		},
+		ClosingBalance: money.New(11550, "GBP"),
+	}
+
+	normaliseSigns(&statement, OutflowPositive)
+
+	assert.Equal(t, int64(-2550), statement.Transactions[0].Amount.Amount())
+	assert.Equal(t, int64(-12550), statement.Transactions[0].RunningBalance.Amount())
+	assert.Equal(t, int64(1000), statement.Transactions[1].Amount.Amount())
+	assert.Nil(t, statement.Transactions[1].RunningBalance)
+	assert.Equal(t, int64(-11550), statement.ClosingBalance.Amount())
+	assert.Nil(t, statement.AvailableBalance)
+}
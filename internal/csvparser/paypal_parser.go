@@ -0,0 +1,431 @@
+package csvparser
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+const paypalInstitution = transaction.PayPalBank
+
+// paypalSkippedStatuses are the statuses of rows that never moved money.
+var paypalSkippedStatuses = map[string]bool{
+	"pending":   true,
+	"denied":    true,
+	"canceled":  true,
+	"cancelled": true,
+	"removed":   true,
+}
+
+// PayPalParser reads PayPal activity downloads. Each row has a gross
+// amount, a fee and the net of the two, in the currency of that row; the
+// balance in each currency is its own statement.
+//
+// A payment in a currency PayPal had to buy comes with a pair of "General
+// Currency Conversion" rows referencing it. The three rows become one
+// transaction in the currency actually spent, with the original amount as
+// its local amount and the rate in its notes. Fees become transactions of
+// their own, linked to the payment they were charged on. A row that
+// references a payment in the same currency, such as the bank or card
+// deposit PayPal took to fund it, is linked to that payment; the import
+// links the payment to the bank or card transaction that funded it.
+// Authorisations and holds only reserve money, so they are left out.
+type PayPalParser struct{}
+
+type paypalColumns struct {
+	date, name, txType, status, currency, gross, fee, net, id int
+	reference, balance, balanceImpact, itemTitle              int
+}
+
+type paypalRow struct {
+	date                    time.Time
+	name, txType, itemTitle string
+	currency                string
+	gross, fee, net         *money.Money
+	balance                 *money.Money
+	id, reference           string
+}
+
+func (p *PayPalParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	return parseSingleStatement(p, r)
+}
+
+// ParseStatements returns a statement for each currency with transactions,
+// in the order the currencies first appear.
+func (p *PayPalParser) ParseStatements(r io.Reader) ([]Statement, error) {
+	return p.parseRows(r, nil)
+}
+
+func (p *PayPalParser) parseRows(r io.Reader, report *rowReport) ([]Statement, error) {
+	rows, err := readPayPalRows(r, report)
+	if err != nil {
+		return nil, err
+	}
+
+	conversions := make(map[string][]paypalRow)
+	for _, row := range rows {
+		if isPayPalConversion(row) && row.reference != "" {
+			conversions[row.reference] = append(conversions[row.reference], row)
+		}
+	}
+
+	var statements []*Statement
+	byCurrency := make(map[string]*Statement)
+	statementFor := func(currency string) *Statement {
+		statement := byCurrency[currency]
+		if statement == nil {
+			statement = &Statement{
+				Institution: paypalInstitution,
+				Account:     AccountDetails{Name: "PayPal " + currency},
+			}
+			byCurrency[currency] = statement
+			statements = append(statements, statement)
+		}
+		return statement
+	}
+
+	var converted []paypalConverted
+	collapsed := make(map[string]bool)
+	for _, row := range rows {
+		if isPayPalConversion(row) {
+			continue
+		}
+		txs, ok := convertedPayPalTransactions(row, conversions[row.id])
+		if ok {
+			collapsed[row.id] = true
+		} else {
+			txs = paypalTransactions(row)
+		}
+		converted = append(converted, paypalConverted{row, txs})
+	}
+
+	// Conversions whose payment is not in the file, or that did not cover
+	// it exactly, are kept as they are: they still moved money.
+	for _, row := range rows {
+		if isPayPalConversion(row) && !collapsed[row.reference] {
+			converted = append(converted, paypalConverted{row, paypalTransactions(row)})
+		}
+	}
+
+	// A row is linked only to a payment in the same currency balance, as
+	// links never cross accounts.
+	currencies := make(map[string]string)
+	for _, c := range converted {
+		if c.row.id != "" {
+			currencies[c.row.id] = c.currency()
+		}
+	}
+	for _, c := range converted {
+		if c.row.reference != "" && currencies[c.row.reference] == c.currency() {
+			reference := c.row.reference
+			c.txs[0].LinkedExternalID = &reference
+		}
+		statement := statementFor(c.currency())
+		statement.Transactions = append(statement.Transactions, c.txs...)
+	}
+
+	// The balance column follows every row, including the conversion rows
+	// folded into a payment.
+	for _, row := range rows {
+		statement := byCurrency[row.currency]
+		if statement != nil && row.balance != nil && !row.date.Before(statement.BalanceDate) {
+			statement.ClosingBalance = row.balance
+			statement.BalanceDate = row.date
+		}
+	}
+
+	if len(statements) == 0 {
+		return []Statement{{Institution: paypalInstitution}}, nil
+	}
+	result := make([]Statement, 0, len(statements))
+	for _, statement := range statements {
+		result = append(result, *statement)
+	}
+	return result, nil
+}
+
+// readPayPalRows reads the rows that moved money, leaving out holds,
+// authorisations and payments that did not complete.
+func readPayPalRows(r io.Reader, report *rowReport) ([]paypalRow, error) {
+	reader := newRowReader(r, report)
+	reader.FieldsPerRecord = -1
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+	cols, err := paypalHeader(headers)
+	if err != nil {
+		return nil, err
+	}
+	required := max(cols.date, cols.name, cols.txType, cols.status, cols.currency, cols.gross, cols.fee, cols.net, cols.id)
+
+	// The dates are read once every row is in, so rowNums keeps each
+	// record's place in the file and raws its text, kept for a lenient parse.
+	var records [][]string
+	var rowNums []int
+	var raws []string
+	var dates []string
+	for rowNum := 1; ; rowNum++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := report.readError(rowNum, reader.Raw(), err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(record) <= required {
+			if err := report.reject(rowNum, reader.Raw(), shortRowError(record, required)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		records = append(records, record)
+		rowNums = append(rowNums, rowNum)
+		raws = append(raws, reader.Raw())
+		dates = append(dates, strings.TrimSpace(record[cols.date]))
+	}
+
+	// The date order follows the account's locale.
+	order, err := qifDateOrder(dates)
+	if err != nil {
+		return nil, fmt.Errorf("PayPal dates mix day-first and month-first order")
+	}
+
+	var rows []paypalRow
+	for i, record := range records {
+		txType := strings.TrimSpace(record[cols.txType])
+		status := strings.ToLower(strings.TrimSpace(record[cols.status]))
+		if isPayPalHold(txType) || strings.EqualFold(field(record, cols.balanceImpact), "memo") || paypalSkippedStatuses[status] {
+			continue
+		}
+
+		row, err := readPayPalRow(record, cols, dates[i], order)
+		if err != nil {
+			if err := report.reject(rowNums[i], raws[i], err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readPayPalRow(record []string, cols paypalColumns, dateValue string, order QIFDateOrder) (paypalRow, error) {
+	date, err := parseQIFDate(dateValue, order)
+	if err != nil {
+		return paypalRow{}, inColumn("Date", fmt.Errorf("parsing date '%s': %w", dateValue, err))
+	}
+	row := paypalRow{
+		date:      date,
+		name:      strings.TrimSpace(record[cols.name]),
+		txType:    strings.TrimSpace(record[cols.txType]),
+		itemTitle: field(record, cols.itemTitle),
+		currency:  strings.ToUpper(strings.TrimSpace(record[cols.currency])),
+		id:        strings.TrimSpace(record[cols.id]),
+		reference: field(record, cols.reference),
+	}
+
+	amounts := []struct {
+		column string
+		value  string
+		dest   **money.Money
+	}{
+		{"Gross", record[cols.gross], &row.gross},
+		{"Fee", record[cols.fee], &row.fee},
+		{"Net", record[cols.net], &row.net},
+		{"Balance", field(record, cols.balance), &row.balance},
+	}
+	for _, amount := range amounts {
+		if strings.TrimSpace(amount.value) == "" {
+			continue
+		}
+		*amount.dest, err = parseAmountIn(amount.value, row.currency)
+		if err != nil {
+			return paypalRow{}, inColumn(amount.column, fmt.Errorf("parsing %s '%s': %w", strings.ToLower(amount.column), amount.value, err))
+		}
+	}
+	if row.gross == nil {
+		return paypalRow{}, inColumn("Gross", fmt.Errorf("gross amount is empty"))
+	}
+	if row.fee == nil {
+		row.fee = money.New(0, row.currency)
+	}
+	if row.net == nil {
+		row.net = money.New(row.gross.Amount()+row.fee.Amount(), row.currency)
+	}
+	return row, nil
+}
+
+func paypalHeader(headers []string) (paypalColumns, error) {
+	cols := paypalColumns{
+		date:          findColumnIndex(headers, "Date"),
+		name:          findColumnIndex(headers, "Name"),
+		txType:        findColumnIndex(headers, "Type"),
+		status:        findColumnIndex(headers, "Status"),
+		currency:      findColumnIndex(headers, "Currency"),
+		gross:         findColumnIndex(headers, "Gross"),
+		fee:           findColumnIndex(headers, "Fee"),
+		net:           findColumnIndex(headers, "Net"),
+		id:            findColumnIndex(headers, "Transaction ID"),
+		reference:     findColumnIndex(headers, "Reference Txn ID"),
+		balance:       findColumnIndex(headers, "Balance"),
+		balanceImpact: findColumnIndex(headers, "Balance Impact"),
+		itemTitle:     findColumnIndex(headers, "Item Title"),
+	}
+	for _, idx := range []int{cols.date, cols.name, cols.txType, cols.status, cols.currency, cols.gross, cols.fee, cols.net, cols.id} {
+		if idx == -1 {
+			return paypalColumns{}, fmt.Errorf("required column not found in CSV headers")
+		}
+	}
+	return cols, nil
+}
+
+// paypalTransactions turns a row into its transaction, followed by its fee
+// when it was charged one.
+func paypalTransactions(row paypalRow) []transaction.Transaction {
+	tx := paypalTransaction(row, row.gross)
+	if row.fee.IsZero() {
+		return []transaction.Transaction{tx}
+	}
+	return []transaction.Transaction{tx, paypalFee(tx, row.fee)}
+}
+
+// paypalConverted is a row as the transactions it becomes.
+type paypalConverted struct {
+	row paypalRow
+	txs []transaction.Transaction
+}
+
+// currency is that of the statement the transactions belong to.
+func (c paypalConverted) currency() string {
+	return c.txs[0].Amount.Currency().Code
+}
+
+// convertedPayPalTransactions folds a payment and the pair of conversions
+// that paid for it, or received it, into transactions in the other
+// currency. It reports false when the pair does not exactly cover the
+// payment's net amount, as when part of it came from an existing balance.
+func convertedPayPalTransactions(row paypalRow, pair []paypalRow) ([]transaction.Transaction, bool) {
+	if len(pair) != 2 || pair[0].currency == pair[1].currency || row.net.IsZero() {
+		return nil, false
+	}
+	foreign, home := pair[0], pair[1]
+	if home.currency == row.currency {
+		foreign, home = home, foreign
+	}
+	if foreign.currency != row.currency || foreign.gross.Amount() != -row.net.Amount() {
+		return nil, false
+	}
+
+	// The gross and fee are converted at the rate the net was, with any
+	// rounding left in the gross so the two still add up to the net.
+	homeNet := home.gross.Amount()
+	homeFee := mulDivRound(row.fee.Amount(), homeNet, row.net.Amount())
+	gross := money.New(homeNet-homeFee, home.currency)
+
+	tx := paypalTransaction(row, gross)
+	tx.LocalAmount = row.gross
+	notes := fmt.Sprintf("1 %s = %s %s", row.currency, conversionRate(row.net, home.gross), home.currency)
+	tx.Notes = &notes
+	if row.fee.IsZero() {
+		return []transaction.Transaction{tx}, true
+	}
+	fee := paypalFee(tx, money.New(homeFee, home.currency))
+	fee.LocalAmount = row.fee
+	return []transaction.Transaction{tx, fee}, true
+}
+
+func paypalTransaction(row paypalRow, amount *money.Money) transaction.Transaction {
+	tx := transaction.Transaction{
+		Date:        row.date,
+		Description: firstNonEmpty(row.name, row.itemTitle, row.txType),
+		Amount:      amount,
+		Bank:        paypalInstitution,
+	}
+	if row.id != "" {
+		id := row.id
+		tx.ExternalID = &id
+	}
+	return tx
+}
+
+func paypalFee(tx transaction.Transaction, fee *money.Money) transaction.Transaction {
+	feeTx := transaction.Transaction{
+		Date:        tx.Date,
+		Description: truncateUTF8("Fee - "+tx.Description, maxDescriptionLength),
+		Amount:      fee,
+		Bank:        paypalInstitution,
+	}
+	if tx.ExternalID != nil {
+		feeRef := *tx.ExternalID + "/fee"
+		feeTx.ExternalID = &feeRef
+		feeTx.LinkedExternalID = tx.ExternalID
+	}
+	return feeTx
+}
+
+// conversionRate is how much of to one unit of from bought, to four
+// decimal places.
+func conversionRate(from, to *money.Money) string {
+	rate := new(big.Rat).SetFrac(
+		new(big.Int).Abs(big.NewInt(to.Amount())),
+		new(big.Int).Abs(big.NewInt(from.Amount())),
+	)
+	scale := new(big.Rat).SetFrac(
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(from.Currency().Fraction)), nil),
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to.Currency().Fraction)), nil),
+	)
+	return rate.Mul(rate, scale).FloatString(4)
+}
+
+// mulDivRound returns a*b/c rounded half away from zero, without
+// overflowing on the way.
+func mulDivRound(a, b, c int64) int64 {
+	quotient, remainder := new(big.Int).QuoRem(
+		new(big.Int).Mul(big.NewInt(a), big.NewInt(b)),
+		big.NewInt(c),
+		new(big.Int),
+	)
+	if new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2))).Cmp(new(big.Int).Abs(big.NewInt(c))) >= 0 {
+		if (remainder.Sign() < 0) != (c < 0) {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient.Int64()
+}
+
+func isPayPalConversion(row paypalRow) bool {
+	return strings.Contains(strings.ToLower(row.txType), "currency conversion")
+}
+
+// isPayPalHold reports whether a row type only reserves money, as
+// authorisations and account holds and their reversals do.
+func isPayPalHold(txType string) bool {
+	lower := strings.ToLower(txType)
+	return strings.Contains(lower, "authorization") || strings.Contains(lower, "authorisation") || strings.Contains(lower, "hold")
+}
+
+func (p *PayPalParser) cellFormat() cellFormat {
+	return cellFormat{delimiter: ',', dateLayout: "02/01/2006"}
+}
+
+func (p *PayPalParser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+func (p *PayPalParser) Detect(sample []byte) bool {
+	rows := sampleRows(sample, 1, ',')
+	return len(rows) == 1 && hasColumns(rows[0], "Transaction ID", "Gross", "Fee", "Net")
+}
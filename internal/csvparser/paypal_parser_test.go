@@ -0,0 +1,153 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayPalParser_ParseStatements(t *testing.T) {
+	file, err := os.Open("testdata/paypal_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := (&PayPalParser{}).ParseStatements(file)
+
+	assert.NoError(t, err)
+	// Every USD row is folded into a GBP transaction, so there is no USD
+	// statement.
+	assert.Len(t, statements, 1)
+	gbp := statements[0]
+	assert.Equal(t, "PayPal", gbp.Institution)
+	assert.Equal(t, AccountDetails{Name: "PayPal GBP"}, gbp.Account)
+	assert.Equal(t, int64(10194), gbp.ClosingBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), gbp.BalanceDate)
+	// The authorisation and the hold are left out.
+	assert.Len(t, gbp.Transactions, 8)
+
+	var total int64
+	for _, tx := range gbp.Transactions {
+		assert.Equal(t, "GBP", tx.Amount.Currency().Code)
+		total += tx.Amount.Amount()
+	}
+	assert.Equal(t, gbp.ClosingBalance.Amount(), total)
+
+	deposit, spotify := gbp.Transactions[0], gbp.Transactions[1]
+	assert.Equal(t, "Bank Deposit to PP Account", deposit.Description)
+	assert.Equal(t, int64(999), deposit.Amount.Amount())
+	assert.Equal(t, "1AA00000AA0000000", *deposit.LinkedExternalID)
+	assert.Equal(t, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), spotify.Date)
+	assert.Equal(t, "Spotify", spotify.Description)
+	assert.Equal(t, int64(-999), spotify.Amount.Amount())
+	assert.Equal(t, "1AA00000AA0000000", *spotify.ExternalID)
+
+	purchase := gbp.Transactions[3]
+	assert.Equal(t, "US Gadget Store", purchase.Description)
+	assert.Equal(t, int64(-1600), purchase.Amount.Amount())
+	assert.Equal(t, int64(-2000), purchase.LocalAmount.Amount())
+	assert.Equal(t, "USD", purchase.LocalAmount.Currency().Code)
+	assert.Equal(t, "1 USD = 0.8000 GBP", *purchase.Notes)
+	assert.Equal(t, "2BB00000BB0000000", *purchase.ExternalID)
+
+	sale, saleFee := gbp.Transactions[4], gbp.Transactions[5]
+	assert.Equal(t, int64(8000), sale.Amount.Amount())
+	assert.Equal(t, int64(10000), sale.LocalAmount.Amount())
+	assert.Equal(t, "Fee - Customer A", saleFee.Description)
+	assert.Equal(t, int64(-256), saleFee.Amount.Amount())
+	assert.Equal(t, int64(-320), saleFee.LocalAmount.Amount())
+	assert.Equal(t, "3CC00000CC0000000/fee", *saleFee.ExternalID)
+	assert.Equal(t, "3CC00000CC0000000", *saleFee.LinkedExternalID)
+
+	fee := gbp.Transactions[7]
+	assert.Equal(t, int64(-50), fee.Amount.Amount())
+	assert.Nil(t, fee.LocalAmount)
+	assert.Equal(t, "6FF00000FF0000000", *fee.LinkedExternalID)
+}
+
+func TestPayPalParser_ParseStatements_KeepsUnmatchedConversions(t *testing.T) {
+	csv := "Date,Name,Type,Status,Currency,Gross,Fee,Net,Transaction ID,Reference Txn ID\n" +
+		"01/15/2026,,General Currency Conversion,Completed,GBP,-16.00,0.00,-16.00,A1,MISSING\n" +
+		"01/15/2026,,General Currency Conversion,Completed,USD,20.00,0.00,20.00,A2,MISSING\n"
+
+	statements, err := (&PayPalParser{}).ParseStatements(strings.NewReader(csv))
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+	assert.Equal(t, int64(-1600), statements[0].Transactions[0].Amount.Amount())
+	assert.Equal(t, "General Currency Conversion", statements[0].Transactions[0].Description)
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), statements[0].Transactions[0].Date)
+	assert.Equal(t, "USD", statements[1].Transactions[0].Amount.Currency().Code)
+}
+
+func TestPayPalParser_ParseStatements_PartialConversion(t *testing.T) {
+	// Half the payment came from an existing USD balance, so the
+	// conversion does not cover it and nothing is folded.
+	csv := "Date,Name,Type,Status,Currency,Gross,Fee,Net,Transaction ID,Reference Txn ID\n" +
+		"15/01/2026,,General Currency Conversion,Completed,GBP,-8.00,0.00,-8.00,A1,P1\n" +
+		"15/01/2026,,General Currency Conversion,Completed,USD,10.00,0.00,10.00,A2,P1\n" +
+		"15/01/2026,Shop,Express Checkout Payment,Completed,USD,-20.00,0.00,-20.00,P1,\n"
+
+	statements, err := (&PayPalParser{}).ParseStatements(strings.NewReader(csv))
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+	usd := statements[0]
+	assert.Len(t, usd.Transactions, 2)
+	assert.Equal(t, int64(-2000), usd.Transactions[0].Amount.Amount())
+	assert.Nil(t, usd.Transactions[0].LocalAmount)
+	assert.Equal(t, "P1", *usd.Transactions[1].LinkedExternalID)
+	// The GBP side of the conversion is on another balance, so it is not
+	// linked to the USD payment.
+	assert.Nil(t, statements[1].Transactions[0].LinkedExternalID)
+}
+
+func TestPayPalParser_ParseStatements_Errors(t *testing.T) {
+	header := "Date,Name,Type,Status,Currency,Gross,Fee,Net,Transaction ID\n"
+	tests := []struct {
+		name   string
+		csv    string
+		errMsg string
+	}{
+		{"missing net column", "Date,Name,Type,Status,Currency,Gross,Fee,Transaction ID\n", "required column not found"},
+		{"short row", header + "15/01/2026,Shop\n", "row 1: expected at least 9 columns, got 2"},
+		{"mixed date order", header + "15/01/2026,A,Payment,Completed,GBP,1.00,0.00,1.00,X\n01/15/2026,B,Payment,Completed,GBP,1.00,0.00,1.00,Y\n", "mix day-first and month-first"},
+		{"bad gross", header + "15/01/2026,Shop,Payment,Completed,GBP,abc,0.00,1.00,X\n", "row 1: parsing gross 'abc'"},
+		{"unknown currency", header + "15/01/2026,Shop,Payment,Completed,XYZ,1.00,0.00,1.00,X\n", "unknown currency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&PayPalParser{}).ParseStatements(strings.NewReader(tt.csv))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestMulDivRound(t *testing.T) {
+	assert.Equal(t, int64(-256), mulDivRound(-320, 7744, 9680))
+	assert.Equal(t, int64(3), mulDivRound(5, 1, 2))
+	assert.Equal(t, int64(-3), mulDivRound(-5, 1, 2))
+	assert.Equal(t, int64(-3), mulDivRound(5, 1, -2))
+	assert.Equal(t, int64(1), mulDivRound(4, 1, 3))
+}
+
+func TestPayPalParser_Detect(t *testing.T) {
+	parser := &PayPalParser{}
+
+	assert.True(t, parser.Detect([]byte(`"Date","Time","TimeZone","Name","Type","Status","Currency","Gross","Fee","Net","Transaction ID"`+"\n")))
+	assert.False(t, parser.Detect([]byte("Type,Product,Started Date,Completed Date,Description,Amount,Fee,Currency,State,Balance\n")))
+}
+
+func TestService_ParseStatements_DetectsPayPal(t *testing.T) {
+	file, err := os.Open("testdata/paypal_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := NewService().ParseStatement(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "paypal", statement.Format)
+}
@@ -0,0 +1,346 @@
+package csvparser
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Rhymond/go-money"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kushturner/finances/internal/account"
+)
+
+var (
+	ErrInvalidProfile  = errors.New("invalid parser profile")
+	ErrProfileConflict = errors.New("parser profile conflict")
+)
+
+const (
+	SignOutflowNegative = "outflow_negative"
+	SignOutflowPositive = "outflow_positive"
+)
+
+//go:embed profiles/*.yaml
+var bundledProfileFS embed.FS
+
+// bundledProfileOrder is the order the built-in profiles are registered in,
+// which is also the order SupportedFormats lists them.
+var bundledProfileOrder = []string{"nationwide", "amex", "monzo"}
+
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Profile describes a bank's CSV export declaratively, so a new bank can be
+// supported without writing a parser. Column names and preamble labels are
+// matched case-insensitively after trimming whitespace.
+type Profile struct {
+	// Name is the format name used for ?bank= and in detection results.
+	Name string `yaml:"name" json:"name"`
+	// Institution is the bank the transactions are recorded under.
+	Institution string `yaml:"institution" json:"institution"`
+	// AccountType is one of the account package's types, or empty.
+	AccountType string `yaml:"account_type,omitempty" json:"account_type,omitempty"`
+	// Currency applies to every amount unless Columns.Currency is set.
+	// It defaults to GBP.
+	Currency string `yaml:"currency,omitempty" json:"currency,omitempty"`
+	// Delimiter separates fields and defaults to a comma.
+	Delimiter string `yaml:"delimiter,omitempty" json:"delimiter,omitempty"`
+	// DecimalSeparator is "." or ","; it defaults to the currency's own.
+	DecimalSeparator string `yaml:"decimal_separator,omitempty" json:"decimal_separator,omitempty"`
+	// DateFormat is a Go time layout such as "02/01/2006".
+	DateFormat string `yaml:"date_format" json:"date_format"`
+	// SignConvention is outflow_negative (the default) or outflow_positive.
+	SignConvention string          `yaml:"sign_convention,omitempty" json:"sign_convention,omitempty"`
+	Preamble       ProfilePreamble `yaml:"preamble,omitempty" json:"preamble"`
+	Columns        ProfileColumns  `yaml:"columns" json:"columns"`
+	Detect         ProfileDetect   `yaml:"detect,omitempty" json:"detect"`
+
+	builtIn bool
+}
+
+// ProfilePreamble describes the rows before the header. Rows with an empty
+// first field between the preamble and the header are skipped.
+type ProfilePreamble struct {
+	SkipRows int `yaml:"skip_rows,omitempty" json:"skip_rows,omitempty"`
+	// The remaining fields are labels of preamble rows whose second field
+	// holds the value. AccountName may end with a masked account number.
+	AccountName      string `yaml:"account_name,omitempty" json:"account_name,omitempty"`
+	ClosingBalance   string `yaml:"closing_balance,omitempty" json:"closing_balance,omitempty"`
+	AvailableBalance string `yaml:"available_balance,omitempty" json:"available_balance,omitempty"`
+}
+
+// ProfileColumns maps header names to transaction fields. Date, Description
+// and either Amount or both PaidIn and PaidOut are required.
+type ProfileColumns struct {
+	Date        string `yaml:"date" json:"date"`
+	Description string `yaml:"description" json:"description"`
+	// DescriptionFallback is used for rows whose Description is empty.
+	DescriptionFallback string `yaml:"description_fallback,omitempty" json:"description_fallback,omitempty"`
+	// Emoji is put in front of the description when the row has one.
+	Emoji    string `yaml:"emoji,omitempty" json:"emoji,omitempty"`
+	Amount   string `yaml:"amount,omitempty" json:"amount,omitempty"`
+	PaidIn   string `yaml:"paid_in,omitempty" json:"paid_in,omitempty"`
+	PaidOut  string `yaml:"paid_out,omitempty" json:"paid_out,omitempty"`
+	Balance  string `yaml:"balance,omitempty" json:"balance,omitempty"`
+	Category string `yaml:"category,omitempty" json:"category,omitempty"`
+	// Reference is the bank's own transaction ID, used to skip duplicates.
+	Reference string `yaml:"reference,omitempty" json:"reference,omitempty"`
+	Currency  string `yaml:"currency,omitempty" json:"currency,omitempty"`
+	// LocalAmount and LocalCurrency give the amount in the currency the
+	// transaction was made in, when that differs from the account's.
+	LocalAmount   string `yaml:"local_amount,omitempty" json:"local_amount,omitempty"`
+	LocalCurrency string `yaml:"local_currency,omitempty" json:"local_currency,omitempty"`
+	Notes         string `yaml:"notes,omitempty" json:"notes,omitempty"`
+	Address       string `yaml:"address,omitempty" json:"address,omitempty"`
+}
+
+// ProfileDetect decides whether an upload without ?bank= is in this format.
+// The header must contain every one of Columns, which defaults to the
+// required columns, and at least one of AnyColumns when that is set.
+type ProfileDetect struct {
+	FirstField string   `yaml:"first_field,omitempty" json:"first_field,omitempty"`
+	Columns    []string `yaml:"columns,omitempty" json:"columns,omitempty"`
+	AnyColumns []string `yaml:"any_columns,omitempty" json:"any_columns,omitempty"`
+}
+
+// BuiltIn reports whether the profile ships with the application.
+func (p Profile) BuiltIn() bool {
+	return p.builtIn
+}
+
+// DecodeProfile reads a profile written as YAML or JSON. Unknown keys are
+// rejected so a misspelt column mapping fails instead of being ignored.
+func DecodeProfile(r io.Reader) (Profile, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var profile Profile
+	if err := decoder.Decode(&profile); err != nil {
+		if err == io.EOF {
+			return Profile{}, fmt.Errorf("%w: empty profile", ErrInvalidProfile)
+		}
+		return Profile{}, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	return profile, nil
+}
+
+func (p Profile) Validate() error {
+	if !profileNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits, '-' or '_'", ErrInvalidProfile)
+	}
+	if strings.TrimSpace(p.Institution) == "" {
+		return fmt.Errorf("%w: institution is required", ErrInvalidProfile)
+	}
+	switch p.AccountType {
+	case "", account.TypeCurrent, account.TypeCredit, account.TypeSavings, account.TypeCash:
+	default:
+		return fmt.Errorf("%w: unknown account_type %q", ErrInvalidProfile, p.AccountType)
+	}
+	if p.Currency != "" && money.GetCurrency(p.Currency) == nil {
+		return fmt.Errorf("%w: unknown currency %q", ErrInvalidProfile, p.Currency)
+	}
+	if p.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(p.Delimiter)
+		if size != len(p.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return fmt.Errorf("%w: delimiter must be a single character", ErrInvalidProfile)
+		}
+	}
+	if p.DecimalSeparator != "" && p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return fmt.Errorf("%w: decimal_separator must be '.' or ','", ErrInvalidProfile)
+	}
+	if !validDateLayout(p.DateFormat) {
+		return fmt.Errorf("%w: date_format %q must be a Go layout with a day, month and year", ErrInvalidProfile, p.DateFormat)
+	}
+	switch p.SignConvention {
+	case "", SignOutflowNegative, SignOutflowPositive:
+	default:
+		return fmt.Errorf("%w: sign_convention must be %s or %s", ErrInvalidProfile, SignOutflowNegative, SignOutflowPositive)
+	}
+	if p.Preamble.SkipRows < 0 || p.Preamble.SkipRows > 50 {
+		return fmt.Errorf("%w: preamble skip_rows must be between 0 and 50", ErrInvalidProfile)
+	}
+
+	columns := p.Columns
+	if columns.Date == "" || columns.Description == "" {
+		return fmt.Errorf("%w: date and description columns are required", ErrInvalidProfile)
+	}
+	split := columns.PaidIn != "" || columns.PaidOut != ""
+	if columns.Amount != "" && split {
+		return fmt.Errorf("%w: use either an amount column or paid_in and paid_out, not both", ErrInvalidProfile)
+	}
+	if columns.Amount == "" && (columns.PaidIn == "" || columns.PaidOut == "") {
+		return fmt.Errorf("%w: an amount column or both paid_in and paid_out are required", ErrInvalidProfile)
+	}
+	if (columns.LocalAmount == "") != (columns.LocalCurrency == "") {
+		return fmt.Errorf("%w: local_amount and local_currency columns must be set together", ErrInvalidProfile)
+	}
+	return nil
+}
+
+// validDateLayout reports whether layout round-trips a date, which rules
+// out layouts that drop the day, month or year.
+func validDateLayout(layout string) bool {
+	if layout == "" {
+		return false
+	}
+	reference := time.Date(2026, 11, 23, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, reference.Format(layout))
+	return err == nil && parsed.Equal(reference)
+}
+
+func (p Profile) currency() string {
+	if p.Currency == "" {
+		return money.GBP
+	}
+	return p.Currency
+}
+
+func (p Profile) delimiter() rune {
+	if p.Delimiter == "" {
+		return ','
+	}
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	return r
+}
+
+func (p Profile) signConvention() SignConvention {
+	if p.SignConvention == SignOutflowPositive {
+		return OutflowPositive
+	}
+	return OutflowNegative
+}
+
+func (p Profile) requiredColumns() []string {
+	if p.Columns.Amount != "" {
+		return []string{p.Columns.Date, p.Columns.Description, p.Columns.Amount}
+	}
+	return []string{p.Columns.Date, p.Columns.Description, p.Columns.PaidOut, p.Columns.PaidIn}
+}
+
+// reportsBalances reports whether files in this format state a balance.
+func (p Profile) reportsBalances() bool {
+	return p.Preamble.ClosingBalance != "" || p.Preamble.AvailableBalance != "" || p.Columns.Balance != ""
+}
+
+// bundledProfiles returns the profiles for the formats built into the
+// application. They are embedded, so failing to read them is a bug.
+func bundledProfiles() []Profile {
+	profiles := make([]Profile, 0, len(bundledProfileOrder))
+	for _, name := range bundledProfileOrder {
+		data, err := bundledProfileFS.ReadFile("profiles/" + name + ".yaml")
+		if err != nil {
+			panic(fmt.Sprintf("reading bundled profile %s: %v", name, err))
+		}
+		profile, err := DecodeProfile(bytes.NewReader(data))
+		if err == nil {
+			err = profile.Validate()
+		}
+		if err != nil {
+			panic(fmt.Sprintf("bundled profile %s: %v", name, err))
+		}
+		profile.builtIn = true
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// profileFile is a user profile and the file it was read from.
+type profileFile struct {
+	profile Profile
+	path    string
+}
+
+// loadProfileDir reads every .yaml, .yml and .json file in dir in name
+// order. A missing directory holds no profiles.
+func loadProfileDir(dir string) ([]profileFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading profile directory: %w", err)
+	}
+
+	var files []profileFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isProfileFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		profile, err := readProfileFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, profileFile{profile: profile, path: path})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+func isProfileFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func readProfileFile(path string) (Profile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer file.Close()
+
+	profile, err := DecodeProfile(file)
+	if err != nil {
+		return Profile{}, err
+	}
+	if err := profile.Validate(); err != nil {
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+// writeProfileFile saves profile to path in the encoding its extension
+// names, replacing the file atomically.
+func writeProfileFile(path string, profile Profile) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(profile, "", "  ")
+	} else {
+		data, err = yaml.Marshal(profile)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".profile-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,424 @@
+package csvparser
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// profileParser reads any CSV export described by a Profile.
+type profileParser struct {
+	profile Profile
+}
+
+// profileColumns holds the header index of each mapped column, or -1 for
+// optional columns the file does not have.
+type profileColumns struct {
+	date, description, amount, paidIn, paidOut int
+	balance, category, reference, currency     int
+	descriptionFallback, emoji                 int
+	localAmount, localCurrency, notes, address int
+}
+
+func (p *profileParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	statement, err := p.ParseStatement(r)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Transactions, nil
+}
+
+func (p *profileParser) ParseStatement(r io.Reader) (Statement, error) {
+	return p.parseStatement(r, nil)
+}
+
+func (p *profileParser) parseRows(r io.Reader, report *rowReport) ([]Statement, error) {
+	statement, err := p.parseStatement(r, report)
+	if err != nil {
+		return nil, err
+	}
+	return []Statement{statement}, nil
+}
+
+func (p *profileParser) parseStatement(r io.Reader, report *rowReport) (Statement, error) {
+	var statement Statement
+	rows, err := p.readStatement(r, report, &statement)
+	if err != nil {
+		return Statement{}, err
+	}
+	for tx, err := range rows {
+		if err != nil {
+			return Statement{}, err
+		}
+		statement.Transactions = append(statement.Transactions, tx)
+	}
+	return statement, nil
+}
+
+// readStatement reads the preamble and header of r into statement and
+// returns its data rows, which are only read as they are ranged over, once.
+// Each row read moves statement.BalanceDate on for profiles that report
+// balances. A row that cannot be read ends the rows with an error, unless
+// report takes it.
+func (p *profileParser) readStatement(r io.Reader, report *rowReport, statement *Statement) (iter.Seq2[transaction.Transaction, error], error) {
+	profile := p.profile
+	reader := newRowReader(r, report)
+	reader.Comma = profile.delimiter()
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	*statement = Statement{
+		Institution: profile.Institution,
+		Account:     AccountDetails{Type: profile.AccountType},
+	}
+
+	for i := 0; i < profile.Preamble.SkipRows; i++ {
+		row, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading preamble row %d: %w", i+1, err)
+		}
+		p.readPreamble(row, statement)
+	}
+
+	var headers []string
+	for {
+		row, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading header row: %w", err)
+		}
+		if firstField(row) != "" {
+			// The reader reuses its rows, and the header is kept.
+			headers = slices.Clone(row)
+			break
+		}
+	}
+
+	cols, err := p.columns(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(transaction.Transaction, error) bool) {
+		for rowNum := 1; ; rowNum++ {
+			row, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				if err := report.readError(rowNum, reader.Raw(), err); err != nil {
+					yield(transaction.Transaction{}, err)
+					return
+				}
+				continue
+			}
+
+			tx, err := p.transaction(row, cols)
+			if err != nil {
+				if err := report.reject(rowNum, reader.Raw(), err); err != nil {
+					yield(transaction.Transaction{}, err)
+					return
+				}
+				continue
+			}
+			if profile.reportsBalances() && tx.Date.After(statement.BalanceDate) {
+				statement.BalanceDate = tx.Date
+			}
+			if !yield(tx, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// transaction reads one data row.
+func (p *profileParser) transaction(row []string, cols profileColumns) (transaction.Transaction, error) {
+	profile := p.profile
+	if required := max(cols.date, cols.description, cols.amount, cols.paidIn, cols.paidOut); len(row) <= required {
+		return transaction.Transaction{}, shortRowError(row, required)
+	}
+
+	date, err := time.Parse(profile.DateFormat, strings.TrimSpace(row[cols.date]))
+	if err != nil {
+		return transaction.Transaction{}, inColumn(profile.Columns.Date, fmt.Errorf("parsing date '%s': %w", row[cols.date], err))
+	}
+
+	currency := profile.currency()
+	if code := field(row, cols.currency); code != "" {
+		currency = strings.ToUpper(code)
+	}
+
+	amount, err := p.amount(row, cols, currency)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	localAmount, err := p.localAmount(row, cols, currency)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	var balance *money.Money
+	if value := field(row, cols.balance); value != "" {
+		balance, err = p.parseAmount(value, currency)
+		if err != nil {
+			return transaction.Transaction{}, inColumn(profile.Columns.Balance, fmt.Errorf("parsing balance '%s': %w", value, err))
+		}
+	}
+
+	return transaction.Transaction{
+		Date:           date,
+		Description:    description(row, cols),
+		Amount:         amount,
+		LocalAmount:    localAmount,
+		RunningBalance: balance,
+		Bank:           profile.Institution,
+		Category:       optionalField(row, cols.category),
+		ExternalID:     reference(row, cols.reference),
+		Notes:          optionalField(row, cols.notes),
+		Address:        address(row, cols.address),
+	}, nil
+}
+
+// readPreamble picks the account details and balances out of a preamble
+// row. The preamble is informational, so unreadable values are skipped
+// rather than failing the import.
+func (p *profileParser) readPreamble(row []string, statement *Statement) {
+	if len(row) < 2 {
+		return
+	}
+	label := strings.TrimSpace(row[0])
+	preamble := p.profile.Preamble
+
+	switch {
+	case labelMatches(label, preamble.AccountName):
+		name, masked := splitAccountName(row[1])
+		statement.Account.Name = name
+		statement.Account.MaskedNumber = masked
+	case labelMatches(label, preamble.ClosingBalance):
+		statement.ClosingBalance = p.preambleAmount(row[1])
+	case labelMatches(label, preamble.AvailableBalance):
+		statement.AvailableBalance = p.preambleAmount(row[1])
+	}
+}
+
+func (p *profileParser) columns(headers []string) (profileColumns, error) {
+	mapping := p.profile.Columns
+	cols := profileColumns{
+		date:        findColumnIndex(headers, mapping.Date),
+		description: findColumnIndex(headers, mapping.Description),
+		amount:      optionalColumnIndex(headers, mapping.Amount),
+		paidIn:      optionalColumnIndex(headers, mapping.PaidIn),
+		paidOut:     optionalColumnIndex(headers, mapping.PaidOut),
+		balance:     optionalColumnIndex(headers, mapping.Balance),
+		category:    optionalColumnIndex(headers, mapping.Category),
+		reference:   optionalColumnIndex(headers, mapping.Reference),
+		currency:    optionalColumnIndex(headers, mapping.Currency),
+
+		descriptionFallback: optionalColumnIndex(headers, mapping.DescriptionFallback),
+		emoji:               optionalColumnIndex(headers, mapping.Emoji),
+		localAmount:         optionalColumnIndex(headers, mapping.LocalAmount),
+		localCurrency:       optionalColumnIndex(headers, mapping.LocalCurrency),
+		notes:               optionalColumnIndex(headers, mapping.Notes),
+		address:             optionalColumnIndex(headers, mapping.Address),
+	}
+
+	missing := cols.date == -1 || cols.description == -1
+	if mapping.Amount != "" {
+		missing = missing || cols.amount == -1
+	} else {
+		missing = missing || cols.paidIn == -1 || cols.paidOut == -1
+	}
+	if missing {
+		return profileColumns{}, fmt.Errorf("required column not found in CSV headers")
+	}
+	return cols, nil
+}
+
+// amount reads a signed amount column, or combines paid out and paid in
+// columns into one amount with outflows negative.
+func (p *profileParser) amount(row []string, cols profileColumns, currency string) (*money.Money, error) {
+	mapping := p.profile.Columns
+	if cols.amount != -1 {
+		value := row[cols.amount]
+		amount, err := p.parseAmount(value, currency)
+		if err != nil {
+			return nil, inColumn(mapping.Amount, fmt.Errorf("parsing amount '%s': %w", value, err))
+		}
+		return amount, nil
+	}
+
+	value, column := row[cols.paidIn], mapping.PaidIn
+	outflow := strings.TrimSpace(row[cols.paidOut]) != ""
+	if outflow {
+		value, column = row[cols.paidOut], mapping.PaidOut
+	}
+	amount, err := p.parseAmount(value, currency)
+	if err != nil {
+		return nil, inColumn(column, fmt.Errorf("parsing amount '%s': %w", value, err))
+	}
+	if outflow != amount.IsNegative() {
+		amount = negate(amount)
+	}
+	return amount, nil
+}
+
+// localAmount reads the amount in the currency the transaction was made in.
+// It is nil when the file has no local amount or it is in the account's
+// own currency, as it would only repeat the amount.
+func (p *profileParser) localAmount(row []string, cols profileColumns, currency string) (*money.Money, error) {
+	value := field(row, cols.localAmount)
+	code := strings.ToUpper(field(row, cols.localCurrency))
+	if value == "" || code == "" || code == currency {
+		return nil, nil
+	}
+	if money.GetCurrency(code) == nil {
+		return nil, inColumn(p.profile.Columns.LocalCurrency, fmt.Errorf("unknown local currency '%s'", code))
+	}
+	amount, err := p.parseAmount(value, code)
+	if err != nil {
+		return nil, inColumn(p.profile.Columns.LocalAmount, fmt.Errorf("parsing local amount '%s': %w", value, err))
+	}
+	return amount, nil
+}
+
+func (p *profileParser) cellFormat() cellFormat {
+	return cellFormat{delimiter: p.profile.delimiter(), dateLayout: p.profile.DateFormat, decimal: p.profile.DecimalSeparator}
+}
+
+func (p *profileParser) SignConvention() SignConvention {
+	return p.profile.signConvention()
+}
+
+func (p *profileParser) Detect(sample []byte) bool {
+	profile := p.profile
+	rows := sampleRows(sample, profile.Preamble.SkipRows+10, profile.delimiter())
+	if len(rows) == 0 {
+		return false
+	}
+	if profile.Detect.FirstField != "" && !labelMatches(firstField(rows[0]), profile.Detect.FirstField) {
+		return false
+	}
+
+	// The header is looked for in every sampled row rather than after
+	// exactly SkipRows, as some exports vary the preamble slightly.
+	columns := profile.Detect.Columns
+	if len(columns) == 0 {
+		columns = profile.requiredColumns()
+	}
+	for _, row := range rows {
+		if hasColumns(row, columns...) && hasAnyColumn(row, profile.Detect.AnyColumns) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyColumn reports whether row has one of columns, or true when there
+// are none to look for.
+func hasAnyColumn(row []string, columns []string) bool {
+	if len(columns) == 0 {
+		return true
+	}
+	for _, column := range columns {
+		if findColumnIndex(row, column) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAccountName separates a trailing masked number from an account
+// name, as in "FlexDirect ****12345".
+func splitAccountName(value string) (name string, maskedNumber string) {
+	name = strings.TrimSpace(value)
+	if i := strings.LastIndex(name, " "); i != -1 && strings.Contains(name[i+1:], "*") {
+		return strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	return name, ""
+}
+
+func (p *profileParser) parseAmount(value string, currency string) (*money.Money, error) {
+	return parseAmountWithDecimal(value, currency, p.profile.DecimalSeparator)
+}
+
+// preambleAmount reads a balance such as "£1234.56", returning nil when it
+// cannot be read.
+func (p *profileParser) preambleAmount(value string) *money.Money {
+	amount, err := p.parseAmount(value, p.profile.currency())
+	if err != nil {
+		return nil
+	}
+	return amount
+}
+
+func labelMatches(label string, want string) bool {
+	return want != "" && strings.EqualFold(strings.TrimSpace(label), strings.TrimSpace(want))
+}
+
+func optionalColumnIndex(headers []string, columnName string) int {
+	if columnName == "" {
+		return -1
+	}
+	return findColumnIndex(headers, columnName)
+}
+
+// field returns the trimmed value at idx, or "" when the column is absent
+// or the row is short.
+func field(row []string, idx int) string {
+	if idx == -1 || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+func optionalField(row []string, idx int) *string {
+	value := field(row, idx)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// description reads the description, falling back to the fallback column
+// when it is empty and putting the emoji, if any, in front.
+func description(row []string, cols profileColumns) string {
+	value := field(row, cols.description)
+	if value == "" {
+		value = field(row, cols.descriptionFallback)
+	}
+	if emoji := field(row, cols.emoji); emoji != "" && value != "" {
+		return emoji + " " + value
+	}
+	return value
+}
+
+// address reads an address, joining one written over several lines.
+func address(row []string, idx int) *string {
+	var lines []string
+	for _, line := range strings.Split(field(row, idx), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	value := strings.Join(lines, ", ")
+	return &value
+}
+
+// reference reads a bank reference. Some banks quote references as
+// 'AT123456789' to stop spreadsheets treating them as numbers.
+func reference(row []string, idx int) *string {
+	value := strings.Trim(field(row, idx), "'")
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,317 @@
+package csvparser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bundledParser returns the parser for a built-in profile.
+func bundledParser(name string) *profileParser {
+	for _, profile := range bundledProfiles() {
+		if profile.Name == name {
+			return &profileParser{profile: profile}
+		}
+	}
+	panic("no bundled profile " + name)
+}
+
+const starlingProfile = `
+name: starling
+institution: Starling
+currency: EUR
+delimiter: ";"
+decimal_separator: ","
+date_format: "2006-01-02"
+columns:
+  date: Date
+  description: Counter Party
+  amount: Amount (EUR)
+  category: Spending Category
+  reference: Reference
+detect:
+  any_columns: [Counter Party]
+`
+
+func validProfile() Profile {
+	return Profile{
+		Name:        "test-bank",
+		Institution: "Test Bank",
+		DateFormat:  "02/01/2006",
+		Columns:     ProfileColumns{Date: "Date", Description: "Description", Amount: "Amount"},
+	}
+}
+
+func TestDecodeProfile_YAML(t *testing.T) {
+	profile, err := DecodeProfile(strings.NewReader(starlingProfile))
+
+	assert.NoError(t, err)
+	assert.NoError(t, profile.Validate())
+	assert.Equal(t, "starling", profile.Name)
+	assert.Equal(t, "Amount (EUR)", profile.Columns.Amount)
+	assert.Equal(t, []string{"Counter Party"}, profile.Detect.AnyColumns)
+	assert.False(t, profile.BuiltIn())
+}
+
+func TestDecodeProfile_JSON(t *testing.T) {
+	profile, err := DecodeProfile(strings.NewReader(`{
+		"name": "test-bank",
+		"institution": "Test Bank",
+		"date_format": "02/01/2006",
+		"columns": {"date": "Date", "description": "Description", "paid_in": "In", "paid_out": "Out"}
+	}`))
+
+	assert.NoError(t, err)
+	assert.NoError(t, profile.Validate())
+	assert.Equal(t, "Out", profile.Columns.PaidOut)
+}
+
+func TestDecodeProfile_RejectsUnknownFields(t *testing.T) {
+	_, err := DecodeProfile(strings.NewReader("name: x\ncolumns:\n  amonut: Amount\n"))
+
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+}
+
+func TestDecodeProfile_Empty(t *testing.T) {
+	_, err := DecodeProfile(strings.NewReader(""))
+
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+}
+
+func TestProfile_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *Profile)
+		errMsg string
+	}{
+		{"valid", func(p *Profile) {}, ""},
+		{"uppercase name", func(p *Profile) { p.Name = "Test" }, "name must be"},
+		{"missing institution", func(p *Profile) { p.Institution = " " }, "institution is required"},
+		{"unknown account type", func(p *Profile) { p.AccountType = "loan" }, "account_type"},
+		{"unknown currency", func(p *Profile) { p.Currency = "XYZ" }, "unknown currency"},
+		{"long delimiter", func(p *Profile) { p.Delimiter = ";;" }, "delimiter"},
+		{"quote delimiter", func(p *Profile) { p.Delimiter = `"` }, "delimiter"},
+		{"tab delimiter", func(p *Profile) { p.Delimiter = "\t" }, ""},
+		{"unknown decimal separator", func(p *Profile) { p.DecimalSeparator = "'" }, "decimal_separator"},
+		{"missing date format", func(p *Profile) { p.DateFormat = "" }, "date_format"},
+		{"date format without year", func(p *Profile) { p.DateFormat = "02/01" }, "date_format"},
+		{"strftime date format", func(p *Profile) { p.DateFormat = "%d/%m/%Y" }, "date_format"},
+		{"unknown sign convention", func(p *Profile) { p.SignConvention = "positive" }, "sign_convention"},
+		{"negative skip rows", func(p *Profile) { p.Preamble.SkipRows = -1 }, "skip_rows"},
+		{"missing date column", func(p *Profile) { p.Columns.Date = "" }, "date and description"},
+		{"amount and paid in", func(p *Profile) { p.Columns.PaidIn = "In" }, "not both"},
+		{"no amount", func(p *Profile) { p.Columns.Amount = "" }, "amount column or both"},
+		{"paid in only", func(p *Profile) {
+			p.Columns.Amount = ""
+			p.Columns.PaidIn = "In"
+		}, "amount column or both"},
+		{"local amount only", func(p *Profile) { p.Columns.LocalAmount = "Local amount" }, "set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := validProfile()
+			tt.modify(&profile)
+
+			err := profile.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidProfile)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestBundledProfiles(t *testing.T) {
+	profiles := bundledProfiles()
+
+	assert.Len(t, profiles, 3)
+	for _, profile := range profiles {
+		assert.NoError(t, profile.Validate())
+		assert.True(t, profile.BuiltIn())
+	}
+}
+
+func TestProfileParser_CustomProfile(t *testing.T) {
+	profile, err := DecodeProfile(strings.NewReader(starlingProfile))
+	assert.NoError(t, err)
+	csvData := "Date;Counter Party;Reference;Amount (EUR);Spending Category\n" +
+		"2026-01-15;Coffee Co; 'REF1' ;-3,50;EATING_OUT\n" +
+		"2026-01-16;Employer;;1.234,00;\n"
+
+	statement, err := (&profileParser{profile: profile}).ParseStatement(strings.NewReader(csvData))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Starling", statement.Institution)
+	assert.True(t, statement.BalanceDate.IsZero())
+	assert.Len(t, statement.Transactions, 2)
+
+	first := statement.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), first.Date)
+	assert.Equal(t, "Coffee Co", first.Description)
+	assert.Equal(t, int64(-350), first.Amount.Amount())
+	assert.Equal(t, "EUR", first.Amount.Currency().Code)
+	assert.Equal(t, "Starling", first.Bank)
+	assert.Equal(t, "EATING_OUT", *first.Category)
+	assert.Equal(t, "REF1", *first.ExternalID)
+
+	assert.Equal(t, int64(123400), statement.Transactions[1].Amount.Amount())
+	assert.Nil(t, statement.Transactions[1].Category)
+	assert.Nil(t, statement.Transactions[1].ExternalID)
+}
+
+func TestProfileParser_CurrencyColumn(t *testing.T) {
+	profile := validProfile()
+	profile.Columns.Currency = "Currency"
+	csvData := "Date,Description,Amount,Currency\n15/01/2026,Hotel,-100.00,usd\n16/01/2026,Cafe,-2.00,\n"
+
+	transactions, err := (&profileParser{profile: profile}).Parse(strings.NewReader(csvData))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", transactions[0].Amount.Currency().Code)
+	assert.Equal(t, "GBP", transactions[1].Amount.Currency().Code)
+}
+
+func TestProfileParser_PaidInAndOutAreUnsigned(t *testing.T) {
+	profile := validProfile()
+	profile.Columns = ProfileColumns{Date: "Date", Description: "Description", PaidIn: "Credit", PaidOut: "Debit"}
+	csvData := "Date,Description,Debit,Credit\n15/01/2026,Shop,-5.00,\n16/01/2026,Refund,,5.00\n17/01/2026,Fee,1.00,\n"
+
+	transactions, err := (&profileParser{profile: profile}).Parse(strings.NewReader(csvData))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-500), transactions[0].Amount.Amount())
+	assert.Equal(t, int64(500), transactions[1].Amount.Amount())
+	assert.Equal(t, int64(-100), transactions[2].Amount.Amount())
+}
+
+func TestProfileParser_HeaderMatchingIgnoresCase(t *testing.T) {
+	transactions, err := (&profileParser{profile: validProfile()}).Parse(strings.NewReader(" date ,DESCRIPTION,amount\n15/01/2026,Shop,-5.00\n"))
+
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+}
+
+func TestProfileParser_InvalidDate(t *testing.T) {
+	_, err := (&profileParser{profile: validProfile()}).Parse(strings.NewReader("Date,Description,Amount\n2026-01-15,Shop,-5.00\n"))
+
+	assert.ErrorContains(t, err, "row 1: parsing date '2026-01-15'")
+}
+
+func TestProfileParser_Detect(t *testing.T) {
+	profile, err := DecodeProfile(strings.NewReader(starlingProfile))
+	assert.NoError(t, err)
+	parser := &profileParser{profile: profile}
+
+	assert.True(t, parser.Detect([]byte("Date;Counter Party;Reference;Amount (EUR)\n")))
+	assert.False(t, parser.Detect([]byte("Date,Counter Party,Reference,Amount (EUR)\n")))
+	assert.False(t, parser.Detect([]byte("Date;Counter Party;Reference\n")))
+}
+
+func TestNewServiceWithProfileDir_LoadsProfiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "starling.yaml"), []byte(starlingProfile), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a profile"), 0o644))
+
+	svc, err := NewServiceWithProfileDir(dir)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nationwide", "amex", "monzo", "ofx", "qif", "camt", "mt940", "revolut", "paypal", "wise", "starling"}, svc.SupportedFormats())
+
+	statement, err := svc.ParseStatement(strings.NewReader("Date;Counter Party;Amount (EUR)\n2026-01-15;Coffee Co;-3,50\n"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "starling", statement.Format)
+	assert.Equal(t, int64(-350), statement.Transactions[0].Amount.Amount())
+}
+
+func TestNewServiceWithProfileDir_MissingDirectory(t *testing.T) {
+	svc, err := NewServiceWithProfileDir(filepath.Join(t.TempDir(), "missing"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nationwide", "amex", "monzo", "ofx", "qif", "camt", "mt940", "revolut", "paypal", "wise"}, svc.SupportedFormats())
+}
+
+func TestNewServiceWithProfileDir_InvalidProfile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("name: broken\n"), 0o644))
+
+	_, err := NewServiceWithProfileDir(dir)
+
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+	assert.ErrorContains(t, err, "broken.yaml")
+}
+
+func TestNewServiceWithProfileDir_CannotOverrideBuiltIn(t *testing.T) {
+	dir := t.TempDir()
+	profile := strings.Replace(starlingProfile, "name: starling", "name: amex", 1)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "amex.yaml"), []byte(profile), 0o644))
+
+	_, err := NewServiceWithProfileDir(dir)
+
+	assert.ErrorIs(t, err, ErrProfileConflict)
+}
+
+func TestService_AddProfile_SavesToDirectory(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewServiceWithProfileDir(dir)
+	assert.NoError(t, err)
+
+	profile := validProfile()
+	assert.NoError(t, svc.AddProfile(profile))
+
+	reloaded, err := NewServiceWithProfileDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nationwide", "amex", "monzo", "ofx", "qif", "camt", "mt940", "revolut", "paypal", "wise", "test-bank"}, reloaded.SupportedFormats())
+	profiles := reloaded.Profiles()
+	assert.Equal(t, profile, profiles[len(profiles)-1])
+}
+
+func TestService_AddProfile_ReplacesUserProfile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, writeProfileFile(filepath.Join(dir, "test-bank.json"), validProfile()))
+	svc, err := NewServiceWithProfileDir(dir)
+	assert.NoError(t, err)
+
+	updated := validProfile()
+	updated.Institution = "Renamed Bank"
+	assert.NoError(t, svc.AddProfile(updated))
+
+	assert.Equal(t, []string{"nationwide", "amex", "monzo", "ofx", "qif", "camt", "mt940", "revolut", "paypal", "wise", "test-bank"}, svc.SupportedFormats())
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	saved, err := readProfileFile(filepath.Join(dir, "test-bank.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed Bank", saved.Institution)
+}
+
+func TestService_AddProfile_InMemory(t *testing.T) {
+	svc := NewService()
+
+	assert.NoError(t, svc.AddProfile(validProfile()))
+
+	transactions, err := svc.Parse(strings.NewReader("Date,Description,Amount\n15/01/2026,Shop,-5.00\n"), "test-bank")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Bank", transactions[0].Bank)
+	assert.Len(t, NewService().SupportedFormats(), 10)
+}
+
+func TestService_AddProfile_Errors(t *testing.T) {
+	svc := NewService()
+
+	invalid := validProfile()
+	invalid.DateFormat = ""
+	assert.ErrorIs(t, svc.AddProfile(invalid), ErrInvalidProfile)
+
+	builtIn := validProfile()
+	builtIn.Name = "nationwide"
+	err := svc.AddProfile(builtIn)
+	assert.True(t, errors.Is(err, ErrProfileConflict))
+	assert.Equal(t, []string{"nationwide", "amex", "monzo", "ofx", "qif", "camt", "mt940", "revolut", "paypal", "wise"}, svc.SupportedFormats())
+}
@@ -0,0 +1,422 @@
+package csvparser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// qifInstitution is recorded for QIF imports, which never name the bank.
+const qifInstitution = "QIF"
+
+// QIFParser reads the Quicken Interchange Format exported by desktop finance
+// tools. Amounts are signed from the account holder's side in every account
+// type, and the file holds no currency, so amounts are read as GBP.
+type QIFParser struct{}
+
+type qifRecord struct {
+	line     int
+	date     string
+	amount   string
+	payee    string
+	memo     string
+	category string
+	splits   []qifSplit
+}
+
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// qifSection is a run of transaction records under one !Type header.
+type qifSection struct {
+	accountType string
+	accountName string
+	records     []qifRecord
+}
+
+// qifTransactionTypes maps the !Type headers that hold bank-style
+// transactions to account types. Investment, category, class and memorised
+// transaction lists are skipped.
+var qifTransactionTypes = map[string]string{
+	"bank":  account.TypeCurrent,
+	"ccard": account.TypeCredit,
+	"cash":  account.TypeCash,
+	"oth a": "",
+	"oth l": "",
+}
+
+func (p *QIFParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	statement, err := p.ParseStatement(r)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Transactions, nil
+}
+
+func (p *QIFParser) ParseStatement(r io.Reader) (Statement, error) {
+	sections, err := readQIF(r)
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(sections) == 0 {
+		return Statement{}, fmt.Errorf("no bank or card transactions found in QIF file")
+	}
+	if len(sections) > 1 {
+		return Statement{}, fmt.Errorf("QIF file contains %d accounts; upload one account at a time", len(sections))
+	}
+	section := sections[0]
+
+	var dates []string
+	for _, record := range section.records {
+		dates = append(dates, record.date)
+	}
+	order, err := qifDateOrder(dates)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	statement := Statement{
+		Institution: qifInstitution,
+		Account:     AccountDetails{Name: section.accountName, Type: section.accountType},
+	}
+	for _, record := range section.records {
+		transactions, err := qifTransactions(record, order)
+		if err != nil {
+			return Statement{}, fmt.Errorf("line %d: %w", record.line, err)
+		}
+		statement.Transactions = append(statement.Transactions, transactions...)
+	}
+	return statement, nil
+}
+
+// readQIF splits a file into its transaction sections, dropping sections
+// with no records.
+func readQIF(r io.Reader) ([]qifSection, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var (
+		sections    []qifSection
+		current     *qifSection
+		accountName string
+		inAccount   bool
+		record      qifRecord
+		hasFields   bool
+	)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '!' {
+			header := strings.ToLower(line)
+			switch {
+			case header == "!account":
+				inAccount = true
+				accountName = ""
+				current = nil
+			case strings.HasPrefix(header, "!type:"):
+				inAccount = false
+				current = nil
+				if accountType, ok := qifTransactionTypes[strings.TrimSpace(header[len("!type:"):])]; ok {
+					sections = append(sections, qifSection{accountType: accountType, accountName: accountName})
+					current = &sections[len(sections)-1]
+				}
+			default:
+				// !Option:AutoSwitch and !Clear:AutoSwitch only control how
+				// Quicken reads the account list.
+				inAccount = false
+			}
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		if code == '^' {
+			if current != nil && hasFields && !inAccount {
+				current.records = append(current.records, record)
+			}
+			inAccount = false
+			record, hasFields = qifRecord{}, false
+			continue
+		}
+
+		if inAccount {
+			if code == 'N' {
+				accountName = value
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if !hasFields {
+			record.line = lineNum
+			hasFields = true
+		}
+
+		switch code {
+		case 'D':
+			record.date = value
+		case 'T':
+			record.amount = value
+		case 'U':
+			// U repeats T, at higher precision in newer Quicken versions.
+			if record.amount == "" {
+				record.amount = value
+			}
+		case 'P':
+			record.payee = value
+		case 'M':
+			record.memo = value
+		case 'L':
+			record.category = value
+		case 'S':
+			record.splits = append(record.splits, qifSplit{category: value})
+		case 'E', '$':
+			if len(record.splits) == 0 {
+				record.splits = append(record.splits, qifSplit{})
+			}
+			split := &record.splits[len(record.splits)-1]
+			if code == 'E' {
+				split.memo = value
+			} else {
+				split.amount = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading QIF file: %w", err)
+	}
+	if current != nil && hasFields {
+		// The final record is not always terminated.
+		current.records = append(current.records, record)
+	}
+
+	nonEmpty := sections[:0]
+	for _, section := range sections {
+		if len(section.records) > 0 {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	return nonEmpty, nil
+}
+
+// qifTransactions converts a record, expanding a split record into one
+// transaction per split so each keeps its own category.
+func qifTransactions(record qifRecord, order QIFDateOrder) ([]transaction.Transaction, error) {
+	if record.date == "" {
+		return nil, fmt.Errorf("missing date")
+	}
+	date, err := parseQIFDate(record.date, order)
+	if err != nil {
+		return nil, fmt.Errorf("parsing date '%s': %w", record.date, err)
+	}
+	if record.amount == "" {
+		return nil, fmt.Errorf("missing amount")
+	}
+	amount, err := parseAmountIn(record.amount, money.GBP)
+	if err != nil {
+		return nil, fmt.Errorf("parsing amount '%s': %w", record.amount, err)
+	}
+
+	if len(record.splits) == 0 {
+		description := firstNonEmpty(record.payee, record.memo)
+		if description == "" {
+			return nil, fmt.Errorf("transaction has no payee or memo")
+		}
+		return []transaction.Transaction{{
+			Date:        date,
+			Description: description,
+			Amount:      amount,
+			Bank:        qifInstitution,
+			Category:    qifCategory(record.category),
+		}}, nil
+	}
+
+	transactions := make([]transaction.Transaction, 0, len(record.splits))
+	var total int64
+	for i, split := range record.splits {
+		if split.amount == "" {
+			return nil, fmt.Errorf("split %d has no amount", i+1)
+		}
+		splitAmount, err := parseAmountIn(split.amount, money.GBP)
+		if err != nil {
+			return nil, fmt.Errorf("split %d: parsing amount '%s': %w", i+1, split.amount, err)
+		}
+		total += splitAmount.Amount()
+
+		description := firstNonEmpty(record.payee, split.memo, record.memo)
+		if description == "" {
+			return nil, fmt.Errorf("split %d has no payee or memo", i+1)
+		}
+		transactions = append(transactions, transaction.Transaction{
+			Date:        date,
+			Description: description,
+			Amount:      splitAmount,
+			Bank:        qifInstitution,
+			Category:    qifCategory(split.category),
+		})
+	}
+	if total != amount.Amount() {
+		return nil, fmt.Errorf("splits add up to %d but the transaction is %d", total, amount.Amount())
+	}
+	return transactions, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// qifCategory drops the "/Class" suffix Quicken appends to categories.
+// Transfers keep their "[Account]" form.
+func qifCategory(value string) *string {
+	if i := strings.IndexByte(value, '/'); i != -1 {
+		value = value[:i]
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// QIFDateOrder is whether QIF dates put the day or the month first. QIF
+// itself does not say, and tools differ by locale.
+type QIFDateOrder int
+
+const (
+	QIFDayFirst QIFDateOrder = iota
+	QIFMonthFirst
+)
+
+// qifDate is a date split into its parts before the day/month order is
+// known. apostrophe is set for Quicken's "1/15'26" style.
+type qifDate struct {
+	first, second, year int
+	iso                 bool
+	apostrophe          bool
+}
+
+// splitQIFDate accepts 15/01/2026, 1/15/26, 1/15'26, 1/ 5'26, 15-01-2026,
+// 15.01.2026 and 2026-01-15.
+func splitQIFDate(value string) (qifDate, error) {
+	var d qifDate
+	d.apostrophe = strings.Contains(value, "'")
+	value = strings.ReplaceAll(value, " ", "")
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	if len(parts) != 3 {
+		return d, fmt.Errorf("expected day, month and year")
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return d, fmt.Errorf("%q is not a number", part)
+		}
+		numbers[i] = n
+	}
+
+	if len(parts[0]) == 4 {
+		d.iso = true
+		d.year, d.first, d.second = numbers[0], numbers[1], numbers[2]
+		return d, nil
+	}
+
+	d.first, d.second, d.year = numbers[0], numbers[1], numbers[2]
+	switch len(parts[2]) {
+	case 4:
+	case 2:
+		// Quicken writes '26 for years from 2000 and /99 before then;
+		// other tools just write two digits.
+		if d.apostrophe || d.year < 70 {
+			d.year += 2000
+		} else {
+			d.year += 1900
+		}
+	default:
+		return d, fmt.Errorf("year must have two or four digits")
+	}
+	return d, nil
+}
+
+// qifDateOrder decides whether a file writes the day or the month first.
+// A part above 12 settles it; a file with no such date is read day-first,
+// unless it uses Quicken's '26 years, which only US Quicken writes.
+func qifDateOrder(values []string) (QIFDateOrder, error) {
+	var dayFirst, monthFirst, apostrophe bool
+	for _, value := range values {
+		d, err := splitQIFDate(value)
+		if err != nil || d.iso {
+			continue
+		}
+		dayFirst = dayFirst || d.first > 12
+		monthFirst = monthFirst || d.second > 12
+		apostrophe = apostrophe || d.apostrophe
+	}
+
+	switch {
+	case dayFirst && monthFirst:
+		return 0, fmt.Errorf("QIF dates mix day-first and month-first order")
+	case monthFirst, !dayFirst && apostrophe:
+		return QIFMonthFirst, nil
+	default:
+		return QIFDayFirst, nil
+	}
+}
+
+func parseQIFDate(value string, order QIFDateOrder) (time.Time, error) {
+	d, err := splitQIFDate(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	day, month := d.first, d.second
+	if d.iso {
+		month, day = d.first, d.second
+	} else if order == QIFMonthFirst {
+		day, month = d.second, d.first
+	}
+
+	date := time.Date(d.year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, fmt.Errorf("no such date")
+	}
+	return date, nil
+}
+
+func (p *QIFParser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+// Detect looks for a !Type, !Account or !Option header on the first line.
+func (p *QIFParser) Detect(sample []byte) bool {
+	sample = bytes.TrimPrefix(sample, []byte("\ufeff"))
+	line, _, _ := bytes.Cut(bytes.TrimSpace(sample), []byte("\n"))
+	line = bytes.ToLower(bytes.TrimSpace(line))
+	return bytes.HasPrefix(line, []byte("!type:")) || bytes.HasPrefix(line, []byte("!account")) ||
+		bytes.HasPrefix(line, []byte("!option:"))
+}
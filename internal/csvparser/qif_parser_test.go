@@ -0,0 +1,133 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQIFParser_ParseStatement(t *testing.T) {
+	file, err := os.Open("testdata/qif_sample.qif")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statement, err := (&QIFParser{}).ParseStatement(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "QIF", statement.Institution)
+	assert.Equal(t, AccountDetails{Name: "Joint Current", Type: "current"}, statement.Account)
+	assert.Len(t, statement.Transactions, 4)
+
+	first := statement.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), first.Date)
+	assert.Equal(t, "TEST PAYEE", first.Description)
+	assert.Equal(t, int64(-5000), first.Amount.Amount())
+	assert.Equal(t, "Bills:Utilities", *first.Category)
+	assert.Equal(t, "QIF", first.Bank)
+
+	groceries, household := statement.Transactions[1], statement.Transactions[2]
+	assert.Equal(t, time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), groceries.Date)
+	assert.Equal(t, "SUPERMARKET", groceries.Description)
+	assert.Equal(t, int64(-120050), groceries.Amount.Amount())
+	assert.Equal(t, "Groceries", *groceries.Category)
+	assert.Equal(t, int64(-1000), household.Amount.Amount())
+	assert.Equal(t, "Household", *household.Category)
+
+	salary := statement.Transactions[3]
+	assert.Equal(t, time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC), salary.Date)
+	assert.Equal(t, "[Savings]", *salary.Category)
+}
+
+func TestQIFParser_ParseStatement_CreditCard(t *testing.T) {
+	qif := "!Type:CCard\nD15/01/2026\nT-25.50\nPRESTAURANT\n^\nD16/01/2026\nT100.00\nMPayment\n"
+
+	statement, err := (&QIFParser{}).ParseStatement(strings.NewReader(qif))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "credit", statement.Account.Type)
+	assert.Len(t, statement.Transactions, 2)
+	assert.Equal(t, "Payment", statement.Transactions[1].Description)
+	assert.Nil(t, statement.Transactions[1].Category)
+}
+
+func TestQIFParser_ParseStatement_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		qif    string
+		errMsg string
+	}{
+		{"no transactions", "!Type:Cat\nNGroceries\n^\n", "no bank or card transactions"},
+		{"two accounts", "!Type:Bank\nD15/01/2026\nT1\nPA\n^\n!Type:CCard\nD15/01/2026\nT1\nPB\n^\n", "contains 2 accounts"},
+		{"missing date", "!Type:Bank\nT1.00\nPA\n^\n", "line 2: missing date"},
+		{"missing amount", "!Type:Bank\nD15/01/2026\nPA\n^\n", "line 2: missing amount"},
+		{"no payee", "!Type:Bank\nD15/01/2026\nT1.00\n^\n", "no payee or memo"},
+		{"bad amount", "!Type:Bank\nD15/01/2026\nTabc\nPA\n^\n", "parsing amount 'abc'"},
+		{"impossible date", "!Type:Bank\nD31/02/2026\nT1.00\nPA\n^\n", "parsing date '31/02/2026'"},
+		{"mixed date order", "!Type:Bank\nD15/01/2026\nT1\nPA\n^\nD01/15/2026\nT1\nPB\n^\n", "mix day-first and month-first"},
+		{"splits do not add up", "!Type:Bank\nD15/01/2026\nT-10.00\nPA\nSFood\n$-4.00\nSHome\n$-5.00\n^\n", "splits add up to -900"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&QIFParser{}).ParseStatement(strings.NewReader(tt.qif))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		value string
+		order QIFDateOrder
+		want  time.Time
+	}{
+		{"15/01/2026", QIFDayFirst, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"01/15/2026", QIFMonthFirst, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"1/15'26", QIFMonthFirst, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"1/ 5'26", QIFMonthFirst, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"15-01-26", QIFDayFirst, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"15.01.1998", QIFDayFirst, time.Date(1998, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"12/31/98", QIFMonthFirst, time.Date(1998, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"2026-01-15", QIFMonthFirst, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseQIFDate(tt.value, tt.order)
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+}
+
+func TestQIFDateOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   QIFDateOrder
+	}{
+		{"day above twelve", []string{"01/02/2026", "15/01/2026"}, QIFDayFirst},
+		{"month first", []string{"01/02/2026", "01/15/2026"}, QIFMonthFirst},
+		{"ambiguous defaults to day first", []string{"01/02/2026"}, QIFDayFirst},
+		{"quicken years are month first", []string{"1/2'26"}, QIFMonthFirst},
+		{"iso dates do not count", []string{"2026-01-15", "03/04/2026"}, QIFDayFirst},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := qifDateOrder(tt.values)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQIFParser_Detect(t *testing.T) {
+	parser := &QIFParser{}
+
+	assert.True(t, parser.Detect([]byte("!Type:Bank\nD15/01/2026\n")))
+	assert.True(t, parser.Detect([]byte("\ufeff!Option:AutoSwitch\n!Account\n")))
+	assert.True(t, parser.Detect([]byte("\r\n!type:ccard\r\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount\n")))
+	assert.False(t, parser.Detect([]byte("OFXHEADER:100\n")))
+}
@@ -0,0 +1,89 @@
+package csvparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// QIFAccount is a group of transactions written as one QIF account.
+// Type is one of the account package's types.
+type QIFAccount struct {
+	Name         string
+	Type         string
+	Transactions []transaction.Transaction
+}
+
+// WriteQIF writes accounts in the multi-account QIF layout that Quicken and
+// similar tools import: an !Account record naming each account followed by
+// its transactions. QIF has no currency, so amounts are written as plain
+// decimals.
+func WriteQIF(w io.Writer, accounts []QIFAccount, order QIFDateOrder) error {
+	bw := bufio.NewWriter(w)
+	for _, acc := range accounts {
+		qifType := qifAccountType(acc.Type)
+		fmt.Fprintf(bw, "!Account\nN%s\nT%s\n^\n!Type:%s\n", qifText(acc.Name), qifType, qifType)
+
+		for _, tx := range acc.Transactions {
+			fmt.Fprintf(bw, "D%s\n", formatQIFDate(tx.Date, order))
+			fmt.Fprintf(bw, "T%s\n", formatQIFAmount(tx.Amount))
+			fmt.Fprintf(bw, "P%s\n", qifText(tx.Description))
+			if tx.Category != nil {
+				fmt.Fprintf(bw, "L%s\n", qifText(*tx.Category))
+			}
+			bw.WriteString("^\n")
+		}
+	}
+	return bw.Flush()
+}
+
+func qifAccountType(accountType string) string {
+	switch accountType {
+	case account.TypeCredit:
+		return "CCard"
+	case account.TypeCash:
+		return "Cash"
+	default:
+		return "Bank"
+	}
+}
+
+// qifText keeps a value on one line, as every QIF field is a single line.
+func qifText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func formatQIFDate(date time.Time, order QIFDateOrder) string {
+	if order == QIFMonthFirst {
+		return date.Format("01/02/2006")
+	}
+	return date.Format("02/01/2006")
+}
+
+// formatQIFAmount writes minor units as a decimal without grouping, such as
+// "-1234.56".
+func formatQIFAmount(amount *money.Money) string {
+	minor := amount.Amount()
+	fraction := amount.Currency().Fraction
+
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if fraction == 0 {
+		return sign + digits
+	}
+	if len(digits) <= fraction {
+		digits = strings.Repeat("0", fraction-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-fraction] + "." + digits[len(digits)-fraction:]
+}
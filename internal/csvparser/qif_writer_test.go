@@ -0,0 +1,62 @@
+package csvparser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteQIF(t *testing.T) {
+	category := "Groceries"
+	accounts := []QIFAccount{
+		{
+			Name: "Debit ****1234",
+			Type: "current",
+			Transactions: []transaction.Transaction{
+				{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "SUPERMARKET\nLONDON", Amount: money.New(-120050, "GBP"), Category: &category},
+				{Date: time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC), Description: "REFUND", Amount: money.New(5, "GBP")},
+			},
+		},
+		{
+			Name:         "Gold Card",
+			Type:         "credit",
+			Transactions: []transaction.Transaction{{Date: time.Date(2026, 1, 17, 0, 0, 0, 0, time.UTC), Description: "HOTEL", Amount: money.New(-1500, "JPY")}},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteQIF(&buf, accounts, QIFMonthFirst)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "!Account\nNDebit ****1234\nTBank\n^\n!Type:Bank\n"+
+		"D01/15/2026\nT-1200.50\nPSUPERMARKET LONDON\nLGroceries\n^\n"+
+		"D01/16/2026\nT0.05\nPREFUND\n^\n"+
+		"!Account\nNGold Card\nTCCard\n^\n!Type:CCard\n"+
+		"D01/17/2026\nT-1500\nPHOTEL\n^\n", buf.String())
+}
+
+func TestWriteQIF_RoundTrip(t *testing.T) {
+	category := "Bills"
+	original := []transaction.Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "UTILITY", Amount: money.New(-7525, "GBP"), Category: &category},
+		{Date: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), Description: "SALARY", Amount: money.New(200000, "GBP")},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteQIF(&buf, []QIFAccount{{Name: "Current", Type: "current", Transactions: original}}, QIFDayFirst))
+	statement, err := (&QIFParser{}).ParseStatement(&buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Current", statement.Account.Name)
+	assert.Len(t, statement.Transactions, 2)
+	for i, tx := range statement.Transactions {
+		assert.Equal(t, original[i].Date, tx.Date)
+		assert.Equal(t, original[i].Description, tx.Description)
+		assert.Equal(t, original[i].Amount.Amount(), tx.Amount.Amount())
+		assert.Equal(t, original[i].Category, tx.Category)
+	}
+}
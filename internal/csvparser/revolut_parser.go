@@ -0,0 +1,249 @@
+package csvparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+const (
+	revolutInstitution = "Revolut"
+	revolutDateFormat  = "2006-01-02 15:04:05"
+	revolutCompleted   = "COMPLETED"
+)
+
+// RevolutParser reads Revolut account statements. One export covers every
+// currency pocket of a product, so each product and currency becomes its
+// own statement. Only completed rows are imported: pending rows are left
+// for a later export, by when they have completed, and reverted, declined
+// and failed rows never moved any money.
+//
+// Revolut gives no transaction IDs, so each row is referenced by the time
+// it started, which the export keeps to the second. A fee becomes a
+// transaction of its own, linked to the row it was charged on.
+type RevolutParser struct{}
+
+type revolutColumns struct {
+	product, started, completed, description int
+	amount, fee, currency, state, balance    int
+}
+
+// revolutPocket collects the statement for one product and currency,
+// along with how often each start time has been seen.
+type revolutPocket struct {
+	statement Statement
+	started   map[string]int
+}
+
+func (p *RevolutParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	return parseSingleStatement(p, r)
+}
+
+// ParseStatements returns a statement for each product and currency with
+// completed transactions, in the order they first appear.
+func (p *RevolutParser) ParseStatements(r io.Reader) ([]Statement, error) {
+	return p.parseRows(r, nil)
+}
+
+func (p *RevolutParser) parseRows(r io.Reader, report *rowReport) ([]Statement, error) {
+	reader := newRowReader(r, report)
+	reader.FieldsPerRecord = -1
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+	cols, err := revolutHeader(headers)
+	if err != nil {
+		return nil, err
+	}
+	required := max(cols.product, cols.started, cols.completed, cols.description, cols.amount, cols.currency, cols.state)
+
+	var pockets []*revolutPocket
+	byKey := make(map[string]*revolutPocket)
+	for rowNum := 1; ; rowNum++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := report.readError(rowNum, reader.Raw(), err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(row) <= required {
+			if err := report.reject(rowNum, reader.Raw(), shortRowError(row, required)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		product := strings.TrimSpace(row[cols.product])
+		currency := strings.ToUpper(strings.TrimSpace(row[cols.currency]))
+		key := product + "|" + currency
+		pocket := byKey[key]
+		if pocket == nil {
+			pocket = &revolutPocket{
+				statement: Statement{
+					Institution: revolutInstitution,
+					Account:     AccountDetails{Name: strings.TrimSpace(product + " " + currency), Type: revolutAccountType(product)},
+				},
+				started: make(map[string]int),
+			}
+			byKey[key] = pocket
+			pockets = append(pockets, pocket)
+		}
+
+		if err := pocket.add(row, cols, currency); err != nil {
+			if err := report.reject(rowNum, reader.Raw(), err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var statements []Statement
+	for _, pocket := range pockets {
+		if len(pocket.statement.Transactions) > 0 {
+			statements = append(statements, pocket.statement)
+		}
+	}
+	if len(statements) == 0 {
+		return []Statement{{Institution: revolutInstitution}}, nil
+	}
+	return statements, nil
+}
+
+func revolutHeader(headers []string) (revolutColumns, error) {
+	cols := revolutColumns{
+		product:     findColumnIndex(headers, "Product"),
+		started:     findColumnIndex(headers, "Started Date"),
+		completed:   findColumnIndex(headers, "Completed Date"),
+		description: findColumnIndex(headers, "Description"),
+		amount:      findColumnIndex(headers, "Amount"),
+		fee:         findColumnIndex(headers, "Fee"),
+		currency:    findColumnIndex(headers, "Currency"),
+		state:       findColumnIndex(headers, "State"),
+		balance:     findColumnIndex(headers, "Balance"),
+	}
+	for _, idx := range []int{cols.product, cols.started, cols.completed, cols.description, cols.amount, cols.currency, cols.state} {
+		if idx == -1 {
+			return revolutColumns{}, fmt.Errorf("required column not found in CSV headers")
+		}
+	}
+	return cols, nil
+}
+
+// add appends a row to the pocket's statement, followed by its fee if it
+// was charged one. Rows that have not completed are only counted, so the
+// references of later rows do not change once they complete.
+func (pocket *revolutPocket) add(row []string, cols revolutColumns, currency string) error {
+	startedValue := strings.TrimSpace(row[cols.started])
+	started, err := time.Parse(revolutDateFormat, startedValue)
+	if err != nil {
+		return inColumn("Started Date", fmt.Errorf("parsing started date '%s': %w", startedValue, err))
+	}
+	ref := started.Format("20060102T150405")
+	pocket.started[ref]++
+	if n := pocket.started[ref]; n > 1 {
+		ref = fmt.Sprintf("%s/%d", ref, n)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(row[cols.state]), revolutCompleted) {
+		return nil
+	}
+
+	date := started
+	if value := strings.TrimSpace(row[cols.completed]); value != "" {
+		date, err = time.Parse(revolutDateFormat, value)
+		if err != nil {
+			return inColumn("Completed Date", fmt.Errorf("parsing completed date '%s': %w", value, err))
+		}
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	amount, err := parseAmountIn(row[cols.amount], currency)
+	if err != nil {
+		return inColumn("Amount", fmt.Errorf("parsing amount '%s': %w", row[cols.amount], err))
+	}
+	var fee *money.Money
+	if value := field(row, cols.fee); value != "" {
+		fee, err = parseAmountIn(value, currency)
+		if err != nil {
+			return inColumn("Fee", fmt.Errorf("parsing fee '%s': %w", value, err))
+		}
+		if fee.IsZero() {
+			fee = nil
+		}
+	}
+	// The balance is after the fee, so the row itself left the balance
+	// higher by the fee.
+	var balance, balanceBeforeFee *money.Money
+	if value := field(row, cols.balance); value != "" {
+		balance, err = parseAmountIn(value, currency)
+		if err != nil {
+			return inColumn("Balance", fmt.Errorf("parsing balance '%s': %w", value, err))
+		}
+		balanceBeforeFee = balance
+		if fee != nil {
+			balanceBeforeFee = money.New(balance.Amount()+fee.Amount(), currency)
+		}
+	}
+
+	description := strings.TrimSpace(row[cols.description])
+	statement := &pocket.statement
+	statement.Transactions = append(statement.Transactions, transaction.Transaction{
+		Date:           date,
+		Description:    description,
+		Amount:         amount,
+		RunningBalance: balanceBeforeFee,
+		Bank:           revolutInstitution,
+		ExternalID:     &ref,
+	})
+	if fee != nil {
+		feeRef := ref + "/fee"
+		statement.Transactions = append(statement.Transactions, transaction.Transaction{
+			Date:             date,
+			Description:      truncateUTF8("Fee - "+description, maxDescriptionLength),
+			Amount:           negate(fee),
+			RunningBalance:   balance,
+			Bank:             revolutInstitution,
+			ExternalID:       &feeRef,
+			LinkedExternalID: &ref,
+		})
+	}
+
+	if balance != nil && !date.Before(statement.BalanceDate) {
+		statement.ClosingBalance = balance
+		statement.BalanceDate = date
+	}
+	return nil
+}
+
+func revolutAccountType(product string) string {
+	switch strings.ToLower(product) {
+	case "current":
+		return account.TypeCurrent
+	case "savings", "deposit":
+		return account.TypeSavings
+	}
+	return ""
+}
+
+func (p *RevolutParser) cellFormat() cellFormat {
+	return cellFormat{delimiter: ',', dateLayout: revolutDateFormat}
+}
+
+func (p *RevolutParser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+func (p *RevolutParser) Detect(sample []byte) bool {
+	rows := sampleRows(sample, 1, ',')
+	return len(rows) == 1 && hasColumns(rows[0], "Product", "Started Date", "Completed Date", "State")
+}
@@ -0,0 +1,122 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevolutParser_ParseStatements(t *testing.T) {
+	file, err := os.Open("testdata/revolut_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := (&RevolutParser{}).ParseStatements(file)
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 3)
+
+	gbp := statements[0]
+	assert.Equal(t, "Revolut", gbp.Institution)
+	assert.Equal(t, AccountDetails{Name: "Current GBP", Type: "current"}, gbp.Account)
+	// The pending and reverted rows are left out and the exchange fee is
+	// added after the exchange.
+	assert.Len(t, gbp.Transactions, 4)
+	assert.Equal(t, int64(37610), gbp.ClosingBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC), gbp.BalanceDate)
+
+	tesco := gbp.Transactions[1]
+	assert.Equal(t, time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC), tesco.Date)
+	assert.Equal(t, "Tesco", tesco.Description)
+	assert.Equal(t, int64(-2340), tesco.Amount.Amount())
+	assert.Equal(t, "20260111T123045", *tesco.ExternalID)
+	assert.Nil(t, tesco.LinkedExternalID)
+
+	exchange, fee := gbp.Transactions[2], gbp.Transactions[3]
+	assert.Equal(t, int64(-10000), exchange.Amount.Amount())
+	assert.Equal(t, int64(37660), exchange.RunningBalance.Amount())
+	assert.Equal(t, "Fee - Exchanged to EUR", fee.Description)
+	assert.Equal(t, int64(-50), fee.Amount.Amount())
+	assert.Equal(t, "GBP", fee.Amount.Currency().Code)
+	assert.Equal(t, int64(37610), fee.RunningBalance.Amount())
+	assert.Equal(t, "20260112T180000/fee", *fee.ExternalID)
+	assert.Equal(t, "20260112T180000", *fee.LinkedExternalID)
+
+	eur := statements[1]
+	assert.Equal(t, AccountDetails{Name: "Current EUR", Type: "current"}, eur.Account)
+	assert.Len(t, eur.Transactions, 2)
+	assert.Equal(t, int64(11620), eur.Transactions[0].Amount.Amount())
+	assert.Equal(t, "EUR", eur.Transactions[0].Amount.Currency().Code)
+	assert.Equal(t, "Café de Paris", eur.Transactions[1].Description)
+	assert.Equal(t, int64(10120), eur.ClosingBalance.Amount())
+
+	savings := statements[2]
+	assert.Equal(t, AccountDetails{Name: "Savings GBP", Type: "savings"}, savings.Account)
+	assert.Len(t, savings.Transactions, 1)
+}
+
+func TestRevolutParser_ParseStatements_NumbersRowsStartedTogether(t *testing.T) {
+	csv := "Product,Started Date,Completed Date,Description,Amount,Fee,Currency,State,Balance\n" +
+		"Current,2026-01-12 18:00:00,2026-01-12 18:00:00,Coffee,-3.00,0.00,GBP,PENDING,\n" +
+		"Current,2026-01-12 18:00:00,2026-01-12 18:00:00,Coffee,-3.00,0.00,GBP,COMPLETED,7.00\n"
+
+	statements, err := (&RevolutParser{}).ParseStatements(strings.NewReader(csv))
+
+	assert.NoError(t, err)
+	assert.Len(t, statements[0].Transactions, 1)
+	assert.Equal(t, "20260112T180000/2", *statements[0].Transactions[0].ExternalID)
+}
+
+func TestRevolutParser_ParseStatements_Errors(t *testing.T) {
+	header := "Product,Started Date,Completed Date,Description,Amount,Fee,Currency,State,Balance\n"
+	tests := []struct {
+		name   string
+		csv    string
+		errMsg string
+	}{
+		{"missing state column", "Product,Started Date,Completed Date,Description,Amount,Currency\n", "required column not found"},
+		{"short row", header + "Current,2026-01-12 18:00:00\n", "row 1: expected at least 8 columns, got 2"},
+		{"bad started date", header + "Current,12/01/2026,,Coffee,-3.00,0.00,GBP,COMPLETED,\n", "row 1: parsing started date '12/01/2026'"},
+		{"unknown currency", header + "Current,2026-01-12 18:00:00,,Coffee,-3.00,0.00,XYZ,COMPLETED,\n", "unknown currency"},
+		{"bad fee", header + "Current,2026-01-12 18:00:00,,Coffee,-3.00,abc,GBP,COMPLETED,\n", "parsing fee 'abc'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&RevolutParser{}).ParseStatements(strings.NewReader(tt.csv))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestRevolutParser_Parse_MultipleStatements(t *testing.T) {
+	file, err := os.Open("testdata/revolut_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = (&RevolutParser{}).Parse(file)
+
+	assert.ErrorContains(t, err, "contains 3 statements")
+}
+
+func TestRevolutParser_Detect(t *testing.T) {
+	parser := &RevolutParser{}
+
+	assert.True(t, parser.Detect([]byte("Type,Product,Started Date,Completed Date,Description,Amount,Fee,Currency,State,Balance\n")))
+	assert.False(t, parser.Detect([]byte("Date,Description,Amount\n")))
+}
+
+func TestService_ParseStatements_DetectsRevolut(t *testing.T) {
+	file, err := os.Open("testdata/revolut_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := NewService().ParseStatements(file, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 3)
+	assert.Equal(t, "revolut", statements[0].Format)
+}
@@ -0,0 +1,155 @@
+package csvparser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RowError is a row a lenient parse left out of its statements.
+type RowError struct {
+	// Row counts the data rows of the file from 1, as parse errors do.
+	Row int `json:"row"`
+	// Raw is the row as the file has it, without its line ending. A quoted
+	// value spanning lines takes in every line it spans.
+	Raw string `json:"raw"`
+	// Column is the header of the column that could not be read, when the
+	// problem was with a single value.
+	Column string `json:"column,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ErrLenientUnsupported is returned for a lenient parse of a format that is
+// read whole, which cannot leave rows out.
+var ErrLenientUnsupported = errors.New("lenient mode is not supported")
+
+// rowParser is implemented by parsers that read a file row by row, which
+// can leave out the rows they cannot read rather than failing the file.
+type rowParser interface {
+	parseRows(r io.Reader, report *rowReport) ([]Statement, error)
+}
+
+// rowReport collects the rows a lenient parse leaves out. A nil report
+// makes the parse strict, so that the first bad row fails the file.
+type rowReport struct {
+	rejected []RowError
+}
+
+// reject records that a row could not be read because of err, returning
+// nil so the parse can carry on without it. A strict parse returns the
+// error instead.
+func (report *rowReport) reject(rowNum int, raw string, err error) error {
+	if report == nil {
+		return fmt.Errorf("row %d: %w", rowNum, err)
+	}
+	rejected := RowError{Row: rowNum, Raw: raw, Reason: err.Error()}
+	var colErr *columnError
+	if errors.As(err, &colErr) {
+		rejected.Column = colErr.column
+	}
+	report.rejected = append(report.rejected, rejected)
+	return nil
+}
+
+// readError handles an error reading the next row. A line that is not
+// valid delimited text is rejected like any other bad row, but other
+// errors, such as the upload failing part way, fail the parse.
+func (report *rowReport) readError(rowNum int, raw string, err error) error {
+	var parseErr *csv.ParseError
+	if report == nil || !errors.As(err, &parseErr) {
+		return fmt.Errorf("reading data row: %w", err)
+	}
+	return report.reject(rowNum, raw, err)
+}
+
+// rows returns the rejected rows in file order.
+func (report *rowReport) rows() []RowError {
+	if report == nil {
+		return nil
+	}
+	return report.rejected
+}
+
+// columnError is a row error caused by the value in one column.
+type columnError struct {
+	column string
+	err    error
+}
+
+func (e *columnError) Error() string {
+	return e.err.Error()
+}
+
+func (e *columnError) Unwrap() error {
+	return e.err
+}
+
+func inColumn(column string, err error) error {
+	return &columnError{column: column, err: err}
+}
+
+// shortRowError is the error for a row without every required column.
+func shortRowError(row []string, required int) error {
+	return fmt.Errorf("expected at least %d columns, got %d", required+1, len(row))
+}
+
+// rowReader reads rows as csv.Reader does. For a lenient parse it keeps
+// the text of the last row read, including one that is not valid
+// delimited text, so a rejected row is reported as the file has it.
+type rowReader struct {
+	*csv.Reader
+	source *keptSource
+	raw    string
+}
+
+// newRowReader reads rows from r, keeping their text only when report
+// takes rejected rows, as a strict parse never reports them.
+func newRowReader(r io.Reader, report *rowReport) *rowReader {
+	if report == nil {
+		return &rowReader{Reader: csv.NewReader(r)}
+	}
+	source := &keptSource{r: r}
+	return &rowReader{Reader: csv.NewReader(source), source: source}
+}
+
+// Read reads the next row, and its text for Raw.
+func (r *rowReader) Read() ([]string, error) {
+	if r.source == nil {
+		return r.Reader.Read()
+	}
+	start := r.InputOffset()
+	row, err := r.Reader.Read()
+	r.raw = r.source.take(start, r.InputOffset())
+	return row, err
+}
+
+// Raw is the text of the last row read, without its line ending.
+func (r *rowReader) Raw() string {
+	return r.raw
+}
+
+// keptSource keeps what has been read from r but not yet taken. The csv
+// reader reads ahead, so that is the current row and the buffered text
+// after it.
+type keptSource struct {
+	r    io.Reader
+	buf  []byte
+	base int64 // the offset of buf[0] in r
+}
+
+func (s *keptSource) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.buf = append(s.buf, p[:n]...)
+	return n, err
+}
+
+// take returns the text from offset start to end and drops everything
+// before end.
+func (s *keptSource) take(start, end int64) string {
+	text := string(s.buf[start-s.base : end-s.base])
+	s.buf = s.buf[:copy(s.buf, s.buf[end-s.base:])]
+	s.base = end
+	return strings.TrimRight(text, "\r\n")
+}
@@ -0,0 +1,158 @@
+package csvparser
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// badAmexCSV is an Amex export with a bad date, a short row, a line that is
+// not valid CSV and a bad amount among its good rows.
+const badAmexCSV = `Date,Description,Amount,Reference
+15/01/2026,TEST RESTAURANT,25.50,'AT1'
+32/01/2026,BAD DATE,4.75,'AT2'
+14/01/2026
+13/01/2026,"BROKEN "QUOTE",1.00,'AT3'
+12/01/2026,"TEST, SUPERMARKET",45.20,'AT4'
+11/01/2026,TEST SHOP,abc,'AT5'
+`
+
+func TestService_ParseStatementsWithOptions_Lenient(t *testing.T) {
+	result, err := NewService().ParseStatementsWithOptions(strings.NewReader(badAmexCSV), ParseOptions{BankType: "amex", Lenient: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TEST RESTAURANT", "TEST, SUPERMARKET"}, descriptions(result.Statements[0].Transactions))
+	assert.Len(t, result.Rejected, 4)
+
+	assert.Equal(t, RowError{Row: 2, Raw: "32/01/2026,BAD DATE,4.75,'AT2'", Column: "Date", Reason: `parsing date '32/01/2026': parsing time "32/01/2026": day out of range`}, result.Rejected[0])
+	assert.Equal(t, RowError{Row: 3, Raw: "14/01/2026", Reason: "expected at least 3 columns, got 1"}, result.Rejected[1])
+	assert.Equal(t, 4, result.Rejected[2].Row)
+	assert.Equal(t, `13/01/2026,"BROKEN "QUOTE",1.00,'AT3'`, result.Rejected[2].Raw)
+	assert.Contains(t, result.Rejected[2].Reason, `extraneous or missing " in quoted-field`)
+	assert.Equal(t, RowError{Row: 6, Raw: "11/01/2026,TEST SHOP,abc,'AT5'", Column: "Amount", Reason: `parsing amount 'abc': invalid amount: unexpected character 'a' in "abc"`}, result.Rejected[3])
+}
+
+func TestService_ParseStatementsWithOptions_StrictFailsOnFirstBadRow(t *testing.T) {
+	result, err := NewService().ParseStatementsWithOptions(strings.NewReader(badAmexCSV), ParseOptions{BankType: "amex"})
+
+	assert.ErrorContains(t, err, "row 2: parsing date '32/01/2026'")
+	assert.Empty(t, result.Statements)
+}
+
+func TestService_ParseStatementsWithOptions_LenientFormats(t *testing.T) {
+	tests := []struct {
+		bankType string
+		fixture  string
+		old, new string
+		column   string
+	}{
+		{"revolut", "testdata/revolut_sample.csv", "Tesco,-23.40", "Tesco,-23.4O", "Amount"},
+		{"paypal", "testdata/paypal_sample.csv", `"GBP","9.99","0.00","9.99"`, `"GBP","9.99","0.00","nine"`, "Net"},
+		{"wise", "testdata/wise_sample.csv", `"CARD-555","16-01-2026"`, `"CARD-555","16/01/2026"`, "Date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bankType, func(t *testing.T) {
+			data, err := os.ReadFile(tt.fixture)
+			assert.NoError(t, err)
+			text := strings.Replace(string(data), tt.old, tt.new, 1)
+			assert.NotEqual(t, string(data), text)
+
+			strict, err := NewService().ParseStatements(strings.NewReader(string(data)), tt.bankType)
+			assert.NoError(t, err)
+
+			result, err := NewService().ParseStatementsWithOptions(strings.NewReader(text), ParseOptions{BankType: tt.bankType, Lenient: true})
+
+			assert.NoError(t, err)
+			assert.Len(t, result.Rejected, 1)
+			assert.Equal(t, 1, len(strings.Split(result.Rejected[0].Raw, "\n")))
+			assert.Contains(t, result.Rejected[0].Raw, tt.new)
+			assert.Equal(t, tt.column, result.Rejected[0].Column)
+			assert.Less(t, countTransactions(result.Statements), countTransactions(strict))
+
+			_, err = NewService().ParseStatements(strings.NewReader(text), tt.bankType)
+			assert.ErrorContains(t, err, "row ")
+		})
+	}
+}
+
+func TestService_ParseStatementsWithOptions_LenientWholeFileFormats(t *testing.T) {
+	// OFX is not read row by row, so even a good file is refused rather
+	// than silently read strictly.
+	data, err := os.ReadFile("testdata/ofx1_sample.ofx")
+	assert.NoError(t, err)
+
+	_, err = NewService().ParseStatementsWithOptions(strings.NewReader(string(data)), ParseOptions{Lenient: true})
+
+	assert.ErrorIs(t, err, ErrLenientUnsupported)
+	assert.ErrorContains(t, err, "ofx files")
+}
+
+func TestRowReport_Strict(t *testing.T) {
+	var report *rowReport
+
+	err := report.reject(3, "a", inColumn("Date", errors.New("bad date")))
+	assert.EqualError(t, err, "row 3: bad date")
+
+	err = report.readError(4, "", errors.New("connection reset"))
+	assert.EqualError(t, err, "reading data row: connection reset")
+	assert.Nil(t, report.rows())
+}
+
+func TestRowReport_LenientFailsOnReadErrors(t *testing.T) {
+	report := &rowReport{}
+
+	err := report.readError(4, "", errors.New("connection reset"))
+
+	assert.EqualError(t, err, "reading data row: connection reset")
+	assert.Empty(t, report.rows())
+}
+
+func TestRowReader_Raw(t *testing.T) {
+	text := "a;\"b;c\";\"say \"\"hi\"\"\"\r\n" +
+		"\"two\nlines\";d\n" +
+		"e;\"BROKEN \"QUOTE\";f\n" +
+		"g;h"
+	reader := newRowReader(strings.NewReader(text), &rowReport{})
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+
+	row, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b;c", `say "hi"`}, row)
+	assert.Equal(t, `a;"b;c";"say ""hi"""`, reader.Raw())
+
+	_, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "\"two\nlines\";d", reader.Raw())
+
+	_, err = reader.Read()
+	var parseErr *csv.ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, `e;"BROKEN "QUOTE";f`, reader.Raw())
+
+	row, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"g", "h"}, row)
+	assert.Equal(t, "g;h", reader.Raw())
+}
+
+func TestRowReader_StrictKeepsNoText(t *testing.T) {
+	reader := newRowReader(strings.NewReader("a,b\n"), nil)
+
+	_, err := reader.Read()
+
+	assert.NoError(t, err)
+	assert.Empty(t, reader.Raw())
+}
+
+func countTransactions(statements []Statement) int {
+	var n int
+	for _, statement := range statements {
+		n += len(statement.Transactions)
+	}
+	return n
+}
@@ -0,0 +1,53 @@
+package csvparser
+
+import (
+	"github.com/Rhymond/go-money"
+
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// SignConvention describes how a statement format signs its amounts.
+type SignConvention int
+
+const (
+	// OutflowNegative is the convention used throughout the application:
+	// money leaving the account is negative.
+	OutflowNegative SignConvention = iota
+	// OutflowPositive is used by card statements that show purchases as
+	// positive balances owed and payments as negative.
+	OutflowPositive
+)
+
+// normaliseSigns rewrites amounts and balances in place so they follow
+// OutflowNegative. A card balance owed becomes negative along with the
+// purchases that built it up.
+func normaliseSigns(statement *Statement, convention SignConvention) {
+	if convention != OutflowPositive {
+		return
+	}
+	for i := range statement.Transactions {
+		normaliseSign(&statement.Transactions[i], convention)
+	}
+	statement.ClosingBalance = negate(statement.ClosingBalance)
+	statement.AvailableBalance = negate(statement.AvailableBalance)
+	statement.OpeningBalance = negate(statement.OpeningBalance)
+}
+
+// normaliseSign is normaliseSigns for a single transaction, for a
+// statement read a row at a time.
+func normaliseSign(tx *transaction.Transaction, convention SignConvention) {
+	if convention != OutflowPositive {
+		return
+	}
+	tx.Amount = negate(tx.Amount)
+	tx.RunningBalance = negate(tx.RunningBalance)
+}
+
+// negate flips the sign of m. money.Money.Negative returns -|amount|,
+// which is not a sign flip.
+func negate(m *money.Money) *money.Money {
+	if m == nil {
+		return nil
+	}
+	return money.New(-m.Amount(), m.Currency().Code)
+}
@@ -0,0 +1,431 @@
+package csvparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSpreadsheet = errors.New("cannot read spreadsheet")
+
+// ZipMagic starts every ZIP archive, including XLSX files, which are a zip
+// of XML parts.
+const ZipMagic = "PK\x03\x04"
+
+// maxSpreadsheetPartSize caps how much of one XML part is decompressed, so
+// a small upload cannot expand without limit.
+const maxSpreadsheetPartSize = 64 << 20
+
+// spreadsheetDetectRows is how many rows of a sheet are offered to Detect.
+const spreadsheetDetectRows = 60
+
+// cellFormat is how a parser expects dates and numbers written, so that
+// spreadsheet cells can be turned into the text it reads.
+type cellFormat struct {
+	delimiter rune
+	// dateLayout writes cells Excel stores as dates.
+	dateLayout string
+	// decimal is the decimal separator for numeric cells.
+	decimal string
+}
+
+// spreadsheetParser is implemented by parsers of delimited text, which can
+// then also read a sheet of an XLSX workbook.
+type spreadsheetParser interface {
+	cellFormat() cellFormat
+}
+
+// workbook is the sheets of an XLSX file in workbook order.
+type workbook struct {
+	sheets []sheet
+}
+
+type sheet struct {
+	name string
+	rows [][]cell
+	// date1904 is set for workbooks counting dates from 1904, as old
+	// Excel for Mac did.
+	date1904 bool
+}
+
+// cell is a cell's stored value. Numbers are kept as Excel wrote them, and
+// date is set for numbers styled as dates, which Excel stores as serial
+// day counts.
+type cell struct {
+	value   string
+	numeric bool
+	date    bool
+}
+
+func isSpreadsheet(sample []byte) bool {
+	return bytes.HasPrefix(sample, []byte(ZipMagic))
+}
+
+// readWorkbook reads the cell values of every sheet in an XLSX file.
+// Formulas are read as the value Excel last calculated for them.
+func readWorkbook(data []byte) (*workbook, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSpreadsheet, err)
+	}
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		parts[strings.TrimPrefix(file.Name, "/")] = file
+	}
+
+	var book xlsxWorkbook
+	if err := readXMLPart(parts, "xl/workbook.xml", &book); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readXMLPart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	shared, err := readSharedStrings(parts)
+	if err != nil {
+		return nil, err
+	}
+	dateStyles, err := readDateStyles(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	wb := &workbook{}
+	for _, s := range book.Sheets {
+		var data xlsxSheetData
+		if err := readXMLPart(parts, targets[s.RelID], &data); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", s.Name, err)
+		}
+		rows, err := sheetRows(data, shared, dateStyles)
+		if err != nil {
+			return nil, fmt.Errorf("%w: sheet %q: %v", ErrSpreadsheet, s.Name, err)
+		}
+		wb.sheets = append(wb.sheets, sheet{name: s.Name, rows: rows, date1904: book.Properties.Date1904})
+	}
+	if len(wb.sheets) == 0 {
+		return nil, fmt.Errorf("%w: workbook has no sheets", ErrSpreadsheet)
+	}
+	return wb, nil
+}
+
+// sheet picks a sheet by name, ignoring case, or by its 1-based position.
+// An empty choice picks the first sheet.
+func (wb *workbook) sheet(choice string) (sheet, error) {
+	if choice == "" {
+		return wb.sheets[0], nil
+	}
+	for _, s := range wb.sheets {
+		if strings.EqualFold(s.name, strings.TrimSpace(choice)) {
+			return s, nil
+		}
+	}
+	if n, err := strconv.Atoi(choice); err == nil && n >= 1 && n <= len(wb.sheets) {
+		return wb.sheets[n-1], nil
+	}
+
+	names := make([]string, 0, len(wb.sheets))
+	for _, s := range wb.sheets {
+		names = append(names, s.name)
+	}
+	return sheet{}, fmt.Errorf("%w: no sheet %q; the workbook has %s", ErrSpreadsheet, choice, strings.Join(names, ", "))
+}
+
+// csv writes up to limit rows of the sheet as delimited text in format,
+// or every row when limit is zero.
+func (s sheet) csv(format cellFormat, limit int) []byte {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = format.delimiter
+	for i, row := range s.rows {
+		if limit > 0 && i == limit {
+			break
+		}
+		record := make([]string, len(row))
+		for j, c := range row {
+			record[j] = c.text(format, s.date1904)
+		}
+		// Writing to a buffer cannot fail.
+		_ = writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes()
+}
+
+// text writes a cell the way a bank's CSV export would. Numbers are
+// rounded to the 15 significant digits Excel shows, so a sum stored as
+// 0.30000000000000004 reads as 0.3.
+func (c cell) text(format cellFormat, date1904 bool) string {
+	if !c.numeric {
+		return c.value
+	}
+	f, err := strconv.ParseFloat(c.value, 64)
+	if err != nil {
+		return c.value
+	}
+	if c.date {
+		return excelDate(f, date1904).Format(format.dateLayout)
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	text := strconv.FormatFloat(rounded, 'f', -1, 64)
+	if format.decimal != "" && format.decimal != "." {
+		text = strings.Replace(text, ".", format.decimal, 1)
+	}
+	return text
+}
+
+// excelDate converts a serial day count to a time. The 1900 system counts
+// from 30 December 1899, which absorbs Lotus 1-2-3's phantom 29 February
+// 1900 for every date after it; the 1904 system counts from 1 January 1904.
+func excelDate(serial float64, date1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+func sheetRows(data xlsxSheetData, shared []string, dateStyles map[int]bool) ([][]cell, error) {
+	var rows [][]cell
+	for _, row := range data.Rows {
+		// Rows and cells may be left out when empty, so positions come
+		// from their references where they have them.
+		rowNum := len(rows) + 1
+		if row.Num > 0 {
+			rowNum = row.Num
+		}
+		for len(rows) < rowNum-1 {
+			rows = append(rows, nil)
+		}
+
+		var cells []cell
+		for _, c := range row.Cells {
+			col := len(cells)
+			if c.Ref != "" {
+				var err error
+				col, err = cellColumn(c.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+			for len(cells) < col {
+				cells = append(cells, cell{})
+			}
+
+			value, err := c.value(shared)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", c.Ref, err)
+			}
+			numeric := (c.Type == "" || c.Type == "n") && value != ""
+			cells = append(cells, cell{value: value, numeric: numeric, date: numeric && dateStyles[c.Style]})
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// cellColumn returns the 0-based column of a reference such as "AB12".
+func cellColumn(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || col > 16384 {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+func readXMLPart(parts map[string]*zip.File, name string, v any) error {
+	file, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrSpreadsheet, name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrSpreadsheet, name, err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxSpreadsheetPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrSpreadsheet, name, err)
+	}
+	return nil
+}
+
+// readSharedStrings reads the workbook's string table, joining the runs of
+// rich text. Workbooks without text cells may have no table.
+func readSharedStrings(parts map[string]*zip.File) ([]string, error) {
+	if _, ok := parts["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+	var table xlsxSharedStrings
+	if err := readXMLPart(parts, "xl/sharedStrings.xml", &table); err != nil {
+		return nil, err
+	}
+	shared := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		shared[i] = item.text()
+	}
+	return shared, nil
+}
+
+// readDateStyles returns the cell styles whose number format shows a date.
+func readDateStyles(parts map[string]*zip.File) (map[int]bool, error) {
+	dateStyles := make(map[int]bool)
+	if _, ok := parts["xl/styles.xml"]; !ok {
+		return dateStyles, nil
+	}
+	var styles xlsxStyles
+	if err := readXMLPart(parts, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+
+	customDates := make(map[int]bool)
+	for _, format := range styles.NumFmts {
+		customDates[format.ID] = isDateFormatCode(format.Code)
+	}
+	for i, xf := range styles.CellXfs {
+		if isBuiltInDateFormat(xf.NumFmtID) || customDates[xf.NumFmtID] {
+			dateStyles[i] = true
+		}
+	}
+	return dateStyles, nil
+}
+
+// isBuiltInDateFormat reports whether one of Excel's built-in number
+// formats shows a date, including the East Asian locale formats.
+func isBuiltInDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// isDateFormatCode reports whether a custom number format shows a day,
+// month or year. Quoted text, escaped characters and [bracketed] colours
+// and locales are skipped, so "0.00 \"days\"" is not a date.
+func isDateFormatCode(code string) bool {
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			if j := strings.IndexByte(code[i+1:], '"'); j != -1 {
+				i += j + 1
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			if j := strings.IndexByte(code[i:], ']'); j != -1 {
+				i += j
+			}
+		case 'd', 'D', 'm', 'M', 'y', 'Y':
+			return true
+		}
+	}
+	return false
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText is a string that is either plain or made of formatted runs.
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) text() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	b.WriteString(t.Text)
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheetData struct {
+	Rows []struct {
+		Num   int        `xml:"r,attr"`
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxCell struct {
+	Ref    string       `xml:"r,attr"`
+	Type   string       `xml:"t,attr"`
+	Style  int          `xml:"s,attr"`
+	Value  string       `xml:"v"`
+	Inline xlsxRichText `xml:"is"`
+}
+
+// value resolves shared and inline strings, booleans and errors to text.
+func (c xlsxCell) value(shared []string) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(shared) {
+			return "", fmt.Errorf("bad shared string index %q", c.Value)
+		}
+		return shared[i], nil
+	case "inlineStr":
+		return c.Inline.text(), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	return c.Value, nil
+}
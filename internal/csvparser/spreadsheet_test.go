@@ -0,0 +1,177 @@
+package csvparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSheet struct {
+	name string
+	// rows is the XML inside <sheetData>.
+	rows string
+}
+
+// buildXLSX writes a minimal workbook. Style 1 shows dates with built-in
+// format 14, style 2 with a custom "dd mmm yyyy" format and style 3 shows
+// numbers as "0.00 \"GBP\"".
+func buildXLSX(t *testing.T, date1904 bool, shared []string, sheets ...testSheet) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	write := func(name, content string) {
+		w, err := archive.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	var sheetList, rels strings.Builder
+	for i, s := range sheets {
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, s.name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1),
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+s.rows+`</sheetData></worksheet>`)
+	}
+	write("xl/workbook.xml", fmt.Sprintf(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+		xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+		<workbookPr date1904="%t"/><sheets>%s</sheets></workbook>`, date1904, sheetList.String()))
+	write("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+	write("xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+		<numFmts count="2"><numFmt numFmtId="164" formatCode="dd mmm yyyy"/><numFmt numFmtId="165" formatCode="0.00 &quot;GBP&quot;"/></numFmts>
+		<cellXfs count="4"><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`)
+
+	var sst strings.Builder
+	for _, s := range shared {
+		fmt.Fprintf(&sst, "<si><t>%s</t></si>", s)
+	}
+	write("xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+sst.String()+`</sst>`)
+
+	assert.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+// amexWorkbook has a cover sheet and then an Amex statement, with dates
+// stored as serials and amounts as numbers.
+func amexWorkbook(t *testing.T) []byte {
+	shared := []string{"Date", "Description", "Amount", "Card Member", "Reference", "TEST RESTAURANT", "MR TEST", "'AT123456789'", "PAYMENT RECEIVED", "Summary"}
+	return buildXLSX(t, false, shared,
+		testSheet{name: "Summary", rows: `<row r="1"><c r="A1" t="s"><v>9</v></c></row>`},
+		testSheet{name: "Transactions", rows: `
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>4</v></c></row>
+			<row r="2"><c r="A2" s="1"><v>46037</v></c><c r="B2" t="s"><v>5</v></c><c r="C2" s="3"><v>25.5</v></c><c r="D2" t="s"><v>6</v></c><c r="E2" t="s"><v>7</v></c></row>
+			<row r="4"><c r="A4" s="2"><v>46036.75</v></c><c r="B4" t="s"><v>8</v></c><c r="C4"><v>-100.00000000000001</v></c><c r="D4" t="s"><v>6</v></c></row>`},
+	)
+}
+
+func TestService_ParseStatementsWithOptions_Spreadsheet(t *testing.T) {
+	data := amexWorkbook(t)
+
+	result, err := NewService().ParseStatementsWithOptions(bytes.NewReader(data), ParseOptions{Sheet: "transactions"})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Statements, 1)
+	statement := result.Statements[0]
+	assert.Equal(t, "amex", statement.Format)
+	// The empty third row is skipped as a blank line.
+	assert.Len(t, statement.Transactions, 2)
+
+	restaurant := statement.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), restaurant.Date)
+	assert.Equal(t, "TEST RESTAURANT", restaurant.Description)
+	// Amex signs purchases positive, and the service normalises them.
+	assert.Equal(t, int64(-2550), restaurant.Amount.Amount())
+	assert.Equal(t, "AT123456789", *restaurant.ExternalID)
+
+	payment := statement.Transactions[1]
+	assert.Equal(t, time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), payment.Date)
+	assert.Equal(t, int64(10000), payment.Amount.Amount())
+}
+
+func TestService_ParseStatementsWithOptions_SpreadsheetBySheetNumber(t *testing.T) {
+	data := amexWorkbook(t)
+
+	result, err := NewService().ParseStatementsWithOptions(bytes.NewReader(data), ParseOptions{BankType: "amex", Sheet: "2"})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Statements[0].Transactions, 2)
+}
+
+func TestService_ParseStatementsWithOptions_SpreadsheetErrors(t *testing.T) {
+	data := amexWorkbook(t)
+	tests := []struct {
+		name    string
+		data    []byte
+		options ParseOptions
+		errMsg  string
+	}{
+		{"unknown sheet", data, ParseOptions{Sheet: "Accounts"}, `no sheet "Accounts"; the workbook has Summary, Transactions`},
+		{"undetectable sheet", data, ParseOptions{}, "could not detect"},
+		{"format without spreadsheets", data, ParseOptions{BankType: "ofx", Sheet: "2"}, "ofx files are not spreadsheets"},
+		{"not a workbook", []byte("PK\x03\x04garbage"), ParseOptions{}, "cannot read spreadsheet"},
+		{"sheet for a CSV", []byte("Date,Description,Amount\n"), ParseOptions{BankType: "amex", Sheet: "1"}, "only be chosen for an XLSX upload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewService().ParseStatementsWithOptions(bytes.NewReader(tt.data), tt.options)
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestReadWorkbook_CellTypes(t *testing.T) {
+	data := buildXLSX(t, true, []string{"shared"}, testSheet{name: "Sheet1", rows: `
+		<row><c t="s"><v>0</v></c><c t="inlineStr"><is><r><t>rich </t></r><r><t>text</t></r></is></c><c t="b"><v>1</v></c><c t="str"><v>formula</v></c></row>
+		<row><c r="C2" s="1"><v>0</v></c><c r="AA2"><v>1.5E3</v></c></row>`})
+
+	book, err := readWorkbook(data)
+
+	assert.NoError(t, err)
+	sheet := book.sheets[0]
+	assert.Equal(t, "shared,rich text,TRUE,formula\n", string(sheet.csv(cellFormat{delimiter: ',', dateLayout: "2006-01-02"}, 1)))
+
+	row := strings.Split(strings.TrimSpace(string(sheet.csv(cellFormat{delimiter: ';', dateLayout: "2006-01-02", decimal: ","}, 0))), "\n")[1]
+	// The 1904 date system counts from 1 January 1904.
+	assert.Equal(t, ";;1904-01-01"+strings.Repeat(";", 24)+"1500", row)
+}
+
+func TestCellText_Numbers(t *testing.T) {
+	format := cellFormat{delimiter: ',', dateLayout: "02/01/2006", decimal: ","}
+
+	assert.Equal(t, "0,3", cell{value: "0.30000000000000004", numeric: true}.text(format, false))
+	assert.Equal(t, "-1234,56", cell{value: "-1234.56", numeric: true}.text(format, false))
+	assert.Equal(t, "15/01/2026", cell{value: "46037.99", numeric: true, date: true}.text(format, false))
+	assert.Equal(t, "not a number", cell{value: "not a number", numeric: true}.text(format, false))
+}
+
+func TestExcelDate(t *testing.T) {
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), excelDate(46037, false))
+	assert.Equal(t, time.Date(2026, 1, 15, 18, 0, 0, 0, time.UTC), excelDate(46037.75, false))
+	assert.Equal(t, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), excelDate(61, false))
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), excelDate(44575, true))
+}
+
+func TestIsDateFormatCode(t *testing.T) {
+	assert.True(t, isDateFormatCode("dd/mm/yyyy"))
+	assert.True(t, isDateFormatCode("[$-809]d mmmm yyyy;@"))
+	assert.False(t, isDateFormatCode(`0.00 "days"`))
+	assert.False(t, isDateFormatCode(`[Red]#,##0.00`))
+	assert.False(t, isDateFormatCode(`#,##0.00\ \d`))
+}
+
+func TestCellColumn(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA2": 26, "XFD1": 16383} {
+		got, err := cellColumn(ref)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, ref)
+	}
+	_, err := cellColumn("12")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package csvparser
+
+import (
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// ErrNotStreamable is returned by StreamStatement for a file that is not
+// read a row at a time, such as a spreadsheet or an OFX file, which is to
+// be parsed whole instead.
+var ErrNotStreamable = errors.New("file cannot be streamed")
+
+// StatementStream is a statement whose transactions are read as they are
+// ranged over, so a large export need not be held in memory. Statement
+// holds everything else the file says, and no Transactions; its
+// BalanceDate is only final once every transaction has been read.
+type StatementStream struct {
+	Statement Statement
+	rows      iter.Seq2[transaction.Transaction, error]
+	report    *rowReport
+}
+
+// Transactions yields the statement's transactions in file order, with
+// outflows negative. A row that fails the stream is yielded as an error,
+// which ends it. The transactions can only be ranged over once.
+func (s *StatementStream) Transactions() iter.Seq2[transaction.Transaction, error] {
+	return s.rows
+}
+
+// Rejected returns the rows a lenient stream has left out so far, which is
+// all of them once the transactions have been read.
+func (s *StatementStream) Rejected() []RowError {
+	return s.report.rows()
+}
+
+// streamingParser is implemented by parsers that can hand a statement's
+// rows back as they read them.
+type streamingParser interface {
+	readStatement(r io.Reader, report *rowReport, statement *Statement) (iter.Seq2[transaction.Transaction, error], error)
+}
+
+func (s *service) StreamStatement(r io.Reader, options ParseOptions) (*StatementStream, error) {
+	stream := &StatementStream{}
+	if options.Lenient {
+		stream.report = &rowReport{}
+	}
+
+	buffered, sample, err := peekSample(r)
+	if err != nil {
+		return nil, err
+	}
+	if isSpreadsheet(sample) {
+		// The workbook is read whole to find its sheets anyway.
+		return nil, ErrNotStreamable
+	}
+
+	parser, bankType, text, err := s.openText(buffered, sample, options)
+	if err != nil {
+		return nil, err
+	}
+	sp, ok := parser.(streamingParser)
+	if !ok {
+		return nil, ErrNotStreamable
+	}
+
+	rows, err := sp.readStatement(text, stream.report, &stream.Statement)
+	if err != nil {
+		return nil, err
+	}
+	finishStatement(&stream.Statement, parser, bankType)
+	convention := parser.SignConvention()
+	stream.rows = func(yield func(transaction.Transaction, error) bool) {
+		for tx, err := range rows {
+			if err == nil {
+				normaliseSign(&tx, convention)
+			}
+			if !yield(tx, err) {
+				return
+			}
+		}
+	}
+	return stream, nil
+}
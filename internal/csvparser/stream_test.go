@@ -0,0 +1,171 @@
+package csvparser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// collect ranges over a stream's transactions, stopping at the first error.
+func collect(stream *StatementStream) ([]transaction.Transaction, error) {
+	var transactions []transaction.Transaction
+	for tx, err := range stream.Transactions() {
+		if err != nil {
+			return transactions, err
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, nil
+}
+
+func TestService_StreamStatement_MatchesParseStatement(t *testing.T) {
+	for _, name := range []string{"amex_sample.csv", "nationwide_sample.csv", "monzo_sample.csv"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			assert.NoError(t, err)
+			service := NewService()
+			want, err := service.ParseStatement(bytes.NewReader(data), "")
+			assert.NoError(t, err)
+
+			stream, err := service.StreamStatement(bytes.NewReader(data), ParseOptions{})
+			assert.NoError(t, err)
+			transactions, err := collect(stream)
+
+			assert.NoError(t, err)
+			assert.Equal(t, want.Transactions, transactions)
+			want.Transactions = nil
+			assert.Equal(t, want, stream.Statement)
+		})
+	}
+}
+
+func TestService_StreamStatement_NotStreamable(t *testing.T) {
+	ofx, err := os.ReadFile("testdata/ofx1_sample.ofx")
+	assert.NoError(t, err)
+
+	for name, data := range map[string][]byte{"ofx": ofx, "xlsx": amexWorkbook(t)} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewService().StreamStatement(bytes.NewReader(data), ParseOptions{})
+
+			assert.ErrorIs(t, err, ErrNotStreamable)
+		})
+	}
+}
+
+func TestService_StreamStatement_NormalisesSigns(t *testing.T) {
+	file, err := os.Open("testdata/amex_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	stream, err := NewService().StreamStatement(file, ParseOptions{BankType: "amex"})
+	assert.NoError(t, err)
+	transactions, err := collect(stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-2550), transactions[0].Amount.Amount())
+	assert.Equal(t, int64(10000), transactions[1].Amount.Amount())
+}
+
+func TestService_StreamStatement_Lenient(t *testing.T) {
+	stream, err := NewService().StreamStatement(strings.NewReader(badAmexCSV), ParseOptions{BankType: "amex", Lenient: true})
+	assert.NoError(t, err)
+	assert.Empty(t, stream.Rejected())
+
+	transactions, err := collect(stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TEST RESTAURANT", "TEST, SUPERMARKET"}, descriptions(transactions))
+	assert.Len(t, stream.Rejected(), 4)
+}
+
+func TestService_StreamStatement_StrictEndsOnFirstBadRow(t *testing.T) {
+	stream, err := NewService().StreamStatement(strings.NewReader(badAmexCSV), ParseOptions{BankType: "amex"})
+	assert.NoError(t, err)
+
+	transactions, err := collect(stream)
+
+	assert.ErrorContains(t, err, "row 2: parsing date '32/01/2026'")
+	assert.Equal(t, []string{"TEST RESTAURANT"}, descriptions(transactions))
+}
+
+func TestService_StreamStatement_HeaderErrors(t *testing.T) {
+	_, err := NewService().StreamStatement(strings.NewReader("Date,Amount\n15/01/2026,1.00\n"), ParseOptions{BankType: "amex"})
+
+	assert.ErrorContains(t, err, "required column not found")
+}
+
+// largeAmexCSV is an Amex export of n rows.
+func largeAmexCSV(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("Date,Description,Amount,Reference,Category\n")
+	for i := range n {
+		fmt.Fprintf(&b, "%02d/01/2026,TEST MERCHANT %d LONDON,%d.%02d,'AT%09d',Shopping-Groceries\n", i%28+1, i%500, i%200, i%100, i)
+	}
+	return b.Bytes()
+}
+
+// liveHeap returns the bytes in use on the heap once garbage is collected.
+func liveHeap() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// The benchmarks report live-B/op, the heap still in use once the whole
+// file has been read, which is what an import holds on to while it writes.
+// It grows with the file for ParseStatement and stays flat when streaming.
+
+func BenchmarkService_ParseStatement_100k(b *testing.B) {
+	data := largeAmexCSV(100_000)
+	service := NewService()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	var live uint64
+	for b.Loop() {
+		before := liveHeap()
+		statement, err := service.ParseStatement(bytes.NewReader(data), "amex")
+		if err != nil {
+			b.Fatal(err)
+		}
+		live += liveHeap() - before
+		runtime.KeepAlive(statement)
+	}
+	b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+}
+
+func BenchmarkService_StreamStatement_100k(b *testing.B) {
+	const rows = 100_000
+	data := largeAmexCSV(rows)
+	service := NewService()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	var live uint64
+	for b.Loop() {
+		before := liveHeap()
+		stream, err := service.StreamStatement(bytes.NewReader(data), ParseOptions{BankType: "amex"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		read := 0
+		for _, err := range stream.Transactions() {
+			if err != nil {
+				b.Fatal(err)
+			}
+			if read++; read == rows {
+				live += liveHeap() - before
+			}
+		}
+		runtime.KeepAlive(stream)
+	}
+	b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+}
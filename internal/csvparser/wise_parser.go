@@ -0,0 +1,333 @@
+package csvparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+const wiseInstitution = "Wise"
+
+// wiseDateFormats are the layouts Wise has used for dates; a time of day
+// after the date is ignored.
+var wiseDateFormats = []string{"02-01-2006", "2006-01-02"}
+
+// WiseParser reads Wise (formerly TransferWise) balance statements. An
+// export can cover several currency balances, and each becomes its own
+// statement. Rows keep Wise's TransferWise ID as their external ID.
+//
+// A conversion between balances appears once in each balance under the
+// same ID. Both legs are kept, each noting the rate used and carrying the
+// other leg's amount as its local amount. Fees, which Wise includes in the
+// amount, become transactions of their own linked to the row they were
+// charged on.
+type WiseParser struct{}
+
+type wiseColumns struct {
+	id, date, amount, currency, description  int
+	balance, note, fees                      int
+	exchangeFrom, exchangeTo, rate, toAmount int
+	merchant, payee, payer                   int
+}
+
+type wiseRow struct {
+	id                    string
+	date                  time.Time
+	amount, fee, balance  *money.Money
+	currency, description string
+	note                  string
+	exchangeFrom          string
+	exchangeTo, rate      string
+	toAmount              *money.Money
+}
+
+func (p *WiseParser) Parse(r io.Reader) ([]transaction.Transaction, error) {
+	return parseSingleStatement(p, r)
+}
+
+// ParseStatements returns a statement for each currency balance, in the
+// order the currencies first appear, with transactions oldest first.
+func (p *WiseParser) ParseStatements(r io.Reader) ([]Statement, error) {
+	return p.parseRows(r, nil)
+}
+
+func (p *WiseParser) parseRows(r io.Reader, report *rowReport) ([]Statement, error) {
+	rows, err := readWiseRows(r, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// The amount leaving the source balance of each conversion, before
+	// fees, so the other leg can carry it.
+	sent := make(map[string]*money.Money)
+	for _, row := range rows {
+		if row.isConversion() && row.currency == row.exchangeFrom {
+			sent[row.id] = row.amountBeforeFee()
+		}
+	}
+
+	var statements []*Statement
+	byCurrency := make(map[string]*Statement)
+	for _, row := range rows {
+		statement := byCurrency[row.currency]
+		if statement == nil {
+			statement = &Statement{
+				Institution: wiseInstitution,
+				Account:     AccountDetails{Name: "Wise " + row.currency},
+			}
+			byCurrency[row.currency] = statement
+			statements = append(statements, statement)
+		}
+
+		statement.Transactions = append(statement.Transactions, row.transactions(sent[row.id])...)
+		if row.balance != nil {
+			statement.ClosingBalance = row.balance
+			statement.BalanceDate = row.date
+		}
+	}
+
+	if len(statements) == 0 {
+		return []Statement{{Institution: wiseInstitution}}, nil
+	}
+	result := make([]Statement, 0, len(statements))
+	for _, statement := range statements {
+		result = append(result, *statement)
+	}
+	return result, nil
+}
+
+// readWiseRows reads every row, oldest first. Wise lists the newest first,
+// but the order is worked out from the dates rather than assumed.
+func readWiseRows(r io.Reader, report *rowReport) ([]wiseRow, error) {
+	reader := newRowReader(r, report)
+	reader.FieldsPerRecord = -1
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+	cols, err := wiseHeader(headers)
+	if err != nil {
+		return nil, err
+	}
+	required := max(cols.id, cols.date, cols.amount, cols.currency, cols.description)
+
+	var rows []wiseRow
+	for rowNum := 1; ; rowNum++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := report.readError(rowNum, reader.Raw(), err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(record) <= required {
+			if err := report.reject(rowNum, reader.Raw(), shortRowError(record, required)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		row, err := readWiseRow(record, cols)
+		if err != nil {
+			if err := report.reject(rowNum, reader.Raw(), err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) > 1 && rows[0].date.After(rows[len(rows)-1].date) {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows, nil
+}
+
+func wiseHeader(headers []string) (wiseColumns, error) {
+	cols := wiseColumns{
+		id:           findColumnIndex(headers, "TransferWise ID"),
+		date:         findColumnIndex(headers, "Date"),
+		amount:       findColumnIndex(headers, "Amount"),
+		currency:     findColumnIndex(headers, "Currency"),
+		description:  findColumnIndex(headers, "Description"),
+		balance:      findColumnIndex(headers, "Running Balance"),
+		note:         findColumnIndex(headers, "Note"),
+		fees:         findColumnIndex(headers, "Total fees"),
+		exchangeFrom: findColumnIndex(headers, "Exchange From"),
+		exchangeTo:   findColumnIndex(headers, "Exchange To"),
+		rate:         findColumnIndex(headers, "Exchange Rate"),
+		toAmount:     findColumnIndex(headers, "Exchange To Amount"),
+		merchant:     findColumnIndex(headers, "Merchant"),
+		payee:        findColumnIndex(headers, "Payee Name"),
+		payer:        findColumnIndex(headers, "Payer Name"),
+	}
+	for _, idx := range []int{cols.id, cols.date, cols.amount, cols.currency, cols.description} {
+		if idx == -1 {
+			return wiseColumns{}, fmt.Errorf("required column not found in CSV headers")
+		}
+	}
+	return cols, nil
+}
+
+func readWiseRow(record []string, cols wiseColumns) (wiseRow, error) {
+	row := wiseRow{
+		id:           field(record, cols.id),
+		currency:     strings.ToUpper(field(record, cols.currency)),
+		description:  firstNonEmpty(field(record, cols.description), field(record, cols.merchant), field(record, cols.payee), field(record, cols.payer)),
+		note:         field(record, cols.note),
+		exchangeFrom: strings.ToUpper(field(record, cols.exchangeFrom)),
+		exchangeTo:   strings.ToUpper(field(record, cols.exchangeTo)),
+		rate:         field(record, cols.rate),
+	}
+
+	value := field(record, cols.date)
+	date, err := parseWiseDate(value)
+	if err != nil {
+		return wiseRow{}, inColumn("Date", fmt.Errorf("parsing date '%s': %w", value, err))
+	}
+	row.date = date
+
+	amounts := []struct {
+		column   string
+		value    string
+		currency string
+		dest     **money.Money
+	}{
+		{"Amount", record[cols.amount], row.currency, &row.amount},
+		{"Total fees", field(record, cols.fees), row.currency, &row.fee},
+		{"Running Balance", field(record, cols.balance), row.currency, &row.balance},
+		{"Exchange To Amount", field(record, cols.toAmount), row.exchangeTo, &row.toAmount},
+	}
+	for _, amount := range amounts {
+		if strings.TrimSpace(amount.value) == "" || amount.currency == "" {
+			continue
+		}
+		*amount.dest, err = parseAmountIn(amount.value, amount.currency)
+		if err != nil {
+			return wiseRow{}, inColumn(amount.column, fmt.Errorf("parsing %s '%s': %w", strings.ToLower(amount.column), amount.value, err))
+		}
+	}
+	if row.amount == nil {
+		return wiseRow{}, inColumn("Amount", fmt.Errorf("amount is empty"))
+	}
+	if row.description == "" {
+		return wiseRow{}, inColumn("Description", fmt.Errorf("row has no description"))
+	}
+	return row, nil
+}
+
+func parseWiseDate(value string) (time.Time, error) {
+	if i := strings.IndexByte(value, ' '); i != -1 {
+		value = value[:i]
+	}
+	var err error
+	for _, layout := range wiseDateFormats {
+		var date time.Time
+		date, err = time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func (row wiseRow) isConversion() bool {
+	return row.exchangeFrom != "" && row.exchangeTo != "" && row.exchangeFrom != row.exchangeTo
+}
+
+// amountBeforeFee is the row's amount without the fee Wise took from it.
+func (row wiseRow) amountBeforeFee() *money.Money {
+	if row.fee == nil || row.fee.IsZero() {
+		return row.amount
+	}
+	return money.New(row.amount.Amount()+row.fee.Amount(), row.currency)
+}
+
+// transactions turns a row into its transaction, followed by its fee when
+// it was charged one. sent is the source leg's amount when the row is the
+// receiving leg of a conversion found in the same file.
+func (row wiseRow) transactions(sent *money.Money) []transaction.Transaction {
+	var balanceBeforeFee *money.Money
+	if row.balance != nil {
+		balanceBeforeFee = money.New(row.balance.Amount()-row.amount.Amount()+row.amountBeforeFee().Amount(), row.currency)
+	}
+
+	tx := transaction.Transaction{
+		Date:           row.date,
+		Description:    row.description,
+		Amount:         row.amountBeforeFee(),
+		RunningBalance: balanceBeforeFee,
+		Bank:           wiseInstitution,
+	}
+	if row.id != "" {
+		id := row.id
+		tx.ExternalID = &id
+	}
+
+	var notes []string
+	if row.note != "" {
+		notes = append(notes, row.note)
+	}
+	if row.isConversion() {
+		if row.rate != "" {
+			notes = append(notes, fmt.Sprintf("1 %s = %s %s", row.exchangeFrom, row.rate, row.exchangeTo))
+		}
+		switch {
+		case row.currency == row.exchangeFrom && row.toAmount != nil:
+			tx.LocalAmount = withSignOf(row.toAmount, tx.Amount)
+		case row.currency == row.exchangeTo && sent != nil:
+			tx.LocalAmount = withSignOf(sent, tx.Amount)
+		}
+	}
+	if len(notes) > 0 {
+		joined := strings.Join(notes, "; ")
+		tx.Notes = &joined
+	}
+
+	if row.fee == nil || row.fee.IsZero() {
+		return []transaction.Transaction{tx}
+	}
+	fee := transaction.Transaction{
+		Date:           row.date,
+		Description:    truncateUTF8("Fee - "+row.description, maxDescriptionLength),
+		Amount:         money.New(-row.fee.Amount(), row.currency),
+		RunningBalance: row.balance,
+		Bank:           wiseInstitution,
+	}
+	if tx.ExternalID != nil {
+		feeRef := *tx.ExternalID + "/fee"
+		fee.ExternalID = &feeRef
+		fee.LinkedExternalID = tx.ExternalID
+	}
+	return []transaction.Transaction{tx, fee}
+}
+
+// withSignOf returns m with the sign of like.
+func withSignOf(m *money.Money, like *money.Money) *money.Money {
+	if m.IsNegative() != like.IsNegative() {
+		return negate(m)
+	}
+	return m
+}
+
+func (p *WiseParser) cellFormat() cellFormat {
+	return cellFormat{delimiter: ',', dateLayout: wiseDateFormats[0]}
+}
+
+func (p *WiseParser) SignConvention() SignConvention {
+	return OutflowNegative
+}
+
+func (p *WiseParser) Detect(sample []byte) bool {
+	rows := sampleRows(sample, 1, ',')
+	return len(rows) == 1 && hasColumns(rows[0], "TransferWise ID", "Amount", "Currency")
+}
@@ -0,0 +1,127 @@
+package csvparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWiseParser_ParseStatements(t *testing.T) {
+	file, err := os.Open("testdata/wise_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := (&WiseParser{}).ParseStatements(file)
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 2)
+
+	gbp := statements[0]
+	assert.Equal(t, "Wise", gbp.Institution)
+	assert.Equal(t, AccountDetails{Name: "Wise GBP"}, gbp.Account)
+	assert.Equal(t, int64(40000), gbp.ClosingBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), gbp.BalanceDate)
+	assert.Len(t, gbp.Transactions, 3)
+
+	salary := gbp.Transactions[0]
+	assert.Equal(t, time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), salary.Date)
+	assert.Equal(t, "Received money from Employer Ltd", salary.Description)
+	assert.Equal(t, int64(50000), salary.Amount.Amount())
+	assert.Equal(t, "TRANSFER-333", *salary.ExternalID)
+	assert.Equal(t, "January pay", *salary.Notes)
+	assert.Nil(t, salary.LocalAmount)
+
+	sent, fee := gbp.Transactions[1], gbp.Transactions[2]
+	assert.Equal(t, int64(-9950), sent.Amount.Amount())
+	assert.Equal(t, int64(-11542), sent.LocalAmount.Amount())
+	assert.Equal(t, "EUR", sent.LocalAmount.Currency().Code)
+	assert.Equal(t, "1 GBP = 1.16 EUR", *sent.Notes)
+	assert.Equal(t, int64(40050), sent.RunningBalance.Amount())
+	assert.Equal(t, "BALANCE-444", *sent.ExternalID)
+	assert.Equal(t, "Fee - Converted 99.50 GBP to 115.42 EUR", fee.Description)
+	assert.Equal(t, int64(-50), fee.Amount.Amount())
+	assert.Equal(t, int64(40000), fee.RunningBalance.Amount())
+	assert.Equal(t, "BALANCE-444/fee", *fee.ExternalID)
+	assert.Equal(t, "BALANCE-444", *fee.LinkedExternalID)
+
+	eur := statements[1]
+	assert.Equal(t, AccountDetails{Name: "Wise EUR"}, eur.Account)
+	assert.Equal(t, int64(10342), eur.ClosingBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC), eur.BalanceDate)
+	assert.Len(t, eur.Transactions, 2)
+
+	received := eur.Transactions[0]
+	assert.Equal(t, int64(11542), received.Amount.Amount())
+	assert.Equal(t, "EUR", received.Amount.Currency().Code)
+	assert.Equal(t, int64(9950), received.LocalAmount.Amount())
+	assert.Equal(t, "GBP", received.LocalAmount.Currency().Code)
+	assert.Equal(t, "1 GBP = 1.16 EUR", *received.Notes)
+	assert.Equal(t, "BALANCE-444", *received.ExternalID)
+
+	card := eur.Transactions[1]
+	assert.Equal(t, "Card transaction of 12.00 EUR issued by Cafe Paris", card.Description)
+	assert.Equal(t, int64(-1200), card.Amount.Amount())
+	assert.Equal(t, "CARD-555", *card.ExternalID)
+}
+
+func TestWiseParser_ParseStatements_OldestFirst(t *testing.T) {
+	csv := "TransferWise ID,Date,Amount,Currency,Description,Running Balance\n" +
+		"TRANSFER-1,2026-01-14,10.00,GBP,First,10.00\n" +
+		"TRANSFER-2,2026-01-15 09:30:00,-4.00,GBP,Second,6.00\n"
+
+	statements, err := (&WiseParser{}).ParseStatements(strings.NewReader(csv))
+
+	assert.NoError(t, err)
+	assert.Len(t, statements, 1)
+	statement := statements[0]
+	assert.Equal(t, "First", statement.Transactions[0].Description)
+	assert.Equal(t, int64(600), statement.ClosingBalance.Amount())
+	assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), statement.BalanceDate)
+}
+
+func TestWiseParser_ParseStatements_Errors(t *testing.T) {
+	header := "TransferWise ID,Date,Amount,Currency,Description,Total fees\n"
+	tests := []struct {
+		name   string
+		csv    string
+		errMsg string
+	}{
+		{"missing ID column", "Date,Amount,Currency,Description\n", "required column not found"},
+		{"short row", header + "TRANSFER-1,14-01-2026\n", "row 1: expected at least 5 columns, got 2"},
+		{"bad date", header + "TRANSFER-1,14/01/2026,1.00,GBP,Pay,0.00\n", "row 1: parsing date '14/01/2026'"},
+		{"bad fee", header + "TRANSFER-1,14-01-2026,1.00,GBP,Pay,abc\n", "parsing total fees 'abc'"},
+		{"unknown currency", header + "TRANSFER-1,14-01-2026,1.00,XYZ,Pay,0.00\n", "unknown currency"},
+		{"no description", header + "TRANSFER-1,14-01-2026,1.00,GBP,,0.00\n", "no description"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&WiseParser{}).ParseStatements(strings.NewReader(tt.csv))
+
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestWiseParser_Parse_MultipleStatements(t *testing.T) {
+	file, err := os.Open("testdata/wise_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = (&WiseParser{}).Parse(file)
+
+	assert.ErrorContains(t, err, "contains 2 statements")
+}
+
+func TestService_ParseStatements_DetectsWise(t *testing.T) {
+	file, err := os.Open("testdata/wise_sample.csv")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	statements, err := NewService().ParseStatements(file, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "wise", statements[0].Format)
+}
@@ -5,24 +5,26 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func Connect() (*pgx.Conn, error) {
+// Connect opens a connection pool, so that requests and background import
+// jobs can use the database at the same time.
+func Connect() (*pgxpool.Pool, error) {
 	dsn := os.Getenv("FINANCES_DATABASE_URL")
 	if dsn == "" {
 		return nil, fmt.Errorf("FINANCES_DATABASE_URL environment variable is not set")
 	}
 
-	conn, err := pgx.Connect(context.Background(), dsn)
+	pool, err := pgxpool.New(context.Background(), dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	if err := conn.Ping(context.Background()); err != nil {
-		conn.Close(context.Background())
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return conn, nil
+	return pool, nil
 }
@@ -8,14 +8,80 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Account struct {
+	ID             int32
+	Institution    string
+	Name           string
+	MaskedNumber   pgtype.Text
+	AccountType    string
+	Currency       string
+	OpeningBalance int64
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+}
+
+type ImportBatch struct {
+	ID                 int32
+	FileName           string
+	Bank               string
+	Checksum           string
+	RowCount           int32
+	InsertedCount      int32
+	SkippedCount       int32
+	Status             string
+	ErrorMessage       pgtype.Text
+	CreatedAt          pgtype.Timestamp
+	CompletedAt        pgtype.Timestamp
+	RolledBackAt       pgtype.Timestamp
+	AccountID          pgtype.Int4
+	ClosingBalance     pgtype.Int8
+	AvailableBalance   pgtype.Int8
+	BalanceDate        pgtype.Date
+	OpeningBalance     pgtype.Int8
+	OpeningBalanceDate pgtype.Date
+}
+
+type ImportJob struct {
+	ID             int32
+	FileName       string
+	Checksum       string
+	Status         string
+	StatementCount int32
+	StatementsDone int32
+	RowCount       int32
+	RowsDone       int32
+	InsertedCount  int32
+	SkippedCount   int32
+	ImportBatchIds []int32
+	RejectedRows   []byte
+	ErrorMessage   pgtype.Text
+	CreatedAt      pgtype.Timestamp
+	StartedAt      pgtype.Timestamp
+	FinishedAt     pgtype.Timestamp
+	ByteCount      int64
+	BytesDone      int64
+	Files          []byte
+}
+
 type Transaction struct {
-	ID          int32
-	Date        pgtype.Date
-	Description string
-	Amount      int64
-	Currency    string
-	Bank        string
-	Category    pgtype.Text
-	CreatedAt   pgtype.Timestamp
-	UpdatedAt   pgtype.Timestamp
+	ID                  int32
+	Date                pgtype.Date
+	Description         string
+	Amount              int64
+	Currency            string
+	Bank                string
+	Category            pgtype.Text
+	CreatedAt           pgtype.Timestamp
+	UpdatedAt           pgtype.Timestamp
+	ExternalID          pgtype.Text
+	Fingerprint         pgtype.Text
+	ImportBatchID       pgtype.Int4
+	AccountID           pgtype.Int4
+	RunningBalance      pgtype.Int8
+	Notes               pgtype.Text
+	Address             pgtype.Text
+	LocalAmount         pgtype.Int8
+	LocalCurrency       pgtype.Text
+	LinkedExternalID    pgtype.Text
+	LinkedTransactionID pgtype.Int4
 }
@@ -6,14 +6,51 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	CancelQueuedImportJob(ctx context.Context, id int32) (ImportJob, error)
+	CompleteImportBatch(ctx context.Context, arg CompleteImportBatchParams) (ImportBatch, error)
+	CopyTransactionsToStaging(ctx context.Context, rowSrc pgx.CopyFromSource) (int64, error)
+	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateImportBatch(ctx context.Context, arg CreateImportBatchParams) (ImportBatch, error)
+	CreateImportJob(ctx context.Context, arg CreateImportJobParams) (ImportJob, error)
 	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error)
-	CreateTransactionsBatch(ctx context.Context, arg []CreateTransactionsBatchParams) (int64, error)
-	DeleteTransaction(ctx context.Context, id int32) error
+	CreateTransactionStaging(ctx context.Context) error
+	CreateTransactionsSkipDuplicates(ctx context.Context, arg CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error)
+	DeleteAccount(ctx context.Context, id int32) (int64, error)
+	DeleteTransaction(ctx context.Context, id int32) (int64, error)
+	DeleteTransactionsByImportBatch(ctx context.Context, importBatchID pgtype.Int4) (int64, error)
+	FailInterruptedImportJobs(ctx context.Context, errorMessage pgtype.Text) (int64, error)
+	FinishImportJob(ctx context.Context, arg FinishImportJobParams) (ImportJob, error)
+	GetAccount(ctx context.Context, id int32) (Account, error)
+	GetImportBatchForUpdate(ctx context.Context, id int32) (ImportBatch, error)
+	GetImportJob(ctx context.Context, id int32) (ImportJob, error)
+	GetLatestImportBalance(ctx context.Context, accountID pgtype.Int4) (ImportBatch, error)
 	GetTransaction(ctx context.Context, id int32) (Transaction, error)
+	InsertStagedTransactions(ctx context.Context, importBatchID pgtype.Int4) (int64, error)
+	LinkTransactions(ctx context.Context, arg LinkTransactionsParams) error
+	ListAccountLedger(ctx context.Context, accountID pgtype.Int4) ([]ListAccountLedgerRow, error)
+	ListAccounts(ctx context.Context) ([]Account, error)
+	ListAccountsByInstitution(ctx context.Context, institution string) ([]Account, error)
+	ListFundingCandidates(ctx context.Context, arg ListFundingCandidatesParams) ([]ListFundingCandidatesRow, error)
+	ListImportBatches(ctx context.Context) ([]ImportBatch, error)
+	ListStoredFingerprints(ctx context.Context, fingerprints []string) ([]pgtype.Text, error)
 	ListTransactions(ctx context.Context) ([]Transaction, error)
+	ListTransactionsByAmountAsc(ctx context.Context, arg ListTransactionsByAmountAscParams) ([]Transaction, error)
+	ListTransactionsByAmountDesc(ctx context.Context, arg ListTransactionsByAmountDescParams) ([]Transaction, error)
+	ListTransactionsByDateAsc(ctx context.Context, arg ListTransactionsByDateAscParams) ([]Transaction, error)
+	ListTransactionsByDateDesc(ctx context.Context, arg ListTransactionsByDateDescParams) ([]Transaction, error)
+	ListTransactionsByDescriptionAsc(ctx context.Context, arg ListTransactionsByDescriptionAscParams) ([]Transaction, error)
+	ListTransactionsByDescriptionDesc(ctx context.Context, arg ListTransactionsByDescriptionDescParams) ([]Transaction, error)
+	ListUnreferencedFingerprints(ctx context.Context, fingerprints []string) ([]pgtype.Text, error)
+	MarkImportBatchRolledBack(ctx context.Context, id int32) (ImportBatch, error)
+	StartImportJob(ctx context.Context, id int32) (ImportJob, error)
+	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) error
 	UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) (Transaction, error)
 }
 
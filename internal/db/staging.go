@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TransactionStagingColumns are the columns of transaction_staging in the
+// order CopyTransactionsToStaging expects each row's values.
+var TransactionStagingColumns = []string{
+	"position", "date", "description", "amount", "currency", "bank", "category", "external_id",
+	"fingerprint", "account_id", "running_balance", "notes", "address", "local_amount",
+	"local_currency", "linked_external_id", "content_fingerprint",
+}
+
+// CopyTransactionsToStaging copies rows into the transaction_staging table
+// made by CreateTransactionStaging. It is written by hand because sqlc's
+// :copyfrom takes a slice, and the point of staging is that the rows come
+// from a source read as it is copied rather than from memory.
+func (q *Queries) CopyTransactionsToStaging(ctx context.Context, rowSrc pgx.CopyFromSource) (int64, error) {
+	return q.db.CopyFrom(ctx, pgx.Identifier{"transaction_staging"}, TransactionStagingColumns, rowSrc)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Store adds transactional execution to the generated Querier.
+type Store interface {
+	Querier
+	ExecTx(ctx context.Context, fn func(Querier) error) error
+}
+
+// InTx returns a Store that runs its queries with q, the Querier of a
+// transaction already under way. Its ExecTx joins that transaction rather
+// than starting another.
+func InTx(q Querier) Store {
+	return txStore{Querier: q}
+}
+
+type txStore struct {
+	Querier
+}
+
+func (s txStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	return fn(s.Querier)
+}
+
+// TxBeginner is satisfied by both *pgx.Conn and *pgxpool.Pool.
+type TxBeginner interface {
+	DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type SQLStore struct {
+	*Queries
+	conn TxBeginner
+}
+
+func NewStore(conn TxBeginner) Store {
+	return &SQLStore{
+		Queries: New(conn),
+		conn:    conn,
+	}
+}
+
+// ExecTx runs fn inside a database transaction, committing if fn succeeds
+// and rolling back otherwise.
+func (s *SQLStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(s.Queries.WithTx(tx)); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -13,19 +13,26 @@ import (
 
 const createTransaction = `-- name: CreateTransaction :one
 INSERT INTO transactions (
-    date, description, amount, currency, bank, category
+    date, description, amount, currency, bank, category, account_id,
+    notes, address, local_amount, local_currency, linked_external_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
-) RETURNING id, date, description, amount, currency, bank, category, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+) RETURNING id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id
 `
 
 type CreateTransactionParams struct {
-	Date        pgtype.Date
-	Description string
-	Amount      int64
-	Currency    string
-	Bank        string
-	Category    pgtype.Text
+	Date             pgtype.Date
+	Description      string
+	Amount           int64
+	Currency         string
+	Bank             string
+	Category         pgtype.Text
+	AccountID        pgtype.Int4
+	Notes            pgtype.Text
+	Address          pgtype.Text
+	LocalAmount      pgtype.Int8
+	LocalCurrency    pgtype.Text
+	LinkedExternalID pgtype.Text
 }
 
 func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error) {
@@ -36,6 +43,12 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		arg.Currency,
 		arg.Bank,
 		arg.Category,
+		arg.AccountID,
+		arg.Notes,
+		arg.Address,
+		arg.LocalAmount,
+		arg.LocalCurrency,
+		arg.LinkedExternalID,
 	)
 	var i Transaction
 	err := row.Scan(
@@ -48,31 +61,179 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		&i.Category,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.ImportBatchID,
+		&i.AccountID,
+		&i.RunningBalance,
+		&i.Notes,
+		&i.Address,
+		&i.LocalAmount,
+		&i.LocalCurrency,
+		&i.LinkedExternalID,
+		&i.LinkedTransactionID,
 	)
 	return i, err
 }
 
-type CreateTransactionsBatchParams struct {
-	Date        pgtype.Date
-	Description string
-	Amount      int64
-	Currency    string
-	Bank        string
-	Category    pgtype.Text
+const createTransactionStaging = `-- name: CreateTransactionStaging :exec
+CREATE TEMPORARY TABLE transaction_staging (
+    position BIGINT NOT NULL,
+    date DATE NOT NULL,
+    description TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    bank TEXT NOT NULL,
+    category TEXT,
+    external_id TEXT,
+    fingerprint TEXT NOT NULL,
+    account_id INTEGER,
+    running_balance BIGINT,
+    notes TEXT,
+    address TEXT,
+    local_amount BIGINT,
+    local_currency TEXT,
+    linked_external_id TEXT,
+    content_fingerprint TEXT
+) ON COMMIT DROP
+`
+
+func (q *Queries) CreateTransactionStaging(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createTransactionStaging)
+	return err
+}
+
+const createTransactionsSkipDuplicates = `-- name: CreateTransactionsSkipDuplicates :many
+INSERT INTO transactions (
+    date, description, amount, currency, bank, category, external_id, fingerprint, account_id,
+    running_balance, import_batch_id, notes, address, local_amount, local_currency, linked_external_id
+)
+SELECT u.date, u.description, u.amount, u.currency, u.bank,
+       NULLIF(u.category, ''), NULLIF(u.external_id, ''), u.fingerprint, NULLIF(u.account_id, 0),
+       CASE WHEN u.has_running_balance THEN u.running_balance END,
+       $1::integer,
+       NULLIF(u.notes, ''), NULLIF(u.address, ''),
+       CASE WHEN u.local_currency <> '' THEN u.local_amount END, NULLIF(u.local_currency, ''),
+       NULLIF(u.linked_external_id, '')
+FROM unnest(
+    $2::date[],
+    $3::text[],
+    $4::bigint[],
+    $5::text[],
+    $6::text[],
+    $7::text[],
+    $8::text[],
+    $9::text[],
+    $10::integer[],
+    $11::bigint[],
+    $12::boolean[],
+    $13::text[],
+    $14::text[],
+    $15::bigint[],
+    $16::text[],
+    $17::text[],
+    $18::text[]
+) AS u(date, description, amount, currency, bank, category, external_id, fingerprint, account_id,
+       running_balance, has_running_balance, notes, address, local_amount, local_currency, linked_external_id,
+       content_fingerprint)
+WHERE NOT EXISTS (
+    SELECT 1 FROM transactions t
+    WHERE u.content_fingerprint <> ''
+      AND t.fingerprint = u.content_fingerprint
+      AND t.external_id IS NULL
+)
+ON CONFLICT (fingerprint) DO NOTHING
+RETURNING fingerprint
+`
+
+type CreateTransactionsSkipDuplicatesParams struct {
+	ImportBatchID       pgtype.Int4
+	Dates               []pgtype.Date
+	Descriptions        []string
+	Amounts             []int64
+	Currencies          []string
+	Banks               []string
+	Categories          []string
+	ExternalIds         []string
+	Fingerprints        []string
+	AccountIds          []int32
+	RunningBalances     []int64
+	HasRunningBalances  []bool
+	Notes               []string
+	Addresses           []string
+	LocalAmounts        []int64
+	LocalCurrencies     []string
+	LinkedExternalIds   []string
+	ContentFingerprints []string
+}
+
+func (q *Queries) CreateTransactionsSkipDuplicates(ctx context.Context, arg CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+	rows, err := q.db.Query(ctx, createTransactionsSkipDuplicates,
+		arg.ImportBatchID,
+		arg.Dates,
+		arg.Descriptions,
+		arg.Amounts,
+		arg.Currencies,
+		arg.Banks,
+		arg.Categories,
+		arg.ExternalIds,
+		arg.Fingerprints,
+		arg.AccountIds,
+		arg.RunningBalances,
+		arg.HasRunningBalances,
+		arg.Notes,
+		arg.Addresses,
+		arg.LocalAmounts,
+		arg.LocalCurrencies,
+		arg.LinkedExternalIds,
+		arg.ContentFingerprints,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Text
+	for rows.Next() {
+		var fingerprint pgtype.Text
+		if err := rows.Scan(&fingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, fingerprint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const deleteTransaction = `-- name: DeleteTransaction :exec
+const deleteTransaction = `-- name: DeleteTransaction :execrows
 DELETE FROM transactions
 WHERE id = $1
 `
 
-func (q *Queries) DeleteTransaction(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteTransaction, id)
-	return err
+func (q *Queries) DeleteTransaction(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTransaction, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTransactionsByImportBatch = `-- name: DeleteTransactionsByImportBatch :execrows
+DELETE FROM transactions
+WHERE import_batch_id = $1
+`
+
+func (q *Queries) DeleteTransactionsByImportBatch(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTransactionsByImportBatch, importBatchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getTransaction = `-- name: GetTransaction :one
-SELECT id, date, description, amount, currency, bank, category, created_at, updated_at FROM transactions
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
 WHERE id = $1
 `
 
@@ -89,12 +250,73 @@ func (q *Queries) GetTransaction(ctx context.Context, id int32) (Transaction, er
 		&i.Category,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.ImportBatchID,
+		&i.AccountID,
+		&i.RunningBalance,
+		&i.Notes,
+		&i.Address,
+		&i.LocalAmount,
+		&i.LocalCurrency,
+		&i.LinkedExternalID,
+		&i.LinkedTransactionID,
 	)
 	return i, err
 }
 
+const insertStagedTransactions = `-- name: InsertStagedTransactions :execrows
+INSERT INTO transactions (
+    date, description, amount, currency, bank, category, external_id, fingerprint, account_id,
+    running_balance, import_batch_id, notes, address, local_amount, local_currency, linked_external_id
+)
+SELECT s.date, s.description, s.amount, s.currency, s.bank, s.category, s.external_id, s.fingerprint, s.account_id,
+       s.running_balance, $1::integer, s.notes, s.address, s.local_amount, s.local_currency,
+       s.linked_external_id
+FROM transaction_staging s
+WHERE NOT EXISTS (
+    SELECT 1 FROM transactions t
+    WHERE t.fingerprint = s.content_fingerprint AND t.external_id IS NULL
+)
+ORDER BY s.position
+ON CONFLICT (fingerprint) DO NOTHING
+`
+
+func (q *Queries) InsertStagedTransactions(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+	result, err := q.db.Exec(ctx, insertStagedTransactions, importBatchID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listStoredFingerprints = `-- name: ListStoredFingerprints :many
+SELECT fingerprint FROM transactions
+WHERE fingerprint = ANY($1::text[])
+`
+
+func (q *Queries) ListStoredFingerprints(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+	rows, err := q.db.Query(ctx, listStoredFingerprints, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Text
+	for rows.Next() {
+		var fingerprint pgtype.Text
+		if err := rows.Scan(&fingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, fingerprint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransactions = `-- name: ListTransactions :many
-SELECT id, date, description, amount, currency, bank, category, created_at, updated_at FROM transactions
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
 ORDER BY date DESC
 `
 
@@ -117,6 +339,266 @@ func (q *Queries) ListTransactions(ctx context.Context) ([]Transaction, error) {
 			&i.Category,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByAmountAsc = `-- name: ListTransactionsByAmountAsc :many
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
+WHERE ($1::date IS NULL OR date >= $1::date)
+  AND ($2::date IS NULL OR date <= $2::date)
+  AND ($3::text IS NULL OR bank = $3::text)
+  AND ($4::text IS NULL OR category = $4::text)
+  AND ($5::bigint IS NULL OR amount >= $5::bigint)
+  AND ($6::bigint IS NULL OR amount <= $6::bigint)
+  AND ($7::text IS NULL OR description ILIKE '%' || $7::text || '%')
+  AND ($8::integer IS NULL OR account_id = $8::integer)
+  AND ($9::integer IS NULL
+    OR (amount, id) > ($10::bigint, $9::integer))
+ORDER BY amount, id
+LIMIT $11::integer
+`
+
+type ListTransactionsByAmountAscParams struct {
+	DateFrom     pgtype.Date
+	DateTo       pgtype.Date
+	Bank         pgtype.Text
+	Category     pgtype.Text
+	MinAmount    pgtype.Int8
+	MaxAmount    pgtype.Int8
+	Search       pgtype.Text
+	AccountID    pgtype.Int4
+	CursorID     pgtype.Int4
+	CursorAmount pgtype.Int8
+	PageLimit    int32
+}
+
+func (q *Queries) ListTransactionsByAmountAsc(ctx context.Context, arg ListTransactionsByAmountAscParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByAmountAsc,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Bank,
+		arg.Category,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Search,
+		arg.AccountID,
+		arg.CursorID,
+		arg.CursorAmount,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Description,
+			&i.Amount,
+			&i.Currency,
+			&i.Bank,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByAmountDesc = `-- name: ListTransactionsByAmountDesc :many
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
+WHERE ($1::date IS NULL OR date >= $1::date)
+  AND ($2::date IS NULL OR date <= $2::date)
+  AND ($3::text IS NULL OR bank = $3::text)
+  AND ($4::text IS NULL OR category = $4::text)
+  AND ($5::bigint IS NULL OR amount >= $5::bigint)
+  AND ($6::bigint IS NULL OR amount <= $6::bigint)
+  AND ($7::text IS NULL OR description ILIKE '%' || $7::text || '%')
+  AND ($8::integer IS NULL OR account_id = $8::integer)
+  AND ($9::integer IS NULL
+    OR (amount, id) < ($10::bigint, $9::integer))
+ORDER BY amount DESC, id DESC
+LIMIT $11::integer
+`
+
+type ListTransactionsByAmountDescParams struct {
+	DateFrom     pgtype.Date
+	DateTo       pgtype.Date
+	Bank         pgtype.Text
+	Category     pgtype.Text
+	MinAmount    pgtype.Int8
+	MaxAmount    pgtype.Int8
+	Search       pgtype.Text
+	AccountID    pgtype.Int4
+	CursorID     pgtype.Int4
+	CursorAmount pgtype.Int8
+	PageLimit    int32
+}
+
+func (q *Queries) ListTransactionsByAmountDesc(ctx context.Context, arg ListTransactionsByAmountDescParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByAmountDesc,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Bank,
+		arg.Category,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Search,
+		arg.AccountID,
+		arg.CursorID,
+		arg.CursorAmount,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Description,
+			&i.Amount,
+			&i.Currency,
+			&i.Bank,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByDateAsc = `-- name: ListTransactionsByDateAsc :many
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
+WHERE ($1::date IS NULL OR date >= $1::date)
+  AND ($2::date IS NULL OR date <= $2::date)
+  AND ($3::text IS NULL OR bank = $3::text)
+  AND ($4::text IS NULL OR category = $4::text)
+  AND ($5::bigint IS NULL OR amount >= $5::bigint)
+  AND ($6::bigint IS NULL OR amount <= $6::bigint)
+  AND ($7::text IS NULL OR description ILIKE '%' || $7::text || '%')
+  AND ($8::integer IS NULL OR account_id = $8::integer)
+  AND ($9::integer IS NULL
+    OR (date, id) > ($10::date, $9::integer))
+ORDER BY date, id
+LIMIT $11::integer
+`
+
+type ListTransactionsByDateAscParams struct {
+	DateFrom   pgtype.Date
+	DateTo     pgtype.Date
+	Bank       pgtype.Text
+	Category   pgtype.Text
+	MinAmount  pgtype.Int8
+	MaxAmount  pgtype.Int8
+	Search     pgtype.Text
+	AccountID  pgtype.Int4
+	CursorID   pgtype.Int4
+	CursorDate pgtype.Date
+	PageLimit  int32
+}
+
+func (q *Queries) ListTransactionsByDateAsc(ctx context.Context, arg ListTransactionsByDateAscParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByDateAsc,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Bank,
+		arg.Category,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Search,
+		arg.AccountID,
+		arg.CursorID,
+		arg.CursorDate,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Description,
+			&i.Amount,
+			&i.Currency,
+			&i.Bank,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
 		); err != nil {
 			return nil, err
 		}
@@ -128,6 +610,280 @@ func (q *Queries) ListTransactions(ctx context.Context) ([]Transaction, error) {
 	return items, nil
 }
 
+const listTransactionsByDateDesc = `-- name: ListTransactionsByDateDesc :many
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
+WHERE ($1::date IS NULL OR date >= $1::date)
+  AND ($2::date IS NULL OR date <= $2::date)
+  AND ($3::text IS NULL OR bank = $3::text)
+  AND ($4::text IS NULL OR category = $4::text)
+  AND ($5::bigint IS NULL OR amount >= $5::bigint)
+  AND ($6::bigint IS NULL OR amount <= $6::bigint)
+  AND ($7::text IS NULL OR description ILIKE '%' || $7::text || '%')
+  AND ($8::integer IS NULL OR account_id = $8::integer)
+  AND ($9::integer IS NULL
+    OR (date, id) < ($10::date, $9::integer))
+ORDER BY date DESC, id DESC
+LIMIT $11::integer
+`
+
+type ListTransactionsByDateDescParams struct {
+	DateFrom   pgtype.Date
+	DateTo     pgtype.Date
+	Bank       pgtype.Text
+	Category   pgtype.Text
+	MinAmount  pgtype.Int8
+	MaxAmount  pgtype.Int8
+	Search     pgtype.Text
+	AccountID  pgtype.Int4
+	CursorID   pgtype.Int4
+	CursorDate pgtype.Date
+	PageLimit  int32
+}
+
+func (q *Queries) ListTransactionsByDateDesc(ctx context.Context, arg ListTransactionsByDateDescParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByDateDesc,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Bank,
+		arg.Category,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Search,
+		arg.AccountID,
+		arg.CursorID,
+		arg.CursorDate,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Description,
+			&i.Amount,
+			&i.Currency,
+			&i.Bank,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByDescriptionAsc = `-- name: ListTransactionsByDescriptionAsc :many
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
+WHERE ($1::date IS NULL OR date >= $1::date)
+  AND ($2::date IS NULL OR date <= $2::date)
+  AND ($3::text IS NULL OR bank = $3::text)
+  AND ($4::text IS NULL OR category = $4::text)
+  AND ($5::bigint IS NULL OR amount >= $5::bigint)
+  AND ($6::bigint IS NULL OR amount <= $6::bigint)
+  AND ($7::text IS NULL OR description ILIKE '%' || $7::text || '%')
+  AND ($8::integer IS NULL OR account_id = $8::integer)
+  AND ($9::integer IS NULL
+    OR (description, id) > ($10::text, $9::integer))
+ORDER BY description, id
+LIMIT $11::integer
+`
+
+type ListTransactionsByDescriptionAscParams struct {
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Bank              pgtype.Text
+	Category          pgtype.Text
+	MinAmount         pgtype.Int8
+	MaxAmount         pgtype.Int8
+	Search            pgtype.Text
+	AccountID         pgtype.Int4
+	CursorID          pgtype.Int4
+	CursorDescription pgtype.Text
+	PageLimit         int32
+}
+
+func (q *Queries) ListTransactionsByDescriptionAsc(ctx context.Context, arg ListTransactionsByDescriptionAscParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByDescriptionAsc,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Bank,
+		arg.Category,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Search,
+		arg.AccountID,
+		arg.CursorID,
+		arg.CursorDescription,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Description,
+			&i.Amount,
+			&i.Currency,
+			&i.Bank,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionsByDescriptionDesc = `-- name: ListTransactionsByDescriptionDesc :many
+SELECT id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id FROM transactions
+WHERE ($1::date IS NULL OR date >= $1::date)
+  AND ($2::date IS NULL OR date <= $2::date)
+  AND ($3::text IS NULL OR bank = $3::text)
+  AND ($4::text IS NULL OR category = $4::text)
+  AND ($5::bigint IS NULL OR amount >= $5::bigint)
+  AND ($6::bigint IS NULL OR amount <= $6::bigint)
+  AND ($7::text IS NULL OR description ILIKE '%' || $7::text || '%')
+  AND ($8::integer IS NULL OR account_id = $8::integer)
+  AND ($9::integer IS NULL
+    OR (description, id) < ($10::text, $9::integer))
+ORDER BY description DESC, id DESC
+LIMIT $11::integer
+`
+
+type ListTransactionsByDescriptionDescParams struct {
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Bank              pgtype.Text
+	Category          pgtype.Text
+	MinAmount         pgtype.Int8
+	MaxAmount         pgtype.Int8
+	Search            pgtype.Text
+	AccountID         pgtype.Int4
+	CursorID          pgtype.Int4
+	CursorDescription pgtype.Text
+	PageLimit         int32
+}
+
+func (q *Queries) ListTransactionsByDescriptionDesc(ctx context.Context, arg ListTransactionsByDescriptionDescParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByDescriptionDesc,
+		arg.DateFrom,
+		arg.DateTo,
+		arg.Bank,
+		arg.Category,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Search,
+		arg.AccountID,
+		arg.CursorID,
+		arg.CursorDescription,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Description,
+			&i.Amount,
+			&i.Currency,
+			&i.Bank,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExternalID,
+			&i.Fingerprint,
+			&i.ImportBatchID,
+			&i.AccountID,
+			&i.RunningBalance,
+			&i.Notes,
+			&i.Address,
+			&i.LocalAmount,
+			&i.LocalCurrency,
+			&i.LinkedExternalID,
+			&i.LinkedTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreferencedFingerprints = `-- name: ListUnreferencedFingerprints :many
+SELECT fingerprint FROM transactions
+WHERE external_id IS NULL AND fingerprint = ANY($1::text[])
+`
+
+func (q *Queries) ListUnreferencedFingerprints(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+	rows, err := q.db.Query(ctx, listUnreferencedFingerprints, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Text
+	for rows.Next() {
+		var fingerprint pgtype.Text
+		if err := rows.Scan(&fingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, fingerprint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateTransaction = `-- name: UpdateTransaction :one
 UPDATE transactions
 SET date = $2,
@@ -136,19 +892,31 @@ SET date = $2,
     currency = $5,
     bank = $6,
     category = $7,
+    account_id = $8,
+    notes = $9,
+    address = $10,
+    local_amount = $11,
+    local_currency = $12,
+    linked_external_id = $13,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, date, description, amount, currency, bank, category, created_at, updated_at
+RETURNING id, date, description, amount, currency, bank, category, created_at, updated_at, external_id, fingerprint, import_batch_id, account_id, running_balance, notes, address, local_amount, local_currency, linked_external_id, linked_transaction_id
 `
 
 type UpdateTransactionParams struct {
-	ID          int32
-	Date        pgtype.Date
-	Description string
-	Amount      int64
-	Currency    string
-	Bank        string
-	Category    pgtype.Text
+	ID               int32
+	Date             pgtype.Date
+	Description      string
+	Amount           int64
+	Currency         string
+	Bank             string
+	Category         pgtype.Text
+	AccountID        pgtype.Int4
+	Notes            pgtype.Text
+	Address          pgtype.Text
+	LocalAmount      pgtype.Int8
+	LocalCurrency    pgtype.Text
+	LinkedExternalID pgtype.Text
 }
 
 func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) (Transaction, error) {
@@ -160,6 +928,12 @@ func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionPa
 		arg.Currency,
 		arg.Bank,
 		arg.Category,
+		arg.AccountID,
+		arg.Notes,
+		arg.Address,
+		arg.LocalAmount,
+		arg.LocalCurrency,
+		arg.LinkedExternalID,
 	)
 	var i Transaction
 	err := row.Scan(
@@ -172,6 +946,85 @@ func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionPa
 		&i.Category,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExternalID,
+		&i.Fingerprint,
+		&i.ImportBatchID,
+		&i.AccountID,
+		&i.RunningBalance,
+		&i.Notes,
+		&i.Address,
+		&i.LocalAmount,
+		&i.LocalCurrency,
+		&i.LinkedExternalID,
+		&i.LinkedTransactionID,
 	)
 	return i, err
 }
+
+const listFundingCandidates = `-- name: ListFundingCandidates :many
+SELECT p.id AS payment_id, f.id AS funding_id, abs(p.date - f.date)::integer AS days_apart
+FROM transactions p
+JOIN transactions f
+  ON f.amount = p.amount
+ AND f.currency = p.currency
+ AND f.bank <> p.bank
+ AND f.account_id IS DISTINCT FROM p.account_id
+ AND f.date BETWEEN p.date - $1::integer AND p.date + $1::integer
+ AND f.description ILIKE '%PAYPAL%'
+WHERE p.bank = $2::text
+  AND p.amount < 0
+  AND p.linked_external_id IS NULL
+  AND p.linked_transaction_id IS NULL
+  AND NOT EXISTS (SELECT 1 FROM transactions l WHERE l.linked_transaction_id = f.id)
+  AND (p.import_batch_id = $3::integer OR f.import_batch_id = $3::integer)
+ORDER BY days_apart, p.id, f.id
+`
+
+type ListFundingCandidatesParams struct {
+	WindowDays    int32
+	Bank          string
+	ImportBatchID int32
+}
+
+type ListFundingCandidatesRow struct {
+	PaymentID int32
+	FundingID int32
+	DaysApart int32
+}
+
+func (q *Queries) ListFundingCandidates(ctx context.Context, arg ListFundingCandidatesParams) ([]ListFundingCandidatesRow, error) {
+	rows, err := q.db.Query(ctx, listFundingCandidates, arg.WindowDays, arg.Bank, arg.ImportBatchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFundingCandidatesRow
+	for rows.Next() {
+		var i ListFundingCandidatesRow
+		if err := rows.Scan(&i.PaymentID, &i.FundingID, &i.DaysApart); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const linkTransactions = `-- name: LinkTransactions :exec
+UPDATE transactions t
+SET linked_transaction_id = l.linked_transaction_id
+FROM unnest($1::integer[], $2::integer[]) AS l(id, linked_transaction_id)
+WHERE t.id = l.id
+`
+
+type LinkTransactionsParams struct {
+	Ids                  []int32
+	LinkedTransactionIds []int32
+}
+
+func (q *Queries) LinkTransactions(ctx context.Context, arg LinkTransactionsParams) error {
+	_, err := q.db.Exec(ctx, linkTransactions, arg.Ids, arg.LinkedTransactionIds)
+	return err
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kushturner/finances/internal/account"
+)
+
+type DailyBalanceResponse struct {
+	Date             time.Time `json:"date"`
+	Balance          int64     `json:"balance"`
+	StatementBalance *int64    `json:"statement_balance"`
+}
+
+type BalanceHistoryResponse struct {
+	AccountID int32                  `json:"account_id"`
+	Currency  string                 `json:"currency"`
+	Balances  []DailyBalanceResponse `json:"balances"`
+}
+
+type DiscrepancyResponse struct {
+	Kind          string    `json:"kind"`
+	TransactionID int32     `json:"transaction_id"`
+	Date          time.Time `json:"date"`
+	Expected      int64     `json:"expected"`
+	Statement     int64     `json:"statement"`
+	Difference    int64     `json:"difference"`
+}
+
+type StatementBalanceResponse struct {
+	ImportID   int32     `json:"import_id"`
+	Date       time.Time `json:"date"`
+	Statement  int64     `json:"statement"`
+	Computed   int64     `json:"computed"`
+	Difference int64     `json:"difference"`
+}
+
+type ReconciliationResponse struct {
+	AccountID        int32                     `json:"account_id"`
+	Currency         string                    `json:"currency"`
+	Reconciled       bool                      `json:"reconciled"`
+	OpeningBalance   int64                     `json:"opening_balance"`
+	ComputedBalance  int64                     `json:"computed_balance"`
+	CheckedRows      int                       `json:"checked_rows"`
+	Discrepancies    []DiscrepancyResponse     `json:"discrepancies"`
+	StatementBalance *StatementBalanceResponse `json:"statement_balance"`
+}
+
+func FromReconciliation(r account.Reconciliation) ReconciliationResponse {
+	response := ReconciliationResponse{
+		AccountID:       r.AccountID,
+		Currency:        r.OpeningBalance.Currency().Code,
+		Reconciled:      r.Reconciled(),
+		OpeningBalance:  r.OpeningBalance.Amount(),
+		ComputedBalance: r.ComputedBalance.Amount(),
+		CheckedRows:     r.CheckedRows,
+		Discrepancies:   make([]DiscrepancyResponse, 0, len(r.Discrepancies)),
+	}
+	for _, d := range r.Discrepancies {
+		response.Discrepancies = append(response.Discrepancies, DiscrepancyResponse{
+			Kind:          d.Kind,
+			TransactionID: d.TransactionID,
+			Date:          d.Date,
+			Expected:      d.Expected.Amount(),
+			Statement:     d.Statement.Amount(),
+			Difference:    d.Difference.Amount(),
+		})
+	}
+	if c := r.StatementBalance; c != nil {
+		response.StatementBalance = &StatementBalanceResponse{
+			ImportID:   c.ImportID,
+			Date:       c.Date,
+			Statement:  c.Statement.Amount(),
+			Computed:   c.Computed.Amount(),
+			Difference: c.Difference.Amount(),
+		}
+	}
+	return response
+}
+
+func NewBalanceHistoryHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid account id", err.Error())
+			return
+		}
+
+		var from, to *time.Time
+		for _, param := range []struct {
+			name string
+			dst  **time.Time
+		}{{"from", &from}, {"to", &to}} {
+			if value := r.URL.Query().Get(param.name); value != "" {
+				date, err := time.Parse(requestDateLayout, value)
+				if err != nil {
+					respondWithError(w, http.StatusBadRequest, "Invalid query parameters", param.name+" must be in YYYY-MM-DD format")
+					return
+				}
+				*param.dst = &date
+			}
+		}
+
+		a, err := accountService.GetAccount(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch account", err.Error())
+			return
+		}
+
+		history, err := accountService.BalanceHistory(r.Context(), id, from, to)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to compute balance history", err.Error())
+			return
+		}
+
+		response := BalanceHistoryResponse{
+			AccountID: a.ID,
+			Currency:  a.OpeningBalance.Currency().Code,
+			Balances:  make([]DailyBalanceResponse, 0, len(history)),
+		}
+		for _, day := range history {
+			response.Balances = append(response.Balances, DailyBalanceResponse{
+				Date:             day.Date,
+				Balance:          day.Balance.Amount(),
+				StatementBalance: amountOrNil(day.StatementBalance),
+			})
+		}
+
+		respondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+func NewReconcileAccountHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid account id", err.Error())
+			return
+		}
+
+		reconciliation, err := accountService.Reconcile(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to reconcile account", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromReconciliation(reconciliation))
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceHistoryHandler(t *testing.T) {
+	mock := &mockAccountService{
+		getAccountFunc: func(ctx context.Context, id int32) (account.Account, error) {
+			return sampleAccount(), nil
+		},
+		balanceHistoryFunc: func(ctx context.Context, id int32, from, to *time.Time) ([]account.DailyBalance, error) {
+			assert.Equal(t, int32(2), id)
+			assert.Equal(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), *from)
+			assert.Nil(t, to)
+			return []account.DailyBalance{
+				{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Balance: money.New(9500, "GBP"), StatementBalance: money.New(9500, "GBP")},
+				{Date: time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC), Balance: money.New(9500, "GBP")},
+			}, nil
+		},
+	}
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/accounts/2/balance-history?from=2026-01-15", nil), "id", "2")
+	rec := httptest.NewRecorder()
+
+	NewBalanceHistoryHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"account_id": 2,
+		"currency": "GBP",
+		"balances": [
+			{"date": "2026-01-15T00:00:00Z", "balance": 9500, "statement_balance": 9500},
+			{"date": "2026-01-16T00:00:00Z", "balance": 9500, "statement_balance": null}
+		]
+	}`, rec.Body.String())
+}
+
+func TestBalanceHistoryHandler_InvalidDate(t *testing.T) {
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/accounts/2/balance-history?to=15-01-2026", nil), "id", "2")
+	rec := httptest.NewRecorder()
+
+	NewBalanceHistoryHandler(&mockAccountService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestBalanceHistoryHandler_AccountNotFound(t *testing.T) {
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/accounts/9/balance-history", nil), "id", "9")
+	rec := httptest.NewRecorder()
+
+	NewBalanceHistoryHandler(&mockAccountService{})(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestReconcileAccountHandler(t *testing.T) {
+	mock := &mockAccountService{
+		reconcileFunc: func(ctx context.Context, id int32) (account.Reconciliation, error) {
+			return account.Reconciliation{
+				AccountID:       id,
+				OpeningBalance:  money.New(10000, "GBP"),
+				ComputedBalance: money.New(9400, "GBP"),
+				CheckedRows:     2,
+				Discrepancies: []account.Discrepancy{{
+					Kind:          account.DiscrepancyGap,
+					TransactionID: 7,
+					Date:          time.Date(2026, 1, 17, 0, 0, 0, 0, time.UTC),
+					Expected:      money.New(9400, "GBP"),
+					Statement:     money.New(9380, "GBP"),
+					Difference:    money.New(-20, "GBP"),
+				}},
+			}, nil
+		},
+	}
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/accounts/2/reconciliation", nil), "id", "2")
+	rec := httptest.NewRecorder()
+
+	NewReconcileAccountHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"account_id": 2,
+		"currency": "GBP",
+		"reconciled": false,
+		"opening_balance": 10000,
+		"computed_balance": 9400,
+		"checked_rows": 2,
+		"discrepancies": [{
+			"kind": "gap",
+			"transaction_id": 7,
+			"date": "2026-01-17T00:00:00Z",
+			"expected": 9400,
+			"statement": 9380,
+			"difference": -20
+		}],
+		"statement_balance": null
+	}`, rec.Body.String())
+}
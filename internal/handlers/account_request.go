@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+)
+
+type AccountRequest struct {
+	Institution    string  `json:"institution"`
+	Name           string  `json:"name"`
+	MaskedNumber   *string `json:"masked_number"`
+	Type           string  `json:"type"`
+	Currency       string  `json:"currency"`
+	OpeningBalance int64   `json:"opening_balance"`
+}
+
+func (req AccountRequest) ToAccount() account.Account {
+	return account.Account{
+		Institution:    req.Institution,
+		Name:           req.Name,
+		MaskedNumber:   req.MaskedNumber,
+		Type:           strings.ToLower(strings.TrimSpace(req.Type)),
+		OpeningBalance: money.New(req.OpeningBalance, currencyOrDefault(req.Currency)),
+	}
+}
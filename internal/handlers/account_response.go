@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/kushturner/finances/internal/account"
+)
+
+type AccountResponse struct {
+	ID             int32     `json:"id"`
+	Institution    string    `json:"institution"`
+	Name           string    `json:"name"`
+	MaskedNumber   *string   `json:"masked_number"`
+	Type           string    `json:"type"`
+	Currency       string    `json:"currency"`
+	OpeningBalance int64     `json:"opening_balance"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func FromAccount(a account.Account) AccountResponse {
+	return AccountResponse{
+		ID:             a.ID,
+		Institution:    a.Institution,
+		Name:           a.Name,
+		MaskedNumber:   a.MaskedNumber,
+		Type:           a.Type,
+		Currency:       a.OpeningBalance.Currency().Code,
+		OpeningBalance: a.OpeningBalance.Amount(),
+		CreatedAt:      a.CreatedAt,
+		UpdatedAt:      a.UpdatedAt,
+	}
+}
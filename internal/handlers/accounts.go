@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kushturner/finances/internal/account"
+)
+
+func NewListAccountsHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accounts, err := accountService.ListAccounts(r.Context())
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch accounts", err.Error())
+			return
+		}
+
+		responses := make([]AccountResponse, 0, len(accounts))
+		for _, a := range accounts {
+			responses = append(responses, FromAccount(a))
+		}
+
+		respondWithJSON(w, http.StatusOK, responses)
+	}
+}
+
+func NewGetAccountHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid account id", err.Error())
+			return
+		}
+
+		a, err := accountService.GetAccount(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch account", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromAccount(a))
+	}
+}
+
+func NewCreateAccountHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req AccountRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		created, err := accountService.CreateAccount(r.Context(), req.ToAccount())
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to create account", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, FromAccount(created))
+	}
+}
+
+func NewUpdateAccountHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid account id", err.Error())
+			return
+		}
+
+		var req AccountRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		a := req.ToAccount()
+		a.ID = id
+
+		updated, err := accountService.UpdateAccount(r.Context(), a)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to update account", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromAccount(updated))
+	}
+}
+
+func NewDeleteAccountHandler(accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid account id", err.Error())
+			return
+		}
+
+		if err := accountService.DeleteAccount(r.Context(), id); err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to delete account", err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAccountService struct {
+	listAccountsFunc   func(ctx context.Context) ([]account.Account, error)
+	getAccountFunc     func(ctx context.Context, id int32) (account.Account, error)
+	createAccountFunc  func(ctx context.Context, a account.Account) (account.Account, error)
+	updateAccountFunc  func(ctx context.Context, a account.Account) (account.Account, error)
+	deleteAccountFunc  func(ctx context.Context, id int32) error
+	resolveAccountFunc func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error)
+	matchAccountFunc   func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error)
+	reconcileFunc      func(ctx context.Context, id int32) (account.Reconciliation, error)
+	balanceHistoryFunc func(ctx context.Context, id int32, from, to *time.Time) ([]account.DailyBalance, error)
+}
+
+func (m *mockAccountService) ListAccounts(ctx context.Context) ([]account.Account, error) {
+	if m.listAccountsFunc != nil {
+		return m.listAccountsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockAccountService) GetAccount(ctx context.Context, id int32) (account.Account, error) {
+	if m.getAccountFunc != nil {
+		return m.getAccountFunc(ctx, id)
+	}
+	return account.Account{}, account.ErrNotFound
+}
+
+func (m *mockAccountService) CreateAccount(ctx context.Context, a account.Account) (account.Account, error) {
+	if m.createAccountFunc != nil {
+		return m.createAccountFunc(ctx, a)
+	}
+	return a, nil
+}
+
+func (m *mockAccountService) UpdateAccount(ctx context.Context, a account.Account) (account.Account, error) {
+	if m.updateAccountFunc != nil {
+		return m.updateAccountFunc(ctx, a)
+	}
+	return a, nil
+}
+
+func (m *mockAccountService) DeleteAccount(ctx context.Context, id int32) error {
+	if m.deleteAccountFunc != nil {
+		return m.deleteAccountFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockAccountService) ResolveAccount(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+	if m.resolveAccountFunc != nil {
+		return m.resolveAccountFunc(ctx, id, hint)
+	}
+	return account.Account{ID: 1, Institution: hint.Institution}, nil
+}
+
+func (m *mockAccountService) MatchAccount(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+	if m.matchAccountFunc != nil {
+		return m.matchAccountFunc(ctx, id, hint)
+	}
+	return account.Account{ID: 1, Institution: hint.Institution}, nil
+}
+
+func (m *mockAccountService) Reconcile(ctx context.Context, id int32) (account.Reconciliation, error) {
+	if m.reconcileFunc != nil {
+		return m.reconcileFunc(ctx, id)
+	}
+	return account.Reconciliation{}, nil
+}
+
+func (m *mockAccountService) BalanceHistory(ctx context.Context, id int32, from, to *time.Time) ([]account.DailyBalance, error) {
+	if m.balanceHistoryFunc != nil {
+		return m.balanceHistoryFunc(ctx, id, from, to)
+	}
+	return nil, nil
+}
+
+// InTx returns the mock itself, whose queries are all stubbed.
+func (m *mockAccountService) InTx(q db.Querier) account.Service {
+	return m
+}
+
+func sampleAccount() account.Account {
+	masked := "****12345"
+	return account.Account{
+		ID:             2,
+		Institution:    "Nationwide",
+		Name:           "FlexDirect",
+		MaskedNumber:   &masked,
+		Type:           account.TypeCurrent,
+		OpeningBalance: money.New(150000, "GBP"),
+		CreatedAt:      time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:      time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestListAccountsHandler(t *testing.T) {
+	mock := &mockAccountService{
+		listAccountsFunc: func(ctx context.Context) ([]account.Account, error) {
+			return []account.Account{sampleAccount()}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	NewListAccountsHandler(mock)(rec, httptest.NewRequest(http.MethodGet, "/accounts", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{
+			"id": 2,
+			"institution": "Nationwide",
+			"name": "FlexDirect",
+			"masked_number": "****12345",
+			"type": "current",
+			"currency": "GBP",
+			"opening_balance": 150000,
+			"created_at": "2026-01-01T09:00:00Z",
+			"updated_at": "2026-01-02T09:00:00Z"
+		}
+	]`, rec.Body.String())
+}
+
+func TestGetAccountHandler_NotFound(t *testing.T) {
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/accounts/9", nil), "id", "9")
+	rec := httptest.NewRecorder()
+
+	NewGetAccountHandler(&mockAccountService{})(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCreateAccountHandler(t *testing.T) {
+	var received account.Account
+	mock := &mockAccountService{
+		createAccountFunc: func(ctx context.Context, a account.Account) (account.Account, error) {
+			received = a
+			a.ID = 4
+			return a, nil
+		},
+	}
+	body := `{"institution":"American Express","name":"Gold","masked_number":"3782 822463 10005","type":"Credit","currency":"gbp","opening_balance":-2500}`
+
+	rec := httptest.NewRecorder()
+	NewCreateAccountHandler(mock)(rec, httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "American Express", received.Institution)
+	assert.Equal(t, account.TypeCredit, received.Type)
+	assert.Equal(t, int64(-2500), received.OpeningBalance.Amount())
+	assert.Equal(t, "GBP", received.OpeningBalance.Currency().Code)
+}
+
+func TestCreateAccountHandler_ValidationError(t *testing.T) {
+	mock := &mockAccountService{
+		createAccountFunc: func(ctx context.Context, a account.Account) (account.Account, error) {
+			return account.Account{}, account.ErrValidation
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	NewCreateAccountHandler(mock)(rec, httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"name":"x"}`)))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestUpdateAccountHandler_SetsID(t *testing.T) {
+	var received account.Account
+	mock := &mockAccountService{
+		updateAccountFunc: func(ctx context.Context, a account.Account) (account.Account, error) {
+			received = a
+			return a, nil
+		},
+	}
+	body := `{"institution":"Nationwide","name":"FlexDirect","type":"current"}`
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/accounts/2", strings.NewReader(body)), "id", "2")
+	rec := httptest.NewRecorder()
+
+	NewUpdateAccountHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(2), received.ID)
+}
+
+func TestDeleteAccountHandler_InUse(t *testing.T) {
+	mock := &mockAccountService{
+		deleteAccountFunc: func(ctx context.Context, id int32) error {
+			return account.ErrInUse
+		},
+	}
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/accounts/2", nil), "id", "2")
+	rec := httptest.NewRecorder()
+
+	NewDeleteAccountHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// unassignedAccountName labels exported transactions that belong to no
+// account.
+const unassignedAccountName = "Unassigned"
+
+// NewExportTransactionsHandler writes every transaction matching the
+// GET /transactions filters in the requested format. Only format=qif is
+// supported; date_order=mdy writes US-style dates for US Quicken.
+func NewExportTransactionsHandler(transactionService transaction.Service, accountService account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if format := strings.ToLower(query.Get("format")); format != "qif" {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters", "format must be qif")
+			return
+		}
+
+		var order csvparser.QIFDateOrder
+		switch strings.ToLower(query.Get("date_order")) {
+		case "", "dmy":
+			order = csvparser.QIFDayFirst
+		case "mdy":
+			order = csvparser.QIFMonthFirst
+		default:
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters", "date_order must be dmy or mdy")
+			return
+		}
+
+		opts, err := parseListOptions(query)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters", err.Error())
+			return
+		}
+		if query.Get("cursor") != "" || query.Get("limit") != "" {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters", "export does not page; omit cursor and limit")
+			return
+		}
+		if opts.SortBy == "" {
+			opts.SortBy = transaction.SortByDate
+			opts.SortDesc = false
+		}
+
+		transactions, err := listAllTransactions(r.Context(), transactionService, opts)
+		if err != nil {
+			if errors.Is(err, transaction.ErrValidation) {
+				respondWithError(w, http.StatusBadRequest, "Invalid query parameters", err.Error())
+				return
+			}
+			respondWithError(w, determineStatusCode(err), "Failed to fetch transactions", err.Error())
+			return
+		}
+
+		accounts, err := groupByAccount(r.Context(), accountService, transactions)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch accounts", err.Error())
+			return
+		}
+
+		// Render fully before writing so a failure can still be reported
+		// with an error status.
+		var body bytes.Buffer
+		if err := csvparser.WriteQIF(&body, accounts, order); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to write export", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/qif")
+		w.Header().Set("Content-Disposition", `attachment; filename="transactions.qif"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body.Bytes())
+	}
+}
+
+// groupByAccount splits transactions into one QIF account per account, in
+// account id order, keeping each account's transactions in list order.
+// Transactions with no account come last.
+func groupByAccount(ctx context.Context, accountService account.Service, transactions []transaction.Transaction) ([]csvparser.QIFAccount, error) {
+	byID := make(map[int32][]transaction.Transaction)
+	var ids []int32
+	var unassigned []transaction.Transaction
+	for _, tx := range transactions {
+		if tx.AccountID == nil {
+			unassigned = append(unassigned, tx)
+			continue
+		}
+		if _, seen := byID[*tx.AccountID]; !seen {
+			ids = append(ids, *tx.AccountID)
+		}
+		byID[*tx.AccountID] = append(byID[*tx.AccountID], tx)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make([]csvparser.QIFAccount, 0, len(ids)+1)
+	for _, id := range ids {
+		acc, err := accountService.GetAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %w", id, err)
+		}
+		accounts = append(accounts, csvparser.QIFAccount{
+			Name:         exportAccountName(acc),
+			Type:         acc.Type,
+			Transactions: byID[id],
+		})
+	}
+	if len(unassigned) > 0 {
+		accounts = append(accounts, csvparser.QIFAccount{Name: unassignedAccountName, Transactions: unassigned})
+	}
+	return accounts, nil
+}
+
+// exportAccountName adds the masked number so two accounts with the same
+// product name stay apart in the importing tool.
+func exportAccountName(acc account.Account) string {
+	if acc.MaskedNumber == nil {
+		return acc.Name
+	}
+	return acc.Name + " " + *acc.MaskedNumber
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTransactions_QIF(t *testing.T) {
+	accountID := int32(7)
+	masked := "****1234"
+	var requests []transaction.ListOptions
+	mock := &mockTransactionService{
+		listTransactionsFunc: func(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error) {
+			requests = append(requests, opts)
+			if opts.Cursor == "" {
+				return transaction.Page{
+					Transactions: []transaction.Transaction{
+						{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "TEST PAYEE", Amount: money.New(-5000, "GBP"), AccountID: &accountID},
+						{Date: time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC), Description: "CASH", Amount: money.New(-1000, "GBP")},
+					},
+					NextCursor: "next",
+				}, nil
+			}
+			return transaction.Page{
+				Transactions: []transaction.Transaction{
+					{Date: time.Date(2026, 1, 17, 0, 0, 0, 0, time.UTC), Description: "SALARY", Amount: money.New(200000, "GBP"), AccountID: &accountID},
+				},
+			}, nil
+		},
+	}
+	accounts := &mockAccountService{
+		getAccountFunc: func(ctx context.Context, id int32) (account.Account, error) {
+			assert.Equal(t, accountID, id)
+			return account.Account{ID: id, Name: "Debit", MaskedNumber: &masked, Type: account.TypeCurrent}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions/export?format=qif&bank=Nationwide", nil)
+	rec := httptest.NewRecorder()
+
+	NewExportTransactionsHandler(mock, accounts)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/qif", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "transactions.qif")
+	assert.Equal(t, "!Account\nNDebit ****1234\nTBank\n^\n!Type:Bank\n"+
+		"D15/01/2026\nT-50.00\nPTEST PAYEE\n^\n"+
+		"D17/01/2026\nT2000.00\nPSALARY\n^\n"+
+		"!Account\nNUnassigned\nTBank\n^\n!Type:Bank\n"+
+		"D16/01/2026\nT-10.00\nPCASH\n^\n", rec.Body.String())
+
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "next", requests[1].Cursor)
+	assert.Equal(t, int32(transaction.MaxPageLimit), requests[0].Limit)
+	assert.Equal(t, transaction.SortByDate, requests[0].SortBy)
+	assert.False(t, requests[0].SortDesc)
+	assert.Equal(t, "Nationwide", *requests[0].Bank)
+}
+
+func TestExportTransactions_MonthFirstDates(t *testing.T) {
+	mock := &mockTransactionService{
+		transactions: []transaction.Transaction{
+			{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "CASH", Amount: money.New(-1000, "GBP")},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions/export?format=qif&date_order=mdy", nil)
+	rec := httptest.NewRecorder()
+
+	NewExportTransactionsHandler(mock, &mockAccountService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "D01/15/2026\n")
+}
+
+func TestExportTransactions_InvalidParameters(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"?format=csv",
+		"?format=qif&date_order=ymd",
+		"?format=qif&limit=10",
+		"?format=qif&from=15/01/2026",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/transactions/export"+query, nil)
+		rec := httptest.NewRecorder()
+
+		NewExportTransactionsHandler(&mockTransactionService{}, &mockAccountService{})(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestExportTransactions_AccountLookupFails(t *testing.T) {
+	accountID := int32(7)
+	mock := &mockTransactionService{
+		transactions: []transaction.Transaction{
+			{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "CASH", Amount: money.New(-1000, "GBP"), AccountID: &accountID},
+		},
+	}
+	accounts := &mockAccountService{
+		getAccountFunc: func(ctx context.Context, id int32) (account.Account, error) {
+			return account.Account{}, fmt.Errorf("%w: connection lost", account.ErrDatabaseFailure)
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions/export?format=qif", nil)
+	rec := httptest.NewRecorder()
+
+	NewExportTransactionsHandler(mock, accounts)(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
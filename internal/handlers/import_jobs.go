@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/importjob"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+type ImportJobResponse struct {
+	ID             int32  `json:"id"`
+	FileName       string `json:"file_name"`
+	Checksum       string `json:"checksum"`
+	Status         string `json:"status"`
+	Statements     int    `json:"statements"`
+	StatementsDone int    `json:"statements_done"`
+	Rows           int    `json:"rows"`
+	RowsDone       int    `json:"rows_done"`
+	// Bytes and BytesDone are how much of the upload has been read, when
+	// it is streamed and Rows is only known once it has all been read.
+	Bytes     int64 `json:"bytes"`
+	BytesDone int64 `json:"bytes_done"`
+	// Percent is how much of the file has been imported, from RowsDone
+	// or, until the rows are counted, BytesDone.
+	Percent  int   `json:"percent"`
+	Inserted int64 `json:"inserted"`
+	Skipped  int64 `json:"skipped"`
+	// ImportIDs are the imports the job has made, one per statement, each
+	// of which can be rolled back with DELETE /imports/batches/{id}.
+	ImportIDs []int32              `json:"import_ids"`
+	Rejected  []csvparser.RowError `json:"rejected,omitempty"`
+	// Files is what became of each file of an upload of several; one
+	// that failed does not stop the others.
+	Files      []importjob.FileResult `json:"files,omitempty"`
+	Error      *string                `json:"error"`
+	CreatedAt  time.Time              `json:"created_at"`
+	StartedAt  *time.Time             `json:"started_at"`
+	FinishedAt *time.Time             `json:"finished_at"`
+}
+
+func FromImportJob(j importjob.Job) ImportJobResponse {
+	response := ImportJobResponse{
+		ID:             j.ID,
+		FileName:       j.FileName,
+		Checksum:       j.Checksum,
+		Status:         j.Status,
+		Statements:     j.Progress.Statements,
+		StatementsDone: j.Progress.StatementsDone,
+		Rows:           j.Progress.Rows,
+		RowsDone:       j.Progress.RowsDone,
+		Bytes:          j.Progress.Bytes,
+		BytesDone:      j.Progress.BytesDone,
+		Inserted:       j.Progress.Inserted,
+		Skipped:        j.Progress.Skipped,
+		ImportIDs:      j.Progress.ImportIDs,
+		Rejected:       j.Progress.Rejected,
+		Files:          j.Progress.Files,
+		Error:          j.ErrorMessage,
+		CreatedAt:      j.CreatedAt,
+		StartedAt:      j.StartedAt,
+		FinishedAt:     j.FinishedAt,
+	}
+	if response.ImportIDs == nil {
+		response.ImportIDs = []int32{}
+	}
+	switch {
+	case j.Status == importjob.StatusCompleted:
+		response.Percent = 100
+	case j.Progress.Rows > 0:
+		response.Percent = j.Progress.RowsDone * 100 / j.Progress.Rows
+	case j.Progress.Bytes > 0:
+		response.Percent = int(j.Progress.BytesDone * 100 / j.Progress.Bytes)
+	}
+	return response
+}
+
+// NewSubmitImportJobHandler accepts an upload as NewUploadTransactionsHandler
+// does, with the same query parameters, but imports it in the background.
+// It responds at once with the queued job, whose progress is polled from
+// GET /imports/{id}.
+func NewSubmitImportJobHandler(transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, jobs importjob.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ups, ok := readUploads(w, r)
+		if !ok {
+			return
+		}
+		submitImportJob(r.Context(), w, transactionService, parserService, accountService, jobs, ups)
+	}
+}
+
+// submitImportJob queues an upload to be imported in the background and
+// responds with the job. The job removes the upload's files once it has
+// run, or they are removed here if it cannot be queued.
+func submitImportJob(ctx context.Context, w http.ResponseWriter, transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, jobs importjob.Service, ups []upload) {
+	job, err := jobs.Submit(ctx, jobFileName(ups), jobChecksum(ups), importJobWork(transactionService, parserService, accountService, ups))
+	if err != nil {
+		removeUploads(ups)
+		respondWithError(w, determineStatusCode(err), "Failed to queue import", err.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/imports/%d", job.ID))
+	respondWithJSON(w, http.StatusAccepted, FromImportJob(job))
+}
+
+func NewGetImportJobHandler(jobs importjob.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid import job id", err.Error())
+			return
+		}
+
+		job, err := jobs.GetJob(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch import job", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromImportJob(job))
+	}
+}
+
+// NewCancelImportJobHandler cancels a queued or running job. A running job
+// takes a moment to stop, so it is returned as 202 Accepted until it has.
+func NewCancelImportJobHandler(jobs importjob.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid import job id", err.Error())
+			return
+		}
+
+		job, err := jobs.Cancel(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to cancel import job", err.Error())
+			return
+		}
+
+		status := http.StatusOK
+		if !job.Done() {
+			status = http.StatusAccepted
+		}
+		respondWithJSON(w, status, FromImportJob(job))
+	}
+}
+
+// jobProgressRows is how many rows a job inserts between reports of its
+// progress, which are each a database write.
+const jobProgressRows = 1000
+
+// maxJobFileName is the longest file name a job records.
+const maxJobFileName = 255
+
+// jobFileName names the files of an upload, or the archives they came
+// from, for the job importing them.
+func jobFileName(ups []upload) string {
+	var parts []string
+	for _, up := range ups {
+		if !slices.Contains(parts, up.part) {
+			parts = append(parts, up.part)
+		}
+	}
+	name := strings.Join(parts, ", ")
+	if len(name) <= maxJobFileName {
+		return name
+	}
+	// Cutting the name in bytes can split its last character, which is
+	// dropped.
+	return strings.ToValidUTF8(name[:maxJobFileName-len("...")], "") + "..."
+}
+
+// jobChecksum is the checksum of a single file, or of the checksums of
+// several.
+func jobChecksum(ups []upload) string {
+	if len(ups) == 1 {
+		return ups[0].file.checksum
+	}
+	hash := sha256.New()
+	for _, up := range ups {
+		io.WriteString(hash, up.file.checksum)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// importJobWork parses and imports an upload as the upload handler does,
+// streaming a CSV export rather than parsing it whole, and removes its
+// files once it is done. It reports progress after parsing, every
+// jobProgressRows rows inserted, and after each statement. Several files
+// are imported together, and reported once they have been.
+func importJobWork(transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, ups []upload) importjob.Work {
+	return func(ctx context.Context, report func(importjob.Progress)) error {
+		defer removeUploads(ups)
+		// The job was cancelled before it started.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(ups) > 1 {
+			return importUploadsJob(ctx, transactionService, parserService, accountService, ups, report)
+		}
+
+		up := ups[0]
+		// The rows of a streamed file are only counted once they have all
+		// been read, so until then its progress is measured in bytes.
+		progress := importjob.Progress{Statements: 1, Bytes: up.file.size}
+		streamed, err := importStream(ctx, transactionService, parserService, accountService, up, func(rows int, read int64) {
+			if rows%jobProgressRows == 0 {
+				progress.RowsDone = rows
+				progress.BytesDone = read
+				report(progress)
+			}
+		})
+		var failure *statementError
+		switch {
+		case err == nil:
+			progress.StatementsDone = 1
+			progress.Rows = streamed.rows.count
+			progress.RowsDone = progress.Rows
+			progress.BytesDone = progress.Bytes
+			progress.Inserted = streamed.summary.Inserted
+			progress.Skipped = streamed.summary.Skipped
+			progress.ImportIDs = []int32{streamed.summary.ImportID}
+			progress.Rejected = streamed.rejected
+			report(progress)
+			return nil
+		case errors.As(err, &failure):
+			return fmt.Errorf("%s: %w", failure.step, failure)
+		case !errors.Is(err, csvparser.ErrNotStreamable):
+			return err
+		}
+
+		parsed, err := parseFile(parserService, up)
+		if err != nil {
+			return err
+		}
+		if err := checkExplicitAccount(up, parsed.Statements); err != nil {
+			return err
+		}
+
+		progress = importjob.Progress{Statements: len(parsed.Statements), Rejected: parsed.Rejected}
+		for _, statement := range parsed.Statements {
+			progress.Rows += len(statement.Transactions)
+		}
+		report(progress)
+
+		// done counts the rows of the statements already imported.
+		done := 0
+		_, failure = importStatements(ctx, transactionService, accountService, up, parsed.Statements, importProgress{
+			copied: func(i, rows int) {
+				if rows%jobProgressRows == 0 {
+					progress.RowsDone = done + rows
+					report(progress)
+				}
+			},
+			imported: func(i int, summary UploadImportSummary) {
+				done += len(parsed.Statements[i].Transactions)
+				progress.StatementsDone++
+				progress.RowsDone = done
+				progress.Inserted += summary.Inserted
+				progress.Skipped += summary.Skipped
+				progress.ImportIDs = append(progress.ImportIDs, summary.ImportID)
+				report(progress)
+			},
+		})
+		if failure != nil {
+			return fmt.Errorf("%s: %w", failure.step, failure)
+		}
+		return nil
+	}
+}
+
+// importUploadsJob imports the files of an upload for importJobWork. The
+// job fails only when none of the files could be imported; otherwise the
+// files that failed are marked so in its progress.
+func importUploadsJob(ctx context.Context, transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, ups []upload, report func(importjob.Progress)) error {
+	files := importUploads(ctx, transactionService, parserService, accountService, ups)
+
+	var progress importjob.Progress
+	failed := 0
+	for _, file := range files {
+		progress.Files = append(progress.Files, importjob.FileResult{
+			FileName: file.FileName,
+			Format:   file.Format,
+			Inserted: file.Inserted,
+			Skipped:  file.Skipped,
+			Failed:   file.Failed,
+			Error:    file.Error,
+		})
+		if file.Failed {
+			failed++
+			continue
+		}
+		progress.Statements += len(file.Imports)
+		progress.Inserted += file.Inserted
+		progress.Skipped += file.Skipped
+		progress.Rejected = append(progress.Rejected, file.Rejected...)
+		for _, summary := range file.Imports {
+			progress.ImportIDs = append(progress.ImportIDs, summary.ImportID)
+		}
+	}
+	progress.StatementsDone = progress.Statements
+	progress.Rows = int(progress.Inserted + progress.Skipped)
+	progress.RowsDone = progress.Rows
+	report(progress)
+	if failed == len(files) {
+		return fmt.Errorf("none of the %d files could be imported", len(files))
+	}
+	return nil
+}
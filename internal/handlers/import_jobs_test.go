@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/importjob"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockImportJobService struct {
+	submitFunc func(ctx context.Context, fileName, checksum string, work importjob.Work) (importjob.Job, error)
+	getJobFunc func(ctx context.Context, id int32) (importjob.Job, error)
+	cancelFunc func(ctx context.Context, id int32) (importjob.Job, error)
+}
+
+func (m *mockImportJobService) Submit(ctx context.Context, fileName, checksum string, work importjob.Work) (importjob.Job, error) {
+	if m.submitFunc != nil {
+		return m.submitFunc(ctx, fileName, checksum, work)
+	}
+	return importjob.Job{ID: 1, FileName: fileName, Checksum: checksum, Status: importjob.StatusQueued}, nil
+}
+
+func (m *mockImportJobService) GetJob(ctx context.Context, id int32) (importjob.Job, error) {
+	if m.getJobFunc != nil {
+		return m.getJobFunc(ctx, id)
+	}
+	return importjob.Job{}, importjob.ErrNotFound
+}
+
+func (m *mockImportJobService) Cancel(ctx context.Context, id int32) (importjob.Job, error) {
+	if m.cancelFunc != nil {
+		return m.cancelFunc(ctx, id)
+	}
+	return importjob.Job{}, importjob.ErrNotFound
+}
+
+func (m *mockImportJobService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockImportJobService) Wait() {}
+
+// twoStatementParser returns two statements of two and one transactions.
+func twoStatementParser() *mockParserService {
+	return &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			return csvparser.ParseResult{
+				Statements: []csvparser.Statement{
+					{Institution: "Test Bank", Transactions: []transaction.Transaction{
+						{Description: "A", Amount: money.New(-100, "GBP")},
+						{Description: "B", Amount: money.New(-200, "GBP")},
+					}},
+					{Institution: "Test Bank", Transactions: []transaction.Transaction{
+						{Description: "C", Amount: money.New(-300, "GBP")},
+					}},
+				},
+				Rejected: []csvparser.RowError{{Row: 3, Reason: "bad date"}},
+			}, nil
+		},
+	}
+}
+
+func TestSubmitImportJobHandler(t *testing.T) {
+	var work importjob.Work
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			assert.Equal(t, "statement.csv", fileName)
+			assert.Len(t, checksum, 64)
+			work = w
+			return importjob.Job{ID: 12, FileName: fileName, Status: importjob.StatusQueued}, nil
+		},
+	}
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{BatchID: int32(len(transactions)) * 10, Inserted: int64(len(transactions))}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "")
+	rec := httptest.NewRecorder()
+	NewSubmitImportJobHandler(mockTxService, twoStatementParser(), &mockAccountService{}, jobs)(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/imports/12", rec.Header().Get("Location"))
+	var response ImportJobResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, int32(12), response.ID)
+	assert.Equal(t, importjob.StatusQueued, response.Status)
+
+	// The work is run later by a worker.
+	var reports []importjob.Progress
+	err := work(context.Background(), func(p importjob.Progress) { reports = append(reports, p) })
+
+	assert.NoError(t, err)
+	assert.Len(t, reports, 3)
+	assert.Equal(t, importjob.Progress{Statements: 2, Rows: 3, Rejected: []csvparser.RowError{{Row: 3, Reason: "bad date"}}}, reports[0])
+	assert.Equal(t, 2, reports[1].RowsDone)
+	assert.Equal(t, importjob.Progress{
+		Statements: 2, StatementsDone: 2, Rows: 3, RowsDone: 3, Inserted: 3,
+		ImportIDs: []int32{20, 10},
+		Rejected:  []csvparser.RowError{{Row: 3, Reason: "bad date"}},
+	}, reports[2])
+}
+
+func TestSubmitImportJobHandler_WorkFailures(t *testing.T) {
+	var work importjob.Work
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			work = w
+			return importjob.Job{ID: 1}, nil
+		},
+	}
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			if transactions[0].Description == "C" {
+				return transaction.ImportResult{}, transaction.ErrDatabaseFailure
+			}
+			return transaction.ImportResult{BatchID: 1, Inserted: int64(len(transactions))}, nil
+		},
+	}
+
+	submit := func(query string) importjob.Work {
+		req := createMultipartRequest(t, "some csv content", "")
+		req.URL.RawQuery += query
+		rec := httptest.NewRecorder()
+		NewSubmitImportJobHandler(mockTxService, twoStatementParser(), &mockAccountService{}, jobs)(rec, req)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		return work
+	}
+	report := func(importjob.Progress) {}
+
+	err := submit("")(context.Background(), report)
+	assert.EqualError(t, err, "Upload failed: statement 2 of 2: database failure; 1 earlier statements were imported")
+
+	err = submit("&account_id=3")(context.Background(), report)
+	assert.ErrorContains(t, err, "file contains 2 statements")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = submit("")(ctx, report)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSubmitImportJobHandler_ReportsRowsAsTheyAreInserted(t *testing.T) {
+	var work importjob.Work
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			work = w
+			return importjob.Job{ID: 1}, nil
+		},
+	}
+	transactions := make([]transaction.Transaction, jobProgressRows+1)
+	for i := range transactions {
+		transactions[i] = transaction.Transaction{Description: fmt.Sprintf("row %d", i), Amount: money.New(-100, "GBP")}
+	}
+	mockParser := &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			return csvparser.ParseResult{Statements: []csvparser.Statement{{Institution: "Test Bank", Transactions: transactions}}}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "")
+	NewSubmitImportJobHandler(&mockTransactionService{}, mockParser, &mockAccountService{}, jobs)(httptest.NewRecorder(), req)
+	var reports []importjob.Progress
+	err := work(context.Background(), func(p importjob.Progress) { reports = append(reports, p) })
+
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 3) {
+		assert.Equal(t, 0, reports[0].RowsDone)
+		assert.Equal(t, jobProgressRows, reports[1].RowsDone)
+		assert.Equal(t, 0, reports[1].StatementsDone)
+		assert.Equal(t, jobProgressRows+1, reports[2].RowsDone)
+		assert.Equal(t, 1, reports[2].StatementsDone)
+	}
+}
+
+func TestSubmitImportJobHandler_QueueFull(t *testing.T) {
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, work importjob.Work) (importjob.Job, error) {
+			return importjob.Job{}, fmt.Errorf("%w: 16 jobs are already waiting", importjob.ErrQueueFull)
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "amex")
+	rec := httptest.NewRecorder()
+	NewSubmitImportJobHandler(&mockTransactionService{}, &mockParserService{}, &mockAccountService{}, jobs)(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestSubmitImportJobHandler_InvalidMode(t *testing.T) {
+	req := createMultipartRequest(t, "some csv content", "amex")
+	req.URL.RawQuery += "&mode=careful"
+	rec := httptest.NewRecorder()
+	NewSubmitImportJobHandler(&mockTransactionService{}, &mockParserService{}, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUploadTransactionsHandler_LargeFileRunsAsJob(t *testing.T) {
+	var submitted string
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			submitted = fileName
+			return importjob.Job{ID: 7, FileName: fileName, Status: importjob.StatusQueued}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			t.Fatal("a large upload should be parsed by its job")
+			return csvparser.ParseResult{}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, strings.Repeat("x", asyncUploadSize+1), "")
+	rec := httptest.NewRecorder()
+	NewUploadTransactionsHandler(&mockTransactionService{}, mockParser, &mockAccountService{}, jobs)(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/imports/7", rec.Header().Get("Location"))
+	assert.Equal(t, "statement.csv", submitted)
+}
+
+func TestSubmitImportJobHandler_RemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	spooled := func() []os.DirEntry {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		return entries
+	}
+
+	var work importjob.Work
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			work = w
+			return importjob.Job{ID: 1}, nil
+		},
+	}
+	req := createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"feb.csv", "february"})
+	NewSubmitImportJobHandler(&mockTransactionService{}, contentParser(), &mockAccountService{}, jobs)(httptest.NewRecorder(), req)
+
+	// The files wait on disk for the job, which removes them even when it
+	// was cancelled before it started.
+	assert.Len(t, spooled(), 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, work(ctx, func(importjob.Progress) {}), context.Canceled)
+	assert.Empty(t, spooled())
+
+	jobs.submitFunc = func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+		return importjob.Job{}, importjob.ErrQueueFull
+	}
+	req = createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"feb.csv", "february"})
+	NewSubmitImportJobHandler(&mockTransactionService{}, contentParser(), &mockAccountService{}, jobs)(httptest.NewRecorder(), req)
+
+	assert.Empty(t, spooled())
+}
+
+func TestGetImportJobHandler(t *testing.T) {
+	errorMessage := "Upload failed: database failure"
+	jobs := &mockImportJobService{
+		getJobFunc: func(ctx context.Context, id int32) (importjob.Job, error) {
+			assert.Equal(t, int32(4), id)
+			return importjob.Job{
+				ID:       4,
+				Status:   importjob.StatusRunning,
+				Progress: importjob.Progress{Statements: 2, StatementsDone: 1, Rows: 400, RowsDone: 150, Inserted: 150},
+			}, nil
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/imports/4", nil), "id", "4")
+	rec := httptest.NewRecorder()
+	NewGetImportJobHandler(jobs)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response ImportJobResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, importjob.StatusRunning, response.Status)
+	assert.Equal(t, 37, response.Percent)
+	assert.Equal(t, []int32{}, response.ImportIDs)
+
+	jobs.getJobFunc = func(ctx context.Context, id int32) (importjob.Job, error) {
+		return importjob.Job{ID: 4, Status: importjob.StatusFailed, ErrorMessage: &errorMessage}, nil
+	}
+	rec = httptest.NewRecorder()
+	NewGetImportJobHandler(jobs)(rec, req)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, errorMessage, *response.Error)
+	assert.Equal(t, 0, response.Percent)
+}
+
+func TestGetImportJobHandler_Errors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewGetImportJobHandler(&mockImportJobService{})(rec, withURLParam(httptest.NewRequest(http.MethodGet, "/imports/9", nil), "id", "9"))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	NewGetImportJobHandler(&mockImportJobService{})(rec, withURLParam(httptest.NewRequest(http.MethodGet, "/imports/abc", nil), "id", "abc"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCancelImportJobHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		job    importjob.Job
+		err    error
+		status int
+	}{
+		{"queued", importjob.Job{ID: 2, Status: importjob.StatusCancelled}, nil, http.StatusOK},
+		{"running", importjob.Job{ID: 2, Status: importjob.StatusRunning}, nil, http.StatusAccepted},
+		{"finished", importjob.Job{}, fmt.Errorf("%w: import job 2 is completed", importjob.ErrConflict), http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &mockImportJobService{
+				cancelFunc: func(ctx context.Context, id int32) (importjob.Job, error) {
+					return tt.job, tt.err
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			NewCancelImportJobHandler(jobs)(rec, withURLParam(httptest.NewRequest(http.MethodDelete, "/imports/2", nil), "id", "2"))
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
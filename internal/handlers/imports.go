@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+type ImportBatchResponse struct {
+	ID            int32      `json:"id"`
+	FileName      string     `json:"file_name"`
+	Bank          string     `json:"bank"`
+	Checksum      string     `json:"checksum"`
+	AccountID     *int32     `json:"account_id"`
+	RowCount      int32      `json:"row_count"`
+	InsertedCount int32      `json:"inserted_count"`
+	SkippedCount  int32      `json:"skipped_count"`
+	Status        string     `json:"status"`
+	Error         *string    `json:"error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	RolledBackAt  *time.Time `json:"rolled_back_at"`
+	// Balances the statement reported, in minor units of the account's
+	// currency.
+	ClosingBalance     *int64     `json:"closing_balance"`
+	AvailableBalance   *int64     `json:"available_balance"`
+	BalanceDate        *time.Time `json:"balance_date"`
+	OpeningBalance     *int64     `json:"opening_balance"`
+	OpeningBalanceDate *time.Time `json:"opening_balance_date"`
+}
+
+type RollbackResponse struct {
+	Message string `json:"message"`
+	Deleted int64  `json:"deleted"`
+}
+
+func FromImportBatch(b transaction.ImportBatch) ImportBatchResponse {
+	return ImportBatchResponse{
+		ID:                 b.ID,
+		FileName:           b.FileName,
+		Bank:               b.Bank,
+		Checksum:           b.Checksum,
+		AccountID:          b.AccountID,
+		RowCount:           b.RowCount,
+		InsertedCount:      b.InsertedCount,
+		SkippedCount:       b.SkippedCount,
+		Status:             b.Status,
+		Error:              b.ErrorMessage,
+		CreatedAt:          b.CreatedAt,
+		CompletedAt:        b.CompletedAt,
+		RolledBackAt:       b.RolledBackAt,
+		ClosingBalance:     b.ClosingBalance,
+		AvailableBalance:   b.AvailableBalance,
+		BalanceDate:        b.BalanceDate,
+		OpeningBalance:     b.OpeningBalance,
+		OpeningBalanceDate: b.OpeningBalanceDate,
+	}
+}
+
+func NewListImportsHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		batches, err := transactionService.ListImportBatches(r.Context())
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch imports", err.Error())
+			return
+		}
+
+		responses := make([]ImportBatchResponse, 0, len(batches))
+		for _, b := range batches {
+			responses = append(responses, FromImportBatch(b))
+		}
+
+		respondWithJSON(w, http.StatusOK, responses)
+	}
+}
+
+func NewRollbackImportHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid import id", err.Error())
+			return
+		}
+
+		deleted, err := transactionService.RollbackImportBatch(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Rollback failed", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, RollbackResponse{
+			Message: fmt.Sprintf("Rolled back import %d", id),
+			Deleted: deleted,
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListImports_Success(t *testing.T) {
+	completedAt := time.Date(2026, 1, 20, 9, 30, 1, 0, time.UTC)
+	mock := &mockTransactionService{
+		listImportsFunc: func(ctx context.Context) ([]transaction.ImportBatch, error) {
+			return []transaction.ImportBatch{
+				{
+					ID:            3,
+					FileName:      "statement.csv",
+					Bank:          "Nationwide",
+					Checksum:      "abc123",
+					RowCount:      5,
+					InsertedCount: 4,
+					SkippedCount:  1,
+					Status:        transaction.ImportStatusCompleted,
+					CreatedAt:     time.Date(2026, 1, 20, 9, 30, 0, 0, time.UTC),
+					CompletedAt:   &completedAt,
+				},
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/imports/batches", nil)
+	rec := httptest.NewRecorder()
+
+	NewListImportsHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{
+			"id": 3,
+			"file_name": "statement.csv",
+			"bank": "Nationwide",
+			"checksum": "abc123",
+			"account_id": null,
+			"row_count": 5,
+			"inserted_count": 4,
+			"skipped_count": 1,
+			"status": "completed",
+			"error": null,
+			"created_at": "2026-01-20T09:30:00Z",
+			"completed_at": "2026-01-20T09:30:01Z",
+			"rolled_back_at": null,
+			"closing_balance": null,
+			"available_balance": null,
+			"balance_date": null,
+			"opening_balance": null,
+			"opening_balance_date": null
+		}
+	]`, rec.Body.String())
+}
+
+func TestListImports_Empty(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/imports/batches", nil)
+	rec := httptest.NewRecorder()
+
+	NewListImportsHandler(&mockTransactionService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+}
+
+func TestRollbackImport_Success(t *testing.T) {
+	mock := &mockTransactionService{
+		rollbackImportFunc: func(ctx context.Context, id int32) (int64, error) {
+			assert.Equal(t, int32(3), id)
+			return 4, nil
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/imports/batches/3", nil), "id", "3")
+	rec := httptest.NewRecorder()
+
+	NewRollbackImportHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response RollbackResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), response.Deleted)
+}
+
+func TestRollbackImport_NotFound(t *testing.T) {
+	mock := &mockTransactionService{
+		rollbackImportFunc: func(ctx context.Context, id int32) (int64, error) {
+			return 0, transaction.ErrImportNotFound
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/imports/batches/3", nil), "id", "3")
+	rec := httptest.NewRecorder()
+
+	NewRollbackImportHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRollbackImport_AlreadyRolledBack(t *testing.T) {
+	mock := &mockTransactionService{
+		rollbackImportFunc: func(ctx context.Context, id int32) (int64, error) {
+			return 0, fmt.Errorf("%w: import 3 is rolled_back", transaction.ErrImportConflict)
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/imports/batches/3", nil), "id", "3")
+	rec := httptest.NewRecorder()
+
+	NewRollbackImportHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestRollbackImport_InvalidID(t *testing.T) {
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/imports/batches/x", nil), "id", "x")
+	rec := httptest.NewRecorder()
+
+	NewRollbackImportHandler(&mockTransactionService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kushturner/finances/internal/csvparser"
+)
+
+// maxProfileSize bounds a profile upload; real profiles are a few hundred
+// bytes.
+const maxProfileSize = 64 << 10
+
+type ParserProfileResponse struct {
+	csvparser.Profile
+	BuiltIn bool `json:"built_in"`
+}
+
+func FromParserProfile(p csvparser.Profile) ParserProfileResponse {
+	return ParserProfileResponse{Profile: p, BuiltIn: p.BuiltIn()}
+}
+
+func NewListParserProfilesHandler(parserService csvparser.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profiles := parserService.Profiles()
+
+		responses := make([]ParserProfileResponse, 0, len(profiles))
+		for _, p := range profiles {
+			responses = append(responses, FromParserProfile(p))
+		}
+
+		respondWithJSON(w, http.StatusOK, responses)
+	}
+}
+
+// NewCreateParserProfileHandler accepts a profile as JSON or YAML.
+func NewCreateParserProfileHandler(parserService csvparser.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profile, err := csvparser.DecodeProfile(http.MaxBytesReader(w, r.Body, maxProfileSize))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		if err := parserService.AddProfile(profile); err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to save parser profile", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, FromParserProfile(profile))
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProfileYAML = `
+name: test-bank
+institution: Test Bank
+date_format: "02/01/2006"
+columns:
+  date: Date
+  description: Description
+  amount: Amount
+`
+
+func TestListParserProfiles_Success(t *testing.T) {
+	mock := &mockParserService{
+		profilesFunc: func() []csvparser.Profile {
+			return csvparser.NewService().Profiles()[1:2]
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/parser-profiles", nil)
+	rec := httptest.NewRecorder()
+
+	NewListParserProfilesHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{
+			"name": "amex",
+			"institution": "American Express",
+			"account_type": "credit",
+			"date_format": "02/01/2006",
+			"sign_convention": "outflow_positive",
+			"preamble": {},
+			"columns": {
+				"date": "Date",
+				"description": "Description",
+				"amount": "Amount",
+				"category": "Category",
+				"reference": "Reference"
+			},
+			"detect": {"any_columns": ["Card Member", "Extended Details"]},
+			"built_in": true
+		}
+	]`, rec.Body.String())
+}
+
+func TestCreateParserProfile_YAML(t *testing.T) {
+	var added csvparser.Profile
+	mock := &mockParserService{
+		addProfileFunc: func(profile csvparser.Profile) error {
+			added = profile
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/parser-profiles", strings.NewReader(testProfileYAML))
+	req.Header.Set("Content-Type", "application/yaml")
+	rec := httptest.NewRecorder()
+
+	NewCreateParserProfileHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "test-bank", added.Name)
+	assert.Equal(t, "Amount", added.Columns.Amount)
+	assert.Contains(t, rec.Body.String(), `"built_in":false`)
+}
+
+func TestCreateParserProfile_JSON(t *testing.T) {
+	var added csvparser.Profile
+	mock := &mockParserService{
+		addProfileFunc: func(profile csvparser.Profile) error {
+			added = profile
+			return nil
+		},
+	}
+
+	body := `{"name": "test-bank", "institution": "Test Bank", "date_format": "2006-01-02",
+		"columns": {"date": "Date", "description": "Payee", "paid_in": "In", "paid_out": "Out"}}`
+	req := httptest.NewRequest(http.MethodPost, "/parser-profiles", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	NewCreateParserProfileHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "Payee", added.Columns.Description)
+}
+
+func TestCreateParserProfile_MalformedBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/parser-profiles", strings.NewReader("name: [unterminated"))
+	rec := httptest.NewRecorder()
+
+	NewCreateParserProfileHandler(&mockParserService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCreateParserProfile_Invalid(t *testing.T) {
+	mock := &mockParserService{
+		addProfileFunc: func(profile csvparser.Profile) error {
+			return fmt.Errorf("%w: institution is required", csvparser.ErrInvalidProfile)
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/parser-profiles", strings.NewReader(testProfileYAML))
+	rec := httptest.NewRecorder()
+
+	NewCreateParserProfileHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "institution is required")
+}
+
+func TestCreateParserProfile_BuiltInConflict(t *testing.T) {
+	mock := &mockParserService{
+		addProfileFunc: func(profile csvparser.Profile) error {
+			return fmt.Errorf("%w: amex is a built-in format", csvparser.ErrProfileConflict)
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/parser-profiles", strings.NewReader(testProfileYAML))
+	rec := httptest.NewRecorder()
+
+	NewCreateParserProfileHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+func NewGetTransactionHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid transaction id", err.Error())
+			return
+		}
+
+		tx, err := transactionService.GetTransaction(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch transaction", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromTransaction(tx))
+	}
+}
+
+func NewCreateTransactionHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TransactionRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		tx, err := req.ToTransaction()
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Invalid transaction", err.Error())
+			return
+		}
+
+		created, err := transactionService.CreateTransaction(r.Context(), tx)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to create transaction", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, FromTransaction(created))
+	}
+}
+
+func NewUpdateTransactionHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid transaction id", err.Error())
+			return
+		}
+
+		var req TransactionRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		tx, err := req.ToTransaction()
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Invalid transaction", err.Error())
+			return
+		}
+		tx.ID = id
+
+		updated, err := transactionService.UpdateTransaction(r.Context(), tx)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to update transaction", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromTransaction(updated))
+	}
+}
+
+func NewPatchTransactionHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid transaction id", err.Error())
+			return
+		}
+
+		var req PatchTransactionRequest
+		if err := decodeJSONBody(r, &req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		existing, err := transactionService.GetTransaction(r.Context(), id)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to fetch transaction", err.Error())
+			return
+		}
+
+		tx, err := req.Apply(existing)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Invalid transaction", err.Error())
+			return
+		}
+
+		updated, err := transactionService.UpdateTransaction(r.Context(), tx)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to update transaction", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, FromTransaction(updated))
+	}
+}
+
+func NewDeleteTransactionHandler(transactionService transaction.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseIDParam(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid transaction id", err.Error())
+			return
+		}
+
+		if err := transactionService.DeleteTransaction(r.Context(), id); err != nil {
+			respondWithError(w, determineStatusCode(err), "Failed to delete transaction", err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func parseIDParam(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil || id <= 0 {
+		return 0, errors.New("id must be a positive integer")
+	}
+	return int32(id), nil
+}
+
+// parseOptionalID reads an id from a query parameter, returning nil when
+// the parameter is absent.
+func parseOptionalID(value string) (*int32, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || id <= 0 {
+		return nil, errors.New("id must be a positive integer")
+	}
+	id32 := int32(id)
+	return &id32, nil
+}
+
+func decodeJSONBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
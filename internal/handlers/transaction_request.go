@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+const requestDateLayout = "2006-01-02"
+
+// TransactionRequest is a whole transaction, so a PUT clears the optional
+// fields it leaves out. LocalAmount is in minor units of LocalCurrency, and
+// one is required with the other.
+type TransactionRequest struct {
+	Date             string  `json:"date"`
+	Description      string  `json:"description"`
+	Amount           *int64  `json:"amount"`
+	Currency         string  `json:"currency"`
+	Bank             string  `json:"bank"`
+	Category         *string `json:"category"`
+	Notes            *string `json:"notes"`
+	Address          *string `json:"address"`
+	LocalAmount      *int64  `json:"local_amount"`
+	LocalCurrency    *string `json:"local_currency"`
+	LinkedExternalID *string `json:"linked_external_id"`
+	AccountID        *int32  `json:"account_id"`
+}
+
+func (req TransactionRequest) ToTransaction() (transaction.Transaction, error) {
+	date, err := parseRequestDate(req.Date)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+	if req.Amount == nil {
+		return transaction.Transaction{}, fmt.Errorf("%w: amount is required", transaction.ErrValidation)
+	}
+	localAmount, err := localMoney(req.LocalAmount, req.LocalCurrency)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	return transaction.Transaction{
+		Date:             date,
+		Description:      strings.TrimSpace(req.Description),
+		Amount:           money.New(*req.Amount, currencyOrDefault(req.Currency)),
+		LocalAmount:      localAmount,
+		Bank:             strings.TrimSpace(req.Bank),
+		Category:         req.Category,
+		Notes:            req.Notes,
+		Address:          req.Address,
+		LinkedExternalID: req.LinkedExternalID,
+		AccountID:        req.AccountID,
+	}, nil
+}
+
+// PatchTransactionRequest only changes the fields present in the body.
+// Category, notes, address, local_amount, linked_external_id and
+// account_id can be cleared by sending null; clearing local_amount clears
+// its currency too.
+type PatchTransactionRequest struct {
+	Date             *string        `json:"date"`
+	Description      *string        `json:"description"`
+	Amount           *int64         `json:"amount"`
+	Currency         *string        `json:"currency"`
+	Bank             *string        `json:"bank"`
+	Category         optionalString `json:"category"`
+	Notes            optionalString `json:"notes"`
+	Address          optionalString `json:"address"`
+	LocalAmount      optionalInt64  `json:"local_amount"`
+	LocalCurrency    optionalString `json:"local_currency"`
+	LinkedExternalID optionalString `json:"linked_external_id"`
+	AccountID        optionalInt32  `json:"account_id"`
+}
+
+func (req PatchTransactionRequest) Apply(tx transaction.Transaction) (transaction.Transaction, error) {
+	if req.Date != nil {
+		date, err := parseRequestDate(*req.Date)
+		if err != nil {
+			return transaction.Transaction{}, err
+		}
+		tx.Date = date
+	}
+	if req.Description != nil {
+		tx.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Amount != nil || req.Currency != nil {
+		amount := tx.Amount.Amount()
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		currency := tx.Amount.Currency().Code
+		if req.Currency != nil {
+			currency = currencyOrDefault(*req.Currency)
+		}
+		tx.Amount = money.New(amount, currency)
+	}
+	if req.Bank != nil {
+		tx.Bank = strings.TrimSpace(*req.Bank)
+	}
+	if req.Category.Set {
+		tx.Category = req.Category.Value
+	}
+	if req.Notes.Set {
+		tx.Notes = req.Notes.Value
+	}
+	if req.Address.Set {
+		tx.Address = req.Address.Value
+	}
+	if req.LocalAmount.Set || req.LocalCurrency.Set {
+		localAmount, err := req.patchLocalAmount(tx.LocalAmount)
+		if err != nil {
+			return transaction.Transaction{}, err
+		}
+		tx.LocalAmount = localAmount
+	}
+	if req.LinkedExternalID.Set {
+		tx.LinkedExternalID = req.LinkedExternalID.Value
+	}
+	if req.AccountID.Set {
+		tx.AccountID = req.AccountID.Value
+	}
+	return tx, nil
+}
+
+// patchLocalAmount applies the local amount and currency in the body to
+// the transaction's current local amount.
+func (req PatchTransactionRequest) patchLocalAmount(current *money.Money) (*money.Money, error) {
+	var amount *int64
+	var currency *string
+	if current != nil {
+		value, code := current.Amount(), current.Currency().Code
+		amount, currency = &value, &code
+	}
+	if req.LocalAmount.Set {
+		amount = req.LocalAmount.Value
+		if amount == nil && !req.LocalCurrency.Set {
+			currency = nil
+		}
+	}
+	if req.LocalCurrency.Set {
+		currency = req.LocalCurrency.Value
+	}
+	return localMoney(amount, currency)
+}
+
+// localMoney is the local amount of a request, which needs both its amount
+// and its currency or neither.
+func localMoney(amount *int64, currency *string) (*money.Money, error) {
+	hasCurrency := currency != nil && strings.TrimSpace(*currency) != ""
+	switch {
+	case amount == nil && !hasCurrency:
+		return nil, nil
+	case amount == nil:
+		return nil, fmt.Errorf("%w: local_amount is required with local_currency", transaction.ErrValidation)
+	case !hasCurrency:
+		return nil, fmt.Errorf("%w: local_currency is required with local_amount", transaction.ErrValidation)
+	}
+	return money.New(*amount, strings.ToUpper(strings.TrimSpace(*currency))), nil
+}
+
+type optionalString struct {
+	Set   bool
+	Value *string
+}
+
+func (o *optionalString) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+type optionalInt64 struct {
+	Set   bool
+	Value *int64
+}
+
+func (o *optionalInt64) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+type optionalInt32 struct {
+	Set   bool
+	Value *int32
+}
+
+func (o *optionalInt32) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+func parseRequestDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: date is required", transaction.ErrValidation)
+	}
+	date, err := time.Parse(requestDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date must be in YYYY-MM-DD format", transaction.ErrValidation)
+	}
+	return date, nil
+}
+
+func currencyOrDefault(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return "GBP"
+	}
+	return code
+}
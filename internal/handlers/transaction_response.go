@@ -3,27 +3,68 @@ package handlers
 import (
 	"time"
 
+	"github.com/Rhymond/go-money"
 	"github.com/kushturner/finances/internal/transaction"
 )
 
 type TransactionResponse struct {
-	ID          int32     `json:"id"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
-	Amount      int64     `json:"amount"`
-	Currency    string    `json:"currency"`
-	Bank        string    `json:"bank"`
-	Category    *string   `json:"category"`
+	ID             int32     `json:"id"`
+	Date           time.Time `json:"date"`
+	Description    string    `json:"description"`
+	Amount         int64     `json:"amount"`
+	Currency       string    `json:"currency"`
+	RunningBalance *int64    `json:"running_balance"`
+	// LocalAmount is in minor units of LocalCurrency, for purchases made
+	// in another currency.
+	LocalAmount   *int64  `json:"local_amount"`
+	LocalCurrency *string `json:"local_currency"`
+	Bank          string  `json:"bank"`
+	Category      *string `json:"category"`
+	Notes         *string `json:"notes"`
+	Address       *string `json:"address"`
+	// ExternalID is the bank's reference for the transaction, and
+	// LinkedExternalID that of the transaction it belongs to, such as the
+	// payment a fee was charged for. LinkedTransactionID is the
+	// transaction on another account that funded it.
+	ExternalID          *string `json:"external_id"`
+	LinkedExternalID    *string `json:"linked_external_id"`
+	LinkedTransactionID *int32  `json:"linked_transaction_id"`
+	AccountID           *int32  `json:"account_id"`
 }
 
 func FromTransaction(t transaction.Transaction) TransactionResponse {
 	return TransactionResponse{
-		ID:          t.ID,
-		Date:        t.Date,
-		Description: t.Description,
-		Amount:      t.Amount.Amount(),
-		Currency:    t.Amount.Currency().Code,
-		Bank:        t.Bank,
-		Category:    t.Category,
+		ID:                  t.ID,
+		Date:                t.Date,
+		Description:         t.Description,
+		Amount:              t.Amount.Amount(),
+		Currency:            t.Amount.Currency().Code,
+		RunningBalance:      amountOrNil(t.RunningBalance),
+		LocalAmount:         amountOrNil(t.LocalAmount),
+		LocalCurrency:       currencyOrNil(t.LocalAmount),
+		Bank:                t.Bank,
+		Category:            t.Category,
+		Notes:               t.Notes,
+		Address:             t.Address,
+		ExternalID:          t.ExternalID,
+		LinkedExternalID:    t.LinkedExternalID,
+		LinkedTransactionID: t.LinkedTransactionID,
+		AccountID:           t.AccountID,
 	}
 }
+
+func currencyOrNil(m *money.Money) *string {
+	if m == nil {
+		return nil
+	}
+	code := m.Currency().Code
+	return &code
+}
+
+func amountOrNil(m *money.Money) *int64 {
+	if m == nil {
+		return nil
+	}
+	amount := m.Amount()
+	return &amount
+}
@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/go-chi/chi/v5"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func withURLParam(req *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func sampleTransaction() transaction.Transaction {
+	category := "groceries"
+	return transaction.Transaction{
+		ID:          5,
+		Date:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Description: "Grocery store",
+		Amount:      money.New(-5000, "GBP"),
+		Bank:        "Nationwide",
+		Category:    &category,
+	}
+}
+
+func TestGetTransaction_Success(t *testing.T) {
+	mock := &mockTransactionService{
+		getTransactionFunc: func(ctx context.Context, id int32) (transaction.Transaction, error) {
+			assert.Equal(t, int32(5), id)
+			return sampleTransaction(), nil
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/transactions/5", nil), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewGetTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"id": 5,
+		"date": "2024-01-15T00:00:00Z",
+		"description": "Grocery store",
+		"amount": -5000,
+		"currency": "GBP",
+		"running_balance": null,
+		"local_amount": null,
+		"local_currency": null,
+		"bank": "Nationwide",
+		"category": "groceries",
+		"notes": null,
+		"address": null,
+		"external_id": null,
+		"linked_external_id": null,
+		"linked_transaction_id": null,
+		"account_id": null
+	}`, rec.Body.String())
+}
+
+func TestGetTransaction_NotFound(t *testing.T) {
+	mock := &mockTransactionService{
+		getTransactionFunc: func(ctx context.Context, id int32) (transaction.Transaction, error) {
+			return transaction.Transaction{}, transaction.ErrNotFound
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/transactions/5", nil), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewGetTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetTransaction_InvalidID(t *testing.T) {
+	mock := &mockTransactionService{}
+
+	req := withURLParam(httptest.NewRequest(http.MethodGet, "/transactions/abc", nil), "id", "abc")
+	rec := httptest.NewRecorder()
+
+	NewGetTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var response ErrorResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid transaction id", response.Error)
+}
+
+func TestCreateTransaction_Success(t *testing.T) {
+	mock := &mockTransactionService{
+		createTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), tx.Date)
+			assert.Equal(t, "Market stall", tx.Description)
+			assert.Equal(t, int64(-1200), tx.Amount.Amount())
+			assert.Equal(t, "GBP", tx.Amount.Currency().Code)
+			assert.Equal(t, "Cash", tx.Bank)
+			assert.Nil(t, tx.Category)
+			tx.ID = 11
+			return tx, nil
+		},
+	}
+
+	body := `{"date":"2024-02-01","description":"Market stall","amount":-1200,"bank":"Cash"}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	NewCreateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var response TransactionResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(11), response.ID)
+}
+
+func TestCreateTransaction_MissingAmount(t *testing.T) {
+	mock := &mockTransactionService{}
+
+	body := `{"date":"2024-02-01","description":"Market stall","bank":"Cash"}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	NewCreateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestCreateTransaction_InvalidDate(t *testing.T) {
+	mock := &mockTransactionService{}
+
+	body := `{"date":"01/02/2024","description":"Market stall","amount":-1200,"bank":"Cash"}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	NewCreateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestCreateTransaction_MalformedJSON(t *testing.T) {
+	mock := &mockTransactionService{}
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(`{"date":`))
+	rec := httptest.NewRecorder()
+
+	NewCreateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCreateTransaction_ValidationError(t *testing.T) {
+	mock := &mockTransactionService{
+		createTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			return transaction.Transaction{}, transaction.ErrValidation
+		},
+	}
+
+	body := `{"date":"2024-02-01","description":"","amount":-1200,"bank":"Cash"}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	NewCreateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestUpdateTransaction_Success(t *testing.T) {
+	mock := &mockTransactionService{
+		updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			assert.Equal(t, int32(5), tx.ID)
+			assert.Equal(t, "EUR", tx.Amount.Currency().Code)
+			return tx, nil
+		},
+	}
+
+	body := `{"date":"2024-02-01","description":"Hotel","amount":-15000,"currency":"eur","bank":"Amex","category":"travel"}`
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewUpdateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUpdateTransaction_SetsDetails(t *testing.T) {
+	mock := &mockTransactionService{
+		updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			assert.Equal(t, "Paid in cash", *tx.Notes)
+			assert.Equal(t, "1 Rue de Rivoli", *tx.Address)
+			assert.Equal(t, int64(-17500), tx.LocalAmount.Amount())
+			assert.Equal(t, "EUR", tx.LocalAmount.Currency().Code)
+			assert.Equal(t, "TX-1", *tx.LinkedExternalID)
+			return tx, nil
+		},
+	}
+
+	body := `{"date":"2024-02-01","description":"Hotel","amount":-15000,"bank":"Amex",` +
+		`"notes":"Paid in cash","address":"1 Rue de Rivoli","local_amount":-17500,"local_currency":"eur","linked_external_id":"TX-1"}`
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewUpdateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response TransactionResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, int64(-17500), *response.LocalAmount)
+	assert.Equal(t, "EUR", *response.LocalCurrency)
+}
+
+func TestUpdateTransaction_ClearsDetailsLeftOut(t *testing.T) {
+	mock := &mockTransactionService{
+		updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			assert.Nil(t, tx.Notes)
+			assert.Nil(t, tx.Address)
+			assert.Nil(t, tx.LocalAmount)
+			assert.Nil(t, tx.LinkedExternalID)
+			return tx, nil
+		},
+	}
+
+	body := `{"date":"2024-02-01","description":"Hotel","amount":-15000,"bank":"Amex"}`
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewUpdateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUpdateTransaction_LocalAmountNeedsCurrency(t *testing.T) {
+	body := `{"date":"2024-02-01","description":"Hotel","amount":-15000,"bank":"Amex","local_amount":-17500}`
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewUpdateTransactionHandler(&mockTransactionService{})(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestUpdateTransaction_NotFound(t *testing.T) {
+	mock := &mockTransactionService{
+		updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			return transaction.Transaction{}, transaction.ErrNotFound
+		},
+	}
+
+	body := `{"date":"2024-02-01","description":"Hotel","amount":-15000,"bank":"Amex"}`
+	req := withURLParam(httptest.NewRequest(http.MethodPut, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewUpdateTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPatchTransaction_UpdatesOnlyProvidedFields(t *testing.T) {
+	mock := &mockTransactionService{
+		getTransactionFunc: func(ctx context.Context, id int32) (transaction.Transaction, error) {
+			return sampleTransaction(), nil
+		},
+		updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			assert.Equal(t, int32(5), tx.ID)
+			assert.Equal(t, "Grocery store", tx.Description)
+			assert.Equal(t, int64(-5000), tx.Amount.Amount())
+			assert.NotNil(t, tx.Category)
+			assert.Equal(t, "household", *tx.Category)
+			return tx, nil
+		},
+	}
+
+	body := `{"category":"household"}`
+	req := withURLParam(httptest.NewRequest(http.MethodPatch, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewPatchTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPatchTransaction_ClearsCategoryWithNull(t *testing.T) {
+	mock := &mockTransactionService{
+		getTransactionFunc: func(ctx context.Context, id int32) (transaction.Transaction, error) {
+			return sampleTransaction(), nil
+		},
+		updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+			assert.Nil(t, tx.Category)
+			return tx, nil
+		},
+	}
+
+	body := `{"category":null}`
+	req := withURLParam(httptest.NewRequest(http.MethodPatch, "/transactions/5", strings.NewReader(body)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewPatchTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPatchTransaction_Details(t *testing.T) {
+	stored := sampleTransaction()
+	notes, linked := "Paid in cash", "TX-1"
+	stored.Notes = &notes
+	stored.LinkedExternalID = &linked
+	stored.LocalAmount = money.New(-5800, "EUR")
+
+	tests := []struct {
+		name  string
+		body  string
+		check func(t *testing.T, tx transaction.Transaction)
+	}{
+		{"keeps details left out", `{"category":"household"}`, func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, "Paid in cash", *tx.Notes)
+			assert.Equal(t, "TX-1", *tx.LinkedExternalID)
+			assert.Equal(t, int64(-5800), tx.LocalAmount.Amount())
+		}},
+		{"sets address", `{"address":"1 High St"}`, func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, "1 High St", *tx.Address)
+		}},
+		{"clears with null", `{"notes":null,"linked_external_id":null,"local_amount":null}`, func(t *testing.T, tx transaction.Transaction) {
+			assert.Nil(t, tx.Notes)
+			assert.Nil(t, tx.LinkedExternalID)
+			assert.Nil(t, tx.LocalAmount)
+		}},
+		{"changes local amount only", `{"local_amount":-6000}`, func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, int64(-6000), tx.LocalAmount.Amount())
+			assert.Equal(t, "EUR", tx.LocalAmount.Currency().Code)
+		}},
+		{"changes local currency only", `{"local_currency":"usd"}`, func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, int64(-5800), tx.LocalAmount.Amount())
+			assert.Equal(t, "USD", tx.LocalAmount.Currency().Code)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockTransactionService{
+				getTransactionFunc: func(ctx context.Context, id int32) (transaction.Transaction, error) {
+					return stored, nil
+				},
+				updateTransactionFunc: func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+					tt.check(t, tx)
+					return tx, nil
+				},
+			}
+			req := withURLParam(httptest.NewRequest(http.MethodPatch, "/transactions/5", strings.NewReader(tt.body)), "id", "5")
+			rec := httptest.NewRecorder()
+
+			NewPatchTransactionHandler(mock)(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestPatchTransaction_NotFound(t *testing.T) {
+	mock := &mockTransactionService{
+		getTransactionFunc: func(ctx context.Context, id int32) (transaction.Transaction, error) {
+			return transaction.Transaction{}, transaction.ErrNotFound
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodPatch, "/transactions/5", strings.NewReader(`{}`)), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewPatchTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeleteTransaction_Success(t *testing.T) {
+	mock := &mockTransactionService{
+		deleteTransactionFunc: func(ctx context.Context, id int32) error {
+			assert.Equal(t, int32(5), id)
+			return nil
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/transactions/5", nil), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewDeleteTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestDeleteTransaction_NotFound(t *testing.T) {
+	mock := &mockTransactionService{
+		deleteTransactionFunc: func(ctx context.Context, id int32) error {
+			return transaction.ErrNotFound
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/transactions/5", nil), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewDeleteTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDeleteTransaction_DatabaseError(t *testing.T) {
+	mock := &mockTransactionService{
+		deleteTransactionFunc: func(ctx context.Context, id int32) error {
+			return transaction.ErrDatabaseFailure
+		},
+	}
+
+	req := withURLParam(httptest.NewRequest(http.MethodDelete, "/transactions/5", nil), "id", "5")
+	rec := httptest.NewRecorder()
+
+	NewDeleteTransactionHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -1,25 +1,56 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kushturner/finances/internal/transaction"
 )
 
+const NextCursorHeader = "X-Next-Cursor"
+
+// NewListTransactionsHandler serves GET /transactions. A request with neither
+// limit nor cursor returns every matching transaction, as it did before
+// pagination; passing either one pages the results and sets X-Next-Cursor.
 func NewListTransactionsHandler(transactionService transaction.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		transactions, err := transactionService.GetAllTransactions(r.Context())
+		query := r.URL.Query()
+		opts, err := parseListOptions(query)
 		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters", err.Error())
+			return
+		}
+
+		var page transaction.Page
+		if !query.Has("limit") && !query.Has("cursor") {
+			page.Transactions, err = listAllTransactions(r.Context(), transactionService, opts)
+		} else {
+			page, err = transactionService.ListTransactions(r.Context(), opts)
+		}
+		if err != nil {
+			if errors.Is(err, transaction.ErrValidation) {
+				respondWithError(w, http.StatusBadRequest, "Invalid query parameters", err.Error())
+				return
+			}
 			http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
 			return
 		}
 
-		responses := make([]TransactionResponse, 0, len(transactions))
-		for _, tx := range transactions {
+		responses := make([]TransactionResponse, 0, len(page.Transactions))
+		for _, tx := range page.Transactions {
 			responses = append(responses, FromTransaction(tx))
 		}
 
+		if page.NextCursor != "" {
+			w.Header().Set(NextCursorHeader, page.NextCursor)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(responses); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
@@ -27,3 +58,94 @@ func NewListTransactionsHandler(transactionService transaction.Service) http.Han
 		}
 	}
 }
+
+// parseListOptions reads the filters for GET /transactions. Amounts are in
+// minor units and dates use YYYY-MM-DD, matching the request bodies.
+func parseListOptions(query url.Values) (transaction.ListOptions, error) {
+	var opts transaction.ListOptions
+
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &opts.DateFrom}, {"to", &opts.DateTo}} {
+		if value := query.Get(param.name); value != "" {
+			date, err := time.Parse(requestDateLayout, value)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be in YYYY-MM-DD format", param.name)
+			}
+			*param.dst = &date
+		}
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  **int64
+	}{{"min_amount", &opts.MinAmount}, {"max_amount", &opts.MaxAmount}} {
+		if value := query.Get(param.name); value != "" {
+			amount, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be an integer amount in minor units", param.name)
+			}
+			*param.dst = &amount
+		}
+	}
+
+	if bank := query.Get("bank"); bank != "" {
+		opts.Bank = &bank
+	}
+	if category := query.Get("category"); category != "" {
+		opts.Category = &category
+	}
+	accountID, err := parseOptionalID(query.Get("account_id"))
+	if err != nil {
+		return opts, fmt.Errorf("account_id: %w", err)
+	}
+	opts.AccountID = accountID
+	opts.Search = query.Get("q")
+
+	if sort := query.Get("sort"); sort != "" {
+		opts.SortBy = transaction.SortField(strings.ToLower(sort))
+		opts.SortDesc = true
+	}
+	switch strings.ToLower(query.Get("order")) {
+	case "":
+	case "asc":
+		opts.SortDesc = false
+		if opts.SortBy == "" {
+			opts.SortBy = transaction.SortByDate
+		}
+	case "desc":
+		opts.SortDesc = true
+	default:
+		return opts, fmt.Errorf("order must be asc or desc")
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || limit <= 0 {
+			return opts, fmt.Errorf("limit must be a positive integer")
+		}
+		opts.Limit = int32(limit)
+	}
+	opts.Cursor = query.Get("cursor")
+
+	return opts, nil
+}
+
+// listAllTransactions follows the list cursor until every matching
+// transaction has been read.
+func listAllTransactions(ctx context.Context, transactionService transaction.Service, opts transaction.ListOptions) ([]transaction.Transaction, error) {
+	opts.Limit = transaction.MaxPageLimit
+	var transactions []transaction.Transaction
+	for {
+		page, err := transactionService.ListTransactions(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, page.Transactions...)
+		if page.NextCursor == "" {
+			return transactions, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -13,22 +16,176 @@ import (
 )
 
 type mockTransactionService struct {
-	transactions        []transaction.Transaction
-	err                 error
-	addTransactionsFunc func(ctx context.Context, transactions []transaction.Transaction) (int64, error)
+	transactions          []transaction.Transaction
+	err                   error
+	listTransactionsFunc  func(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error)
+	addTransactionsFunc   func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error)
+	addStatementsFunc     func(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error)
+	addStreamFunc         func(ctx context.Context, statement transaction.StreamImport) (transaction.ImportResult, error)
+	findDuplicatesFunc    func(ctx context.Context, transactions []transaction.Transaction) ([]bool, error)
+	listImportsFunc       func(ctx context.Context) ([]transaction.ImportBatch, error)
+	rollbackImportFunc    func(ctx context.Context, id int32) (int64, error)
+	getTransactionFunc    func(ctx context.Context, id int32) (transaction.Transaction, error)
+	createTransactionFunc func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error)
+	updateTransactionFunc func(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error)
+	deleteTransactionFunc func(ctx context.Context, id int32) error
 }
 
 func (m *mockTransactionService) GetAllTransactions(ctx context.Context) ([]transaction.Transaction, error) {
 	return m.transactions, m.err
 }
 
-func (m *mockTransactionService) AddTransactions(ctx context.Context, transactions []transaction.Transaction) (int64, error) {
+func (m *mockTransactionService) ListTransactions(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error) {
+	if m.listTransactionsFunc != nil {
+		return m.listTransactionsFunc(ctx, opts)
+	}
+	return transaction.Page{Transactions: m.transactions}, m.err
+}
+
+func (m *mockTransactionService) AddTransactions(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
 	if m.addTransactionsFunc != nil {
-		return m.addTransactionsFunc(ctx, transactions)
+		return m.addTransactionsFunc(ctx, source, transactions)
+	}
+	return transaction.ImportResult{}, nil
+}
+
+// AddStatements starts each statement with startStatement and imports it
+// with AddTransactions unless addStatementsFunc is set.
+func (m *mockTransactionService) AddStatements(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error) {
+	if m.addStatementsFunc != nil {
+		return m.addStatementsFunc(ctx, statements)
+	}
+	results := make([]transaction.ImportResult, 0, len(statements))
+	for _, statement := range statements {
+		source, err := startStatement(ctx, statement)
+		if err != nil {
+			return nil, err
+		}
+		result, err := m.AddTransactions(ctx, source, statement.Transactions)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// AddTransactionStream reads the statement with readStream and imports it
+// with AddTransactions unless addStreamFunc is set.
+func (m *mockTransactionService) AddTransactionStream(ctx context.Context, statement transaction.StreamImport) (transaction.ImportResult, error) {
+	if m.addStreamFunc != nil {
+		return m.addStreamFunc(ctx, statement)
+	}
+	source, read, err := readStream(ctx, statement)
+	if err != nil {
+		return transaction.ImportResult{}, err
+	}
+	return m.AddTransactions(ctx, source, read)
+}
+
+// startStatement runs the Start hook of a statement as the service does,
+// with a nil Querier, and returns its completed source.
+func startStatement(ctx context.Context, statement transaction.StatementImport) (transaction.ImportSource, error) {
+	source := statement.Source
+	if statement.Start == nil {
+		return source, nil
+	}
+	if err := statement.Start(ctx, nil, &source); err != nil {
+		return source, fmt.Errorf("%w: %s", transaction.ErrDatabaseFailure, err)
+	}
+	for i := range statement.Transactions {
+		statement.Transactions[i].AccountID = source.AccountID
+	}
+	return source, nil
+}
+
+// readStream reads a streamed statement as the service does, running its
+// hooks with a nil Querier around the rows, and returns its completed
+// source and its transactions.
+func readStream(ctx context.Context, statement transaction.StreamImport) (transaction.ImportSource, []transaction.Transaction, error) {
+	next, stop := iter.Pull2(statement.Transactions)
+	defer stop()
+
+	source := statement.Source
+	var read []transaction.Transaction
+	for {
+		tx, err, ok := next()
+		if !ok {
+			break
+		}
+		if err != nil {
+			return source, nil, fmt.Errorf("%w: %s", transaction.ErrParseFailure, err)
+		}
+		if len(read) == 0 && statement.Start != nil {
+			if err := statement.Start(ctx, nil, &tx, &source); err != nil {
+				return source, nil, fmt.Errorf("%w: %s", transaction.ErrDatabaseFailure, err)
+			}
+		}
+		tx.AccountID = source.AccountID
+		read = append(read, tx)
+	}
+	if len(read) == 0 && statement.Start != nil {
+		if err := statement.Start(ctx, nil, nil, &source); err != nil {
+			return source, nil, fmt.Errorf("%w: %s", transaction.ErrDatabaseFailure, err)
+		}
+	}
+	if statement.Finish != nil {
+		if err := statement.Finish(ctx, nil, &source); err != nil {
+			return source, nil, fmt.Errorf("%w: %s", transaction.ErrDatabaseFailure, err)
+		}
+	}
+	return source, read, nil
+}
+
+func (m *mockTransactionService) FindDuplicates(ctx context.Context, transactions []transaction.Transaction) ([]bool, error) {
+	if m.findDuplicatesFunc != nil {
+		return m.findDuplicatesFunc(ctx, transactions)
+	}
+	return make([]bool, len(transactions)), nil
+}
+
+func (m *mockTransactionService) ListImportBatches(ctx context.Context) ([]transaction.ImportBatch, error) {
+	if m.listImportsFunc != nil {
+		return m.listImportsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockTransactionService) RollbackImportBatch(ctx context.Context, id int32) (int64, error) {
+	if m.rollbackImportFunc != nil {
+		return m.rollbackImportFunc(ctx, id)
 	}
 	return 0, nil
 }
 
+func (m *mockTransactionService) GetTransaction(ctx context.Context, id int32) (transaction.Transaction, error) {
+	if m.getTransactionFunc != nil {
+		return m.getTransactionFunc(ctx, id)
+	}
+	return transaction.Transaction{}, nil
+}
+
+func (m *mockTransactionService) CreateTransaction(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+	if m.createTransactionFunc != nil {
+		return m.createTransactionFunc(ctx, tx)
+	}
+	return tx, nil
+}
+
+func (m *mockTransactionService) UpdateTransaction(ctx context.Context, tx transaction.Transaction) (transaction.Transaction, error) {
+	if m.updateTransactionFunc != nil {
+		return m.updateTransactionFunc(ctx, tx)
+	}
+	return tx, nil
+}
+
+func (m *mockTransactionService) DeleteTransaction(ctx context.Context, id int32) error {
+	if m.deleteTransactionFunc != nil {
+		return m.deleteTransactionFunc(ctx, id)
+	}
+	return nil
+}
+
 func TestListTransactions_EmptyList(t *testing.T) {
 	mock := &mockTransactionService{
 		transactions: []transaction.Transaction{},
@@ -89,8 +246,17 @@ func TestListTransactions_MultipleTransactions(t *testing.T) {
 			"description": "Grocery store",
 			"amount": 5000,
 			"currency": "USD",
+			"running_balance": null,
+			"local_amount": null,
+			"local_currency": null,
 			"bank": "Chase",
-			"category": "groceries"
+			"category": "groceries",
+			"notes": null,
+			"address": null,
+			"external_id": null,
+			"linked_external_id": null,
+			"linked_transaction_id": null,
+			"account_id": null
 		},
 		{
 			"id": 2,
@@ -98,8 +264,17 @@ func TestListTransactions_MultipleTransactions(t *testing.T) {
 			"description": "Coffee shop",
 			"amount": 500,
 			"currency": "GBP",
+			"running_balance": null,
+			"local_amount": null,
+			"local_currency": null,
 			"bank": "Barclays",
-			"category": null
+			"category": null,
+			"notes": null,
+			"address": null,
+			"external_id": null,
+			"linked_external_id": null,
+			"linked_transaction_id": null,
+			"account_id": null
 		}
 	]`
 
@@ -162,8 +337,17 @@ func TestListTransactions_CategoryNullable(t *testing.T) {
 			"description": "With category",
 			"amount": 1000,
 			"currency": "USD",
+			"running_balance": null,
+			"local_amount": null,
+			"local_currency": null,
 			"bank": "Test Bank",
-			"category": "transport"
+			"category": "transport",
+			"notes": null,
+			"address": null,
+			"external_id": null,
+			"linked_external_id": null,
+			"linked_transaction_id": null,
+			"account_id": null
 		},
 		{
 			"id": 2,
@@ -171,10 +355,131 @@ func TestListTransactions_CategoryNullable(t *testing.T) {
 			"description": "Without category",
 			"amount": 2000,
 			"currency": "USD",
+			"running_balance": null,
+			"local_amount": null,
+			"local_currency": null,
 			"bank": "Test Bank",
-			"category": null
+			"category": null,
+			"notes": null,
+			"address": null,
+			"external_id": null,
+			"linked_external_id": null,
+			"linked_transaction_id": null,
+			"account_id": null
 		}
 	]`
 
 	assert.JSONEq(t, expectedJSON, rec.Body.String())
 }
+
+func TestListTransactions_ParsesQueryParameters(t *testing.T) {
+	mock := &mockTransactionService{
+		listTransactionsFunc: func(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error) {
+			assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *opts.DateFrom)
+			assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), *opts.DateTo)
+			assert.Equal(t, "Nationwide", *opts.Bank)
+			assert.Equal(t, "groceries", *opts.Category)
+			assert.Equal(t, int32(2), *opts.AccountID)
+			assert.Equal(t, int64(-5000), *opts.MinAmount)
+			assert.Equal(t, int64(0), *opts.MaxAmount)
+			assert.Equal(t, "tesco", opts.Search)
+			assert.Equal(t, transaction.SortByAmount, opts.SortBy)
+			assert.False(t, opts.SortDesc)
+			assert.Equal(t, int32(25), opts.Limit)
+			assert.Equal(t, "abc", opts.Cursor)
+			return transaction.Page{}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions?from=2024-01-01&to=2024-03-31&bank=Nationwide&category=groceries&account_id=2&min_amount=-5000&max_amount=0&q=tesco&sort=amount&order=asc&limit=25&cursor=abc", nil)
+	rec := httptest.NewRecorder()
+
+	NewListTransactionsHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+}
+
+func TestListTransactions_SetsNextCursorHeader(t *testing.T) {
+	mock := &mockTransactionService{
+		listTransactionsFunc: func(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error) {
+			return transaction.Page{NextCursor: "next-page"}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions?limit=1", nil)
+	rec := httptest.NewRecorder()
+
+	NewListTransactionsHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "next-page", rec.Header().Get(NextCursorHeader))
+}
+
+func TestListTransactions_WithoutLimitReturnsEveryPage(t *testing.T) {
+	var cursors []string
+	mock := &mockTransactionService{
+		listTransactionsFunc: func(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error) {
+			cursors = append(cursors, opts.Cursor)
+			assert.Equal(t, int32(transaction.MaxPageLimit), opts.Limit)
+			if opts.Cursor == "" {
+				return transaction.Page{
+					Transactions: []transaction.Transaction{{ID: 1, Description: "First", Amount: money.New(-100, "GBP"), Bank: "Nationwide"}},
+					NextCursor:   "page-2",
+				}, nil
+			}
+			return transaction.Page{
+				Transactions: []transaction.Transaction{{ID: 2, Description: "Second", Amount: money.New(-200, "GBP"), Bank: "Nationwide"}},
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions", nil)
+	rec := httptest.NewRecorder()
+
+	NewListTransactionsHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"", "page-2"}, cursors)
+	assert.Empty(t, rec.Header().Get(NextCursorHeader))
+
+	var got []TransactionResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "First", got[0].Description)
+		assert.Equal(t, "Second", got[1].Description)
+	}
+}
+
+func TestListTransactions_InvalidDate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/transactions?from=15/01/2024", nil)
+	rec := httptest.NewRecorder()
+
+	NewListTransactionsHandler(&mockTransactionService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListTransactions_InvalidOrder(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/transactions?order=sideways", nil)
+	rec := httptest.NewRecorder()
+
+	NewListTransactionsHandler(&mockTransactionService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListTransactions_ServiceValidationError(t *testing.T) {
+	mock := &mockTransactionService{
+		listTransactionsFunc: func(ctx context.Context, opts transaction.ListOptions) (transaction.Page, error) {
+			return transaction.Page{}, fmt.Errorf("%w: invalid cursor", transaction.ErrValidation)
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/transactions?cursor=bad", nil)
+	rec := httptest.NewRecorder()
+
+	NewListTransactionsHandler(mock)(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
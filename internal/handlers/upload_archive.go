@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kushturner/finances/internal/csvparser"
+)
+
+const (
+	// maxArchiveFiles caps how many statements one archive can hold.
+	maxArchiveFiles = 100
+	// maxArchiveFileSize caps how much one file of an archive is
+	// decompressed to, so a small upload cannot expand without limit.
+	maxArchiveFileSize = 64 << 20
+	// maxArchiveTotalSize caps how much the archives of one upload are
+	// decompressed to between them.
+	maxArchiveTotalSize = 256 << 20
+)
+
+// archiveFile is a statement unpacked from an uploaded archive.
+type archiveFile struct {
+	name string
+	file spooledFile
+}
+
+// isArchive reports whether file is a ZIP archive of statements. An XLSX
+// workbook is a ZIP archive too, but is read as a single statement.
+func isArchive(file spooledFile) bool {
+	f, err := file.open()
+	if err != nil {
+		// Left for the parser to report.
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(csvparser.ZipMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != csvparser.ZipMagic {
+		return false
+	}
+	archive, err := zip.NewReader(f, file.size)
+	if err != nil {
+		// Left for the parser to report as an unreadable spreadsheet.
+		return false
+	}
+	for _, f := range archive.File {
+		if f.Name == "xl/workbook.xml" {
+			return false
+		}
+	}
+	return true
+}
+
+// unpackArchive spools the files of a ZIP archive in archive order,
+// leaving out folders and the hidden files that archiving tools add. The
+// files' sizes are taken from budget, which is shared by every archive of
+// an upload. Nothing is left spooled when it fails.
+func unpackArchive(file spooledFile, budget *int64) (files []archiveFile, err error) {
+	f, err := file.open()
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	defer f.Close()
+	archive, err := zip.NewReader(f, file.size)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			for _, unpacked := range files {
+				unpacked.file.remove()
+			}
+			files = nil
+		}
+	}()
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || isHiddenArchiveFile(f.Name) {
+			continue
+		}
+		if len(files) == maxArchiveFiles {
+			return files, fmt.Errorf("archive holds more than %d files", maxArchiveFiles)
+		}
+
+		spooled, err := spoolArchiveFile(f, budget)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, archiveFile{name: f.Name, file: spooled})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("archive holds no files")
+	}
+	return files, nil
+}
+
+func spoolArchiveFile(f *zip.File, budget *int64) (spooledFile, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return spooledFile{}, fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	limit := min(maxArchiveFileSize, *budget)
+	spooled, err := spool(rc, limit)
+	switch {
+	case errors.Is(err, errTooLarge) && limit == maxArchiveFileSize:
+		return spooledFile{}, fmt.Errorf("%s is larger than %d MB", f.Name, maxArchiveFileSize>>20)
+	case errors.Is(err, errTooLarge):
+		return spooledFile{}, fmt.Errorf("archives unpack to more than %d MB", maxArchiveTotalSize>>20)
+	case err != nil:
+		return spooledFile{}, fmt.Errorf("reading %s: %w", f.Name, err)
+	}
+	*budget -= spooled.size
+	return spooled, nil
+}
+
+// isHiddenArchiveFile reports whether name is metadata such as macOS's
+// __MACOSX folder or a .DS_Store file rather than a statement.
+func isHiddenArchiveFile(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
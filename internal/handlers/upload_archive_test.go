@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/importjob"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedFile struct {
+	name    string
+	content string
+}
+
+// createMultiFileRequest uploads each file as its own "file" part.
+func createMultiFileRequest(t *testing.T, files ...namedFile) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, file := range files {
+		part, err := writer.CreateFormFile("file", file.name)
+		assert.NoError(t, err)
+		_, err = io.WriteString(part, file.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions/upload?bank=", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func zipFiles(t *testing.T, files ...namedFile) string {
+	var b bytes.Buffer
+	archive := zip.NewWriter(&b)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		assert.NoError(t, err)
+		_, err = io.WriteString(w, file.content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, archive.Close())
+	return b.String()
+}
+
+// contentParser returns a statement holding one transaction described by
+// the file's content, and a rejected row for content "bad row".
+func contentParser() *mockParserService {
+	return &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			content, err := io.ReadAll(r)
+			if err != nil {
+				return csvparser.ParseResult{}, err
+			}
+			if string(content) == "not a statement" {
+				return csvparser.ParseResult{}, errors.New("required column not found in CSV headers")
+			}
+			result := csvparser.ParseResult{Statements: []csvparser.Statement{{
+				Format:       "amex",
+				Institution:  "Amex",
+				Transactions: []transaction.Transaction{{Bank: "Amex", Description: string(content), Amount: money.New(-100, "GBP")}},
+			}}}
+			if string(content) == "bad row" {
+				result.Rejected = []csvparser.RowError{{Row: 2, Reason: "bad date"}}
+			}
+			return result, nil
+		},
+	}
+}
+
+// runUploadJob uploads req, which should be queued as a job, and runs the
+// job's work as a worker would, returning the queued job and the progress
+// it last reported.
+func runUploadJob(t *testing.T, transactionService transaction.Service, req *http.Request) (ImportJobResponse, importjob.Progress, error) {
+	t.Helper()
+	return runUploadJobWithAccounts(t, transactionService, &mockAccountService{}, req)
+}
+
+// runUploadJobWithAccounts is runUploadJob with accounts from
+// accountService.
+func runUploadJobWithAccounts(t *testing.T, transactionService transaction.Service, accountService account.Service, req *http.Request) (ImportJobResponse, importjob.Progress, error) {
+	t.Helper()
+	var work importjob.Work
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			assert.Len(t, checksum, 64)
+			work = w
+			return importjob.Job{ID: 1, FileName: fileName, Checksum: checksum, Status: importjob.StatusQueued}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	NewUploadTransactionsHandler(transactionService, contentParser(), accountService, jobs)(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var job ImportJobResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+	var progress importjob.Progress
+	err := work(context.Background(), func(p importjob.Progress) { progress = p })
+	return job, progress, err
+}
+
+func TestUploadTransactionsHandler_SeveralFiles(t *testing.T) {
+	calls := 0
+	mockTxService := &mockTransactionService{
+		addStatementsFunc: func(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error) {
+			calls++
+			assert.Len(t, statements, 2)
+			assert.Equal(t, "jan.csv", statements[0].Source.FileName)
+			assert.Equal(t, "feb.csv", statements[1].Source.FileName)
+			assert.NotEqual(t, statements[0].Source.Checksum, statements[1].Source.Checksum)
+			return []transaction.ImportResult{{BatchID: 1, Inserted: 1}, {BatchID: 2, Skipped: 1}}, nil
+		},
+	}
+
+	req := createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"feb.csv", "bad row"})
+	req.URL.RawQuery += "&mode=lenient"
+	job, progress, err := runUploadJob(t, mockTxService, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "jan.csv, feb.csv", job.FileName)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, importjob.Progress{
+		Statements: 2, StatementsDone: 2, Rows: 2, RowsDone: 2, Inserted: 1, Skipped: 1,
+		ImportIDs: []int32{1, 2},
+		Rejected:  []csvparser.RowError{{Row: 2, Reason: "bad date"}},
+		Files: []importjob.FileResult{
+			{FileName: "jan.csv", Format: "amex", Inserted: 1},
+			{FileName: "feb.csv", Format: "amex", Skipped: 1},
+		},
+	}, progress)
+}
+
+func TestUploadTransactionsHandler_Archive(t *testing.T) {
+	var fileNames []string
+	mockTxService := &mockTransactionService{
+		addStatementsFunc: func(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error) {
+			results := make([]transaction.ImportResult, len(statements))
+			for i, statement := range statements {
+				fileNames = append(fileNames, statement.Source.FileName)
+				results[i] = transaction.ImportResult{BatchID: int32(i + 1), Inserted: 1}
+			}
+			return results, nil
+		},
+	}
+	archive := zipFiles(t,
+		namedFile{"2026/jan.csv", "january"},
+		namedFile{"2026/", ""},
+		namedFile{"__MACOSX/2026/._jan.csv", "metadata"},
+		namedFile{"2026/.DS_Store", "metadata"},
+		namedFile{"2026/feb.csv", "february"},
+	)
+
+	req := createMultiFileRequest(t, namedFile{"statements.zip", archive}, namedFile{"mar.csv", "march"})
+	job, progress, err := runUploadJob(t, mockTxService, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "statements.zip, mar.csv", job.FileName)
+	assert.Equal(t, []string{"statements.zip/2026/jan.csv", "statements.zip/2026/feb.csv", "mar.csv"}, fileNames)
+	assert.Equal(t, int64(3), progress.Inserted)
+	assert.Equal(t, []int32{1, 2, 3}, progress.ImportIDs)
+}
+
+func TestUploadTransactionsHandler_ArchiveOfOneFile(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			assert.Equal(t, "statements.zip/jan.csv", source.FileName)
+			assert.Equal(t, "january", transactions[0].Description)
+			return transaction.ImportResult{BatchID: 1, Inserted: 1}, nil
+		},
+	}
+
+	req := createMultiFileRequest(t, namedFile{"statements.zip", zipFiles(t, namedFile{"jan.csv", "january"})})
+	job, progress, err := runUploadJob(t, mockTxService, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "statements.zip", job.FileName)
+	assert.Equal(t, []int32{1}, progress.ImportIDs)
+}
+
+func TestUploadTransactionsHandler_SpreadsheetIsNotAnArchive(t *testing.T) {
+	workbook := zipFiles(t, namedFile{"[Content_Types].xml", "<Types/>"}, namedFile{"xl/workbook.xml", "<workbook/>"})
+	mockParser := &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			content, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, workbook, string(content))
+			return csvparser.ParseResult{Statements: []csvparser.Statement{{}}}, nil
+		},
+	}
+
+	req := createMultiFileRequest(t, namedFile{"statement.xlsx", workbook})
+	rec := httptest.NewRecorder()
+	NewUploadTransactionsHandler(&mockTransactionService{}, mockParser, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUploadTransactionsHandler_SeveralFilesSkipUnreadable(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addStatementsFunc: func(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error) {
+			if assert.Len(t, statements, 1) {
+				assert.Equal(t, "jan.csv", statements[0].Source.FileName)
+			}
+			return []transaction.ImportResult{{BatchID: 1, Inserted: 1}}, nil
+		},
+	}
+
+	req := createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"notes.txt", "not a statement"})
+	_, progress, err := runUploadJob(t, mockTxService, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{1}, progress.ImportIDs)
+	assert.Equal(t, []importjob.FileResult{
+		{FileName: "jan.csv", Format: "amex", Inserted: 1},
+		{FileName: "notes.txt", Failed: true, Error: "required column not found in CSV headers"},
+	}, progress.Files)
+}
+
+func TestUploadTransactionsHandler_SeveralFilesFailTogether(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addStatementsFunc: func(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error) {
+			return nil, errors.Join(transaction.ErrDatabaseFailure, errors.New("feb.csv: disk full"))
+		},
+	}
+
+	req := createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"feb.csv", "february"})
+	_, progress, err := runUploadJob(t, mockTxService, req)
+
+	assert.EqualError(t, err, "none of the 2 files could be imported")
+	if assert.Len(t, progress.Files, 2) {
+		for _, file := range progress.Files {
+			assert.True(t, file.Failed)
+			assert.Contains(t, file.Error, "feb.csv: disk full")
+		}
+	}
+	assert.Empty(t, progress.ImportIDs)
+}
+
+func TestUploadTransactionsHandler_SeveralFilesResolveAccountsInTransaction(t *testing.T) {
+	var resolved []string
+	mockTxService := &mockTransactionService{
+		addStatementsFunc: func(ctx context.Context, statements []transaction.StatementImport) ([]transaction.ImportResult, error) {
+			assert.Empty(t, resolved, "accounts are resolved in the import's transaction")
+			results := make([]transaction.ImportResult, len(statements))
+			for i, statement := range statements {
+				source, err := startStatement(ctx, statement)
+				if err != nil {
+					return nil, err
+				}
+				assert.Equal(t, int32(1), *source.AccountID)
+				assert.Equal(t, int32(1), *statement.Transactions[0].AccountID)
+				results[i] = transaction.ImportResult{BatchID: int32(i + 1), Inserted: 1}
+			}
+			return results, nil
+		},
+	}
+	accounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			resolved = append(resolved, hint.Institution)
+			return account.Account{ID: 1}, nil
+		},
+	}
+
+	req := createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"feb.csv", "february"})
+	_, progress, err := runUploadJobWithAccounts(t, mockTxService, accounts, req)
+
+	assert.NoError(t, err)
+	assert.Len(t, resolved, 2)
+	assert.Equal(t, []int32{1, 2}, progress.ImportIDs)
+}
+
+func TestUploadTransactionsHandler_InvalidArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		details string
+	}{
+		{"empty", zipFiles(t, namedFile{"__MACOSX/._jan.csv", "metadata"}), "statements.zip: archive holds no files"},
+		{"too many files", zipFiles(t, manyFiles(maxArchiveFiles+1)...), "statements.zip: archive holds more than 100 files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultiFileRequest(t, namedFile{"statements.zip", tt.archive})
+			rec := httptest.NewRecorder()
+			NewUploadTransactionsHandler(&mockTransactionService{}, contentParser(), &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var response ErrorResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.details, response.Details)
+		})
+	}
+}
+
+func TestUnpackArchive_SharesBudgetAcrossArchives(t *testing.T) {
+	budget := int64(10)
+	archive := zipFiles(t, namedFile{"jan.csv", "123456"})
+
+	files, err := unpackArchive(spoolString(t, archive), &budget)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	files[0].file.remove()
+	assert.Equal(t, int64(4), budget)
+
+	_, err = unpackArchive(spoolString(t, archive), &budget)
+	assert.EqualError(t, err, "archives unpack to more than 256 MB")
+}
+
+func manyFiles(n int) []namedFile {
+	files := make([]namedFile, n)
+	for i := range files {
+		files[i] = namedFile{name: fmt.Sprintf("%03d.csv", i), content: "row"}
+	}
+	return files
+}
+
+func TestPreviewUploadHandler_RefusesSeveralFiles(t *testing.T) {
+	req := createMultiFileRequest(t, namedFile{"jan.csv", "january"}, namedFile{"feb.csv", "february"})
+	rec := httptest.NewRecorder()
+	NewPreviewUploadHandler(&mockTransactionService{}, contentParser(), &mockAccountService{}, NewPreviewStore())(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+const (
+	// previewTTL is how long a kept preview can be committed for.
+	previewTTL = 15 * time.Minute
+	// maxPreviews bounds the uploads kept on disk, each of which can be as
+	// large as the upload limit.
+	maxPreviews = 32
+)
+
+// PreviewResponse is what importing an upload would do. Totals sum the new
+// transactions, which are the ones a commit would add.
+type PreviewResponse struct {
+	// Token commits the previewed upload without sending it again. It is
+	// only set when the preview was asked to keep the upload.
+	Token      string               `json:"token,omitempty"`
+	ExpiresAt  *time.Time           `json:"expires_at,omitempty"`
+	New        int                  `json:"new"`
+	Duplicates int                  `json:"duplicates"`
+	Statements []PreviewStatement   `json:"statements"`
+	Totals     []PreviewTotal       `json:"totals"`
+	Rejected   []csvparser.RowError `json:"rejected,omitempty"`
+	Warnings   []string             `json:"warnings"`
+}
+
+// PreviewStatement is one statement of a previewed upload and the account
+// it would be imported into. AccountID is nil when importing it would
+// create a new account.
+type PreviewStatement struct {
+	Format       string               `json:"format"`
+	Institution  string               `json:"institution"`
+	AccountID    *int32               `json:"account_id"`
+	AccountName  string               `json:"account_name"`
+	NewAccount   bool                 `json:"new_account"`
+	New          int                  `json:"new"`
+	Duplicates   int                  `json:"duplicates"`
+	Transactions []PreviewTransaction `json:"transactions"`
+}
+
+// PreviewTransaction is a parsed transaction, with its category as the file
+// gave it, and whether importing it would be skipped as a duplicate.
+type PreviewTransaction struct {
+	TransactionResponse
+	Duplicate bool `json:"duplicate"`
+}
+
+// PreviewTotal sums the new transactions in one currency, in minor units.
+// Outflow is negative.
+type PreviewTotal struct {
+	Currency     string `json:"currency"`
+	Inflow       int64  `json:"inflow"`
+	InflowCount  int    `json:"inflow_count"`
+	Outflow      int64  `json:"outflow"`
+	OutflowCount int    `json:"outflow_count"`
+}
+
+// PreviewStore keeps previewed uploads so they can be committed by token
+// without uploading them again. It owns the spooled files of the uploads it
+// keeps, removing them when they are dropped.
+type PreviewStore struct {
+	mu       sync.Mutex
+	previews map[string]keptPreview
+	now      func() time.Time
+}
+
+type keptPreview struct {
+	upload    upload
+	expiresAt time.Time
+}
+
+func NewPreviewStore() *PreviewStore {
+	return &PreviewStore{previews: make(map[string]keptPreview), now: time.Now}
+}
+
+// keep stores an upload, dropping expired previews and, when the store is
+// full, the one closest to expiring.
+func (s *PreviewStore) keep(up upload) (string, time.Time, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(b[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var oldest string
+	for t, kept := range s.previews {
+		if !now.Before(kept.expiresAt) {
+			kept.upload.file.remove()
+			delete(s.previews, t)
+			continue
+		}
+		if oldest == "" || kept.expiresAt.Before(s.previews[oldest].expiresAt) {
+			oldest = t
+		}
+	}
+	if len(s.previews) >= maxPreviews {
+		s.previews[oldest].upload.file.remove()
+		delete(s.previews, oldest)
+	}
+
+	expiresAt := now.Add(previewTTL)
+	s.previews[token] = keptPreview{upload: up, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// take removes a preview from the store so that only one commit can use it.
+// The caller then owns its file, until it puts it back.
+func (s *PreviewStore) take(token string) (keptPreview, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept, ok := s.previews[token]
+	delete(s.previews, token)
+	if !ok {
+		return keptPreview{}, false
+	}
+	if !s.now().Before(kept.expiresAt) {
+		kept.upload.file.remove()
+		return keptPreview{}, false
+	}
+	return kept, true
+}
+
+// putBack returns a preview whose commit failed, so it can be tried again
+// until it expires.
+func (s *PreviewStore) putBack(token string, kept keptPreview) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.previews[token] = kept
+}
+
+// NewPreviewUploadHandler parses an upload as NewUploadTransactionsHandler
+// would, matching accounts and finding duplicates without writing anything.
+// With ?token=true the upload is kept to be committed later.
+func NewPreviewUploadHandler(transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, previews *PreviewStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keep, err := parseOptionalBool(r.URL.Query().Get("token"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid token parameter", err.Error())
+			return
+		}
+		up, ok := readUpload(w, r)
+		if !ok {
+			return
+		}
+		// A kept upload's file belongs to previews.
+		kept := false
+		defer func() {
+			if !kept {
+				up.file.remove()
+			}
+		}()
+		parsed, ok := parseUpload(w, parserService, up)
+		if !ok {
+			return
+		}
+
+		response, err := previewUpload(r.Context(), transactionService, accountService, up, parsed)
+		if err != nil {
+			respondWithError(w, determineStatusCode(err), "Preview failed", err.Error())
+			return
+		}
+
+		if keep {
+			token, expiresAt, err := previews.keep(up)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Preview failed", err.Error())
+				return
+			}
+			kept = true
+			response.Token = token
+			response.ExpiresAt = &expiresAt
+		}
+		respondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// NewCommitPreviewHandler imports an upload kept by a preview, responding as
+// NewUploadTransactionsHandler does.
+func NewCommitPreviewHandler(transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, previews *PreviewStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := chi.URLParam(r, "token")
+		kept, ok := previews.take(token)
+		if !ok {
+			respondWithError(w, http.StatusNotFound, "Preview not found",
+				"the preview does not exist or has expired; upload the file again")
+			return
+		}
+
+		parsed, ok := parseUpload(w, parserService, kept.upload)
+		if !ok {
+			kept.upload.file.remove()
+			return
+		}
+		if !importUpload(r.Context(), w, transactionService, accountService, kept.upload, parsed) {
+			previews.putBack(token, kept)
+			return
+		}
+		kept.upload.file.remove()
+	}
+}
+
+func previewUpload(ctx context.Context, transactionService transaction.Service, accountService account.Service, up upload, parsed csvparser.ParseResult) (PreviewResponse, error) {
+	response := PreviewResponse{
+		Statements: make([]PreviewStatement, 0, len(parsed.Statements)),
+		Totals:     []PreviewTotal{},
+		Rejected:   parsed.Rejected,
+		Warnings:   []string{},
+	}
+	totals := make(map[string]*PreviewTotal)
+	warn := func(i int, format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		if len(parsed.Statements) > 1 {
+			message = fmt.Sprintf("statement %d: %s", i+1, message)
+		}
+		response.Warnings = append(response.Warnings, message)
+	}
+
+	for i, statement := range parsed.Statements {
+		rows := summariseRows(statement.Transactions)
+		bank := importBank(statement, rows, up.options.BankType)
+		acc, err := accountService.MatchAccount(ctx, up.accountID, statementAccountHint(statement, rows, bank))
+		if err != nil {
+			return PreviewResponse{}, withStatement(i, len(parsed.Statements), err)
+		}
+
+		preview := PreviewStatement{
+			Format:       statement.Format,
+			Institution:  acc.Institution,
+			AccountName:  acc.Name,
+			NewAccount:   acc.ID == 0,
+			Transactions: make([]PreviewTransaction, 0, len(statement.Transactions)),
+		}
+		// Nothing can be stored yet for an account that would be created.
+		duplicates := make([]bool, len(statement.Transactions))
+		if preview.NewAccount {
+			warn(i, "a new %s account %q would be created", acc.Institution, acc.Name)
+		} else {
+			preview.AccountID = &acc.ID
+			for j := range statement.Transactions {
+				statement.Transactions[j].AccountID = &acc.ID
+			}
+			duplicates, err = transactionService.FindDuplicates(ctx, statement.Transactions)
+			if err != nil {
+				return PreviewResponse{}, withStatement(i, len(parsed.Statements), err)
+			}
+		}
+		if len(statement.Transactions) == 0 {
+			warn(i, "the statement has no transactions")
+		}
+
+		for j, tx := range statement.Transactions {
+			if err := tx.Validate(); err != nil {
+				warn(i, "transaction %d: %v", j+1, err)
+			}
+			preview.Transactions = append(preview.Transactions, PreviewTransaction{
+				TransactionResponse: FromTransaction(tx),
+				Duplicate:           duplicates[j],
+			})
+			if duplicates[j] {
+				preview.Duplicates++
+				continue
+			}
+			preview.New++
+			if tx.Amount != nil {
+				addToTotal(totals, tx)
+			}
+		}
+
+		response.New += preview.New
+		response.Duplicates += preview.Duplicates
+		response.Statements = append(response.Statements, preview)
+	}
+
+	if len(parsed.Rejected) > 0 {
+		response.Warnings = append(response.Warnings, fmt.Sprintf("%d rows could not be read and would be left out", len(parsed.Rejected)))
+	}
+	for _, total := range totals {
+		response.Totals = append(response.Totals, *total)
+	}
+	slices.SortFunc(response.Totals, func(a, b PreviewTotal) int {
+		return cmp.Compare(a.Currency, b.Currency)
+	})
+	return response, nil
+}
+
+func addToTotal(totals map[string]*PreviewTotal, tx transaction.Transaction) {
+	currency := tx.Amount.Currency().Code
+	total, ok := totals[currency]
+	if !ok {
+		total = &PreviewTotal{Currency: currency}
+		totals[currency] = total
+	}
+	if amount := tx.Amount.Amount(); amount < 0 {
+		total.Outflow += amount
+		total.OutflowCount++
+	} else {
+		total.Inflow += amount
+		total.InflowCount++
+	}
+}
+
+// withStatement says which statement of a file an error came from, when
+// there is more than one.
+func withStatement(i, count int, err error) error {
+	if count == 1 {
+		return err
+	}
+	return fmt.Errorf("statement %d of %d: %w", i+1, count, err)
+}
+
+func parseOptionalBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
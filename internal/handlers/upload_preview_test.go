@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func previewDate() time.Time {
+	return time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+}
+
+// previewParser returns a Monzo statement followed by a Wise one.
+func previewParser() *mockParserService {
+	return &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			return csvparser.ParseResult{
+				Statements: []csvparser.Statement{
+					{
+						Format:      "monzo",
+						Institution: "Monzo",
+						Transactions: []transaction.Transaction{
+							{Date: previewDate(), Bank: "Monzo", Description: "RENT", Amount: money.New(-90000, "GBP")},
+							{Date: previewDate(), Bank: "Monzo", Description: "SALARY", Amount: money.New(250000, "GBP")},
+							{Date: previewDate(), Bank: "Monzo", Description: "COFFEE", Amount: money.New(-350, "GBP")},
+						},
+					},
+					{
+						Format:      "wise",
+						Institution: "Wise",
+						Transactions: []transaction.Transaction{
+							{Date: previewDate(), Bank: "Wise", Description: "", Amount: money.New(-1200, "EUR")},
+						},
+					},
+				},
+				Rejected: []csvparser.RowError{{Row: 7, Raw: "bad", Reason: "expected at least 3 columns, got 1"}},
+			}, nil
+		},
+	}
+}
+
+func previewAccounts() *mockAccountService {
+	return &mockAccountService{
+		matchAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			if hint.Institution == "Wise" {
+				return account.Account{Institution: "Wise", Name: "Wise"}, nil
+			}
+			return account.Account{ID: 4, Institution: "Monzo", Name: "Current"}, nil
+		},
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			if hint.Institution == "Wise" {
+				return account.Account{ID: 5}, nil
+			}
+			return account.Account{ID: 4}, nil
+		},
+	}
+}
+
+func TestPreviewUploadHandler(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			t.Error("preview imported transactions")
+			return transaction.ImportResult{}, nil
+		},
+		findDuplicatesFunc: func(ctx context.Context, transactions []transaction.Transaction) ([]bool, error) {
+			for _, tx := range transactions {
+				assert.Equal(t, int32(4), *tx.AccountID)
+			}
+			return []bool{true, false, false}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "<Document/>", "")
+	rec := httptest.NewRecorder()
+
+	NewPreviewUploadHandler(mockTxService, previewParser(), previewAccounts(), NewPreviewStore())(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response PreviewResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Empty(t, response.Token)
+	assert.Nil(t, response.ExpiresAt)
+	assert.Equal(t, 3, response.New)
+	assert.Equal(t, 1, response.Duplicates)
+
+	assert.Len(t, response.Statements, 2)
+	monzo := response.Statements[0]
+	assert.Equal(t, "monzo", monzo.Format)
+	assert.Equal(t, int32(4), *monzo.AccountID)
+	assert.False(t, monzo.NewAccount)
+	assert.Equal(t, 2, monzo.New)
+	assert.Equal(t, 1, monzo.Duplicates)
+	assert.True(t, monzo.Transactions[0].Duplicate)
+	assert.Equal(t, "SALARY", monzo.Transactions[1].Description)
+
+	wise := response.Statements[1]
+	assert.Nil(t, wise.AccountID)
+	assert.True(t, wise.NewAccount)
+	assert.Equal(t, 1, wise.New)
+
+	// The duplicate rent payment is left out of the totals.
+	assert.Equal(t, []PreviewTotal{
+		{Currency: "EUR", Outflow: -1200, OutflowCount: 1},
+		{Currency: "GBP", Inflow: 250000, InflowCount: 1, Outflow: -350, OutflowCount: 1},
+	}, response.Totals)
+	assert.Len(t, response.Rejected, 1)
+	assert.Equal(t, []string{
+		`statement 2: a new Wise account "Wise" would be created`,
+		"statement 2: transaction 1: validation failure: description is required",
+		"1 rows could not be read and would be left out",
+	}, response.Warnings)
+}
+
+func TestPreviewUploadHandler_AmbiguousAccount(t *testing.T) {
+	mockAccounts := &mockAccountService{
+		matchAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			return account.Account{}, account.ErrAmbiguous
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "amex")
+	rec := httptest.NewRecorder()
+
+	NewPreviewUploadHandler(&mockTransactionService{}, &mockParserService{}, mockAccounts, NewPreviewStore())(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestCommitPreviewHandler(t *testing.T) {
+	previews := NewPreviewStore()
+	parser := previewParser()
+
+	req := createMultipartRequest(t, "<Document/>", "")
+	req.URL.RawQuery += "&token=true"
+	rec := httptest.NewRecorder()
+	NewPreviewUploadHandler(&mockTransactionService{}, parser, previewAccounts(), previews)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var preview PreviewResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&preview))
+	assert.Len(t, preview.Token, 32)
+	assert.NotNil(t, preview.ExpiresAt)
+
+	calls := 0
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			calls++
+			assert.Equal(t, "statement.csv", source.FileName)
+			if calls == 1 {
+				return transaction.ImportResult{}, transaction.ErrDatabaseFailure
+			}
+			return transaction.ImportResult{BatchID: int32(calls), Inserted: int64(len(transactions))}, nil
+		},
+	}
+	commit := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/upload/preview/"+preview.Token+"/commit", nil)
+		rec := httptest.NewRecorder()
+		NewCommitPreviewHandler(mockTxService, parser, previewAccounts(), previews)(rec, withURLParam(req, "token", preview.Token))
+		return rec
+	}
+
+	// A failed commit can be tried again.
+	assert.Equal(t, http.StatusInternalServerError, commit().Code)
+
+	rec = commit()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response UploadResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, int64(4), response.Inserted)
+	assert.Len(t, response.Imports, 2)
+
+	// A committed preview is gone.
+	assert.Equal(t, http.StatusNotFound, commit().Code)
+}
+
+func TestCommitPreviewHandler_Expired(t *testing.T) {
+	previews := NewPreviewStore()
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	previews.now = func() time.Time { return now }
+	token, _, err := previews.keep(upload{fileName: "statement.csv"})
+	assert.NoError(t, err)
+
+	now = now.Add(previewTTL)
+	req := httptest.NewRequest(http.MethodPost, "/transactions/upload/preview/"+token+"/commit", nil)
+	rec := httptest.NewRecorder()
+	NewCommitPreviewHandler(&mockTransactionService{}, &mockParserService{}, &mockAccountService{}, previews)(rec, withURLParam(req, "token", token))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPreviewStore_EvictsClosestToExpiring(t *testing.T) {
+	previews := NewPreviewStore()
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	previews.now = func() time.Time { return now }
+
+	first, _, err := previews.keep(upload{})
+	assert.NoError(t, err)
+	for range maxPreviews {
+		now = now.Add(time.Second)
+		_, _, err := previews.keep(upload{})
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, previews.previews, maxPreviews)
+	_, ok := previews.take(first)
+	assert.False(t, ok)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+)
+
+// maxUploadFileSize caps how large one uploaded file can be.
+const maxUploadFileSize = 256 << 20
+
+// errTooLarge is returned by spool for a file larger than its limit.
+var errTooLarge = errors.New("file is too large")
+
+// spooledFile is an uploaded file written to a temporary file, so that an
+// upload is never held in memory and can outlive its request when it is
+// imported by a job. Whoever holds it removes it once it is done with it.
+type spooledFile struct {
+	path     string
+	size     int64
+	checksum string
+}
+
+// spool writes r to a temporary file, hashing it as it goes. It fails with
+// errTooLarge, leaving nothing behind, once more than limit bytes are read.
+func spool(r io.Reader, limit int64) (spooledFile, error) {
+	f, err := os.CreateTemp("", "finances-upload-*")
+	if err != nil {
+		return spooledFile{}, err
+	}
+	file := spooledFile{path: f.Name()}
+
+	hash := sha256.New()
+	file.size, err = io.Copy(io.MultiWriter(f, hash), io.LimitReader(r, limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && file.size > limit {
+		err = errTooLarge
+	}
+	if err != nil {
+		file.remove()
+		return spooledFile{}, err
+	}
+	file.checksum = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
+
+func (f spooledFile) open() (*os.File, error) {
+	return os.Open(f.path)
+}
+
+// remove deletes the file. It is best effort: a file left behind is in the
+// temporary directory, which is cleared in time anyway.
+func (f spooledFile) remove() {
+	if f.path != "" {
+		_ = os.Remove(f.path)
+	}
+}
+
+// removeUploads removes the spooled files of ups.
+func removeUploads(ups []upload) {
+	for _, up := range ups {
+		up.file.remove()
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// spoolString spools content as an upload would, removing it when the test
+// ends.
+func spoolString(t *testing.T, content string) spooledFile {
+	t.Helper()
+	file, err := spool(strings.NewReader(content), int64(len(content)))
+	assert.NoError(t, err)
+	t.Cleanup(file.remove)
+	return file
+}
+
+func TestSpool(t *testing.T) {
+	file := spoolString(t, "date,amount\n")
+
+	sum := sha256.Sum256([]byte("date,amount\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), file.checksum)
+	assert.Equal(t, int64(12), file.size)
+	content, err := os.ReadFile(file.path)
+	assert.NoError(t, err)
+	assert.Equal(t, "date,amount\n", string(content))
+
+	file.remove()
+	_, err = os.Stat(file.path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSpool_TooLarge(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	_, err := spool(strings.NewReader("date,amount\n"), 11)
+
+	assert.ErrorIs(t, err, errTooLarge)
+	left, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, left, "the spooled file was left behind")
+}
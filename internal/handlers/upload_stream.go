@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/kushturner/finances/internal/transaction"
+)
+
+// streamedStatement is the single statement of a streamed upload once it
+// has been imported: everything the file says but its transactions, with
+// its final balance date, the rows a lenient upload rejected, and what was
+// imported.
+type streamedStatement struct {
+	statement csvparser.Statement
+	rows      *statementRows
+	rejected  []csvparser.RowError
+	summary   UploadImportSummary
+}
+
+// result is the streamed statement as a parse of the upload, without its
+// transactions.
+func (s streamedStatement) result() csvparser.ParseResult {
+	return csvparser.ParseResult{Statements: []csvparser.Statement{s.statement}, Rejected: s.rejected}
+}
+
+// streamUpload imports an upload of one file as importUpload does, but
+// reads a CSV export a row at a time as it is copied into the database
+// rather than hold its transactions. It returns false without responding
+// for a file that is to be parsed whole.
+func streamUpload(ctx context.Context, w http.ResponseWriter, transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, up upload) bool {
+	streamed, err := importStream(ctx, transactionService, parserService, accountService, up, nil)
+	var failure *statementError
+	switch {
+	case errors.Is(err, csvparser.ErrNotStreamable):
+		return false
+	case errors.As(err, &failure):
+		respondWithError(w, determineStatusCode(failure), failure.step, failure.Error())
+	case err != nil:
+		respondWithParseError(w, up, err)
+	default:
+		file := newFileSummary(up, streamed.result())
+		file.add(streamed.summary)
+		respondWithSuccess(w, []UploadFileSummary{file})
+	}
+	return true
+}
+
+// importStream imports the single statement of an upload in one pass,
+// reading it a row at a time as it is copied into the database. The
+// account is matched from the statement and its first row, and created if
+// need be, in the import's database transaction, so a failed import
+// leaves no account behind. The balance a new account opens with depends
+// on every row, so it is set once they have all been read.
+//
+// read, when it is not nil, is called as rows are read with how many have
+// been and how much of the file. importStream fails with
+// csvparser.ErrNotStreamable for a file that is to be parsed whole, with
+// the parse error of a file that cannot be read, and with a
+// *statementError when the import does.
+func importStream(ctx context.Context, transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, up upload, read func(rows int, bytes int64)) (streamedStatement, error) {
+	failure := func(step string, err error) *statementError {
+		return &statementError{step: step, count: 1, err: err}
+	}
+	if err := ctx.Err(); err != nil {
+		return streamedStatement{}, failure("Upload cancelled", err)
+	}
+
+	f, err := up.open()
+	if err != nil {
+		return streamedStatement{}, err
+	}
+	defer f.Close()
+	file := &countingReader{r: f}
+	stream, err := parserService.StreamStatement(file, up.options)
+	if err != nil {
+		return streamedStatement{}, err
+	}
+
+	streamed := streamedStatement{rows: &statementRows{}}
+	// parseErr and accountErr keep what failed the import, which the
+	// database transaction reports only as text.
+	var parseErr, accountErr error
+	transactions := func(yield func(transaction.Transaction, error) bool) {
+		for tx, err := range stream.Transactions() {
+			if err != nil {
+				parseErr = err
+			} else {
+				streamed.rows.add(tx)
+				if read != nil {
+					read(streamed.rows.count, file.n)
+				}
+			}
+			if !yield(tx, err) {
+				return
+			}
+		}
+	}
+
+	var acc account.Account
+	// created is set when the import creates the account.
+	var created bool
+	result, err := transactionService.AddTransactionStream(ctx, transaction.StreamImport{
+		Source:       transaction.ImportSource{FileName: up.fileName, Checksum: up.file.checksum},
+		Transactions: transactions,
+		Start: func(ctx context.Context, q db.Querier, first *transaction.Transaction, source *transaction.ImportSource) error {
+			accounts := accountService.InTx(q)
+			hint := statementAccountHint(stream.Statement, streamed.rows, importBank(stream.Statement, streamed.rows, up.options.BankType))
+			match, err := accounts.MatchAccount(ctx, up.accountID, hint)
+			if err == nil {
+				acc, err = accounts.ResolveAccount(ctx, up.accountID, hint)
+			}
+			if err != nil {
+				accountErr = err
+				return err
+			}
+			created = match.ID == 0
+			source.AccountID = &acc.ID
+			return nil
+		},
+		Finish: func(ctx context.Context, q db.Querier, source *transaction.ImportSource) error {
+			bank := importBank(stream.Statement, streamed.rows, up.options.BankType)
+			*source = statementSource(stream.Statement, up.fileName, bank, up.file.checksum, acc.ID)
+			if !created || acc.OpeningBalance == nil {
+				return nil
+			}
+			opening := statementOpeningBalance(stream.Statement, streamed.rows)
+			if opening == acc.OpeningBalance.Amount() {
+				return nil
+			}
+			acc.OpeningBalance = money.New(opening, acc.OpeningBalance.Currency().Code)
+			_, err := accountService.InTx(q).UpdateAccount(ctx, acc)
+			return err
+		},
+	})
+	switch {
+	case parseErr != nil:
+		return streamedStatement{}, parseErr
+	case accountErr != nil:
+		return streamedStatement{}, failure("Could not determine account", accountErr)
+	case err != nil:
+		return streamedStatement{}, failure("Upload failed", err)
+	}
+
+	streamed.statement = stream.Statement
+	streamed.rejected = stream.Rejected()
+	streamed.summary = UploadImportSummary{
+		ImportID:  result.BatchID,
+		AccountID: acc.ID,
+		Inserted:  result.Inserted,
+		Skipped:   result.Skipped,
+	}
+	return streamed, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// sliceRows streams parsed transactions.
+func sliceRows(transactions []transaction.Transaction) iter.Seq2[transaction.Transaction, error] {
+	return func(yield func(transaction.Transaction, error) bool) {
+		for _, tx := range transactions {
+			if !yield(tx, nil) {
+				return
+			}
+		}
+	}
+}
+
+// countRows calls copied with how many transactions have been read each
+// time the insert reads one. A nil copied leaves transactions as they are.
+func countRows(transactions iter.Seq2[transaction.Transaction, error], copied func(rows int)) iter.Seq2[transaction.Transaction, error] {
+	if copied == nil {
+		return transactions
+	}
+	return func(yield func(transaction.Transaction, error) bool) {
+		rows := 0
+		for tx, err := range transactions {
+			if err == nil {
+				rows++
+				copied(rows)
+			}
+			if !yield(tx, err) {
+				return
+			}
+		}
+	}
+}
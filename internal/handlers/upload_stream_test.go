@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/importjob"
+	"github.com/kushturner/finances/internal/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+const streamedAmexCSV = "Date,Description,Amount,Reference,Category\n" +
+	"15/01/2026,TEST RESTAURANT,25.50,'AT1',Dining\n" +
+	"32/01/2026,BAD DATE,1.00,'AT2',\n" +
+	"14/01/2026,TEST REFUND,-10.00,'AT3',\n"
+
+// streamingTransactionService records what is streamed to it, and fails
+// the test if an upload is imported from a slice instead.
+func streamingTransactionService(t *testing.T, streamed *[]transaction.Transaction) *mockTransactionService {
+	return &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			t.Fatal("a CSV export should be streamed")
+			return transaction.ImportResult{}, nil
+		},
+		addStreamFunc: func(ctx context.Context, statement transaction.StreamImport) (transaction.ImportResult, error) {
+			source, read, err := readStream(ctx, statement)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(4), *source.AccountID)
+			*streamed = append(*streamed, read...)
+			return transaction.ImportResult{BatchID: 9, Inserted: int64(len(read))}, nil
+		},
+	}
+}
+
+func streamedAccount(t *testing.T) *mockAccountService {
+	return &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			assert.Equal(t, "GBP", hint.Currency)
+			return account.Account{ID: 4}, nil
+		},
+	}
+}
+
+func TestUploadTransactionsHandler_StreamsCSVExport(t *testing.T) {
+	var streamed []transaction.Transaction
+	req := createMultipartRequest(t, streamedAmexCSV, "amex")
+	req.URL.RawQuery += "&mode=lenient"
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(streamingTransactionService(t, &streamed), csvparser.NewService(), streamedAccount(t), &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, streamed, 2) {
+		assert.Equal(t, int64(-2550), streamed[0].Amount.Amount())
+		assert.Equal(t, int32(4), *streamed[0].AccountID)
+		assert.Equal(t, int64(1000), streamed[1].Amount.Amount())
+		assert.Equal(t, int32(4), *streamed[1].AccountID)
+	}
+	var response UploadResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, int32(9), response.ImportID)
+	assert.Equal(t, int64(2), response.Inserted)
+	assert.Len(t, response.Rejected, 1)
+	assert.Equal(t, "amex", response.Files[0].Format)
+}
+
+func TestUploadTransactionsHandler_StreamedBadRowImportsNothing(t *testing.T) {
+	// The account is chosen from the first row, in the import's database
+	// transaction, which the bad row then rolls back.
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			t.Fatal("a file with a bad row was imported")
+			return transaction.ImportResult{}, nil
+		},
+	}
+	req := createMultipartRequest(t, streamedAmexCSV, "amex")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(mockTxService, csvparser.NewService(), streamedAccount(t), &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "Failed to parse file", response.Error)
+	assert.Contains(t, response.Details, "row 2")
+}
+
+func TestSubmitImportJobHandler_StreamsCSVExport(t *testing.T) {
+	var work importjob.Work
+	jobs := &mockImportJobService{
+		submitFunc: func(ctx context.Context, fileName, checksum string, w importjob.Work) (importjob.Job, error) {
+			work = w
+			return importjob.Job{ID: 1}, nil
+		},
+	}
+	var streamed []transaction.Transaction
+	req := createMultipartRequest(t, streamedAmexCSV, "amex")
+	req.URL.RawQuery += "&mode=lenient"
+	NewSubmitImportJobHandler(streamingTransactionService(t, &streamed), csvparser.NewService(), streamedAccount(t), jobs)(httptest.NewRecorder(), req)
+
+	var reports []importjob.Progress
+	err := work(context.Background(), func(p importjob.Progress) { reports = append(reports, p) })
+
+	assert.NoError(t, err)
+	assert.Len(t, streamed, 2)
+	// Fewer than jobProgressRows rows are only reported once imported.
+	if assert.Len(t, reports, 1) {
+		assert.Len(t, reports[0].Rejected, 1)
+		size := int64(len(streamedAmexCSV))
+		assert.Equal(t, importjob.Progress{
+			Statements: 1, StatementsDone: 1, Rows: 2, RowsDone: 2, Bytes: size, BytesDone: size, Inserted: 2,
+			ImportIDs: []int32{9},
+			Rejected:  reports[0].Rejected,
+		}, reports[0])
+	}
+}
+
+func TestUploadTransactionsHandler_StreamedNewAccountOpensWithStatement(t *testing.T) {
+	// Running balances are only in the rows, newest first, so the balance
+	// the account opens with is known once they have all been read: the
+	// first row alone would open it with £1000.
+	const csv = "\"Account Name:\",\"Debit ****12345\"\n" +
+		"\"Account Balance:\",\"£100.00\"\n" +
+		"\"Available Balance: \",\"£100.00\"\n" +
+		"\n" +
+		"\"Date\",\"Transaction type\",\"Description\",\"Paid out\",\"Paid in\",\"Balance\"\n" +
+		"\"02 Jan 2026\",\"Payment to\",\"RENT\",\"£900.00\",\"\",\"£100.00\"\n" +
+		"\"01 Jan 2026\",\"Bank credit\",\"SALARY\",\"\",\"£1000.00\",\"£1000.00\"\n"
+	var created int64
+	var opened *money.Money
+	mockAccounts := &mockAccountService{
+		matchAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			return account.Account{Institution: hint.Institution}, nil
+		},
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			created = hint.OpeningBalance
+			return account.Account{ID: 6, Institution: hint.Institution, OpeningBalance: money.New(hint.OpeningBalance, hint.Currency)}, nil
+		},
+		updateAccountFunc: func(ctx context.Context, a account.Account) (account.Account, error) {
+			opened = a.OpeningBalance
+			return a, nil
+		},
+	}
+	req := createMultipartRequest(t, csv, "")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, csvparser.NewService(), mockAccounts, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(100000), created)
+	if assert.NotNil(t, opened) {
+		assert.Equal(t, int64(0), opened.Amount())
+	}
+}
+
+// largeAmexUpload is a multipart upload of an Amex export of n rows.
+func largeAmexUpload(b *testing.B, n int) (body []byte, contentType string) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("file", "statement.csv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	io.WriteString(part, "Date,Description,Amount,Reference,Category\n")
+	for i := range n {
+		fmt.Fprintf(part, "%02d/01/2026,TEST MERCHANT %d LONDON,%d.%02d,'AT%09d',Shopping-Groceries\n", i%28+1, i%500, i%200, i%100, i)
+	}
+	if err := writer.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes(), writer.FormDataContentType()
+}
+
+// liveHeap returns the bytes in use on the heap once garbage is collected.
+func liveHeap() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// BenchmarkUploadTransactionsHandler_Stream_100k uploads an export through
+// the handler and reports live-B/op, the heap in use once the import has
+// read the last row. It stays flat as the file grows, where the whole
+// statement is held by BenchmarkService_ParseStatement_100k.
+func BenchmarkUploadTransactionsHandler_Stream_100k(b *testing.B) {
+	const rows = 100_000
+	body, contentType := largeAmexUpload(b, rows)
+	var before, live uint64
+	transactions := &mockTransactionService{
+		addStreamFunc: func(ctx context.Context, statement transaction.StreamImport) (transaction.ImportResult, error) {
+			read := 0
+			for _, err := range statement.Transactions {
+				if err != nil {
+					return transaction.ImportResult{}, err
+				}
+				read++
+			}
+			if read != rows {
+				b.Fatalf("streamed %d rows, want %d", read, rows)
+			}
+			live += liveHeap() - before
+			return transaction.ImportResult{BatchID: 1, Inserted: int64(read)}, nil
+		},
+	}
+	accounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			return account.Account{ID: 1}, nil
+		},
+	}
+	handler := NewUploadTransactionsHandler(transactions, csvparser.NewService(), accounts, &mockImportJobService{})
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+
+	for b.Loop() {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/upload?bank=amex", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		before = liveHeap()
+		handler(rec, req)
+		if rec.Code != http.StatusOK {
+			b.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+}
@@ -1,63 +1,717 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/kushturner/finances/internal/account"
 	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/kushturner/finances/internal/importjob"
 	"github.com/kushturner/finances/internal/transaction"
 )
 
+// UploadResponse totals the imports an upload made. A file holding
+// statements for several accounts is imported as one batch per statement;
+// ImportID and AccountID are those of the first. Rejected lists the rows a
+// lenient upload left out, and Files breaks all of it down by file.
 type UploadResponse struct {
-	Message string `json:"message"`
+	Message   string                `json:"message"`
+	ImportID  int32                 `json:"import_id"`
+	AccountID int32                 `json:"account_id"`
+	Inserted  int64                 `json:"inserted"`
+	Skipped   int64                 `json:"skipped"`
+	Imports   []UploadImportSummary `json:"imports"`
+	Rejected  []csvparser.RowError  `json:"rejected,omitempty"`
+	Files     []UploadFileSummary   `json:"files"`
+}
+
+// UploadFileSummary is what one file of an upload imported: the rows it
+// inserted, those skipped as duplicates of stored transactions, and those
+// a lenient upload rejected.
+type UploadFileSummary struct {
+	FileName string                `json:"file_name"`
+	Format   string                `json:"format"`
+	Inserted int64                 `json:"inserted"`
+	Skipped  int64                 `json:"skipped"`
+	Rejected []csvparser.RowError  `json:"rejected"`
+	Imports  []UploadImportSummary `json:"imports"`
+	// Failed is set for a file of an upload of several that imported
+	// nothing, with Error saying why.
+	Failed bool   `json:"failed"`
+	Error  string `json:"error,omitempty"`
+}
+
+type UploadImportSummary struct {
+	ImportID  int32 `json:"import_id"`
+	AccountID int32 `json:"account_id"`
+	Inserted  int64 `json:"inserted"`
+	Skipped   int64 `json:"skipped"`
 }
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Error      string   `json:"error"`
+	Details    string   `json:"details,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
 }
 
-func NewUploadTransactionsHandler(transactionService transaction.Service, parserService csvparser.Service) http.HandlerFunc {
+// asyncUploadSize is the size above which a single uploaded file is
+// imported as a background job rather than while the request waits.
+const asyncUploadSize = 10 << 20
+
+// NewUploadTransactionsHandler imports the files of an upload: one or more
+// "file" parts, any of which can be a ZIP archive of statements. A single
+// file imports each of its statements in turn, keeping those imported
+// before one that fails, and a single CSV export is read a row at a time
+// rather than held in memory.
+//
+// Several files, an archive, or a single file larger than asyncUploadSize
+// are handed to jobs as POST /imports would, and the response is 202
+// Accepted with the queued job, whose progress is polled from GET
+// /imports/{id}. A job imports several files in one database transaction,
+// leaving out any that cannot be read, and reports what became of each.
+func NewUploadTransactionsHandler(transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, jobs importjob.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := r.ParseMultipartForm(10 << 20); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Failed to parse multipart form", err.Error())
+		ups, ok := readUploads(w, r)
+		if !ok {
 			return
 		}
+		if len(ups) > 1 || ups[0].unpacked() || ups[0].file.size > asyncUploadSize {
+			submitImportJob(r.Context(), w, transactionService, parserService, accountService, jobs, ups)
+			return
+		}
+		defer removeUploads(ups)
 
-		bankType := r.URL.Query().Get("bank")
-		if bankType == "" {
-			respondWithError(w, http.StatusBadRequest, "Missing bank type parameter", "")
+		if streamUpload(r.Context(), w, transactionService, parserService, accountService, ups[0]) {
 			return
 		}
 
-		file, _, err := r.FormFile("file")
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Failed to get file from form", err.Error())
+		parsed, ok := parseUpload(w, parserService, ups[0])
+		if !ok {
 			return
 		}
-		defer file.Close()
+		importUpload(r.Context(), w, transactionService, accountService, ups[0], parsed)
+	}
+}
+
+// upload is an uploaded file with the query parameters saying how to read
+// and import it.
+type upload struct {
+	fileName string
+	// part is the name of the uploaded file, which for a file unpacked
+	// from an archive is the archive's.
+	part    string
+	file    spooledFile
+	options csvparser.ParseOptions
+	// accountID is the account chosen with ?account_id=, or nil to match
+	// each statement to an account from the file.
+	accountID *int32
+	// several is set for one of several files uploaded together, whose
+	// errors then name it.
+	several bool
+}
+
+// withFile returns up for the named file of the uploaded part.
+func (up upload) withFile(part, name string, file spooledFile) upload {
+	up.part = part
+	up.fileName = name
+	up.file = file
+	return up
+}
+
+// unpacked reports whether up was unpacked from an archive.
+func (up upload) unpacked() bool {
+	return up.fileName != up.part
+}
+
+// open opens the uploaded file to be read from the start.
+func (up upload) open() (io.ReadCloser, error) {
+	return up.file.open()
+}
+
+// describe names the file an error was about when it is one of several.
+func (up upload) describe(err error) string {
+	if up.several {
+		return fmt.Sprintf("%s: %s", up.fileName, err)
+	}
+	return err.Error()
+}
+
+// readUpload reads an upload of a single file, as readUploads does,
+// responding with an error when there are several.
+func readUpload(w http.ResponseWriter, r *http.Request) (upload, bool) {
+	ups, ok := readUploads(w, r)
+	if !ok {
+		return upload{}, false
+	}
+	if len(ups) > 1 {
+		removeUploads(ups)
+		respondWithError(w, http.StatusBadRequest, "Too many files", fmt.Sprintf("upload holds %d files; upload one at a time", len(ups)))
+		return upload{}, false
+	}
+	return ups[0], true
+}
+
+// readUploads spools the uploaded files to disk, unpacking ZIP archives
+// into the files they hold, and reads the query parameters that apply to
+// all of them. It responds with an error and returns false when the
+// request is not valid. The caller removes the files it returns once it is
+// done with them.
+func readUploads(w http.ResponseWriter, r *http.Request) ([]upload, bool) {
+	// The parts are read as they arrive rather than parsed into a form,
+	// which would hold them in memory.
+	form, err := r.MultipartReader()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse multipart form", err.Error())
+		return nil, false
+	}
+
+	query := r.URL.Query()
+	up := upload{
+		options: csvparser.ParseOptions{
+			// An empty bank lets the parser detect the format from the file.
+			BankType: query.Get("bank"),
+			// ?sheet= picks the worksheet of an XLSX upload, by name or number.
+			Sheet: query.Get("sheet"),
+			// ?encoding= overrides the detected character encoding of a text file.
+			Encoding: query.Get("encoding"),
+		},
+	}
+	// Without ?account_id= the account is matched from the file.
+	accountID, err := parseOptionalID(query.Get("account_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid account id", err.Error())
+		return nil, false
+	}
+	up.accountID = accountID
+	up.options.Lenient, err = parseImportMode(query.Get("mode"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid import mode", err.Error())
+		return nil, false
+	}
+
+	var ups []upload
+	fail := func(statusCode int, errorMsg, details string) ([]upload, bool) {
+		removeUploads(ups)
+		respondWithError(w, statusCode, errorMsg, details)
+		return nil, false
+	}
+	archiveBudget := int64(maxArchiveTotalSize)
+	for {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(http.StatusBadRequest, "Failed to parse multipart form", err.Error())
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			continue
+		}
 
-		transactions, err := parserService.Parse(file, bankType)
+		name := part.FileName()
+		file, err := spool(part, maxUploadFileSize)
+		if errors.Is(err, errTooLarge) {
+			return fail(http.StatusRequestEntityTooLarge, "Failed to read uploaded file", fmt.Sprintf("%s is larger than %d MB", name, maxUploadFileSize>>20))
+		}
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Failed to parse CSV file", err.Error())
-			return
+			return fail(http.StatusBadRequest, "Failed to read uploaded file", err.Error())
+		}
+		if !isArchive(file) {
+			ups = append(ups, up.withFile(name, name, file))
+			continue
 		}
 
-		count, err := transactionService.AddTransactions(r.Context(), transactions)
+		files, err := unpackArchive(file, &archiveBudget)
+		file.remove()
 		if err != nil {
-			statusCode := determineStatusCode(err)
-			respondWithError(w, statusCode, "Upload failed", err.Error())
-			return
+			return fail(http.StatusBadRequest, "Failed to read archive", fmt.Sprintf("%s: %s", name, err))
+		}
+		for _, unpacked := range files {
+			ups = append(ups, up.withFile(name, name+"/"+unpacked.name, unpacked.file))
+		}
+	}
+	if len(ups) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Failed to get file from form", http.ErrMissingFile.Error())
+		return nil, false
+	}
+	for i := range ups {
+		ups[i].several = len(ups) > 1
+	}
+	return ups, true
+}
+
+// parseFile reads the statements in an uploaded file.
+func parseFile(parserService csvparser.Service, up upload) (csvparser.ParseResult, error) {
+	f, err := up.open()
+	if err != nil {
+		return csvparser.ParseResult{}, err
+	}
+	defer f.Close()
+	return parserService.ParseStatementsWithOptions(f, up.options)
+}
+
+// parseUpload reads the statements in an upload, responding with an error
+// and returning false when it cannot.
+func parseUpload(w http.ResponseWriter, parserService csvparser.Service, up upload) (csvparser.ParseResult, bool) {
+	parsed, err := parseFile(parserService, up)
+	if err != nil {
+		respondWithParseError(w, up, err)
+		return csvparser.ParseResult{}, false
+	}
+
+	if err := checkExplicitAccount(up, parsed.Statements); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Could not determine account", up.describe(err))
+		return csvparser.ParseResult{}, false
+	}
+	return parsed, true
+}
+
+// respondWithParseError responds to an upload whose file could not be
+// read.
+func respondWithParseError(w http.ResponseWriter, up upload, err error) {
+	var detectionErr *csvparser.DetectionError
+	if errors.As(err, &detectionErr) {
+		respondWithJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+			Error:      "Could not detect bank format",
+			Details:    up.describe(err) + "; pass ?bank= to choose one",
+			Candidates: detectionCandidates(detectionErr),
+		})
+		return
+	}
+	if errors.Is(err, csvparser.ErrLenientUnsupported) {
+		respondWithError(w, http.StatusBadRequest, "Invalid import mode", up.describe(err))
+		return
+	}
+	respondWithError(w, http.StatusBadRequest, "Failed to parse file", up.describe(err))
+}
+
+// checkExplicitAccount refuses a file of several statements uploaded with
+// ?account_id=, which would put every statement in the one account.
+func checkExplicitAccount(up upload, statements []csvparser.Statement) error {
+	if up.accountID != nil && len(statements) > 1 {
+		return fmt.Errorf("file contains %d statements; omit account_id to match each to its own account", len(statements))
+	}
+	return nil
+}
+
+// importUpload imports the parsed statements of an upload and responds with
+// what was imported, returning whether every statement was.
+func importUpload(ctx context.Context, w http.ResponseWriter, transactionService transaction.Service, accountService account.Service, up upload, parsed csvparser.ParseResult) bool {
+	imports, err := importStatements(ctx, transactionService, accountService, up, parsed.Statements, importProgress{})
+	if err != nil {
+		respondWithError(w, determineStatusCode(err), err.step, err.Error())
+		return false
+	}
+
+	file := newFileSummary(up, parsed)
+	for _, summary := range imports {
+		file.add(summary)
+	}
+	respondWithSuccess(w, []UploadFileSummary{file})
+	return true
+}
+
+// importUploads parses every file of an upload and imports all of their
+// statements in one database transaction, returning what became of each
+// file. Their accounts are resolved in that transaction too, so a failed
+// import leaves none behind. A file that cannot be read or matched to an
+// account is marked failed and left out rather than stopping the others;
+// if the import itself fails, every file it took in is marked failed.
+func importUploads(ctx context.Context, transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, ups []upload) []UploadFileSummary {
+	files := make([]UploadFileSummary, len(ups))
+	var statements []transaction.StatementImport
+	// owners holds the index of the file each statement came from, and
+	// accountIDs the account it was imported into.
+	var owners []int
+	var accountIDs []*int32
+	for i, up := range ups {
+		parsed, err := parseFile(parserService, up)
+		if err == nil {
+			err = checkExplicitAccount(up, parsed.Statements)
+		}
+		if err != nil {
+			files[i] = failedFile(up, err)
+			continue
+		}
+		files[i] = newFileSummary(up, parsed)
+
+		imports, err := uploadStatements(ctx, accountService, up, parsed.Statements)
+		if err != nil {
+			files[i].fail(fmt.Errorf("Could not determine account: %w", err))
+			continue
+		}
+		for _, statement := range imports {
+			statements = append(statements, statement.StatementImport)
+			owners = append(owners, i)
+			accountIDs = append(accountIDs, statement.accountID)
+		}
+	}
+	if len(statements) == 0 {
+		return files
+	}
+
+	results, err := transactionService.AddStatements(ctx, statements)
+	if err != nil {
+		for _, i := range owners {
+			files[i].fail(fmt.Errorf("Upload failed: %w", err))
+		}
+		return files
+	}
+	for j, result := range results {
+		files[owners[j]].add(UploadImportSummary{
+			ImportID:  result.BatchID,
+			AccountID: *accountIDs[j],
+			Inserted:  result.Inserted,
+			Skipped:   result.Skipped,
+		})
+	}
+	return files
+}
+
+// uploadStatement is a statement for AddStatements, with the account its
+// Start hook resolves it to.
+type uploadStatement struct {
+	transaction.StatementImport
+	accountID *int32
+}
+
+// uploadStatements prepares the statements of a file for AddStatements.
+// Each account is only matched here, so that a file with a statement that
+// cannot be is left out before anything is written; it is resolved, and
+// created if it is new, in the import's transaction.
+func uploadStatements(ctx context.Context, accountService account.Service, up upload, statements []csvparser.Statement) ([]uploadStatement, error) {
+	imports := make([]uploadStatement, 0, len(statements))
+	for _, statement := range statements {
+		rows := summariseRows(statement.Transactions)
+		bank := importBank(statement, rows, up.options.BankType)
+		hint := statementAccountHint(statement, rows, bank)
+		if _, err := accountService.MatchAccount(ctx, up.accountID, hint); err != nil {
+			return nil, err
+		}
+
+		source := statementSource(statement, up.fileName, bank, up.file.checksum, 0)
+		source.AccountID = nil
+		accountID := new(int32)
+		imports = append(imports, uploadStatement{
+			StatementImport: transaction.StatementImport{
+				Source:       source,
+				Transactions: statement.Transactions,
+				Start: func(ctx context.Context, q db.Querier, source *transaction.ImportSource) error {
+					acc, err := accountService.InTx(q).ResolveAccount(ctx, up.accountID, hint)
+					if err != nil {
+						return fmt.Errorf("Could not determine account: %w", err)
+					}
+					*accountID = acc.ID
+					source.AccountID = accountID
+					return nil
+				},
+			},
+			accountID: accountID,
+		})
+	}
+	return imports, nil
+}
+
+// failedFile is the summary of a file that could not be read.
+func failedFile(up upload, err error) UploadFileSummary {
+	file := newFileSummary(up, csvparser.ParseResult{})
+	file.fail(err)
+	return file
+}
+
+func newFileSummary(up upload, parsed csvparser.ParseResult) UploadFileSummary {
+	file := UploadFileSummary{
+		FileName: up.fileName,
+		Rejected: parsed.Rejected,
+		Imports:  []UploadImportSummary{},
+	}
+	if file.Rejected == nil {
+		file.Rejected = []csvparser.RowError{}
+	}
+	if len(parsed.Statements) > 0 {
+		file.Format = parsed.Statements[0].Format
+	}
+	return file
+}
+
+// fail marks the file failed with err, as nothing in it was imported.
+func (f *UploadFileSummary) fail(err error) {
+	f.Failed = true
+	f.Error = err.Error()
+	f.Inserted, f.Skipped = 0, 0
+	f.Imports = []UploadImportSummary{}
+}
+
+// add counts an import of one of the file's statements.
+func (f *UploadFileSummary) add(summary UploadImportSummary) {
+	f.Imports = append(f.Imports, summary)
+	f.Inserted += summary.Inserted
+	f.Skipped += summary.Skipped
+}
+
+// importProgress is told how an import of statements is going. Either
+// func can be nil.
+type importProgress struct {
+	// copied is called as the rows of statement i are inserted, with how
+	// many have been so far.
+	copied func(i, rows int)
+	// imported is called once statement i has been imported.
+	imported func(i int, summary UploadImportSummary)
+}
+
+// importStatements imports each statement into the account it belongs to,
+// telling progress how it goes. It stops before the next statement once
+// ctx is cancelled.
+func importStatements(ctx context.Context, transactionService transaction.Service, accountService account.Service, up upload, statements []csvparser.Statement, progress importProgress) ([]UploadImportSummary, *statementError) {
+	// Each statement is its own import, so one that fails leaves the
+	// ones before it in place to be rolled back individually.
+	imports := make([]UploadImportSummary, 0, len(statements))
+	for i, statement := range statements {
+		failure := func(step string, err error) *statementError {
+			return &statementError{step: step, index: i, count: len(statements), err: err}
+		}
+		if err := ctx.Err(); err != nil {
+			return imports, failure("Upload cancelled", err)
+		}
+
+		rows := summariseRows(statement.Transactions)
+		bank := importBank(statement, rows, up.options.BankType)
+		acc, err := accountService.ResolveAccount(ctx, up.accountID, statementAccountHint(statement, rows, bank))
+		if err != nil {
+			return imports, failure("Could not determine account", err)
+		}
+		for j := range statement.Transactions {
+			statement.Transactions[j].AccountID = &acc.ID
+		}
+
+		source := statementSource(statement, up.fileName, bank, up.file.checksum, acc.ID)
+		var result transaction.ImportResult
+		if progress.copied == nil {
+			result, err = transactionService.AddTransactions(ctx, source, statement.Transactions)
+		} else {
+			// Rows can only be counted as they go in when they are
+			// streamed into the insert.
+			rows := countRows(sliceRows(statement.Transactions), func(rows int) { progress.copied(i, rows) })
+			result, err = transactionService.AddTransactionStream(ctx, transaction.StreamImport{Source: source, Transactions: rows})
+		}
+		if err != nil {
+			return imports, failure("Upload failed", err)
+		}
+		summary := UploadImportSummary{
+			ImportID:  result.BatchID,
+			AccountID: acc.ID,
+			Inserted:  result.Inserted,
+			Skipped:   result.Skipped,
+		}
+		imports = append(imports, summary)
+		if progress.imported != nil {
+			progress.imported(i, summary)
+		}
+	}
+	return imports, nil
+}
+
+// statementError is the failure of one statement of an upload. Step says
+// what was being done, and the message which statement it was when the
+// file holds several.
+type statementError struct {
+	step  string
+	index int
+	count int
+	err   error
+}
+
+func (e *statementError) Error() string {
+	if e.count == 1 {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("statement %d of %d: %s; %d earlier statements were imported", e.index+1, e.count, e.err, e.index)
+}
+
+func (e *statementError) Unwrap() error {
+	return e.err
+}
+
+// parseImportMode reads ?mode=. A strict upload, the default, fails on the
+// first row it cannot read; a lenient one imports the rest and reports it.
+func parseImportMode(value string) (lenient bool, err error) {
+	switch value {
+	case "", "strict":
+		return false, nil
+	case "lenient":
+		return true, nil
+	}
+	return false, fmt.Errorf("mode must be strict or lenient, got '%s'", value)
+}
+
+// statementSource describes the import of one statement, with the balances
+// it reported.
+func statementSource(statement csvparser.Statement, fileName, bank, checksum string, accountID int32) transaction.ImportSource {
+	source := transaction.ImportSource{
+		FileName:         fileName,
+		Bank:             bank,
+		Checksum:         checksum,
+		AccountID:        &accountID,
+		ClosingBalance:   statement.ClosingBalance,
+		AvailableBalance: statement.AvailableBalance,
+		OpeningBalance:   statement.OpeningBalance,
+	}
+	if !statement.BalanceDate.IsZero() {
+		source.BalanceDate = &statement.BalanceDate
+	}
+	if !statement.OpeningBalanceDate.IsZero() {
+		source.OpeningBalanceDate = &statement.OpeningBalanceDate
+	}
+	return source
+}
+
+// importBank names the bank an import is recorded under, preferring what
+// the parser reported over the raw ?bank= value.
+func importBank(statement csvparser.Statement, rows *statementRows, bankType string) string {
+	if statement.Institution != "" {
+		return statement.Institution
+	}
+	if rows.first != nil {
+		return rows.first.Bank
+	}
+	if bankType != "" {
+		return bankType
+	}
+	return "unknown"
+}
+
+func statementAccountHint(statement csvparser.Statement, rows *statementRows, institution string) account.Hint {
+	hint := account.Hint{
+		Institution:  institution,
+		Name:         statement.Account.Name,
+		MaskedNumber: statement.Account.MaskedNumber,
+		Type:         statement.Account.Type,
+		Currency:     rows.currency,
+	}
+	hint.OpeningBalance = statementOpeningBalance(statement, rows)
+	return hint
+}
+
+// statementOpeningBalance is the balance before the statement's first
+// transaction: the opening balance the file reports, or else the one its
+// earliest running balance implies. It is zero when the file gives
+// neither.
+func statementOpeningBalance(statement csvparser.Statement, rows *statementRows) int64 {
+	if statement.OpeningBalance != nil && statement.OpeningBalance.Currency().Code == rows.currency {
+		return statement.OpeningBalance.Amount()
+	}
+	if len(rows.balanceRows) == 0 {
+		return 0
+	}
+
+	// Statements list either newest or oldest first, so go by date.
+	var before int64
+	for day, total := range rows.dayTotals {
+		if day < rows.balanceDay.UnixNano() {
+			before += total
+		}
+	}
+	// Any of the day's rows could have come first; it is the one no other
+	// row's balance leads into.
+	sameDay := rows.balanceRows
+	for _, tx := range sameDay {
+		start := tx.RunningBalance.Amount() - tx.Amount.Amount()
+		if !slices.ContainsFunc(sameDay, func(other transaction.Transaction) bool {
+			return other.RunningBalance.Amount() == start
+		}) {
+			return start - before
+		}
+	}
+	return sameDay[0].RunningBalance.Amount() - sameDay[0].Amount.Amount() - before
+}
+
+// statementRows is what importing a statement needs to know of its
+// transactions besides the transactions themselves. Rows are added in
+// file order as they are read, so that a streamed statement need not be
+// held in memory to learn it.
+type statementRows struct {
+	first *transaction.Transaction
+	// currency is the first row's; rows in other currencies do not count
+	// towards the balances.
+	currency string
+	count    int
+	// dayTotals sums the amounts of each day's rows, keyed by the day's
+	// UnixNano.
+	dayTotals map[int64]int64
+	// balanceDay is the earliest day with a running balance, and
+	// balanceRows are that day's rows with one, in file order.
+	balanceDay  time.Time
+	balanceRows []transaction.Transaction
+}
+
+// summariseRows adds every transaction of a parsed statement.
+func summariseRows(transactions []transaction.Transaction) *statementRows {
+	rows := &statementRows{}
+	for _, tx := range transactions {
+		rows.add(tx)
+	}
+	return rows
+}
+
+func (r *statementRows) add(tx transaction.Transaction) {
+	r.count++
+	if r.first == nil {
+		r.first = &tx
+		if tx.Amount != nil {
+			r.currency = tx.Amount.Currency().Code
 		}
+	}
+	if tx.Amount == nil || tx.Amount.Currency().Code != r.currency {
+		return
+	}
 
-		respondWithSuccess(w, count)
+	if r.dayTotals == nil {
+		r.dayTotals = make(map[int64]int64)
+	}
+	r.dayTotals[tx.Date.UnixNano()] += tx.Amount.Amount()
+	if tx.RunningBalance == nil {
+		return
+	}
+	switch {
+	case len(r.balanceRows) == 0 || tx.Date.Before(r.balanceDay):
+		r.balanceDay = tx.Date
+		r.balanceRows = []transaction.Transaction{tx}
+	case tx.Date.Equal(r.balanceDay):
+		r.balanceRows = append(r.balanceRows, tx)
 	}
 }
 
+func detectionCandidates(err *csvparser.DetectionError) []string {
+	if len(err.Candidates) > 0 {
+		return err.Candidates
+	}
+	return err.Supported
+}
+
 func determineStatusCode(err error) int {
-	if errors.Is(err, transaction.ErrParseFailure) {
+	if errors.Is(err, transaction.ErrNotFound) || errors.Is(err, transaction.ErrImportNotFound) ||
+		errors.Is(err, account.ErrNotFound) || errors.Is(err, importjob.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, transaction.ErrImportConflict) || errors.Is(err, account.ErrInUse) ||
+		errors.Is(err, csvparser.ErrProfileConflict) || errors.Is(err, importjob.ErrConflict) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, importjob.ErrQueueFull) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, transaction.ErrValidation) || errors.Is(err, account.ErrValidation) ||
+		errors.Is(err, account.ErrAmbiguous) {
+		return http.StatusUnprocessableEntity
+	}
+	if errors.Is(err, transaction.ErrParseFailure) || errors.Is(err, csvparser.ErrInvalidProfile) {
 		return http.StatusUnprocessableEntity
 	}
 	if errors.Is(err, transaction.ErrDatabaseFailure) {
@@ -66,9 +720,24 @@ func determineStatusCode(err error) int {
 	return http.StatusInternalServerError
 }
 
-func respondWithSuccess(w http.ResponseWriter, count int64) {
-	response := UploadResponse{
-		Message: fmt.Sprintf("Successfully uploaded %d transactions", count),
+func respondWithSuccess(w http.ResponseWriter, files []UploadFileSummary) {
+	response := UploadResponse{Imports: []UploadImportSummary{}, Files: files}
+	for _, file := range files {
+		response.Imports = append(response.Imports, file.Imports...)
+		response.Rejected = append(response.Rejected, file.Rejected...)
+		response.Inserted += file.Inserted
+		response.Skipped += file.Skipped
+	}
+	if len(response.Imports) > 0 {
+		response.ImportID = response.Imports[0].ImportID
+		response.AccountID = response.Imports[0].AccountID
+	}
+	response.Message = fmt.Sprintf("Successfully uploaded %d transactions", response.Inserted)
+	if len(files) > 1 {
+		response.Message += fmt.Sprintf(" from %d files", len(files))
+	}
+	if len(response.Rejected) > 0 {
+		response.Message += fmt.Sprintf("; %d rows were rejected", len(response.Rejected))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -78,6 +747,14 @@ func respondWithSuccess(w http.ResponseWriter, count int64) {
 	}
 }
 
+func respondWithJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
 func respondWithError(w http.ResponseWriter, statusCode int, errorMsg string, details string) {
 	response := ErrorResponse{
 		Error:   errorMsg,
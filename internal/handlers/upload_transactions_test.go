@@ -3,21 +3,33 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Rhymond/go-money"
+	"github.com/kushturner/finances/internal/account"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/db"
 	"github.com/kushturner/finances/internal/transaction"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockParserService struct {
-	parseFunc func(r io.Reader, bankType string) ([]transaction.Transaction, error)
+	parseFunc           func(r io.Reader, bankType string) ([]transaction.Transaction, error)
+	parseStatementFunc  func(r io.Reader, bankType string) (csvparser.Statement, error)
+	parseStatementsFunc func(r io.Reader, bankType string) ([]csvparser.Statement, error)
+	parseOptionsFunc    func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error)
+	profilesFunc        func() []csvparser.Profile
+	addProfileFunc      func(profile csvparser.Profile) error
 }
 
 func (m *mockParserService) Parse(r io.Reader, bankType string) ([]transaction.Transaction, error) {
@@ -27,6 +39,63 @@ func (m *mockParserService) Parse(r io.Reader, bankType string) ([]transaction.T
 	return nil, nil
 }
 
+func (m *mockParserService) ParseStatement(r io.Reader, bankType string) (csvparser.Statement, error) {
+	if m.parseStatementFunc != nil {
+		return m.parseStatementFunc(r, bankType)
+	}
+	transactions, err := m.Parse(r, bankType)
+	if err != nil {
+		return csvparser.Statement{}, err
+	}
+	return csvparser.Statement{Transactions: transactions}, nil
+}
+
+func (m *mockParserService) ParseStatements(r io.Reader, bankType string) ([]csvparser.Statement, error) {
+	if m.parseStatementsFunc != nil {
+		return m.parseStatementsFunc(r, bankType)
+	}
+	statement, err := m.ParseStatement(r, bankType)
+	if err != nil {
+		return nil, err
+	}
+	return []csvparser.Statement{statement}, nil
+}
+
+func (m *mockParserService) ParseStatementsWithOptions(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+	if m.parseOptionsFunc != nil {
+		return m.parseOptionsFunc(r, options)
+	}
+	statements, err := m.ParseStatements(r, options.BankType)
+	if err != nil {
+		return csvparser.ParseResult{}, err
+	}
+	return csvparser.ParseResult{Statements: statements}, nil
+}
+
+// StreamStatement treats every upload as a file to be parsed whole; tests
+// of streamed uploads use the real parser service.
+func (m *mockParserService) StreamStatement(r io.Reader, options csvparser.ParseOptions) (*csvparser.StatementStream, error) {
+	return nil, csvparser.ErrNotStreamable
+}
+
+func (m *mockParserService) SupportedFormats() []string {
+	return []string{"nationwide", "amex"}
+}
+
+func (m *mockParserService) Profiles() []csvparser.Profile {
+	if m.profilesFunc != nil {
+		return m.profilesFunc()
+	}
+	return nil
+}
+
+func (m *mockParserService) AddProfile(profile csvparser.Profile) error {
+	if m.addProfileFunc != nil {
+		return m.addProfileFunc(profile)
+	}
+	return nil
+}
+
 func createMultipartRequest(t *testing.T, csvContent string, bankType string) *http.Request {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -50,8 +119,8 @@ func TestUploadTransactionsHandler_Success_Nationwide(t *testing.T) {
 	csvContent := `some csv content`
 
 	mockTxService := &mockTransactionService{
-		addTransactionsFunc: func(ctx context.Context, transactions []transaction.Transaction) (int64, error) {
-			return int64(len(transactions)), nil
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{Inserted: int64(len(transactions))}, nil
 		},
 	}
 
@@ -68,7 +137,7 @@ func TestUploadTransactionsHandler_Success_Nationwide(t *testing.T) {
 	req := createMultipartRequest(t, csvContent, "nationwide")
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
@@ -83,8 +152,8 @@ func TestUploadTransactionsHandler_Success_Amex(t *testing.T) {
 	csvContent := `some csv content`
 
 	mockTxService := &mockTransactionService{
-		addTransactionsFunc: func(ctx context.Context, transactions []transaction.Transaction) (int64, error) {
-			return int64(len(transactions)), nil
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{Inserted: int64(len(transactions))}, nil
 		},
 	}
 
@@ -101,7 +170,7 @@ func TestUploadTransactionsHandler_Success_Amex(t *testing.T) {
 	req := createMultipartRequest(t, csvContent, "amex")
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
@@ -126,7 +195,7 @@ func TestUploadTransactionsHandler_ParsingError(t *testing.T) {
 	req := createMultipartRequest(t, csvContent, "amex")
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
@@ -134,7 +203,7 @@ func TestUploadTransactionsHandler_ParsingError(t *testing.T) {
 	var response ErrorResponse
 	err := json.NewDecoder(rec.Body).Decode(&response)
 	assert.NoError(t, err)
-	assert.Equal(t, "Failed to parse CSV file", response.Error)
+	assert.Equal(t, "Failed to parse file", response.Error)
 }
 
 func TestUploadTransactionsHandler_MissingFile(t *testing.T) {
@@ -145,7 +214,7 @@ func TestUploadTransactionsHandler_MissingFile(t *testing.T) {
 	req.Header.Set("Content-Type", "multipart/form-data")
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
@@ -155,8 +224,8 @@ func TestUploadTransactionsHandler_DatabaseError(t *testing.T) {
 	csvContent := `some csv content`
 
 	mockTxService := &mockTransactionService{
-		addTransactionsFunc: func(ctx context.Context, transactions []transaction.Transaction) (int64, error) {
-			return 0, transaction.ErrDatabaseFailure
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{}, transaction.ErrDatabaseFailure
 		},
 	}
 
@@ -171,7 +240,7 @@ func TestUploadTransactionsHandler_DatabaseError(t *testing.T) {
 	req := createMultipartRequest(t, csvContent, "amex")
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
@@ -182,11 +251,22 @@ func TestUploadTransactionsHandler_DatabaseError(t *testing.T) {
 	assert.Equal(t, "Upload failed", response.Error)
 }
 
-func TestUploadTransactionsHandler_MissingBankParameter(t *testing.T) {
+func TestUploadTransactionsHandler_MissingBankParameterDetectsFormat(t *testing.T) {
 	csvContent := `some csv content`
 
-	mockTxService := &mockTransactionService{}
-	mockParser := &mockParserService{}
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{Inserted: int64(len(transactions))}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseFunc: func(r io.Reader, bankType string) ([]transaction.Transaction, error) {
+			assert.Equal(t, "", bankType)
+			return []transaction.Transaction{
+				{Bank: "Nationwide", Description: "TEST", Amount: money.New(-100, "GBP")},
+			}, nil
+		},
+	}
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -204,23 +284,163 @@ func TestUploadTransactionsHandler_MissingBankParameter(t *testing.T) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response UploadResponse
+	err = json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Successfully uploaded 1 transactions", response.Message)
+}
+
+func TestUploadTransactionsHandler_ParseOptions(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{Inserted: int64(len(transactions))}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			assert.Equal(t, csvparser.ParseOptions{BankType: "amex", Sheet: "Transactions", Encoding: "windows-1252"}, options)
+			return csvparser.ParseResult{Statements: []csvparser.Statement{{Transactions: []transaction.Transaction{
+				{Bank: "amex", Description: "TEST", Amount: money.New(-2550, "GBP")},
+			}}}}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "PK", "amex")
+	req.URL.RawQuery += "&sheet=Transactions&encoding=windows-1252"
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUploadTransactionsHandler_LenientReportsRejectedRows(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{Inserted: int64(len(transactions))}, nil
+		},
+	}
+	rejected := []csvparser.RowError{{Row: 2, Raw: "32/01/2026,TEST,1.00", Column: "Date", Reason: "parsing date '32/01/2026': day out of range"}}
+	mockParser := &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			assert.True(t, options.Lenient)
+			return csvparser.ParseResult{
+				Statements: []csvparser.Statement{{Transactions: []transaction.Transaction{
+					{Bank: "amex", Description: "TEST", Amount: money.New(-2550, "GBP")},
+				}}},
+				Rejected: rejected,
+			}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "amex")
+	req.URL.RawQuery += "&mode=lenient"
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response UploadResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Successfully uploaded 1 transactions; 1 rows were rejected", response.Message)
+	assert.Equal(t, rejected, response.Rejected)
+}
+
+func TestUploadTransactionsHandler_InvalidMode(t *testing.T) {
+	req := createMultipartRequest(t, "some csv content", "amex")
+	req.URL.RawQuery += "&mode=relaxed"
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(&mockTransactionService{}, &mockParserService{}, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 
 	var response ErrorResponse
-	err = json.NewDecoder(rec.Body).Decode(&response)
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid import mode", response.Error)
+}
+
+func TestUploadTransactionsHandler_LenientWholeFileFormat(t *testing.T) {
+	mockParser := &mockParserService{
+		parseOptionsFunc: func(r io.Reader, options csvparser.ParseOptions) (csvparser.ParseResult, error) {
+			return csvparser.ParseResult{}, fmt.Errorf("%w for ofx files", csvparser.ErrLenientUnsupported)
+		},
+	}
+
+	req := createMultipartRequest(t, "OFXHEADER:100", "ofx")
+	req.URL.RawQuery += "&mode=lenient"
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, mockParser, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "Invalid import mode", response.Error)
+}
+
+func TestUploadTransactionsHandler_AmbiguousFormat(t *testing.T) {
+	mockTxService := &mockTransactionService{}
+	mockParser := &mockParserService{
+		parseFunc: func(r io.Reader, bankType string) ([]transaction.Transaction, error) {
+			return nil, &csvparser.DetectionError{Candidates: []string{"amex", "nationwide"}}
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "")
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var response ErrorResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Could not detect bank format", response.Error)
+	assert.Equal(t, []string{"amex", "nationwide"}, response.Candidates)
+}
+
+func TestUploadTransactionsHandler_UnknownFormatListsSupported(t *testing.T) {
+	mockTxService := &mockTransactionService{}
+	mockParser := &mockParserService{
+		parseFunc: func(r io.Reader, bankType string) ([]transaction.Transaction, error) {
+			return nil, &csvparser.DetectionError{Supported: []string{"nationwide", "amex"}}
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "")
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var response ErrorResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
 	assert.NoError(t, err)
-	assert.Equal(t, "Missing bank type parameter", response.Error)
+	assert.Equal(t, []string{"nationwide", "amex"}, response.Candidates)
 }
 
 func TestUploadTransactionsHandler_EmptyCSVFile(t *testing.T) {
 	csvContent := ""
 
 	mockTxService := &mockTransactionService{
-		addTransactionsFunc: func(ctx context.Context, transactions []transaction.Transaction) (int64, error) {
-			return 0, nil
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{}, nil
 		},
 	}
 
@@ -233,7 +453,7 @@ func TestUploadTransactionsHandler_EmptyCSVFile(t *testing.T) {
 	req := createMultipartRequest(t, csvContent, "nationwide")
 	rec := httptest.NewRecorder()
 
-	handler := NewUploadTransactionsHandler(mockTxService, mockParser)
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
 	handler(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
@@ -243,3 +463,396 @@ func TestUploadTransactionsHandler_EmptyCSVFile(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Successfully uploaded 0 transactions", response.Message)
 }
+
+func TestUploadTransactionsHandler_RecordsImportSource(t *testing.T) {
+	csvContent := "Date,Description,Amount\n"
+
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			assert.Equal(t, "statement.csv", source.FileName)
+			assert.Equal(t, "American Express", source.Bank)
+			sum := sha256.Sum256([]byte(csvContent))
+			assert.Equal(t, hex.EncodeToString(sum[:]), source.Checksum)
+			return transaction.ImportResult{BatchID: 42, Inserted: 1}, nil
+		},
+	}
+
+	mockParser := &mockParserService{
+		parseFunc: func(r io.Reader, bankType string) ([]transaction.Transaction, error) {
+			content, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, csvContent, string(content))
+			return []transaction.Transaction{
+				{Bank: "American Express", Description: "A", Amount: money.New(100, "GBP")},
+			}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, csvContent, "amex")
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response UploadResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(42), response.ImportID)
+}
+
+func TestUploadTransactionsHandler_ReportsSkippedDuplicates(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			return transaction.ImportResult{Inserted: 1, Skipped: 2}, nil
+		},
+	}
+
+	mockParser := &mockParserService{
+		parseFunc: func(r io.Reader, bankType string) ([]transaction.Transaction, error) {
+			return []transaction.Transaction{
+				{Bank: "Nationwide", Description: "A", Amount: money.New(-100, "GBP")},
+				{Bank: "Nationwide", Description: "B", Amount: money.New(-200, "GBP")},
+				{Bank: "Nationwide", Description: "C", Amount: money.New(-300, "GBP")},
+			}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "nationwide")
+	rec := httptest.NewRecorder()
+
+	handler := NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})
+	handler(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response UploadResponse
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Successfully uploaded 1 transactions", response.Message)
+	assert.Equal(t, int64(1), response.Inserted)
+	assert.Equal(t, int64(2), response.Skipped)
+}
+
+func TestUploadTransactionsHandler_MatchesAccountFromStatement(t *testing.T) {
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			assert.Equal(t, int32(8), *source.AccountID)
+			for _, tx := range transactions {
+				assert.Equal(t, int32(8), *tx.AccountID)
+			}
+			return transaction.ImportResult{Inserted: int64(len(transactions))}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseStatementFunc: func(r io.Reader, bankType string) (csvparser.Statement, error) {
+			return csvparser.Statement{
+				Format:      "nationwide",
+				Institution: "Nationwide",
+				Account:     csvparser.AccountDetails{Name: "Debit", MaskedNumber: "****12345"},
+				Transactions: []transaction.Transaction{
+					{Bank: "Nationwide", Description: "A", Amount: money.New(-100, "GBP")},
+				},
+			}, nil
+		},
+	}
+	mockAccounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			assert.Nil(t, id)
+			assert.Equal(t, account.Hint{
+				Institution:  "Nationwide",
+				Name:         "Debit",
+				MaskedNumber: "****12345",
+				Currency:     "GBP",
+			}, hint)
+			return account.Account{ID: 8}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(mockTxService, mockParser, mockAccounts, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response UploadResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, int32(8), response.AccountID)
+}
+
+func TestUploadTransactionsHandler_SeedsOpeningBalanceFromRunningBalances(t *testing.T) {
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	mockParser := &mockParserService{
+		parseStatementFunc: func(r io.Reader, bankType string) (csvparser.Statement, error) {
+			// Newest first, with two rows on the earliest day.
+			return csvparser.Statement{
+				Institution: "Monzo",
+				Transactions: []transaction.Transaction{
+					{Date: day.AddDate(0, 0, 1), Amount: money.New(-500, "GBP"), RunningBalance: money.New(9200, "GBP")},
+					{Date: day, Amount: money.New(-300, "GBP"), RunningBalance: money.New(9700, "GBP")},
+					{Date: day, Amount: money.New(-1000, "GBP"), RunningBalance: money.New(10000, "GBP")},
+				},
+			}, nil
+		},
+	}
+	var hint account.Hint
+	mockAccounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, h account.Hint) (account.Account, error) {
+			hint = h
+			return account.Account{ID: 1}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, mockParser, mockAccounts, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(11000), hint.OpeningBalance)
+}
+
+// accountStore keeps accounts in memory for the account service.
+type accountStore struct {
+	db.Store
+	accounts []db.Account
+}
+
+func (s *accountStore) ListAccountsByInstitution(ctx context.Context, institution string) ([]db.Account, error) {
+	var matches []db.Account
+	for _, a := range s.accounts {
+		if a.Institution == institution {
+			matches = append(matches, a)
+		}
+	}
+	return matches, nil
+}
+
+func (s *accountStore) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	a := db.Account{
+		ID:             int32(len(s.accounts) + 1),
+		Institution:    arg.Institution,
+		Name:           arg.Name,
+		MaskedNumber:   arg.MaskedNumber,
+		AccountType:    arg.AccountType,
+		Currency:       arg.Currency,
+		OpeningBalance: arg.OpeningBalance,
+	}
+	s.accounts = append(s.accounts, a)
+	return a, nil
+}
+
+func TestUploadTransactionsHandler_TwoCurrencyFileUsesAccountPerCurrency(t *testing.T) {
+	csv := "TransferWise ID,Date,Amount,Currency,Description,Running Balance\n" +
+		"TRANSFER-1,14-01-2026,500.00,GBP,Salary,500.00\n" +
+		"CARD-2,16-01-2026,-12.00,EUR,Cafe Paris,103.42\n"
+	store := &accountStore{}
+	handler := NewUploadTransactionsHandler(&mockTransactionService{}, csvparser.NewService(), account.NewService(store), &mockImportJobService{})
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler(rec, createMultipartRequest(t, csv, ""))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response UploadResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+		if assert.Len(t, response.Imports, 2) {
+			assert.Equal(t, int32(1), response.Imports[0].AccountID)
+			assert.Equal(t, int32(2), response.Imports[1].AccountID)
+		}
+	}
+
+	// The second upload lands on the accounts the first created.
+	if assert.Len(t, store.accounts, 2) {
+		assert.Equal(t, "GBP", store.accounts[0].Currency)
+		assert.Equal(t, "EUR", store.accounts[1].Currency)
+	}
+}
+
+func TestUploadTransactionsHandler_ExplicitAccount(t *testing.T) {
+	mockAccounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			assert.Equal(t, int32(3), *id)
+			return account.Account{ID: 3}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "amex")
+	req.URL.RawQuery += "&account_id=3"
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, &mockParserService{}, mockAccounts, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUploadTransactionsHandler_InvalidAccountID(t *testing.T) {
+	req := createMultipartRequest(t, "some csv content", "amex")
+	req.URL.RawQuery += "&account_id=abc"
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, &mockParserService{}, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUploadTransactionsHandler_AmbiguousAccount(t *testing.T) {
+	mockAccounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			return account.Account{}, account.ErrAmbiguous
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "amex")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, &mockParserService{}, mockAccounts, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestUploadTransactionsHandler_RecordsStatementBalances(t *testing.T) {
+	balanceDate := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			assert.Equal(t, int64(123456), source.ClosingBalance.Amount())
+			assert.Nil(t, source.AvailableBalance)
+			assert.Equal(t, balanceDate, *source.BalanceDate)
+			return transaction.ImportResult{}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseStatementFunc: func(r io.Reader, bankType string) (csvparser.Statement, error) {
+			return csvparser.Statement{
+				Institution:    "Nationwide",
+				ClosingBalance: money.New(123456, "GBP"),
+				BalanceDate:    balanceDate,
+			}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "some csv content", "nationwide")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUploadTransactionsHandler_ImportsEachStatement(t *testing.T) {
+	openingDate := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	var sources []transaction.ImportSource
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			sources = append(sources, source)
+			for _, tx := range transactions {
+				assert.Equal(t, *source.AccountID, *tx.AccountID)
+			}
+			return transaction.ImportResult{BatchID: int32(len(sources)), Inserted: int64(len(transactions)), Skipped: 1}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseStatementsFunc: func(r io.Reader, bankType string) ([]csvparser.Statement, error) {
+			return []csvparser.Statement{
+				{
+					Institution:        "Test Bank",
+					Account:            csvparser.AccountDetails{MaskedNumber: "****6819"},
+					OpeningBalance:     money.New(100000, "GBP"),
+					OpeningBalanceDate: openingDate,
+					Transactions: []transaction.Transaction{
+						{Description: "A", Amount: money.New(-100, "GBP")},
+						{Description: "B", Amount: money.New(-200, "GBP")},
+					},
+				},
+				{
+					Institution:  "Test Bank",
+					Account:      csvparser.AccountDetails{MaskedNumber: "****3000"},
+					Transactions: []transaction.Transaction{{Description: "C", Amount: money.New(-300, "EUR")}},
+				},
+			}, nil
+		},
+	}
+	mockAccounts := &mockAccountService{
+		resolveAccountFunc: func(ctx context.Context, id *int32, hint account.Hint) (account.Account, error) {
+			if hint.MaskedNumber == "****3000" {
+				assert.Equal(t, "EUR", hint.Currency)
+				return account.Account{ID: 9}, nil
+			}
+			assert.Equal(t, int64(100000), hint.OpeningBalance)
+			return account.Account{ID: 8}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "<Document/>", "")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(mockTxService, mockParser, mockAccounts, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response UploadResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "Successfully uploaded 3 transactions", response.Message)
+	assert.Equal(t, int32(1), response.ImportID)
+	assert.Equal(t, int32(8), response.AccountID)
+	assert.Equal(t, int64(2), response.Skipped)
+	assert.Equal(t, []UploadImportSummary{
+		{ImportID: 1, AccountID: 8, Inserted: 2, Skipped: 1},
+		{ImportID: 2, AccountID: 9, Inserted: 1, Skipped: 1},
+	}, response.Imports)
+
+	assert.Len(t, sources, 2)
+	assert.Equal(t, int64(100000), sources[0].OpeningBalance.Amount())
+	assert.Equal(t, openingDate, *sources[0].OpeningBalanceDate)
+	assert.Nil(t, sources[1].OpeningBalance)
+	assert.Nil(t, sources[1].OpeningBalanceDate)
+	assert.Equal(t, sources[0].Checksum, sources[1].Checksum)
+}
+
+func TestUploadTransactionsHandler_ExplicitAccountWithSeveralStatements(t *testing.T) {
+	mockParser := &mockParserService{
+		parseStatementsFunc: func(r io.Reader, bankType string) ([]csvparser.Statement, error) {
+			return []csvparser.Statement{{}, {}}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "<Document/>", "camt")
+	req.URL.RawQuery += "&account_id=3"
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(&mockTransactionService{}, mockParser, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Contains(t, response.Details, "contains 2 statements")
+}
+
+func TestUploadTransactionsHandler_StatementFailureReportsEarlierImports(t *testing.T) {
+	calls := 0
+	mockTxService := &mockTransactionService{
+		addTransactionsFunc: func(ctx context.Context, source transaction.ImportSource, transactions []transaction.Transaction) (transaction.ImportResult, error) {
+			calls++
+			if calls == 2 {
+				return transaction.ImportResult{}, transaction.ErrDatabaseFailure
+			}
+			return transaction.ImportResult{BatchID: 1}, nil
+		},
+	}
+	mockParser := &mockParserService{
+		parseStatementsFunc: func(r io.Reader, bankType string) ([]csvparser.Statement, error) {
+			return []csvparser.Statement{{}, {}}, nil
+		},
+	}
+
+	req := createMultipartRequest(t, "<Document/>", "camt")
+	rec := httptest.NewRecorder()
+
+	NewUploadTransactionsHandler(mockTxService, mockParser, &mockAccountService{}, &mockImportJobService{})(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "Upload failed", response.Error)
+	assert.Equal(t, "statement 2 of 2: database failure; 1 earlier statements were imported", response.Details)
+}
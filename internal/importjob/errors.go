@@ -0,0 +1,10 @@
+package importjob
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("import job not found")
+	ErrConflict        = errors.New("import job conflict")
+	ErrQueueFull       = errors.New("import queue full")
+	ErrDatabaseFailure = errors.New("database failure")
+)
@@ -0,0 +1,74 @@
+package importjob
+
+import (
+	"time"
+
+	"github.com/kushturner/finances/internal/csvparser"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job is an upload imported in the background. Its files are spooled to
+// temporary files that are removed once the job ends, so a job never
+// outlives the process that accepted it.
+type Job struct {
+	ID           int32
+	FileName     string
+	Checksum     string
+	Status       string
+	Progress     Progress
+	ErrorMessage *string
+	CreatedAt    time.Time
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+}
+
+// Progress is how far a job has got. RowsDone counts the rows handed to
+// the database so far; each statement is imported in its own database
+// transaction, so they are only stored once StatementsDone counts it.
+type Progress struct {
+	Statements     int
+	StatementsDone int
+	Rows           int
+	RowsDone       int
+	// Bytes and BytesDone measure how much of the upload has been read,
+	// for a file that is streamed and so has its rows counted only once
+	// they have all been read.
+	Bytes     int64
+	BytesDone int64
+	Inserted  int64
+	Skipped   int64
+	// ImportIDs are the import batches created so far, one per statement,
+	// which can each be rolled back.
+	ImportIDs []int32
+	// Rejected are the rows a lenient upload left out.
+	Rejected []csvparser.RowError
+	// Files is what became of each file of an upload of several.
+	Files []FileResult
+}
+
+// FileResult is what became of one file of an upload. A file that failed
+// imported nothing, and Error says why.
+type FileResult struct {
+	FileName string `json:"file_name"`
+	Format   string `json:"format"`
+	Inserted int64  `json:"inserted"`
+	Skipped  int64  `json:"skipped"`
+	Failed   bool   `json:"failed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Done reports whether the job has stopped, whether or not it succeeded.
+func (j Job) Done() bool {
+	switch j.Status {
+	case StatusCompleted, StatusFailed, StatusCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package importjob
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/db"
+)
+
+func JobFromDB(j db.ImportJob) Job {
+	job := Job{
+		ID:       j.ID,
+		FileName: j.FileName,
+		Checksum: j.Checksum,
+		Status:   j.Status,
+		Progress: Progress{
+			Statements:     int(j.StatementCount),
+			StatementsDone: int(j.StatementsDone),
+			Rows:           int(j.RowCount),
+			RowsDone:       int(j.RowsDone),
+			Bytes:          j.ByteCount,
+			BytesDone:      j.BytesDone,
+			Inserted:       int64(j.InsertedCount),
+			Skipped:        int64(j.SkippedCount),
+			ImportIDs:      j.ImportBatchIds,
+		},
+		ErrorMessage: textOrNil(j.ErrorMessage),
+		CreatedAt:    j.CreatedAt.Time,
+		StartedAt:    timestampOrNil(j.StartedAt),
+		FinishedAt:   timestampOrNil(j.FinishedAt),
+	}
+	// The rows and files were written by progressToDB, so they always
+	// decode.
+	var rejected []csvparser.RowError
+	if err := json.Unmarshal(j.RejectedRows, &rejected); err == nil {
+		job.Progress.Rejected = rejected
+	}
+	var files []FileResult
+	if err := json.Unmarshal(j.Files, &files); err == nil {
+		job.Progress.Files = files
+	}
+	return job
+}
+
+func progressToDB(id int32, p Progress) (db.UpdateImportJobProgressParams, error) {
+	rejected := p.Rejected
+	if rejected == nil {
+		rejected = []csvparser.RowError{}
+	}
+	rejectedRows, err := json.Marshal(rejected)
+	if err != nil {
+		return db.UpdateImportJobProgressParams{}, err
+	}
+	files := p.Files
+	if files == nil {
+		files = []FileResult{}
+	}
+	fileResults, err := json.Marshal(files)
+	if err != nil {
+		return db.UpdateImportJobProgressParams{}, err
+	}
+	importIDs := p.ImportIDs
+	if importIDs == nil {
+		importIDs = []int32{}
+	}
+	return db.UpdateImportJobProgressParams{
+		ID:             id,
+		StatementCount: int32(p.Statements),
+		StatementsDone: int32(p.StatementsDone),
+		RowCount:       int32(p.Rows),
+		RowsDone:       int32(p.RowsDone),
+		InsertedCount:  int32(p.Inserted),
+		SkippedCount:   int32(p.Skipped),
+		ImportBatchIds: importIDs,
+		RejectedRows:   rejectedRows,
+		ByteCount:      p.Bytes,
+		BytesDone:      p.BytesDone,
+		Files:          fileResults,
+	}, nil
+}
+
+func textOrNil(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func textFromPtr(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+func timestampOrNil(t pgtype.Timestamp) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
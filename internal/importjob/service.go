@@ -0,0 +1,235 @@
+package importjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kushturner/finances/internal/db"
+)
+
+// interruptedMessage is recorded against jobs the server stopped before they
+// finished, and those left queued or running by a previous process, whose
+// uploads went with it.
+const interruptedMessage = "the server restarted before the import finished; upload the file again"
+
+// errCancelled is the cause given to a running job's context when it is
+// cancelled, telling it apart from the workers shutting down.
+var errCancelled = errors.New("import job cancelled")
+
+// Work imports a job's upload, calling report whenever it has made
+// progress. It should stop, returning an error, once ctx is cancelled.
+//
+// Work is called exactly once for every job Submit accepts, even one that
+// is cancelled before it starts, which it is then called with a cancelled
+// ctx, so that it can always release the upload it holds. It is never
+// called for a job Submit refuses.
+type Work func(ctx context.Context, report func(Progress)) error
+
+type Service interface {
+	// Submit records a queued job for an upload and hands work to the
+	// workers to run. It fails with ErrQueueFull rather than wait when
+	// every worker is busy and the queue is full.
+	Submit(ctx context.Context, fileName, checksum string, work Work) (Job, error)
+	GetJob(ctx context.Context, id int32) (Job, error)
+	// Cancel stops a queued or running job. A running job stops once its
+	// work next checks its context, which rolls back the statement it was
+	// importing; statements it had already imported are kept.
+	Cancel(ctx context.Context, id int32) (Job, error)
+	// Start fails the jobs a previous process left unfinished and starts
+	// the workers, which run until ctx is done. It must be called before
+	// any job is submitted, which it would otherwise fail too.
+	Start(ctx context.Context) error
+	// Wait returns once the workers have stopped after Start's ctx is done,
+	// failing the jobs still queued. Nothing may be submitted once it has
+	// been called.
+	Wait()
+}
+
+type service struct {
+	store   db.Store
+	workers int
+	queue   chan queuedJob
+	stopped sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[int32]context.CancelCauseFunc
+}
+
+type queuedJob struct {
+	id   int32
+	work Work
+}
+
+// NewService returns a service running jobs on the given number of workers,
+// with room for queueSize more to wait.
+func NewService(store db.Store, workers, queueSize int) Service {
+	return &service{
+		store:   store,
+		workers: workers,
+		queue:   make(chan queuedJob, queueSize),
+		running: make(map[int32]context.CancelCauseFunc),
+	}
+}
+
+func (s *service) Submit(ctx context.Context, fileName, checksum string, work Work) (Job, error) {
+	dbJob, err := s.store.CreateImportJob(ctx, db.CreateImportJobParams{FileName: fileName, Checksum: checksum})
+	if err != nil {
+		return Job{}, wrapQueryError(err)
+	}
+
+	select {
+	case s.queue <- queuedJob{id: dbJob.ID, work: work}:
+		return JobFromDB(dbJob), nil
+	default:
+		message := "the import queue was full"
+		// Best effort: the job is refused either way, and failing it keeps
+		// it from looking queued.
+		_, _ = s.store.FinishImportJob(ctx, db.FinishImportJobParams{ID: dbJob.ID, Status: StatusFailed, ErrorMessage: textFromPtr(&message)})
+		return Job{}, fmt.Errorf("%w: %d jobs are already waiting", ErrQueueFull, cap(s.queue))
+	}
+}
+
+func (s *service) GetJob(ctx context.Context, id int32) (Job, error) {
+	dbJob, err := s.store.GetImportJob(ctx, id)
+	if err != nil {
+		return Job{}, wrapQueryError(err)
+	}
+	return JobFromDB(dbJob), nil
+}
+
+func (s *service) Cancel(ctx context.Context, id int32) (Job, error) {
+	dbJob, err := s.store.CancelQueuedImportJob(ctx, id)
+	if err == nil {
+		return JobFromDB(dbJob), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Job{}, wrapQueryError(err)
+	}
+
+	// A worker registers a job before starting it, so a job that was not
+	// queued is either registered here or already finished.
+	s.mu.Lock()
+	cancel, running := s.running[id]
+	s.mu.Unlock()
+	if running {
+		cancel(errCancelled)
+	}
+
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if !running {
+		return Job{}, fmt.Errorf("%w: import job %d is %s", ErrConflict, id, job.Status)
+	}
+	return job, nil
+}
+
+func (s *service) Start(ctx context.Context) error {
+	// Their uploads went with the process, so these jobs can never finish.
+	message := interruptedMessage
+	if _, err := s.store.FailInterruptedImportJobs(ctx, textFromPtr(&message)); err != nil {
+		return wrapQueryError(err)
+	}
+
+	s.stopped.Add(s.workers)
+	for range s.workers {
+		go func() {
+			defer s.stopped.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.queue:
+					s.run(ctx, job)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func (s *service) Wait() {
+	s.stopped.Wait()
+
+	// The work of the jobs left queued is called as for a job cancelled
+	// while it waited, so that it releases its upload.
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	for drained := false; !drained; {
+		select {
+		case job := <-s.queue:
+			_ = job.work(done, func(Progress) {})
+		default:
+			drained = true
+		}
+	}
+
+	// Best effort: the next Start fails these jobs if this does not.
+	message := interruptedMessage
+	_, _ = s.store.FailInterruptedImportJobs(context.Background(), textFromPtr(&message))
+}
+
+// run starts a queued job, unless it was cancelled while it waited, and
+// records how it ended.
+func (s *service) run(ctx context.Context, job queuedJob) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	s.mu.Lock()
+	s.running[job.id] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.id)
+		s.mu.Unlock()
+	}()
+
+	if _, err := s.store.StartImportJob(ctx, job.id); err != nil {
+		// Cancelled while queued, or the database is unavailable; either
+		// way there is nothing to run, but the work still releases its
+		// upload.
+		cancel(err)
+		_ = job.work(jobCtx, func(Progress) {})
+		return
+	}
+
+	// Progress and the outcome are still recorded once the job's context
+	// is cancelled.
+	record := context.WithoutCancel(ctx)
+	err := job.work(jobCtx, func(p Progress) {
+		params, err := progressToDB(job.id, p)
+		if err != nil {
+			return
+		}
+		// Best effort: a missed update is made good by the next one.
+		_ = s.store.UpdateImportJobProgress(record, params)
+	})
+
+	params := db.FinishImportJobParams{ID: job.id, Status: StatusCompleted}
+	switch {
+	case err == nil:
+	case errors.Is(context.Cause(jobCtx), errCancelled):
+		params.Status = StatusCancelled
+	case ctx.Err() != nil:
+		message := interruptedMessage
+		params.Status = StatusFailed
+		params.ErrorMessage = textFromPtr(&message)
+	default:
+		params.Status = StatusFailed
+		message := err.Error()
+		params.ErrorMessage = textFromPtr(&message)
+	}
+	// Nothing is left to report a failure to.
+	_, _ = s.store.FinishImportJob(record, params)
+}
+
+func wrapQueryError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+}
@@ -0,0 +1,309 @@
+package importjob
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/csvparser"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockStore keeps import jobs in memory, following the status rules of the
+// import job queries.
+type mockStore struct {
+	db.Store
+
+	mu      sync.Mutex
+	jobs    map[int32]db.ImportJob
+	nextID  int32
+	updates []db.UpdateImportJobProgressParams
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{jobs: make(map[int32]db.ImportJob)}
+}
+
+func (m *mockStore) CreateImportJob(ctx context.Context, arg db.CreateImportJobParams) (db.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	job := db.ImportJob{ID: m.nextID, FileName: arg.FileName, Checksum: arg.Checksum, Status: StatusQueued, RejectedRows: []byte("[]"), Files: []byte("[]")}
+	m.jobs[job.ID] = job
+	return job, nil
+}
+
+func (m *mockStore) GetImportJob(ctx context.Context, id int32) (db.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return db.ImportJob{}, pgx.ErrNoRows
+	}
+	return job, nil
+}
+
+func (m *mockStore) StartImportJob(ctx context.Context, id int32) (db.ImportJob, error) {
+	return m.transition(id, StatusQueued, StatusRunning, pgtype.Text{})
+}
+
+func (m *mockStore) CancelQueuedImportJob(ctx context.Context, id int32) (db.ImportJob, error) {
+	return m.transition(id, StatusQueued, StatusCancelled, pgtype.Text{})
+}
+
+func (m *mockStore) FinishImportJob(ctx context.Context, arg db.FinishImportJobParams) (db.ImportJob, error) {
+	return m.transition(arg.ID, "", arg.Status, arg.ErrorMessage)
+}
+
+func (m *mockStore) UpdateImportJobProgress(ctx context.Context, arg db.UpdateImportJobProgressParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates = append(m.updates, arg)
+	job := m.jobs[arg.ID]
+	job.StatementCount, job.StatementsDone = arg.StatementCount, arg.StatementsDone
+	job.RowCount, job.RowsDone = arg.RowCount, arg.RowsDone
+	job.ByteCount, job.BytesDone = arg.ByteCount, arg.BytesDone
+	job.InsertedCount, job.SkippedCount = arg.InsertedCount, arg.SkippedCount
+	job.ImportBatchIds, job.RejectedRows = arg.ImportBatchIds, arg.RejectedRows
+	job.Files = arg.Files
+	m.jobs[arg.ID] = job
+	return nil
+}
+
+func (m *mockStore) FailInterruptedImportJobs(ctx context.Context, errorMessage pgtype.Text) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, job := range m.jobs {
+		if job.Status == StatusQueued || job.Status == StatusRunning {
+			job.Status, job.ErrorMessage = StatusFailed, errorMessage
+			m.jobs[id] = job
+			n++
+		}
+	}
+	return n, nil
+}
+
+// transition moves a job to status, if it is in from or from is empty.
+func (m *mockStore) transition(id int32, from, status string, errorMessage pgtype.Text) (db.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || (from != "" && job.Status != from) {
+		return db.ImportJob{}, pgx.ErrNoRows
+	}
+	job.Status, job.ErrorMessage = status, errorMessage
+	m.jobs[id] = job
+	return job, nil
+}
+
+// waitForStatus polls until the job reaches status, as a client would.
+func waitForStatus(t *testing.T, service Service, id int32, status string) Job {
+	t.Helper()
+	var job Job
+	assert.Eventually(t, func() bool {
+		var err error
+		job, err = service.GetJob(context.Background(), id)
+		return err == nil && job.Status == status
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+func TestService_RunsJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := newMockStore()
+	service := NewService(store, 2, 4)
+	assert.NoError(t, service.Start(ctx))
+
+	job, err := service.Submit(ctx, "statement.csv", "abc", func(ctx context.Context, report func(Progress)) error {
+		report(Progress{Statements: 1, Rows: 3, Rejected: []csvparser.RowError{{Row: 4, Reason: "bad date"}}})
+		report(Progress{Statements: 1, StatementsDone: 1, Rows: 3, RowsDone: 3, Inserted: 2, Skipped: 1, ImportIDs: []int32{7}, Rejected: []csvparser.RowError{{Row: 4, Reason: "bad date"}}})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+
+	job = waitForStatus(t, service, job.ID, StatusCompleted)
+	assert.Len(t, store.updates, 2)
+	assert.Equal(t, Progress{
+		Statements: 1, StatementsDone: 1, Rows: 3, RowsDone: 3, Inserted: 2, Skipped: 1,
+		ImportIDs: []int32{7},
+		Rejected:  []csvparser.RowError{{Row: 4, Reason: "bad date"}},
+		Files:     []FileResult{},
+	}, job.Progress)
+	assert.Nil(t, job.ErrorMessage)
+	assert.True(t, job.Done())
+}
+
+func TestService_RecordsFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewService(newMockStore(), 1, 1)
+	assert.NoError(t, service.Start(ctx))
+
+	job, err := service.Submit(ctx, "statement.csv", "abc", func(ctx context.Context, report func(Progress)) error {
+		return errors.New("could not detect bank format")
+	})
+	assert.NoError(t, err)
+
+	job = waitForStatus(t, service, job.ID, StatusFailed)
+	assert.Equal(t, "could not detect bank format", *job.ErrorMessage)
+}
+
+func TestService_CancelQueued(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewService(newMockStore(), 1, 2)
+	assert.NoError(t, service.Start(ctx))
+
+	// The only worker is kept busy so that the next job stays queued.
+	release := make(chan struct{})
+	busy, err := service.Submit(ctx, "busy.csv", "abc", func(ctx context.Context, report func(Progress)) error {
+		<-release
+		return nil
+	})
+	assert.NoError(t, err)
+	job, err := service.Submit(ctx, "statement.csv", "def", func(ctx context.Context, report func(Progress)) error {
+		if ctx.Err() == nil {
+			t.Error("a cancelled job ran")
+		}
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	cancelled, err := service.Cancel(ctx, job.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+
+	close(release)
+	waitForStatus(t, service, busy.ID, StatusCompleted)
+	// The worker takes jobs in order, so the next one finishing means the
+	// cancelled one was skipped.
+	next, err := service.Submit(ctx, "next.csv", "ghi", func(ctx context.Context, report func(Progress)) error { return nil })
+	assert.NoError(t, err)
+	waitForStatus(t, service, next.ID, StatusCompleted)
+
+	job, err = service.GetJob(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, job.Status)
+}
+
+func TestService_CancelRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewService(newMockStore(), 1, 1)
+	assert.NoError(t, service.Start(ctx))
+
+	started := make(chan struct{})
+	job, err := service.Submit(ctx, "statement.csv", "abc", func(ctx context.Context, report func(Progress)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+	<-started
+
+	running, err := service.Cancel(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRunning, running.Status)
+
+	job = waitForStatus(t, service, job.ID, StatusCancelled)
+	assert.Nil(t, job.ErrorMessage)
+
+	_, err = service.Cancel(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorContains(t, err, "is cancelled")
+}
+
+func TestService_ShutdownFailsRunningJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	service := NewService(newMockStore(), 1, 1)
+	assert.NoError(t, service.Start(ctx))
+
+	started := make(chan struct{})
+	job, err := service.Submit(ctx, "statement.csv", "abc", func(ctx context.Context, report func(Progress)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+	<-started
+	cancel()
+
+	job = waitForStatus(t, service, job.ID, StatusFailed)
+	assert.Equal(t, interruptedMessage, *job.ErrorMessage)
+}
+
+func TestService_WaitReleasesQueuedJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := newMockStore()
+	service := NewService(store, 1, 2)
+	assert.NoError(t, service.Start(ctx))
+
+	started := make(chan struct{})
+	running, err := service.Submit(ctx, "running.csv", "abc", func(ctx context.Context, report func(Progress)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+	<-started
+	released := false
+	queued, err := service.Submit(ctx, "queued.csv", "def", func(ctx context.Context, report func(Progress)) error {
+		released = ctx.Err() != nil
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	cancel()
+	service.Wait()
+
+	assert.True(t, released)
+	for _, id := range []int32{running.ID, queued.ID} {
+		job, err := service.GetJob(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusFailed, job.Status)
+		assert.Equal(t, interruptedMessage, *job.ErrorMessage)
+	}
+}
+
+func TestService_QueueFull(t *testing.T) {
+	store := newMockStore()
+	service := NewService(store, 0, 1)
+	work := func(ctx context.Context, report func(Progress)) error { return nil }
+
+	_, err := service.Submit(context.Background(), "first.csv", "abc", work)
+	assert.NoError(t, err)
+	_, err = service.Submit(context.Background(), "second.csv", "def", work)
+
+	assert.ErrorIs(t, err, ErrQueueFull)
+	refused, err := service.GetJob(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, refused.Status)
+}
+
+func TestService_StartFailsInterruptedJobs(t *testing.T) {
+	store := newMockStore()
+	store.jobs[1] = db.ImportJob{ID: 1, Status: StatusRunning}
+	store.jobs[2] = db.ImportJob{ID: 2, Status: StatusCompleted}
+	service := NewService(store, 0, 1)
+
+	assert.NoError(t, service.Start(context.Background()))
+
+	assert.Equal(t, StatusFailed, store.jobs[1].Status)
+	assert.Equal(t, interruptedMessage, store.jobs[1].ErrorMessage.String)
+	assert.Equal(t, StatusCompleted, store.jobs[2].Status)
+}
+
+func TestService_GetJobNotFound(t *testing.T) {
+	_, err := NewService(newMockStore(), 0, 1).GetJob(context.Background(), 9)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
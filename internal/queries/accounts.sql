@@ -0,0 +1,40 @@
+-- name: CreateAccount :one
+INSERT INTO accounts (
+    institution, name, masked_number, account_type, currency, opening_balance
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetAccount :one
+SELECT * FROM accounts
+WHERE id = $1;
+
+-- name: ListAccounts :many
+SELECT * FROM accounts
+ORDER BY institution, name, id;
+
+-- name: ListAccountsByInstitution :many
+SELECT * FROM accounts
+WHERE institution = $1
+ORDER BY id;
+
+-- name: UpdateAccount :one
+UPDATE accounts
+SET institution = $2,
+    name = $3,
+    masked_number = $4,
+    account_type = $5,
+    currency = $6,
+    opening_balance = $7,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteAccount :execrows
+DELETE FROM accounts
+WHERE id = $1;
+
+-- name: ListAccountLedger :many
+SELECT id, date, amount, currency, running_balance FROM transactions
+WHERE account_id = $1
+ORDER BY date, id;
@@ -0,0 +1,42 @@
+-- name: CreateImportBatch :one
+INSERT INTO import_batches (
+    file_name, bank, checksum, row_count, status, error_message, account_id,
+    closing_balance, available_balance, balance_date, opening_balance,
+    opening_balance_date
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+) RETURNING *;
+
+-- name: CompleteImportBatch :one
+UPDATE import_batches
+SET inserted_count = $2,
+    skipped_count = $3,
+    row_count = $4,
+    status = 'completed',
+    completed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetImportBatchForUpdate :one
+SELECT * FROM import_batches
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListImportBatches :many
+SELECT * FROM import_batches
+ORDER BY created_at DESC, id DESC;
+
+-- name: MarkImportBatchRolledBack :one
+UPDATE import_batches
+SET status = 'rolled_back',
+    rolled_back_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetLatestImportBalance :one
+SELECT * FROM import_batches
+WHERE account_id = $1
+  AND status = 'completed'
+  AND closing_balance IS NOT NULL
+ORDER BY balance_date DESC, id DESC
+LIMIT 1;
@@ -0,0 +1,52 @@
+-- name: CreateImportJob :one
+INSERT INTO import_jobs (file_name, checksum, status)
+VALUES ($1, $2, 'queued')
+RETURNING *;
+
+-- name: GetImportJob :one
+SELECT * FROM import_jobs
+WHERE id = $1;
+
+-- name: StartImportJob :one
+UPDATE import_jobs
+SET status = 'running',
+    started_at = NOW()
+WHERE id = $1 AND status = 'queued'
+RETURNING *;
+
+-- name: UpdateImportJobProgress :exec
+UPDATE import_jobs
+SET statement_count = $2,
+    statements_done = $3,
+    row_count = $4,
+    rows_done = $5,
+    inserted_count = $6,
+    skipped_count = $7,
+    import_batch_ids = $8,
+    rejected_rows = $9,
+    byte_count = $10,
+    bytes_done = $11,
+    files = $12
+WHERE id = $1;
+
+-- name: FinishImportJob :one
+UPDATE import_jobs
+SET status = $2,
+    error_message = $3,
+    finished_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelQueuedImportJob :one
+UPDATE import_jobs
+SET status = 'cancelled',
+    finished_at = NOW()
+WHERE id = $1 AND status = 'queued'
+RETURNING *;
+
+-- name: FailInterruptedImportJobs :execrows
+UPDATE import_jobs
+SET status = 'failed',
+    error_message = $1,
+    finished_at = NOW()
+WHERE status IN ('queued', 'running');
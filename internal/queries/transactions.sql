@@ -1,8 +1,9 @@
 -- name: CreateTransaction :one
 INSERT INTO transactions (
-    date, description, amount, currency, bank, category
+    date, description, amount, currency, bank, category, account_id,
+    notes, address, local_amount, local_currency, linked_external_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 ) RETURNING *;
 
 -- name: GetTransaction :one
@@ -21,14 +22,231 @@ SET date = $2,
     currency = $5,
     bank = $6,
     category = $7,
+    account_id = $8,
+    notes = $9,
+    address = $10,
+    local_amount = $11,
+    local_currency = $12,
+    linked_external_id = $13,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
--- name: DeleteTransaction :exec
+-- name: DeleteTransaction :execrows
 DELETE FROM transactions
 WHERE id = $1;
 
--- name: CreateTransactionsBatch :copyfrom
-INSERT INTO transactions (date, description, amount, currency, bank, category)
-VALUES ($1, $2, $3, $4, $5, $6);
+-- name: CreateTransactionsSkipDuplicates :many
+INSERT INTO transactions (
+    date, description, amount, currency, bank, category, external_id, fingerprint, account_id,
+    running_balance, import_batch_id, notes, address, local_amount, local_currency, linked_external_id
+)
+SELECT u.date, u.description, u.amount, u.currency, u.bank,
+       NULLIF(u.category, ''), NULLIF(u.external_id, ''), u.fingerprint, NULLIF(u.account_id, 0),
+       CASE WHEN u.has_running_balance THEN u.running_balance END,
+       sqlc.narg('import_batch_id')::integer,
+       NULLIF(u.notes, ''), NULLIF(u.address, ''),
+       CASE WHEN u.local_currency <> '' THEN u.local_amount END, NULLIF(u.local_currency, ''),
+       NULLIF(u.linked_external_id, '')
+FROM unnest(
+    sqlc.arg('dates')::date[],
+    sqlc.arg('descriptions')::text[],
+    sqlc.arg('amounts')::bigint[],
+    sqlc.arg('currencies')::text[],
+    sqlc.arg('banks')::text[],
+    sqlc.arg('categories')::text[],
+    sqlc.arg('external_ids')::text[],
+    sqlc.arg('fingerprints')::text[],
+    sqlc.arg('account_ids')::integer[],
+    sqlc.arg('running_balances')::bigint[],
+    sqlc.arg('has_running_balances')::boolean[],
+    sqlc.arg('notes')::text[],
+    sqlc.arg('addresses')::text[],
+    sqlc.arg('local_amounts')::bigint[],
+    sqlc.arg('local_currencies')::text[],
+    sqlc.arg('linked_external_ids')::text[],
+    sqlc.arg('content_fingerprints')::text[]
+) AS u(date, description, amount, currency, bank, category, external_id, fingerprint, account_id,
+       running_balance, has_running_balance, notes, address, local_amount, local_currency, linked_external_id,
+       content_fingerprint)
+WHERE NOT EXISTS (
+    SELECT 1 FROM transactions t
+    WHERE u.content_fingerprint <> ''
+      AND t.fingerprint = u.content_fingerprint
+      AND t.external_id IS NULL
+)
+ON CONFLICT (fingerprint) DO NOTHING
+RETURNING fingerprint;
+
+-- name: CreateTransactionStaging :exec
+CREATE TEMPORARY TABLE transaction_staging (
+    position BIGINT NOT NULL,
+    date DATE NOT NULL,
+    description TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    bank TEXT NOT NULL,
+    category TEXT,
+    external_id TEXT,
+    fingerprint TEXT NOT NULL,
+    account_id INTEGER,
+    running_balance BIGINT,
+    notes TEXT,
+    address TEXT,
+    local_amount BIGINT,
+    local_currency TEXT,
+    linked_external_id TEXT,
+    content_fingerprint TEXT
+) ON COMMIT DROP;
+
+-- name: InsertStagedTransactions :execrows
+INSERT INTO transactions (
+    date, description, amount, currency, bank, category, external_id, fingerprint, account_id,
+    running_balance, import_batch_id, notes, address, local_amount, local_currency, linked_external_id
+)
+SELECT s.date, s.description, s.amount, s.currency, s.bank, s.category, s.external_id, s.fingerprint, s.account_id,
+       s.running_balance, sqlc.narg('import_batch_id')::integer, s.notes, s.address, s.local_amount, s.local_currency,
+       s.linked_external_id
+FROM transaction_staging s
+WHERE NOT EXISTS (
+    SELECT 1 FROM transactions t
+    WHERE t.fingerprint = s.content_fingerprint AND t.external_id IS NULL
+)
+ORDER BY s.position
+ON CONFLICT (fingerprint) DO NOTHING;
+
+-- name: ListStoredFingerprints :many
+SELECT fingerprint FROM transactions
+WHERE fingerprint = ANY(sqlc.arg('fingerprints')::text[]);
+
+-- name: ListUnreferencedFingerprints :many
+SELECT fingerprint FROM transactions
+WHERE external_id IS NULL AND fingerprint = ANY(sqlc.arg('fingerprints')::text[]);
+
+-- The list queries come one per sort key and direction. Each orders by
+-- plain columns matching an index ((date, id), (amount, id) or
+-- (description, id)), so the planner can walk the index, forwards or
+-- backwards, from the cursor and stop after the page; a CASE in the
+-- ORDER BY matches no index and sorts every row the filters keep.
+
+-- name: ListTransactionsByAmountAsc :many
+SELECT * FROM transactions
+WHERE (sqlc.narg('date_from')::date IS NULL OR date >= sqlc.narg('date_from')::date)
+  AND (sqlc.narg('date_to')::date IS NULL OR date <= sqlc.narg('date_to')::date)
+  AND (sqlc.narg('bank')::text IS NULL OR bank = sqlc.narg('bank')::text)
+  AND (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category')::text)
+  AND (sqlc.narg('min_amount')::bigint IS NULL OR amount >= sqlc.narg('min_amount')::bigint)
+  AND (sqlc.narg('max_amount')::bigint IS NULL OR amount <= sqlc.narg('max_amount')::bigint)
+  AND (sqlc.narg('search')::text IS NULL OR description ILIKE '%' || sqlc.narg('search')::text || '%')
+  AND (sqlc.narg('account_id')::integer IS NULL OR account_id = sqlc.narg('account_id')::integer)
+  AND (sqlc.narg('cursor_id')::integer IS NULL
+    OR (amount, id) > (sqlc.narg('cursor_amount')::bigint, sqlc.narg('cursor_id')::integer))
+ORDER BY amount, id
+LIMIT sqlc.arg('page_limit')::integer;
+
+-- name: ListTransactionsByAmountDesc :many
+SELECT * FROM transactions
+WHERE (sqlc.narg('date_from')::date IS NULL OR date >= sqlc.narg('date_from')::date)
+  AND (sqlc.narg('date_to')::date IS NULL OR date <= sqlc.narg('date_to')::date)
+  AND (sqlc.narg('bank')::text IS NULL OR bank = sqlc.narg('bank')::text)
+  AND (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category')::text)
+  AND (sqlc.narg('min_amount')::bigint IS NULL OR amount >= sqlc.narg('min_amount')::bigint)
+  AND (sqlc.narg('max_amount')::bigint IS NULL OR amount <= sqlc.narg('max_amount')::bigint)
+  AND (sqlc.narg('search')::text IS NULL OR description ILIKE '%' || sqlc.narg('search')::text || '%')
+  AND (sqlc.narg('account_id')::integer IS NULL OR account_id = sqlc.narg('account_id')::integer)
+  AND (sqlc.narg('cursor_id')::integer IS NULL
+    OR (amount, id) < (sqlc.narg('cursor_amount')::bigint, sqlc.narg('cursor_id')::integer))
+ORDER BY amount DESC, id DESC
+LIMIT sqlc.arg('page_limit')::integer;
+
+-- name: ListTransactionsByDateAsc :many
+SELECT * FROM transactions
+WHERE (sqlc.narg('date_from')::date IS NULL OR date >= sqlc.narg('date_from')::date)
+  AND (sqlc.narg('date_to')::date IS NULL OR date <= sqlc.narg('date_to')::date)
+  AND (sqlc.narg('bank')::text IS NULL OR bank = sqlc.narg('bank')::text)
+  AND (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category')::text)
+  AND (sqlc.narg('min_amount')::bigint IS NULL OR amount >= sqlc.narg('min_amount')::bigint)
+  AND (sqlc.narg('max_amount')::bigint IS NULL OR amount <= sqlc.narg('max_amount')::bigint)
+  AND (sqlc.narg('search')::text IS NULL OR description ILIKE '%' || sqlc.narg('search')::text || '%')
+  AND (sqlc.narg('account_id')::integer IS NULL OR account_id = sqlc.narg('account_id')::integer)
+  AND (sqlc.narg('cursor_id')::integer IS NULL
+    OR (date, id) > (sqlc.narg('cursor_date')::date, sqlc.narg('cursor_id')::integer))
+ORDER BY date, id
+LIMIT sqlc.arg('page_limit')::integer;
+
+-- name: ListTransactionsByDateDesc :many
+SELECT * FROM transactions
+WHERE (sqlc.narg('date_from')::date IS NULL OR date >= sqlc.narg('date_from')::date)
+  AND (sqlc.narg('date_to')::date IS NULL OR date <= sqlc.narg('date_to')::date)
+  AND (sqlc.narg('bank')::text IS NULL OR bank = sqlc.narg('bank')::text)
+  AND (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category')::text)
+  AND (sqlc.narg('min_amount')::bigint IS NULL OR amount >= sqlc.narg('min_amount')::bigint)
+  AND (sqlc.narg('max_amount')::bigint IS NULL OR amount <= sqlc.narg('max_amount')::bigint)
+  AND (sqlc.narg('search')::text IS NULL OR description ILIKE '%' || sqlc.narg('search')::text || '%')
+  AND (sqlc.narg('account_id')::integer IS NULL OR account_id = sqlc.narg('account_id')::integer)
+  AND (sqlc.narg('cursor_id')::integer IS NULL
+    OR (date, id) < (sqlc.narg('cursor_date')::date, sqlc.narg('cursor_id')::integer))
+ORDER BY date DESC, id DESC
+LIMIT sqlc.arg('page_limit')::integer;
+
+-- name: ListTransactionsByDescriptionAsc :many
+SELECT * FROM transactions
+WHERE (sqlc.narg('date_from')::date IS NULL OR date >= sqlc.narg('date_from')::date)
+  AND (sqlc.narg('date_to')::date IS NULL OR date <= sqlc.narg('date_to')::date)
+  AND (sqlc.narg('bank')::text IS NULL OR bank = sqlc.narg('bank')::text)
+  AND (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category')::text)
+  AND (sqlc.narg('min_amount')::bigint IS NULL OR amount >= sqlc.narg('min_amount')::bigint)
+  AND (sqlc.narg('max_amount')::bigint IS NULL OR amount <= sqlc.narg('max_amount')::bigint)
+  AND (sqlc.narg('search')::text IS NULL OR description ILIKE '%' || sqlc.narg('search')::text || '%')
+  AND (sqlc.narg('account_id')::integer IS NULL OR account_id = sqlc.narg('account_id')::integer)
+  AND (sqlc.narg('cursor_id')::integer IS NULL
+    OR (description, id) > (sqlc.narg('cursor_description')::text, sqlc.narg('cursor_id')::integer))
+ORDER BY description, id
+LIMIT sqlc.arg('page_limit')::integer;
+
+-- name: ListTransactionsByDescriptionDesc :many
+SELECT * FROM transactions
+WHERE (sqlc.narg('date_from')::date IS NULL OR date >= sqlc.narg('date_from')::date)
+  AND (sqlc.narg('date_to')::date IS NULL OR date <= sqlc.narg('date_to')::date)
+  AND (sqlc.narg('bank')::text IS NULL OR bank = sqlc.narg('bank')::text)
+  AND (sqlc.narg('category')::text IS NULL OR category = sqlc.narg('category')::text)
+  AND (sqlc.narg('min_amount')::bigint IS NULL OR amount >= sqlc.narg('min_amount')::bigint)
+  AND (sqlc.narg('max_amount')::bigint IS NULL OR amount <= sqlc.narg('max_amount')::bigint)
+  AND (sqlc.narg('search')::text IS NULL OR description ILIKE '%' || sqlc.narg('search')::text || '%')
+  AND (sqlc.narg('account_id')::integer IS NULL OR account_id = sqlc.narg('account_id')::integer)
+  AND (sqlc.narg('cursor_id')::integer IS NULL
+    OR (description, id) < (sqlc.narg('cursor_description')::text, sqlc.narg('cursor_id')::integer))
+ORDER BY description DESC, id DESC
+LIMIT sqlc.arg('page_limit')::integer;
+
+-- name: DeleteTransactionsByImportBatch :execrows
+DELETE FROM transactions
+WHERE import_batch_id = $1;
+
+-- name: ListFundingCandidates :many
+-- Pairs payments on the given bank, such as PayPal, with rows on other
+-- accounts that could have funded them: the same amount within a few
+-- days, described as a PayPal payment, and not yet funding anything.
+-- Either side may be from the import batch.
+SELECT p.id AS payment_id, f.id AS funding_id, abs(p.date - f.date)::integer AS days_apart
+FROM transactions p
+JOIN transactions f
+  ON f.amount = p.amount
+ AND f.currency = p.currency
+ AND f.bank <> p.bank
+ AND f.account_id IS DISTINCT FROM p.account_id
+ AND f.date BETWEEN p.date - sqlc.arg('window_days')::integer AND p.date + sqlc.arg('window_days')::integer
+ AND f.description ILIKE '%PAYPAL%'
+WHERE p.bank = sqlc.arg('bank')::text
+  AND p.amount < 0
+  AND p.linked_external_id IS NULL
+  AND p.linked_transaction_id IS NULL
+  AND NOT EXISTS (SELECT 1 FROM transactions l WHERE l.linked_transaction_id = f.id)
+  AND (p.import_batch_id = sqlc.arg('import_batch_id')::integer OR f.import_batch_id = sqlc.arg('import_batch_id')::integer)
+ORDER BY days_apart, p.id, f.id;
+
+-- name: LinkTransactions :exec
+UPDATE transactions t
+SET linked_transaction_id = l.linked_transaction_id
+FROM unnest(sqlc.arg('ids')::integer[], sqlc.arg('linked_transaction_ids')::integer[]) AS l(id, linked_transaction_id)
+WHERE t.id = l.id;
@@ -4,25 +4,55 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/kushturner/finances/internal/account"
 	"github.com/kushturner/finances/internal/csvparser"
 	"github.com/kushturner/finances/internal/handlers"
+	"github.com/kushturner/finances/internal/importjob"
 	"github.com/kushturner/finances/internal/transaction"
 )
 
-func NewRouter(transactionService transaction.Service, parserService csvparser.Service) *chi.Mux {
+func NewRouter(transactionService transaction.Service, parserService csvparser.Service, accountService account.Service, importJobService importjob.Service) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Content-Type"},
+		ExposedHeaders:   []string{handlers.NextCursorHeader},
 		AllowCredentials: false,
 	}))
 
 	r.Use(middleware.Logger)
 
+	previews := handlers.NewPreviewStore()
+
 	r.Get("/transactions", handlers.NewListTransactionsHandler(transactionService))
-	r.Post("/transactions/upload", handlers.NewUploadTransactionsHandler(transactionService, parserService))
+	r.Post("/transactions", handlers.NewCreateTransactionHandler(transactionService))
+	r.Get("/transactions/export", handlers.NewExportTransactionsHandler(transactionService, accountService))
+	r.Post("/transactions/upload", handlers.NewUploadTransactionsHandler(transactionService, parserService, accountService, importJobService))
+	r.Post("/transactions/upload/preview", handlers.NewPreviewUploadHandler(transactionService, parserService, accountService, previews))
+	r.Post("/transactions/upload/preview/{token}/commit", handlers.NewCommitPreviewHandler(transactionService, parserService, accountService, previews))
+	r.Get("/transactions/{id}", handlers.NewGetTransactionHandler(transactionService))
+	r.Put("/transactions/{id}", handlers.NewUpdateTransactionHandler(transactionService))
+	r.Patch("/transactions/{id}", handlers.NewPatchTransactionHandler(transactionService))
+	r.Delete("/transactions/{id}", handlers.NewDeleteTransactionHandler(transactionService))
+
+	r.Get("/accounts", handlers.NewListAccountsHandler(accountService))
+	r.Post("/accounts", handlers.NewCreateAccountHandler(accountService))
+	r.Get("/accounts/{id}", handlers.NewGetAccountHandler(accountService))
+	r.Put("/accounts/{id}", handlers.NewUpdateAccountHandler(accountService))
+	r.Delete("/accounts/{id}", handlers.NewDeleteAccountHandler(accountService))
+	r.Get("/accounts/{id}/balance-history", handlers.NewBalanceHistoryHandler(accountService))
+	r.Get("/accounts/{id}/reconciliation", handlers.NewReconcileAccountHandler(accountService))
+
+	r.Get("/parser-profiles", handlers.NewListParserProfilesHandler(parserService))
+	r.Post("/parser-profiles", handlers.NewCreateParserProfileHandler(parserService))
+
+	r.Post("/imports", handlers.NewSubmitImportJobHandler(transactionService, parserService, accountService, importJobService))
+	r.Get("/imports/batches", handlers.NewListImportsHandler(transactionService))
+	r.Delete("/imports/batches/{id}", handlers.NewRollbackImportHandler(transactionService))
+	r.Get("/imports/{id}", handlers.NewGetImportJobHandler(importJobService))
+	r.Delete("/imports/{id}", handlers.NewCancelImportJobHandler(importJobService))
 
 	return r
 }
@@ -0,0 +1,88 @@
+package transaction
+
+import (
+	"iter"
+)
+
+// copySource feeds transactions to a COPY into the staging table as they
+// are read, fingerprinting each one on the way. It is a
+// pgx.CopyFromSource, and stop must be called once the copy is done.
+type copySource struct {
+	next         func() (Transaction, error, bool)
+	stop         func()
+	fingerprints *fingerprinter
+	// peeked is the first transaction, once peek has read it ahead of the
+	// copy.
+	peeked *Transaction
+	// accountID, when it is set, is the account every transaction is
+	// imported into.
+	accountID *int32
+	values    []any
+	// rows counts the transactions read so far, and numbers them so the
+	// insert can keep them in order.
+	rows int
+	// err is the error the transactions ended with, which fails the copy.
+	err error
+}
+
+func newCopySource(transactions iter.Seq2[Transaction, error]) *copySource {
+	next, stop := iter.Pull2(transactions)
+	return &copySource{
+		next:         next,
+		stop:         stop,
+		fingerprints: newFingerprinter(),
+	}
+}
+
+// peek reads the first transaction before the copy starts, returning nil
+// when there are none.
+func (s *copySource) peek() (*Transaction, error) {
+	tx, err, ok := s.next()
+	if !ok {
+		return nil, nil
+	}
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	s.peeked = &tx
+	return s.peeked, nil
+}
+
+func (s *copySource) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	var tx Transaction
+	if s.peeked != nil {
+		tx, s.peeked = *s.peeked, nil
+	} else {
+		next, err, ok := s.next()
+		if !ok {
+			return false
+		}
+		if err != nil {
+			s.err = err
+			return false
+		}
+		tx = next
+	}
+
+	if s.accountID != nil {
+		tx.AccountID = s.accountID
+	}
+	s.fingerprints.assign(&tx)
+	// pgx encodes a row before asking for the next, so its values can be
+	// reused.
+	s.values = transactionToStaging(int64(s.rows), tx, s.values[:0])
+	s.rows++
+	return true
+}
+
+func (s *copySource) Values() ([]any, error) {
+	return s.values, nil
+}
+
+func (s *copySource) Err() error {
+	return s.err
+}
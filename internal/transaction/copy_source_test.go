@@ -0,0 +1,116 @@
+package transaction
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopySource_Values(t *testing.T) {
+	accountID := int32(4)
+	empty := ""
+	notes := "Team breakfast"
+	date := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	source := newCopySource(streamOf([]Transaction{{
+		Date:        date,
+		Bank:        "Monzo",
+		Description: "PRET",
+		Amount:      money.New(-450, "GBP"),
+		LocalAmount: money.New(-500, "EUR"),
+		AccountID:   &accountID,
+		Category:    &empty,
+		Notes:       &notes,
+	}}, nil))
+	defer source.stop()
+
+	assert.True(t, source.Next())
+	values, err := source.Values()
+
+	assert.NoError(t, err)
+	assert.Len(t, values, len(db.TransactionStagingColumns))
+	assert.Equal(t, []any{
+		int64(0), pgtype.Date{Time: date, Valid: true}, "PRET", int64(-450), "GBP", "Monzo",
+		nil, nil, values[8], int32(4), nil, "Team breakfast", nil, int64(-500), "EUR", nil, nil,
+	}, values)
+	assert.Len(t, values[8], 64)
+	assert.False(t, source.Next())
+	assert.NoError(t, source.Err())
+}
+
+// benchmarkTransaction returns the ith row of a large statement.
+func benchmarkTransaction(i int) Transaction {
+	reference := fmt.Sprintf("AT%09d", i)
+	return Transaction{
+		Date:        time.Date(2026, 1, i%28+1, 0, 0, 0, 0, time.UTC),
+		Bank:        "Amex",
+		Description: fmt.Sprintf("TEST MERCHANT %d LONDON", i%500),
+		Amount:      money.New(-int64(i%20000), "GBP"),
+		ExternalID:  &reference,
+	}
+}
+
+// liveHeap returns the bytes in use on the heap once garbage is collected.
+func liveHeap() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// The benchmarks report live-B/op, the heap in use once 100k rows are
+// ready to write. Building the unnest insert's column arrays holds every
+// row twice over; feeding COPY holds one row's values and the occurrence
+// counts.
+
+func BenchmarkTransactionsToImportDB_100k(b *testing.B) {
+	const rows = 100_000
+	b.ReportAllocs()
+
+	var live uint64
+	for b.Loop() {
+		before := liveHeap()
+		transactions := make([]Transaction, 0, rows)
+		for i := range rows {
+			transactions = append(transactions, benchmarkTransaction(i))
+		}
+		AssignFingerprints(transactions)
+		params := TransactionsToImportDB(transactions)
+		live += liveHeap() - before
+		runtime.KeepAlive(transactions)
+		runtime.KeepAlive(params)
+	}
+	b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+}
+
+func BenchmarkCopySource_100k(b *testing.B) {
+	const rows = 100_000
+	b.ReportAllocs()
+
+	var live uint64
+	for b.Loop() {
+		before := liveHeap()
+		source := newCopySource(func(yield func(Transaction, error) bool) {
+			for i := range rows {
+				if !yield(benchmarkTransaction(i), nil) {
+					return
+				}
+			}
+		})
+		for source.Next() {
+			if _, err := source.Values(); err != nil {
+				b.Fatal(err)
+			}
+			if source.rows == rows {
+				live += liveHeap() - before
+			}
+		}
+		source.stop()
+	}
+	b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+}
@@ -6,4 +6,8 @@ var (
 	ErrInvalidBankType = errors.New("invalid bank type")
 	ErrParseFailure    = errors.New("parse failure")
 	ErrDatabaseFailure = errors.New("database failure")
+	ErrNotFound        = errors.New("transaction not found")
+	ErrValidation      = errors.New("validation failure")
+	ErrImportNotFound  = errors.New("import not found")
+	ErrImportConflict  = errors.New("import conflict")
 )
@@ -0,0 +1,102 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignFingerprints_StableAcrossImports(t *testing.T) {
+	first := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "TEST PAYEE", Amount: money.New(-5000, "GBP"), Bank: "Nationwide"},
+	}
+	second := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "TEST PAYEE", Amount: money.New(-5000, "GBP"), Bank: "Nationwide"},
+	}
+
+	AssignFingerprints(first)
+	AssignFingerprints(second)
+
+	assert.Len(t, first[0].Fingerprint, 64)
+	assert.Equal(t, first[0].Fingerprint, second[0].Fingerprint)
+}
+
+func TestAssignFingerprints_IdenticalRowsGetOccurrenceCounter(t *testing.T) {
+	txs := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "COFFEE", Amount: money.New(-300, "GBP"), Bank: "Nationwide"},
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "COFFEE", Amount: money.New(-300, "GBP"), Bank: "Nationwide"},
+	}
+
+	AssignFingerprints(txs)
+
+	assert.NotEqual(t, txs[0].Fingerprint, txs[1].Fingerprint)
+}
+
+func TestAssignFingerprints_MatchesMigrationBackfillKey(t *testing.T) {
+	txs := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "TEST PAYEE", Amount: money.New(-5000, "GBP"), Bank: "Nationwide"},
+	}
+
+	AssignFingerprints(txs)
+
+	assert.Equal(t, hashKey("2026-01-15|-5000|GBP|TEST PAYEE|Nationwide|0"), txs[0].Fingerprint)
+}
+
+func TestAssignFingerprints_PrefersBankReference(t *testing.T) {
+	ref := "AT123456789"
+	txs := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "RESTAURANT", Amount: money.New(2550, "GBP"), Bank: "American Express", ExternalID: &ref},
+		{Date: time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC), Description: "RESTAURANT (corrected)", Amount: money.New(2550, "GBP"), Bank: "American Express", ExternalID: &ref},
+	}
+
+	AssignFingerprints(txs)
+
+	assert.Equal(t, txs[0].Fingerprint, txs[1].Fingerprint)
+	assert.Equal(t, hashKey("ref|American Express|AT123456789"), txs[0].Fingerprint)
+}
+
+func TestAssignFingerprints_ScopedToAccount(t *testing.T) {
+	current, savings := int32(1), int32(2)
+	txs := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "INTEREST", Amount: money.New(12, "GBP"), Bank: "Nationwide", AccountID: &current},
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "INTEREST", Amount: money.New(12, "GBP"), Bank: "Nationwide", AccountID: &savings},
+	}
+
+	AssignFingerprints(txs)
+
+	assert.NotEqual(t, txs[0].Fingerprint, txs[1].Fingerprint)
+	assert.Equal(t, hashKey("2026-01-15|12|GBP|INTEREST|account:1|0"), txs[0].Fingerprint)
+	assert.Equal(t, hashKey("2026-01-15|12|GBP|INTEREST|account:2|0"), txs[1].Fingerprint)
+}
+
+func TestAssignFingerprints_ReferenceScopedToAccount(t *testing.T) {
+	ref := "AT123456789"
+	accountID := int32(3)
+	txs := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "RESTAURANT", Amount: money.New(-2550, "GBP"), Bank: "American Express", AccountID: &accountID, ExternalID: &ref},
+	}
+
+	AssignFingerprints(txs)
+
+	assert.Equal(t, hashKey("ref|account:3|AT123456789"), txs[0].Fingerprint)
+}
+
+func TestAssignFingerprints_ReferencedRowsKeepContentKey(t *testing.T) {
+	ref := "AT123456789"
+	accountID := int32(3)
+	txs := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "COFFEE", Amount: money.New(-300, "GBP"), Bank: "American Express", AccountID: &accountID, ExternalID: &ref},
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "COFFEE", Amount: money.New(-300, "GBP"), Bank: "American Express", AccountID: &accountID},
+	}
+
+	AssignFingerprints(txs)
+
+	// The content key matches what migration 006 gave the row when it was
+	// stored without its reference.
+	assert.Equal(t, hashKey("2026-01-15|-300|GBP|COFFEE|account:3|0"), txs[0].ContentFingerprint)
+	// Counting it apart leaves the unreferenced row's fingerprint alone.
+	assert.Equal(t, hashKey("2026-01-15|-300|GBP|COFFEE|account:3|0"), txs[1].Fingerprint)
+	assert.Empty(t, txs[1].ContentFingerprint)
+}
@@ -0,0 +1,44 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/kushturner/finances/internal/db"
+)
+
+// PayPalBank is the bank of transactions imported from PayPal.
+const PayPalBank = "PayPal"
+
+// fundingWindowDays is how far apart a PayPal payment and the bank or card
+// payment funding it can be dated, as the card side often posts a day or
+// two later.
+const fundingWindowDays = 3
+
+// linkFunding links each PayPal payment to the bank or card transaction on
+// another account that paid for it, once both are stored, whichever was
+// imported first. Candidates come closest dates first, and neither side
+// is used twice, so two payments of the same amount each get their own.
+func linkFunding(ctx context.Context, q db.Querier, batchID int32) error {
+	candidates, err := q.ListFundingCandidates(ctx, db.ListFundingCandidatesParams{
+		WindowDays:    fundingWindowDays,
+		Bank:          PayPalBank,
+		ImportBatchID: batchID,
+	})
+	if err != nil || len(candidates) == 0 {
+		return err
+	}
+
+	var params db.LinkTransactionsParams
+	payments := make(map[int32]bool)
+	funding := make(map[int32]bool)
+	for _, c := range candidates {
+		if payments[c.PaymentID] || funding[c.FundingID] {
+			continue
+		}
+		payments[c.PaymentID] = true
+		funding[c.FundingID] = true
+		params.Ids = append(params.Ids, c.PaymentID)
+		params.LinkedTransactionIds = append(params.LinkedTransactionIds, c.FundingID)
+	}
+	return q.LinkTransactions(ctx, params)
+}
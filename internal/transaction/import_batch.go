@@ -0,0 +1,383 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+)
+
+const (
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+	ImportStatusRolledBack = "rolled_back"
+)
+
+// ImportSource describes the uploaded file a set of transactions came from,
+// including any balances the statement reported as at BalanceDate, and the
+// balance it opened with as at OpeningBalanceDate.
+type ImportSource struct {
+	FileName           string
+	Bank               string
+	Checksum           string
+	AccountID          *int32
+	ClosingBalance     *money.Money
+	AvailableBalance   *money.Money
+	BalanceDate        *time.Time
+	OpeningBalance     *money.Money
+	OpeningBalanceDate *time.Time
+}
+
+type ImportBatch struct {
+	ID            int32
+	FileName      string
+	Bank          string
+	Checksum      string
+	AccountID     *int32
+	RowCount      int32
+	InsertedCount int32
+	SkippedCount  int32
+	Status        string
+	ErrorMessage  *string
+	CreatedAt     time.Time
+	CompletedAt   *time.Time
+	RolledBackAt  *time.Time
+	// Balances are in minor units of the account's currency.
+	ClosingBalance     *int64
+	AvailableBalance   *int64
+	BalanceDate        *time.Time
+	OpeningBalance     *int64
+	OpeningBalanceDate *time.Time
+}
+
+type ImportResult struct {
+	BatchID  int32
+	Inserted int64
+	Skipped  int64
+}
+
+// StatementImport is one statement of an upload to be imported with
+// others by AddStatements.
+type StatementImport struct {
+	Source       ImportSource
+	Transactions []Transaction
+	// Start, when it is set, is called with q before the statement is
+	// imported, so that the account it sets in source.AccountID is written
+	// in the same database transaction. Every transaction is then imported
+	// into that account.
+	Start func(ctx context.Context, q db.Querier, source *ImportSource) error
+}
+
+// StreamImport is a statement imported by AddTransactionStream as it is
+// read. What the statement says of its account and balances can depend on
+// rows not yet read, so it is completed by hooks run in the import's
+// database transaction.
+type StreamImport struct {
+	// Source describes the import as far as it is known before any row is
+	// read.
+	Source       ImportSource
+	Transactions iter.Seq2[Transaction, error]
+	// Start, when it is set, is called with q before any row is copied,
+	// with the first row, or nil for a statement without any. It sets
+	// source.AccountID, which every row is then imported into; without it
+	// Source.AccountID must already be set.
+	Start func(ctx context.Context, q db.Querier, first *Transaction, source *ImportSource) error
+	// Finish, when it is set, is called with q once every row has been
+	// read, to complete source with what only the whole statement tells.
+	Finish func(ctx context.Context, q db.Querier, source *ImportSource) error
+}
+
+// AddTransactions records an import batch for source and inserts the
+// transactions against it in a single database transaction, skipping rows
+// whose fingerprint is already stored. If the insert fails the batch is
+// kept with a failed status so the attempt still shows up in the history.
+func (s *service) AddTransactions(ctx context.Context, source ImportSource, transactions []Transaction) (ImportResult, error) {
+	results, err := s.AddStatements(ctx, []StatementImport{{Source: source, Transactions: transactions}})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return results[0], nil
+}
+
+// AddStatements imports each statement as AddTransactions does, as its own
+// batch, but all in one database transaction: if one fails, none are
+// imported, and each is kept as a failed batch.
+func (s *service) AddStatements(ctx context.Context, statements []StatementImport) ([]ImportResult, error) {
+	sources := make([]ImportSource, len(statements))
+	for i, statement := range statements {
+		sources[i] = statement.Source
+	}
+	results := make([]ImportResult, 0, len(statements))
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		for i, statement := range statements {
+			result, err := addStatement(ctx, q, statement, &sources[i])
+			if err != nil {
+				if len(statements) > 1 {
+					return fmt.Errorf("%s: %w", statement.Source.FileName, err)
+				}
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		for i, statement := range statements {
+			s.recordFailure(ctx, sources[i], len(statement.Transactions), err.Error())
+		}
+		return nil, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+	}
+
+	return results, nil
+}
+
+// addStatement imports statement with q as addBatch does, once its Start
+// hook has completed source.
+func addStatement(ctx context.Context, q db.Querier, statement StatementImport, source *ImportSource) (ImportResult, error) {
+	if statement.Start != nil {
+		if err := statement.Start(ctx, q, source); err != nil {
+			return ImportResult{}, err
+		}
+		for i := range statement.Transactions {
+			statement.Transactions[i].AccountID = source.AccountID
+		}
+	}
+	return addBatch(ctx, q, *source, statement.Transactions)
+}
+
+// addBatch records an import batch for source and inserts transactions
+// against it with q.
+func addBatch(ctx context.Context, q db.Querier, source ImportSource, transactions []Transaction) (ImportResult, error) {
+	AssignFingerprints(transactions)
+
+	batch, err := q.CreateImportBatch(ctx, importBatchParams(source, len(transactions), ImportStatusCompleted, nil))
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	params := TransactionsToImportDB(transactions)
+	params.ImportBatchID = pgtype.Int4{Int32: batch.ID, Valid: true}
+	inserted, err := q.CreateTransactionsSkipDuplicates(ctx, params)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if err := linkFunding(ctx, q, batch.ID); err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{
+		BatchID:  batch.ID,
+		Inserted: int64(len(inserted)),
+		Skipped:  int64(len(transactions) - len(inserted)),
+	}
+	_, err = q.CompleteImportBatch(ctx, db.CompleteImportBatchParams{
+		ID:            batch.ID,
+		InsertedCount: int32(result.Inserted),
+		SkippedCount:  int32(result.Skipped),
+		RowCount:      int32(len(transactions)),
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+// AddTransactionStream imports a statement as AddTransactions does, but
+// copies its transactions into a staging table as they are read and
+// inserts them from there, so they are read once and never all in memory.
+// An error from the transactions fails the import as a parse failure.
+func (s *service) AddTransactionStream(ctx context.Context, statement StreamImport) (ImportResult, error) {
+	rows := newCopySource(statement.Transactions)
+	defer rows.stop()
+
+	source := statement.Source
+	var result ImportResult
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		// No other query can run on the connection while the copy is
+		// under way, so the account is settled from the first row before
+		// it starts.
+		first, err := rows.peek()
+		if err != nil {
+			return err
+		}
+		if statement.Start != nil {
+			if err := statement.Start(ctx, q, first, &source); err != nil {
+				return err
+			}
+		}
+		rows.accountID = source.AccountID
+
+		if err := q.CreateTransactionStaging(ctx); err != nil {
+			return err
+		}
+		copied, err := q.CopyTransactionsToStaging(ctx, rows)
+		if err != nil {
+			return err
+		}
+		if statement.Finish != nil {
+			if err := statement.Finish(ctx, q, &source); err != nil {
+				return err
+			}
+		}
+
+		// How many rows there are is only known once they have been read,
+		// so the count is recorded when the batch completes.
+		batch, err := q.CreateImportBatch(ctx, importBatchParams(source, 0, ImportStatusCompleted, nil))
+		if err != nil {
+			return err
+		}
+		inserted, err := q.InsertStagedTransactions(ctx, pgtype.Int4{Int32: batch.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		if err := linkFunding(ctx, q, batch.ID); err != nil {
+			return err
+		}
+
+		result = ImportResult{
+			BatchID:  batch.ID,
+			Inserted: inserted,
+			Skipped:  copied - inserted,
+		}
+		_, err = q.CompleteImportBatch(ctx, db.CompleteImportBatchParams{
+			ID:            batch.ID,
+			InsertedCount: int32(result.Inserted),
+			SkippedCount:  int32(result.Skipped),
+			RowCount:      int32(copied),
+		})
+		return err
+	})
+	if err != nil {
+		s.recordFailure(ctx, source, rows.rows, err.Error())
+		if rows.err != nil {
+			return ImportResult{}, fmt.Errorf("%w: %s", ErrParseFailure, rows.err.Error())
+		}
+		return ImportResult{}, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+	}
+
+	return result, nil
+}
+
+// recordFailure keeps a failed import in the history. It is best effort:
+// the import has already failed, so an error here is not worth masking the
+// original one. An account the import created was rolled back with it, so
+// the batch is recorded without its account when it cannot be with it.
+func (s *service) recordFailure(ctx context.Context, source ImportSource, rowCount int, message string) {
+	if _, err := s.store.CreateImportBatch(ctx, importBatchParams(source, rowCount, ImportStatusFailed, &message)); err == nil || source.AccountID == nil {
+		return
+	}
+	source.AccountID = nil
+	_, _ = s.store.CreateImportBatch(ctx, importBatchParams(source, rowCount, ImportStatusFailed, &message))
+}
+
+// FindDuplicates assigns fingerprints as AddTransactions does and looks
+// them up, along with the content fingerprints of referenced rows among
+// stored rows without a reference. A transaction repeating the fingerprint
+// of an earlier one in the set is a duplicate too, as the insert would skip
+// it.
+func (s *service) FindDuplicates(ctx context.Context, transactions []Transaction) ([]bool, error) {
+	AssignFingerprints(transactions)
+
+	fingerprints := make([]string, len(transactions))
+	var contentFingerprints []string
+	for i, tx := range transactions {
+		fingerprints[i] = tx.Fingerprint
+		if tx.ContentFingerprint != "" {
+			contentFingerprints = append(contentFingerprints, tx.ContentFingerprint)
+		}
+	}
+	rows, err := s.store.ListStoredFingerprints(ctx, fingerprints)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+	}
+	unreferenced := make(map[string]bool)
+	if len(contentFingerprints) > 0 {
+		contentRows, err := s.store.ListUnreferencedFingerprints(ctx, contentFingerprints)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+		}
+		for _, row := range contentRows {
+			unreferenced[row.String] = true
+		}
+	}
+
+	seen := make(map[string]bool, len(transactions))
+	for _, row := range rows {
+		seen[row.String] = true
+	}
+	duplicates := make([]bool, len(transactions))
+	for i, tx := range transactions {
+		duplicates[i] = seen[tx.Fingerprint] || unreferenced[tx.ContentFingerprint]
+		seen[tx.Fingerprint] = true
+	}
+	return duplicates, nil
+}
+
+func (s *service) ListImportBatches(ctx context.Context) ([]ImportBatch, error) {
+	dbBatches, err := s.store.ListImportBatches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+	}
+
+	batches := make([]ImportBatch, 0, len(dbBatches))
+	for _, b := range dbBatches {
+		batches = append(batches, ImportBatchFromDB(b))
+	}
+	return batches, nil
+}
+
+// RollbackImportBatch deletes every transaction created by the batch and
+// marks it rolled back, returning how many transactions were removed.
+func (s *service) RollbackImportBatch(ctx context.Context, id int32) (int64, error) {
+	var deleted int64
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		batch, err := q.GetImportBatchForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if batch.Status != ImportStatusCompleted {
+			return fmt.Errorf("%w: import %d is %s", ErrImportConflict, id, batch.Status)
+		}
+
+		deleted, err = q.DeleteTransactionsByImportBatch(ctx, pgtype.Int4{Int32: id, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.MarkImportBatchRolledBack(ctx, id)
+		return err
+	})
+	switch {
+	case err == nil:
+		return deleted, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, ErrImportNotFound
+	case errors.Is(err, ErrImportConflict):
+		return 0, err
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+	}
+}
+
+func importBatchParams(source ImportSource, rowCount int, status string, errorMessage *string) db.CreateImportBatchParams {
+	return db.CreateImportBatchParams{
+		FileName:           source.FileName,
+		Bank:               source.Bank,
+		Checksum:           source.Checksum,
+		RowCount:           int32(rowCount),
+		Status:             status,
+		ErrorMessage:       pgtype.Text{String: stringOrEmpty(errorMessage), Valid: errorMessage != nil},
+		AccountID:          int4FromPtr(source.AccountID),
+		ClosingBalance:     int8FromMoney(source.ClosingBalance),
+		AvailableBalance:   int8FromMoney(source.AvailableBalance),
+		BalanceDate:        dateFromPtr(source.BalanceDate),
+		OpeningBalance:     int8FromMoney(source.OpeningBalance),
+		OpeningBalanceDate: dateFromPtr(source.OpeningBalanceDate),
+	}
+}
@@ -0,0 +1,494 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_AddTransactions_RecordsImportBatch(t *testing.T) {
+	var completed db.CompleteImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			assert.Equal(t, "statement.csv", arg.FileName)
+			assert.Equal(t, "Nationwide", arg.Bank)
+			assert.Equal(t, "abc123", arg.Checksum)
+			assert.Equal(t, int32(2), arg.RowCount)
+			assert.Equal(t, ImportStatusCompleted, arg.Status)
+			return db.ImportBatch{ID: 9}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			assert.Equal(t, pgtype.Int4{Int32: 9, Valid: true}, arg.ImportBatchID)
+			return insertedFingerprints(arg.Fingerprints[:1]), nil
+		},
+		completeImportBatchFunc: func(ctx context.Context, arg db.CompleteImportBatchParams) (db.ImportBatch, error) {
+			completed = arg
+			return db.ImportBatch{ID: arg.ID}, nil
+		},
+	}
+
+	service := NewService(mock)
+	result, err := service.AddTransactions(context.Background(), ImportSource{
+		FileName: "statement.csv",
+		Bank:     "Nationwide",
+		Checksum: "abc123",
+	}, []Transaction{
+		{Bank: "Nationwide", Description: "A", Amount: money.New(-100, "GBP")},
+		{Bank: "Nationwide", Description: "B", Amount: money.New(-200, "GBP")},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(9), result.BatchID)
+	assert.Equal(t, 1, mock.txCount)
+	assert.Equal(t, db.CompleteImportBatchParams{ID: 9, InsertedCount: 1, SkippedCount: 1, RowCount: 2}, completed)
+}
+
+func TestService_AddTransactions_StoresAccount(t *testing.T) {
+	accountID := int32(4)
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			assert.Equal(t, pgtype.Int4{Int32: 4, Valid: true}, arg.AccountID)
+			return db.ImportBatch{ID: 1}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			assert.Equal(t, []int32{4, 0}, arg.AccountIds)
+			return insertedFingerprints(arg.Fingerprints), nil
+		},
+	}
+
+	service := NewService(mock)
+	_, err := service.AddTransactions(context.Background(), ImportSource{
+		FileName:  "statement.csv",
+		Bank:      "Nationwide",
+		AccountID: &accountID,
+	}, []Transaction{
+		{Bank: "Nationwide", Description: "A", Amount: money.New(-100, "GBP"), AccountID: &accountID},
+		{Bank: "Nationwide", Description: "B", Amount: money.New(-200, "GBP")},
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestService_AddTransactions_LinksPayPalFunding(t *testing.T) {
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			return db.ImportBatch{ID: 7}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			return insertedFingerprints(arg.Fingerprints), nil
+		},
+		fundingCandidatesFunc: func(ctx context.Context, arg db.ListFundingCandidatesParams) ([]db.ListFundingCandidatesRow, error) {
+			assert.Equal(t, db.ListFundingCandidatesParams{WindowDays: 3, Bank: PayPalBank, ImportBatchID: 7}, arg)
+			// Payments 1 and 2 are for the same amount, and card rows 10
+			// and 11 could fund either; each takes the closest one left.
+			return []db.ListFundingCandidatesRow{
+				{PaymentID: 1, FundingID: 10, DaysApart: 0},
+				{PaymentID: 2, FundingID: 10, DaysApart: 1},
+				{PaymentID: 1, FundingID: 11, DaysApart: 1},
+				{PaymentID: 2, FundingID: 11, DaysApart: 2},
+			}, nil
+		},
+	}
+
+	_, err := NewService(mock).AddTransactions(context.Background(), ImportSource{FileName: "paypal.csv", Bank: PayPalBank}, []Transaction{
+		{Bank: PayPalBank, Description: "Shop", Amount: money.New(-1500, "GBP")},
+		{Bank: PayPalBank, Description: "Shop", Amount: money.New(-1500, "GBP")},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int32]int32{1: 10, 2: 11}, mock.links)
+}
+
+func TestService_AddTransactions_RecordsFailedBatch(t *testing.T) {
+	var statuses []string
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			statuses = append(statuses, arg.Status)
+			if arg.Status == ImportStatusFailed {
+				assert.True(t, arg.ErrorMessage.Valid)
+				assert.Contains(t, arg.ErrorMessage.String, "disk full")
+			}
+			return db.ImportBatch{ID: 1}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			return nil, errors.New("disk full")
+		},
+	}
+
+	service := NewService(mock)
+	_, err := service.AddTransactions(context.Background(), ImportSource{FileName: "statement.csv"}, []Transaction{
+		{Bank: "Nationwide", Description: "A", Amount: money.New(-100, "GBP")},
+	})
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+	assert.Equal(t, []string{ImportStatusCompleted, ImportStatusFailed}, statuses)
+}
+
+func TestService_AddStatements(t *testing.T) {
+	var batches []string
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			batches = append(batches, arg.FileName)
+			return db.ImportBatch{ID: int32(len(batches))}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			return insertedFingerprints(arg.Fingerprints), nil
+		},
+	}
+
+	results, err := NewService(mock).AddStatements(context.Background(), []StatementImport{
+		{Source: ImportSource{FileName: "jan.csv"}, Transactions: []Transaction{{Bank: "Amex", Description: "A", Amount: money.New(-100, "GBP")}}},
+		{Source: ImportSource{FileName: "feb.csv"}, Transactions: []Transaction{
+			{Bank: "Amex", Description: "B", Amount: money.New(-200, "GBP")},
+			{Bank: "Amex", Description: "C", Amount: money.New(-300, "GBP")},
+		}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mock.txCount)
+	assert.Equal(t, []string{"jan.csv", "feb.csv"}, batches)
+	assert.Equal(t, []ImportResult{{BatchID: 1, Inserted: 1}, {BatchID: 2, Inserted: 2}}, results)
+}
+
+func TestService_AddStatements_FailsEveryStatement(t *testing.T) {
+	var failed []db.CreateImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			if arg.Status == ImportStatusFailed {
+				failed = append(failed, arg)
+			}
+			return db.ImportBatch{ID: 1}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			if arg.Descriptions[0] == "B" {
+				return nil, errors.New("disk full")
+			}
+			return insertedFingerprints(arg.Fingerprints), nil
+		},
+	}
+
+	results, err := NewService(mock).AddStatements(context.Background(), []StatementImport{
+		{Source: ImportSource{FileName: "jan.csv"}, Transactions: []Transaction{{Bank: "Amex", Description: "A", Amount: money.New(-100, "GBP")}}},
+		{Source: ImportSource{FileName: "feb.csv"}, Transactions: []Transaction{{Bank: "Amex", Description: "B", Amount: money.New(-200, "GBP")}}},
+	})
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+	assert.ErrorContains(t, err, "feb.csv: disk full")
+	assert.Nil(t, results)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, "jan.csv", failed[0].FileName)
+}
+
+func TestService_AddStatements_Start(t *testing.T) {
+	var batches []db.CreateImportBatchParams
+	var fingerprints []string
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			batches = append(batches, arg)
+			return db.ImportBatch{ID: 1}, nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			fingerprints = arg.Fingerprints
+			return insertedFingerprints(arg.Fingerprints), nil
+		},
+	}
+	transactions := []Transaction{{Bank: "Amex", Description: "A", Amount: money.New(-100, "GBP")}}
+	accountID := int32(4)
+
+	_, err := NewService(mock).AddStatements(context.Background(), []StatementImport{{
+		Source:       ImportSource{FileName: "jan.csv"},
+		Transactions: transactions,
+		Start: func(ctx context.Context, q db.Querier, source *ImportSource) error {
+			assert.Empty(t, batches, "the account is settled before the batch")
+			source.AccountID = &accountID
+			return nil
+		},
+	}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mock.txCount)
+	assert.Equal(t, pgtype.Int4{Int32: 4, Valid: true}, batches[0].AccountID)
+	// The fingerprint includes the account Start chose.
+	expected := []Transaction{{Bank: "Amex", Description: "A", Amount: money.New(-100, "GBP"), AccountID: &accountID}}
+	AssignFingerprints(expected)
+	assert.Equal(t, []string{expected[0].Fingerprint}, fingerprints)
+}
+
+func TestService_AddStatements_StartFails(t *testing.T) {
+	var failed []db.CreateImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			if arg.Status == ImportStatusFailed {
+				failed = append(failed, arg)
+			}
+			return db.ImportBatch{ID: 1}, nil
+		},
+	}
+
+	_, err := NewService(mock).AddStatements(context.Background(), []StatementImport{
+		{Source: ImportSource{FileName: "jan.csv"}, Transactions: []Transaction{{Bank: "Amex", Description: "A", Amount: money.New(-100, "GBP")}}},
+		{
+			Source:       ImportSource{FileName: "feb.csv"},
+			Transactions: []Transaction{{Bank: "Amex", Description: "B", Amount: money.New(-200, "GBP")}},
+			Start: func(ctx context.Context, q db.Querier, source *ImportSource) error {
+				return errors.New("account not found")
+			},
+		},
+	})
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+	assert.ErrorContains(t, err, "feb.csv: account not found")
+	assert.Len(t, failed, 2)
+}
+
+func TestService_ListImportBatches(t *testing.T) {
+	mock := &mockQuerier{
+		importBatches: []db.ImportBatch{
+			{
+				ID:          2,
+				FileName:    "amex.csv",
+				Status:      ImportStatusRolledBack,
+				CreatedAt:   pgtype.Timestamp{Time: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+				CompletedAt: pgtype.Timestamp{Time: time.Date(2026, 1, 2, 0, 0, 1, 0, time.UTC), Valid: true},
+			},
+		},
+	}
+
+	batches, err := NewService(mock).ListImportBatches(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, batches, 1)
+	assert.Equal(t, "amex.csv", batches[0].FileName)
+	assert.NotNil(t, batches[0].CompletedAt)
+	assert.Nil(t, batches[0].RolledBackAt)
+}
+
+func TestService_RollbackImportBatch_Success(t *testing.T) {
+	var markedRolledBack bool
+	mock := &mockQuerier{
+		deleteByImportBatchFunc: func(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+			assert.Equal(t, pgtype.Int4{Int32: 4, Valid: true}, importBatchID)
+			return 12, nil
+		},
+		markRolledBackFunc: func(ctx context.Context, id int32) (db.ImportBatch, error) {
+			markedRolledBack = true
+			return db.ImportBatch{ID: id, Status: ImportStatusRolledBack}, nil
+		},
+	}
+
+	deleted, err := NewService(mock).RollbackImportBatch(context.Background(), 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), deleted)
+	assert.True(t, markedRolledBack)
+	assert.Equal(t, 1, mock.txCount)
+}
+
+func TestService_RollbackImportBatch_NotFound(t *testing.T) {
+	mock := &mockQuerier{
+		getImportBatchFunc: func(ctx context.Context, id int32) (db.ImportBatch, error) {
+			return db.ImportBatch{}, pgx.ErrNoRows
+		},
+	}
+
+	_, err := NewService(mock).RollbackImportBatch(context.Background(), 4)
+
+	assert.ErrorIs(t, err, ErrImportNotFound)
+}
+
+func TestService_RollbackImportBatch_AlreadyRolledBack(t *testing.T) {
+	mock := &mockQuerier{
+		getImportBatchFunc: func(ctx context.Context, id int32) (db.ImportBatch, error) {
+			return db.ImportBatch{ID: id, Status: ImportStatusRolledBack}, nil
+		},
+		deleteByImportBatchFunc: func(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+			t.Fatal("transactions should not be deleted twice")
+			return 0, nil
+		},
+	}
+
+	_, err := NewService(mock).RollbackImportBatch(context.Background(), 4)
+
+	assert.ErrorIs(t, err, ErrImportConflict)
+}
+
+func TestService_RollbackImportBatch_DatabaseError(t *testing.T) {
+	mock := &mockQuerier{
+		deleteByImportBatchFunc: func(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+			return 0, errors.New("lock timeout")
+		},
+	}
+
+	_, err := NewService(mock).RollbackImportBatch(context.Background(), 4)
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+}
+
+// streamOf yields transactions, then err if it is not nil.
+func streamOf(transactions []Transaction, err error) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		for _, tx := range transactions {
+			if !yield(tx, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(Transaction{}, err)
+		}
+	}
+}
+
+func TestService_AddTransactionStream(t *testing.T) {
+	var completed db.CompleteImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			assert.Equal(t, ImportStatusCompleted, arg.Status)
+			return db.ImportBatch{ID: 9}, nil
+		},
+		insertStagedFunc: func(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+			assert.Equal(t, pgtype.Int4{Int32: 9, Valid: true}, importBatchID)
+			return 2, nil
+		},
+		completeImportBatchFunc: func(ctx context.Context, arg db.CompleteImportBatchParams) (db.ImportBatch, error) {
+			completed = arg
+			return db.ImportBatch{ID: arg.ID}, nil
+		},
+	}
+	transactions := []Transaction{
+		{Bank: "Amex", Description: "COFFEE", Amount: money.New(-350, "GBP")},
+		{Bank: "Amex", Description: "COFFEE", Amount: money.New(-350, "GBP")},
+		{Bank: "Amex", Description: "RENT", Amount: money.New(-90000, "GBP")},
+	}
+
+	result, err := NewService(mock).AddTransactionStream(context.Background(), StreamImport{
+		Source:       ImportSource{FileName: "statement.csv", Bank: "Amex"},
+		Transactions: streamOf(transactions, nil),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ImportResult{BatchID: 9, Inserted: 2, Skipped: 1}, result)
+	assert.Equal(t, db.CompleteImportBatchParams{ID: 9, InsertedCount: 2, SkippedCount: 1, RowCount: 3}, completed)
+	assert.Equal(t, 1, mock.txCount)
+
+	// Rows are fingerprinted as AddTransactions would, and numbered in
+	// file order.
+	AssignFingerprints(transactions)
+	assert.Len(t, mock.staged, 3)
+	for i, row := range mock.staged {
+		assert.Equal(t, int64(i), row[0])
+		assert.Equal(t, transactions[i].Fingerprint, row[8])
+	}
+}
+
+func TestService_AddTransactionStream_ParseFailure(t *testing.T) {
+	var failed db.CreateImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			if arg.Status == ImportStatusFailed {
+				failed = arg
+			}
+			return db.ImportBatch{ID: 1}, nil
+		},
+		insertStagedFunc: func(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+			t.Error("a failed copy was inserted")
+			return 0, nil
+		},
+	}
+	transactions := []Transaction{{Bank: "Amex", Description: "COFFEE", Amount: money.New(-350, "GBP")}}
+
+	_, err := NewService(mock).AddTransactionStream(context.Background(), StreamImport{
+		Source:       ImportSource{FileName: "statement.csv"},
+		Transactions: streamOf(transactions, errors.New("row 2: parsing date '32/01/2026'")),
+	})
+
+	assert.ErrorIs(t, err, ErrParseFailure)
+	assert.ErrorContains(t, err, "row 2")
+	assert.Equal(t, int32(1), failed.RowCount)
+	assert.Contains(t, failed.ErrorMessage.String, "row 2")
+}
+
+func TestService_AddTransactionStream_Hooks(t *testing.T) {
+	var batch db.CreateImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			batch = arg
+			return db.ImportBatch{ID: 9}, nil
+		},
+	}
+	transactions := []Transaction{
+		{Bank: "Amex", Description: "COFFEE", Amount: money.New(-350, "GBP")},
+		{Bank: "Amex", Description: "RENT", Amount: money.New(-90000, "GBP")},
+	}
+	accountID := int32(4)
+	closing := money.New(-90350, "GBP")
+
+	_, err := NewService(mock).AddTransactionStream(context.Background(), StreamImport{
+		Source:       ImportSource{FileName: "statement.csv"},
+		Transactions: streamOf(transactions, nil),
+		Start: func(ctx context.Context, q db.Querier, first *Transaction, source *ImportSource) error {
+			assert.Equal(t, "COFFEE", first.Description)
+			assert.Empty(t, mock.staged, "the account is settled before the copy")
+			source.AccountID = &accountID
+			return nil
+		},
+		Finish: func(ctx context.Context, q db.Querier, source *ImportSource) error {
+			assert.Len(t, mock.staged, 2, "the source is completed once every row is read")
+			source.ClosingBalance = closing
+			return nil
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, pgtype.Int4{Int32: 4, Valid: true}, batch.AccountID)
+	assert.Equal(t, pgtype.Int8{Int64: -90350, Valid: true}, batch.ClosingBalance)
+	// Rows are imported into the account Start chose, which their
+	// fingerprints include.
+	for i := range transactions {
+		transactions[i].AccountID = &accountID
+	}
+	AssignFingerprints(transactions)
+	for i, row := range mock.staged {
+		assert.Equal(t, transactions[i].Fingerprint, row[8])
+	}
+}
+
+func TestService_AddTransactionStream_FailureWithNewAccount(t *testing.T) {
+	var failed []db.CreateImportBatchParams
+	mock := &mockQuerier{
+		createImportBatchFunc: func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+			if arg.Status != ImportStatusFailed {
+				return db.ImportBatch{}, errors.New("connection reset")
+			}
+			failed = append(failed, arg)
+			// The account was created in the rolled back transaction.
+			if arg.AccountID.Valid {
+				return db.ImportBatch{}, errors.New("violates foreign key constraint")
+			}
+			return db.ImportBatch{ID: 2}, nil
+		},
+	}
+	accountID := int32(12)
+
+	_, err := NewService(mock).AddTransactionStream(context.Background(), StreamImport{
+		Source:       ImportSource{FileName: "statement.csv"},
+		Transactions: streamOf([]Transaction{{Bank: "Amex", Description: "COFFEE", Amount: money.New(-350, "GBP")}}, nil),
+		Start: func(ctx context.Context, q db.Querier, first *Transaction, source *ImportSource) error {
+			source.AccountID = &accountID
+			return nil
+		},
+	})
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+	if assert.Len(t, failed, 2) {
+		assert.False(t, failed[1].AccountID.Valid)
+		assert.Equal(t, "connection reset", failed[1].ErrorMessage.String)
+	}
+}
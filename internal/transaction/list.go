@@ -0,0 +1,231 @@
+package transaction
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+)
+
+type SortField string
+
+const (
+	SortByDate        SortField = "date"
+	SortByAmount      SortField = "amount"
+	SortByDescription SortField = "description"
+)
+
+// DefaultPageLimit applies when a caller pages with a cursor but no limit.
+// The list handler returns every row when given neither.
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+type ListOptions struct {
+	DateFrom  *time.Time
+	DateTo    *time.Time
+	Bank      *string
+	Category  *string
+	AccountID *int32
+	MinAmount *int64
+	MaxAmount *int64
+	Search    string
+	SortBy    SortField
+	SortDesc  bool
+	Limit     int32
+	Cursor    string
+}
+
+type Page struct {
+	Transactions []Transaction
+	NextCursor   string
+}
+
+// pageCursor marks the last row of a page. It records the sort it was
+// issued for so a cursor cannot be replayed against a different ordering.
+type pageCursor struct {
+	SortBy      SortField `json:"s"`
+	SortDesc    bool      `json:"d"`
+	ID          int32     `json:"id"`
+	Date        string    `json:"date,omitempty"`
+	Amount      int64     `json:"amount,omitempty"`
+	Description string    `json:"desc,omitempty"`
+}
+
+func (o ListOptions) normalise() (ListOptions, error) {
+	if o.SortBy == "" {
+		o.SortBy = SortByDate
+		o.SortDesc = true
+	}
+	switch o.SortBy {
+	case SortByDate, SortByAmount, SortByDescription:
+	default:
+		return o, fmt.Errorf("%w: unsupported sort field %q", ErrValidation, o.SortBy)
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultPageLimit
+	}
+	if o.Limit > MaxPageLimit {
+		return o, fmt.Errorf("%w: limit must be at most %d", ErrValidation, MaxPageLimit)
+	}
+	if o.DateFrom != nil && o.DateTo != nil && o.DateFrom.After(*o.DateTo) {
+		return o, fmt.Errorf("%w: from must not be after to", ErrValidation)
+	}
+	if o.MinAmount != nil && o.MaxAmount != nil && *o.MinAmount > *o.MaxAmount {
+		return o, fmt.Errorf("%w: min_amount must not exceed max_amount", ErrValidation)
+	}
+	return o, nil
+}
+
+// listParams holds the arguments of every ListTransactionsBy query; each
+// query takes the filters and the cursor value for its own sort key.
+type listParams struct {
+	SortBy            SortField
+	SortDesc          bool
+	DateFrom          pgtype.Date
+	DateTo            pgtype.Date
+	Bank              pgtype.Text
+	Category          pgtype.Text
+	MinAmount         pgtype.Int8
+	MaxAmount         pgtype.Int8
+	Search            pgtype.Text
+	AccountID         pgtype.Int4
+	CursorID          pgtype.Int4
+	CursorDate        pgtype.Date
+	CursorAmount      pgtype.Int8
+	CursorDescription pgtype.Text
+	PageLimit         int32
+}
+
+func (o ListOptions) toDB() (listParams, error) {
+	params := listParams{
+		SortBy:   o.SortBy,
+		SortDesc: o.SortDesc,
+		// One extra row tells us whether another page exists.
+		PageLimit: o.Limit + 1,
+	}
+	if o.DateFrom != nil {
+		params.DateFrom = pgtype.Date{Time: *o.DateFrom, Valid: true}
+	}
+	if o.DateTo != nil {
+		params.DateTo = pgtype.Date{Time: *o.DateTo, Valid: true}
+	}
+	if o.Bank != nil {
+		params.Bank = pgtype.Text{String: *o.Bank, Valid: true}
+	}
+	if o.Category != nil {
+		params.Category = pgtype.Text{String: *o.Category, Valid: true}
+	}
+	params.AccountID = int4FromPtr(o.AccountID)
+	if o.MinAmount != nil {
+		params.MinAmount = pgtype.Int8{Int64: *o.MinAmount, Valid: true}
+	}
+	if o.MaxAmount != nil {
+		params.MaxAmount = pgtype.Int8{Int64: *o.MaxAmount, Valid: true}
+	}
+	if search := strings.TrimSpace(o.Search); search != "" {
+		params.Search = pgtype.Text{String: escapeLikePattern(search), Valid: true}
+	}
+
+	if o.Cursor == "" {
+		return params, nil
+	}
+
+	cursor, err := decodeCursor(o.Cursor)
+	if err != nil {
+		return params, err
+	}
+	if cursor.SortBy != o.SortBy || cursor.SortDesc != o.SortDesc {
+		return params, fmt.Errorf("%w: cursor does not match the requested sort order", ErrValidation)
+	}
+
+	params.CursorID = pgtype.Int4{Int32: cursor.ID, Valid: true}
+	switch cursor.SortBy {
+	case SortByDate:
+		date, err := time.Parse(time.DateOnly, cursor.Date)
+		if err != nil {
+			return params, fmt.Errorf("%w: invalid cursor", ErrValidation)
+		}
+		params.CursorDate = pgtype.Date{Time: date, Valid: true}
+	case SortByAmount:
+		params.CursorAmount = pgtype.Int8{Int64: cursor.Amount, Valid: true}
+	case SortByDescription:
+		params.CursorDescription = pgtype.Text{String: cursor.Description, Valid: true}
+	}
+
+	return params, nil
+}
+
+// listTransactionsPage runs the query for the page's sort key and
+// direction, so its ORDER BY can use that key's index.
+func listTransactionsPage(ctx context.Context, q db.Querier, p listParams) ([]db.Transaction, error) {
+	switch p.SortBy {
+	case SortByAmount:
+		arg := db.ListTransactionsByAmountAscParams{
+			DateFrom: p.DateFrom, DateTo: p.DateTo, Bank: p.Bank, Category: p.Category,
+			MinAmount: p.MinAmount, MaxAmount: p.MaxAmount, Search: p.Search, AccountID: p.AccountID,
+			CursorID: p.CursorID, CursorAmount: p.CursorAmount, PageLimit: p.PageLimit,
+		}
+		if p.SortDesc {
+			return q.ListTransactionsByAmountDesc(ctx, db.ListTransactionsByAmountDescParams(arg))
+		}
+		return q.ListTransactionsByAmountAsc(ctx, arg)
+	case SortByDescription:
+		arg := db.ListTransactionsByDescriptionAscParams{
+			DateFrom: p.DateFrom, DateTo: p.DateTo, Bank: p.Bank, Category: p.Category,
+			MinAmount: p.MinAmount, MaxAmount: p.MaxAmount, Search: p.Search, AccountID: p.AccountID,
+			CursorID: p.CursorID, CursorDescription: p.CursorDescription, PageLimit: p.PageLimit,
+		}
+		if p.SortDesc {
+			return q.ListTransactionsByDescriptionDesc(ctx, db.ListTransactionsByDescriptionDescParams(arg))
+		}
+		return q.ListTransactionsByDescriptionAsc(ctx, arg)
+	default:
+		arg := db.ListTransactionsByDateAscParams{
+			DateFrom: p.DateFrom, DateTo: p.DateTo, Bank: p.Bank, Category: p.Category,
+			MinAmount: p.MinAmount, MaxAmount: p.MaxAmount, Search: p.Search, AccountID: p.AccountID,
+			CursorID: p.CursorID, CursorDate: p.CursorDate, PageLimit: p.PageLimit,
+		}
+		if p.SortDesc {
+			return q.ListTransactionsByDateDesc(ctx, db.ListTransactionsByDateDescParams(arg))
+		}
+		return q.ListTransactionsByDateAsc(ctx, arg)
+	}
+}
+
+func encodeCursor(o ListOptions, last Transaction) string {
+	cursor := pageCursor{SortBy: o.SortBy, SortDesc: o.SortDesc, ID: last.ID}
+	switch o.SortBy {
+	case SortByDate:
+		cursor.Date = last.Date.Format(time.DateOnly)
+	case SortByAmount:
+		cursor.Amount = last.Amount.Amount()
+	case SortByDescription:
+		cursor.Description = last.Description
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, fmt.Errorf("%w: invalid cursor", ErrValidation)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return cursor, fmt.Errorf("%w: invalid cursor", ErrValidation)
+	}
+	return cursor, nil
+}
+
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
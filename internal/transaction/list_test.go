@@ -0,0 +1,140 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/kushturner/finances/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func dbTransactionOn(id int32, day int, amount int64) db.Transaction {
+	return db.Transaction{
+		ID:          id,
+		Date:        pgtype.Date{Time: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC), Valid: true},
+		Description: "Row",
+		Amount:      amount,
+		Currency:    "GBP",
+		Bank:        "Nationwide",
+	}
+}
+
+func TestService_ListTransactions_Defaults(t *testing.T) {
+	mock := &mockQuerier{
+		listByDateDescFunc: func(ctx context.Context, arg db.ListTransactionsByDateDescParams) ([]db.Transaction, error) {
+			assert.Equal(t, int32(DefaultPageLimit+1), arg.PageLimit)
+			assert.False(t, arg.CursorID.Valid)
+			assert.False(t, arg.Bank.Valid)
+			return []db.Transaction{dbTransactionOn(1, 15, -500)}, nil
+		},
+	}
+
+	page, err := NewService(mock).ListTransactions(context.Background(), ListOptions{})
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Transactions, 1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestService_ListTransactions_PassesFilters(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	bank := "Nationwide"
+	minAmount := int64(-10000)
+
+	mock := &mockQuerier{
+		listByDateDescFunc: func(ctx context.Context, arg db.ListTransactionsByDateDescParams) ([]db.Transaction, error) {
+			assert.Equal(t, pgtype.Date{Time: from, Valid: true}, arg.DateFrom)
+			assert.Equal(t, pgtype.Date{Time: to, Valid: true}, arg.DateTo)
+			assert.Equal(t, pgtype.Text{String: bank, Valid: true}, arg.Bank)
+			assert.Equal(t, pgtype.Int8{Int64: minAmount, Valid: true}, arg.MinAmount)
+			assert.False(t, arg.MaxAmount.Valid)
+			assert.Equal(t, pgtype.Text{String: `50\% off`, Valid: true}, arg.Search)
+			return nil, nil
+		},
+	}
+
+	_, err := NewService(mock).ListTransactions(context.Background(), ListOptions{
+		DateFrom:  &from,
+		DateTo:    &to,
+		Bank:      &bank,
+		MinAmount: &minAmount,
+		Search:    " 50% off ",
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestService_ListTransactions_ReturnsNextCursorAndFollowsIt(t *testing.T) {
+	first := &mockQuerier{
+		listByAmountAscFunc: func(ctx context.Context, arg db.ListTransactionsByAmountAscParams) ([]db.Transaction, error) {
+			assert.Equal(t, int32(3), arg.PageLimit)
+			return []db.Transaction{
+				dbTransactionOn(1, 10, -100),
+				dbTransactionOn(2, 11, -200),
+				dbTransactionOn(3, 12, -300),
+			}, nil
+		},
+	}
+
+	opts := ListOptions{SortBy: SortByAmount, Limit: 2}
+	page, err := NewService(first).ListTransactions(context.Background(), opts)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Transactions, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	second := &mockQuerier{
+		listByAmountAscFunc: func(ctx context.Context, arg db.ListTransactionsByAmountAscParams) ([]db.Transaction, error) {
+			assert.Equal(t, pgtype.Int4{Int32: 2, Valid: true}, arg.CursorID)
+			assert.Equal(t, pgtype.Int8{Int64: -200, Valid: true}, arg.CursorAmount)
+			return nil, nil
+		},
+	}
+
+	opts.Cursor = page.NextCursor
+	_, err = NewService(second).ListTransactions(context.Background(), opts)
+
+	assert.NoError(t, err)
+}
+
+func TestService_ListTransactions_CursorSortMismatch(t *testing.T) {
+	cursor := encodeCursor(ListOptions{SortBy: SortByDate, SortDesc: true}, Transaction{ID: 4, Date: time.Now()})
+
+	_, err := NewService(&mockQuerier{}).ListTransactions(context.Background(), ListOptions{
+		SortBy: SortByAmount,
+		Cursor: cursor,
+	})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "cursor does not match")
+}
+
+func TestService_ListTransactions_InvalidCursor(t *testing.T) {
+	_, err := NewService(&mockQuerier{}).ListTransactions(context.Background(), ListOptions{Cursor: "not-a-cursor"})
+
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestService_ListTransactions_InvalidSortField(t *testing.T) {
+	_, err := NewService(&mockQuerier{}).ListTransactions(context.Background(), ListOptions{SortBy: "bank"})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "unsupported sort field")
+}
+
+func TestService_ListTransactions_LimitTooLarge(t *testing.T) {
+	_, err := NewService(&mockQuerier{}).ListTransactions(context.Background(), ListOptions{Limit: MaxPageLimit + 1})
+
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestService_ListTransactions_DatabaseError(t *testing.T) {
+	mock := &mockQuerier{err: assert.AnError}
+
+	_, err := NewService(mock).ListTransactions(context.Background(), ListOptions{})
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+}
@@ -1,6 +1,8 @@
 package transaction
 
 import (
+	"time"
+
 	"github.com/Rhymond/go-money"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kushturner/finances/internal/db"
@@ -13,14 +15,23 @@ func TransactionFromDB(dbTx db.Transaction) Transaction {
 	}
 
 	return Transaction{
-		ID:          dbTx.ID,
-		Date:        dbTx.Date.Time,
-		Description: dbTx.Description,
-		Amount:      money.New(dbTx.Amount, dbTx.Currency),
-		Bank:        dbTx.Bank,
-		Category:    category,
-		CreatedAt:   dbTx.CreatedAt.Time,
-		UpdatedAt:   dbTx.UpdatedAt.Time,
+		ID:                  dbTx.ID,
+		Date:                dbTx.Date.Time,
+		Description:         dbTx.Description,
+		Amount:              money.New(dbTx.Amount, dbTx.Currency),
+		RunningBalance:      moneyOrNil(dbTx.RunningBalance, dbTx.Currency),
+		LocalAmount:         moneyOrNil(dbTx.LocalAmount, dbTx.LocalCurrency.String),
+		Bank:                dbTx.Bank,
+		Category:            category,
+		Notes:               textOrNil(dbTx.Notes),
+		Address:             textOrNil(dbTx.Address),
+		AccountID:           int4OrNil(dbTx.AccountID),
+		ExternalID:          textOrNil(dbTx.ExternalID),
+		LinkedExternalID:    textOrNil(dbTx.LinkedExternalID),
+		LinkedTransactionID: int4OrNil(dbTx.LinkedTransactionID),
+		Fingerprint:         dbTx.Fingerprint.String,
+		CreatedAt:           dbTx.CreatedAt.Time,
+		UpdatedAt:           dbTx.UpdatedAt.Time,
 	}
 }
 
@@ -32,18 +43,170 @@ func TransactionToDB(tx Transaction) db.CreateTransactionParams {
 		Currency:    tx.Amount.Currency().Code,
 		Bank:        tx.Bank,
 		Category:    pgtype.Text{String: stringOrEmpty(tx.Category), Valid: tx.Category != nil},
+		AccountID:   int4FromPtr(tx.AccountID),
+		// The details imports fill in can be entered by hand too.
+		Notes:            textFromPtr(tx.Notes),
+		Address:          textFromPtr(tx.Address),
+		LocalAmount:      int8FromMoney(tx.LocalAmount),
+		LocalCurrency:    currencyText(tx.LocalAmount),
+		LinkedExternalID: textFromPtr(tx.LinkedExternalID),
 	}
 }
 
-func TransactionToBatchDB(tx Transaction) db.CreateTransactionsBatchParams {
-	return db.CreateTransactionsBatchParams{
-		Date:        pgtype.Date{Time: tx.Date, Valid: true},
-		Description: tx.Description,
-		Amount:      tx.Amount.Amount(),
-		Currency:    tx.Amount.Currency().Code,
-		Bank:        tx.Bank,
-		Category:    pgtype.Text{String: stringOrEmpty(tx.Category), Valid: tx.Category != nil},
+// TransactionsToImportDB lays the transactions out column by column for the
+// unnest-based insert. Empty categories, external IDs, linked external IDs,
+// notes and addresses, and a zero account ID, are stored as NULL. Running balances travel with a
+// validity mask because zero is a real balance; a local amount is stored
+// only alongside its currency.
+func TransactionsToImportDB(txs []Transaction) db.CreateTransactionsSkipDuplicatesParams {
+	params := db.CreateTransactionsSkipDuplicatesParams{
+		Dates:               make([]pgtype.Date, len(txs)),
+		Descriptions:        make([]string, len(txs)),
+		Amounts:             make([]int64, len(txs)),
+		Currencies:          make([]string, len(txs)),
+		Banks:               make([]string, len(txs)),
+		Categories:          make([]string, len(txs)),
+		ExternalIds:         make([]string, len(txs)),
+		Fingerprints:        make([]string, len(txs)),
+		AccountIds:          make([]int32, len(txs)),
+		RunningBalances:     make([]int64, len(txs)),
+		HasRunningBalances:  make([]bool, len(txs)),
+		Notes:               make([]string, len(txs)),
+		Addresses:           make([]string, len(txs)),
+		LocalAmounts:        make([]int64, len(txs)),
+		LocalCurrencies:     make([]string, len(txs)),
+		LinkedExternalIds:   make([]string, len(txs)),
+		ContentFingerprints: make([]string, len(txs)),
+	}
+	for i, tx := range txs {
+		params.Dates[i] = pgtype.Date{Time: tx.Date, Valid: true}
+		params.Descriptions[i] = tx.Description
+		params.Amounts[i] = tx.Amount.Amount()
+		params.Currencies[i] = tx.Amount.Currency().Code
+		params.Banks[i] = tx.Bank
+		params.Categories[i] = stringOrEmpty(tx.Category)
+		params.ExternalIds[i] = stringOrEmpty(tx.ExternalID)
+		params.LinkedExternalIds[i] = stringOrEmpty(tx.LinkedExternalID)
+		params.Fingerprints[i] = tx.Fingerprint
+		if tx.AccountID != nil {
+			params.AccountIds[i] = *tx.AccountID
+		}
+		if tx.RunningBalance != nil {
+			params.RunningBalances[i] = tx.RunningBalance.Amount()
+			params.HasRunningBalances[i] = true
+		}
+		params.Notes[i] = stringOrEmpty(tx.Notes)
+		params.Addresses[i] = stringOrEmpty(tx.Address)
+		if tx.LocalAmount != nil {
+			params.LocalAmounts[i] = tx.LocalAmount.Amount()
+			params.LocalCurrencies[i] = tx.LocalAmount.Currency().Code
+		}
+		params.ContentFingerprints[i] = tx.ContentFingerprint
 	}
+	return params
+}
+
+// transactionToStaging appends the values of a row of transaction_staging
+// to values, in the order of db.TransactionStagingColumns. Empty and
+// missing values are stored as NULL, as TransactionsToImportDB's insert
+// stores them.
+func transactionToStaging(position int64, tx Transaction, values []any) []any {
+	var accountID, runningBalance, localAmount, localCurrency any
+	if tx.AccountID != nil && *tx.AccountID != 0 {
+		accountID = *tx.AccountID
+	}
+	if tx.RunningBalance != nil {
+		runningBalance = tx.RunningBalance.Amount()
+	}
+	if tx.LocalAmount != nil {
+		localAmount = tx.LocalAmount.Amount()
+		localCurrency = tx.LocalAmount.Currency().Code
+	}
+	return append(values,
+		position,
+		pgtype.Date{Time: tx.Date, Valid: true},
+		tx.Description,
+		tx.Amount.Amount(),
+		tx.Amount.Currency().Code,
+		tx.Bank,
+		nilIfEmpty(tx.Category),
+		nilIfEmpty(tx.ExternalID),
+		tx.Fingerprint,
+		accountID,
+		runningBalance,
+		nilIfEmpty(tx.Notes),
+		nilIfEmpty(tx.Address),
+		localAmount,
+		localCurrency,
+		nilIfEmpty(tx.LinkedExternalID),
+		nilIfEmpty(&tx.ContentFingerprint),
+	)
+}
+
+// nilIfEmpty returns nil for a missing or empty string, which COPY writes
+// as NULL.
+func nilIfEmpty(s *string) any {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return *s
+}
+
+func textOrNil(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func textFromPtr(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+// currencyText is the currency of m, which is NULL along with its amount.
+func currencyText(m *money.Money) pgtype.Text {
+	if m == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: m.Currency().Code, Valid: true}
+}
+
+func moneyOrNil(amount pgtype.Int8, currency string) *money.Money {
+	if !amount.Valid {
+		return nil
+	}
+	return money.New(amount.Int64, currency)
+}
+
+func int8FromMoney(m *money.Money) pgtype.Int8 {
+	if m == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: m.Amount(), Valid: true}
+}
+
+func dateFromPtr(t *time.Time) pgtype.Date {
+	if t == nil {
+		return pgtype.Date{}
+	}
+	return pgtype.Date{Time: *t, Valid: true}
+}
+
+func int4OrNil(i pgtype.Int4) *int32 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int32
+}
+
+func int4FromPtr(i *int32) pgtype.Int4 {
+	if i == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *i, Valid: true}
 }
 
 func stringOrEmpty(s *string) string {
@@ -52,3 +215,67 @@ func stringOrEmpty(s *string) string {
 	}
 	return *s
 }
+
+func TransactionToUpdateDB(tx Transaction) db.UpdateTransactionParams {
+	return db.UpdateTransactionParams{
+		ID:               tx.ID,
+		Date:             pgtype.Date{Time: tx.Date, Valid: true},
+		Description:      tx.Description,
+		Amount:           tx.Amount.Amount(),
+		Currency:         tx.Amount.Currency().Code,
+		Bank:             tx.Bank,
+		Category:         pgtype.Text{String: stringOrEmpty(tx.Category), Valid: tx.Category != nil},
+		AccountID:        int4FromPtr(tx.AccountID),
+		Notes:            textFromPtr(tx.Notes),
+		Address:          textFromPtr(tx.Address),
+		LocalAmount:      int8FromMoney(tx.LocalAmount),
+		LocalCurrency:    currencyText(tx.LocalAmount),
+		LinkedExternalID: textFromPtr(tx.LinkedExternalID),
+	}
+}
+
+func ImportBatchFromDB(b db.ImportBatch) ImportBatch {
+	return ImportBatch{
+		ID:            b.ID,
+		FileName:      b.FileName,
+		Bank:          b.Bank,
+		Checksum:      b.Checksum,
+		AccountID:     int4OrNil(b.AccountID),
+		RowCount:      b.RowCount,
+		InsertedCount: b.InsertedCount,
+		SkippedCount:  b.SkippedCount,
+		Status:        b.Status,
+		ErrorMessage:  textOrNil(b.ErrorMessage),
+		CreatedAt:     b.CreatedAt.Time,
+		CompletedAt:   timestampOrNil(b.CompletedAt),
+		RolledBackAt:  timestampOrNil(b.RolledBackAt),
+		// Statement balances are in the account's currency, which the
+		// batch does not record; the caller knows it.
+		ClosingBalance:     int8OrNil(b.ClosingBalance),
+		AvailableBalance:   int8OrNil(b.AvailableBalance),
+		BalanceDate:        dateOrNil(b.BalanceDate),
+		OpeningBalance:     int8OrNil(b.OpeningBalance),
+		OpeningBalanceDate: dateOrNil(b.OpeningBalanceDate),
+	}
+}
+
+func int8OrNil(i pgtype.Int8) *int64 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int64
+}
+
+func dateOrNil(d pgtype.Date) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Time
+}
+
+func timestampOrNil(t pgtype.Timestamp) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
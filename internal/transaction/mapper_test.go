@@ -84,6 +84,15 @@ func TestTransactionToDB_WithNilCategory(t *testing.T) {
 	assert.Equal(t, "", result.Category.String)
 }
 
+func TestTransactionsToImportDB_ContentFingerprint(t *testing.T) {
+	params := TransactionsToImportDB([]Transaction{
+		{Amount: money.New(-100, "GBP"), Fingerprint: "a"},
+		{Amount: money.New(-200, "GBP"), Fingerprint: "b", ContentFingerprint: "c"},
+	})
+
+	assert.Equal(t, []string{"", "c"}, params.ContentFingerprints)
+}
+
 func TestStringOrEmpty_WithNil(t *testing.T) {
 	result := stringOrEmpty(nil)
 
@@ -96,3 +105,64 @@ func TestStringOrEmpty_WithValue(t *testing.T) {
 
 	assert.Equal(t, str, result)
 }
+
+func TestTransactionsToImportDB_RunningBalanceMask(t *testing.T) {
+	params := TransactionsToImportDB([]Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "A", Amount: money.New(-100, "GBP"), RunningBalance: money.New(0, "GBP")},
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "B", Amount: money.New(-100, "GBP")},
+	})
+
+	assert.Equal(t, []int64{0, 0}, params.RunningBalances)
+	assert.Equal(t, []bool{true, false}, params.HasRunningBalances)
+}
+
+func TestTransactionFromDB_RunningBalance(t *testing.T) {
+	tx := TransactionFromDB(db.Transaction{
+		Amount:         -100,
+		Currency:       "EUR",
+		RunningBalance: pgtype.Int8{Int64: 4200, Valid: true},
+	})
+
+	assert.Equal(t, int64(4200), tx.RunningBalance.Amount())
+	assert.Equal(t, "EUR", tx.RunningBalance.Currency().Code)
+}
+
+func TestTransactionsToImportDB_Details(t *testing.T) {
+	notes := "Lunch with the team #work"
+	address := "1 High Street, London"
+	params := TransactionsToImportDB([]Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "A", Amount: money.New(-2150, "GBP"),
+			LocalAmount: money.New(-2500, "EUR"), Notes: &notes, Address: &address},
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "B", Amount: money.New(-100, "GBP")},
+	})
+
+	assert.Equal(t, []string{notes, ""}, params.Notes)
+	assert.Equal(t, []string{address, ""}, params.Addresses)
+	assert.Equal(t, []int64{-2500, 0}, params.LocalAmounts)
+	assert.Equal(t, []string{"EUR", ""}, params.LocalCurrencies)
+}
+
+func TestTransactionsToImportDB_LinkedExternalID(t *testing.T) {
+	ref, feeRef := "20260112T180000", "20260112T180000/fee"
+	params := TransactionsToImportDB([]Transaction{
+		{Date: time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC), Description: "Exchange", Amount: money.New(-10000, "GBP"), ExternalID: &ref},
+		{Date: time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC), Description: "Fee", Amount: money.New(-50, "GBP"), ExternalID: &feeRef, LinkedExternalID: &ref},
+	})
+
+	assert.Equal(t, []string{"", ref}, params.LinkedExternalIds)
+}
+
+func TestTransactionFromDB_Details(t *testing.T) {
+	tx := TransactionFromDB(db.Transaction{
+		Amount:        -2150,
+		Currency:      "GBP",
+		LocalAmount:   pgtype.Int8{Int64: -2500, Valid: true},
+		LocalCurrency: pgtype.Text{String: "EUR", Valid: true},
+		Notes:         pgtype.Text{String: "Lunch", Valid: true},
+	})
+
+	assert.Equal(t, int64(-2500), tx.LocalAmount.Amount())
+	assert.Equal(t, "EUR", tx.LocalAmount.Currency().Code)
+	assert.Equal(t, "Lunch", *tx.Notes)
+	assert.Nil(t, tx.Address)
+}
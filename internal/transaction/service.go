@@ -2,28 +2,49 @@ package transaction
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/kushturner/finances/internal/db"
 )
 
 type Service interface {
 	GetAllTransactions(ctx context.Context) ([]Transaction, error)
-	AddTransactions(ctx context.Context, transactions []Transaction) (int64, error)
+	ListTransactions(ctx context.Context, opts ListOptions) (Page, error)
+	AddTransactions(ctx context.Context, source ImportSource, transactions []Transaction) (ImportResult, error)
+	// AddStatements is AddTransactions for several statements imported
+	// together, returning their results in order. Either all of them are
+	// imported or none are.
+	AddStatements(ctx context.Context, statements []StatementImport) ([]ImportResult, error)
+	// AddTransactionStream is AddTransactions for a statement read as it
+	// is imported, such as a large export, whose rows it never holds all
+	// at once.
+	AddTransactionStream(ctx context.Context, statement StreamImport) (ImportResult, error)
+	// FindDuplicates reports, by index, which transactions AddTransactions
+	// would skip as already stored. It writes nothing.
+	FindDuplicates(ctx context.Context, transactions []Transaction) ([]bool, error)
+	GetTransaction(ctx context.Context, id int32) (Transaction, error)
+	CreateTransaction(ctx context.Context, tx Transaction) (Transaction, error)
+	UpdateTransaction(ctx context.Context, tx Transaction) (Transaction, error)
+	DeleteTransaction(ctx context.Context, id int32) error
+	ListImportBatches(ctx context.Context) ([]ImportBatch, error)
+	RollbackImportBatch(ctx context.Context, id int32) (int64, error)
 }
 
 type service struct {
-	querier db.Querier
+	store db.Store
 }
 
-func NewService(querier db.Querier) Service {
+func NewService(store db.Store) Service {
 	return &service{
-		querier: querier,
+		store: store,
 	}
 }
 
 func (s *service) GetAllTransactions(ctx context.Context) ([]Transaction, error) {
-	dbTransactions, err := s.querier.ListTransactions(ctx)
+	dbTransactions, err := s.store.ListTransactions(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -36,16 +57,96 @@ func (s *service) GetAllTransactions(ctx context.Context) ([]Transaction, error)
 	return transactions, nil
 }
 
-func (s *service) AddTransactions(ctx context.Context, transactions []Transaction) (int64, error) {
-	batchParams := make([]db.CreateTransactionsBatchParams, len(transactions))
-	for i, tx := range transactions {
-		batchParams[i] = TransactionToBatchDB(tx)
+func (s *service) ListTransactions(ctx context.Context, opts ListOptions) (Page, error) {
+	opts, err := opts.normalise()
+	if err != nil {
+		return Page{}, err
+	}
+
+	params, err := opts.toDB()
+	if err != nil {
+		return Page{}, err
+	}
+
+	dbTransactions, err := listTransactionsPage(ctx, s.store, params)
+	if err != nil {
+		return Page{}, wrapQueryError(err)
+	}
+
+	hasMore := len(dbTransactions) > int(opts.Limit)
+	if hasMore {
+		dbTransactions = dbTransactions[:opts.Limit]
+	}
+
+	page := Page{Transactions: make([]Transaction, 0, len(dbTransactions))}
+	for _, dbTx := range dbTransactions {
+		page.Transactions = append(page.Transactions, TransactionFromDB(dbTx))
+	}
+	if hasMore {
+		page.NextCursor = encodeCursor(opts, page.Transactions[len(page.Transactions)-1])
+	}
+
+	return page, nil
+}
+
+func (s *service) GetTransaction(ctx context.Context, id int32) (Transaction, error) {
+	dbTx, err := s.store.GetTransaction(ctx, id)
+	if err != nil {
+		return Transaction{}, wrapQueryError(err)
+	}
+
+	return TransactionFromDB(dbTx), nil
+}
+
+func (s *service) CreateTransaction(ctx context.Context, tx Transaction) (Transaction, error) {
+	if err := tx.Validate(); err != nil {
+		return Transaction{}, err
+	}
+
+	dbTx, err := s.store.CreateTransaction(ctx, TransactionToDB(tx))
+	if err != nil {
+		return Transaction{}, wrapQueryError(err)
+	}
+
+	return TransactionFromDB(dbTx), nil
+}
+
+func (s *service) UpdateTransaction(ctx context.Context, tx Transaction) (Transaction, error) {
+	if err := tx.Validate(); err != nil {
+		return Transaction{}, err
+	}
+
+	dbTx, err := s.store.UpdateTransaction(ctx, TransactionToUpdateDB(tx))
+	if err != nil {
+		return Transaction{}, wrapQueryError(err)
 	}
 
-	count, err := s.querier.CreateTransactionsBatch(ctx, batchParams)
+	return TransactionFromDB(dbTx), nil
+}
+
+func (s *service) DeleteTransaction(ctx context.Context, id int32) error {
+	rows, err := s.store.DeleteTransaction(ctx, id)
 	if err != nil {
-		return 0, fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
+		return wrapQueryError(err)
+	}
+	if rows == 0 {
+		return ErrNotFound
 	}
 
-	return count, nil
+	return nil
+}
+
+// foreignKeyViolation is the Postgres error code raised when a transaction
+// names an account that does not exist.
+const foreignKeyViolation = "23503"
+
+func wrapQueryError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return fmt.Errorf("%w: account does not exist", ErrValidation)
+	}
+	return fmt.Errorf("%w: %s", ErrDatabaseFailure, err.Error())
 }
@@ -3,52 +3,289 @@ package transaction
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/Rhymond/go-money"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/kushturner/finances/internal/db"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockQuerier struct {
-	transactions                []db.Transaction
-	err                         error
-	createTransactionsBatchFunc func(ctx context.Context, arg []db.CreateTransactionsBatchParams) (int64, error)
+	transactions             []db.Transaction
+	err                      error
+	createSkipDuplicatesFunc func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error)
+	getTransactionFunc       func(ctx context.Context, id int32) (db.Transaction, error)
+	createTransactionFunc    func(ctx context.Context, arg db.CreateTransactionParams) (db.Transaction, error)
+	updateTransactionFunc    func(ctx context.Context, arg db.UpdateTransactionParams) (db.Transaction, error)
+	deleteTransactionFunc    func(ctx context.Context, id int32) (int64, error)
+	listByDateDescFunc       func(ctx context.Context, arg db.ListTransactionsByDateDescParams) ([]db.Transaction, error)
+	listByAmountAscFunc      func(ctx context.Context, arg db.ListTransactionsByAmountAscParams) ([]db.Transaction, error)
+	createImportBatchFunc    func(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error)
+	completeImportBatchFunc  func(ctx context.Context, arg db.CompleteImportBatchParams) (db.ImportBatch, error)
+	getImportBatchFunc       func(ctx context.Context, id int32) (db.ImportBatch, error)
+	deleteByImportBatchFunc  func(ctx context.Context, importBatchID pgtype.Int4) (int64, error)
+	markRolledBackFunc       func(ctx context.Context, id int32) (db.ImportBatch, error)
+	storedFingerprintsFunc   func(ctx context.Context, fingerprints []string) ([]pgtype.Text, error)
+	unreferencedFunc         func(ctx context.Context, fingerprints []string) ([]pgtype.Text, error)
+	insertStagedFunc         func(ctx context.Context, importBatchID pgtype.Int4) (int64, error)
+	fundingCandidatesFunc    func(ctx context.Context, arg db.ListFundingCandidatesParams) ([]db.ListFundingCandidatesRow, error)
+	// links holds the funding links set, by payment ID.
+	links map[int32]int32
+	// staged holds the rows copied to the staging table, as pgx would
+	// read them from the source.
+	staged        [][]any
+	importBatches []db.ImportBatch
+	txCount       int
 }
 
 func (m *mockQuerier) ListTransactions(ctx context.Context) ([]db.Transaction, error) {
 	return m.transactions, m.err
 }
 
+func (m *mockQuerier) ListTransactionsByAmountAsc(ctx context.Context, arg db.ListTransactionsByAmountAscParams) ([]db.Transaction, error) {
+	if m.listByAmountAscFunc != nil {
+		return m.listByAmountAscFunc(ctx, arg)
+	}
+	return m.transactions, m.err
+}
+
+func (m *mockQuerier) ListTransactionsByAmountDesc(ctx context.Context, arg db.ListTransactionsByAmountDescParams) ([]db.Transaction, error) {
+	return m.transactions, m.err
+}
+
+func (m *mockQuerier) ListTransactionsByDateAsc(ctx context.Context, arg db.ListTransactionsByDateAscParams) ([]db.Transaction, error) {
+	return m.transactions, m.err
+}
+
+func (m *mockQuerier) ListTransactionsByDateDesc(ctx context.Context, arg db.ListTransactionsByDateDescParams) ([]db.Transaction, error) {
+	if m.listByDateDescFunc != nil {
+		return m.listByDateDescFunc(ctx, arg)
+	}
+	return m.transactions, m.err
+}
+
+func (m *mockQuerier) ListTransactionsByDescriptionAsc(ctx context.Context, arg db.ListTransactionsByDescriptionAscParams) ([]db.Transaction, error) {
+	return m.transactions, m.err
+}
+
+func (m *mockQuerier) ListTransactionsByDescriptionDesc(ctx context.Context, arg db.ListTransactionsByDescriptionDescParams) ([]db.Transaction, error) {
+	return m.transactions, m.err
+}
+
 func (m *mockQuerier) GetTransaction(ctx context.Context, id int32) (db.Transaction, error) {
+	if m.getTransactionFunc != nil {
+		return m.getTransactionFunc(ctx, id)
+	}
 	return db.Transaction{}, nil
 }
 
 func (m *mockQuerier) CreateTransaction(ctx context.Context, arg db.CreateTransactionParams) (db.Transaction, error) {
+	if m.createTransactionFunc != nil {
+		return m.createTransactionFunc(ctx, arg)
+	}
 	return db.Transaction{}, nil
 }
 
 func (m *mockQuerier) UpdateTransaction(ctx context.Context, arg db.UpdateTransactionParams) (db.Transaction, error) {
+	if m.updateTransactionFunc != nil {
+		return m.updateTransactionFunc(ctx, arg)
+	}
 	return db.Transaction{}, nil
 }
 
-func (m *mockQuerier) DeleteTransaction(ctx context.Context, id int32) error {
+func (m *mockQuerier) DeleteTransaction(ctx context.Context, id int32) (int64, error) {
+	if m.deleteTransactionFunc != nil {
+		return m.deleteTransactionFunc(ctx, id)
+	}
+	return 1, nil
+}
+
+func (m *mockQuerier) CreateTransactionsSkipDuplicates(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+	if m.createSkipDuplicatesFunc != nil {
+		return m.createSkipDuplicatesFunc(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListStoredFingerprints(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+	if m.storedFingerprintsFunc != nil {
+		return m.storedFingerprintsFunc(ctx, fingerprints)
+	}
+	return nil, m.err
+}
+
+func (m *mockQuerier) ListUnreferencedFingerprints(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+	if m.unreferencedFunc != nil {
+		return m.unreferencedFunc(ctx, fingerprints)
+	}
+	return nil, m.err
+}
+
+func (m *mockQuerier) CreateTransactionStaging(ctx context.Context) error {
 	return nil
 }
 
-func (m *mockQuerier) CreateTransactionsBatch(ctx context.Context, arg []db.CreateTransactionsBatchParams) (int64, error) {
-	if m.createTransactionsBatchFunc != nil {
-		return m.createTransactionsBatchFunc(ctx, arg)
+func (m *mockQuerier) CopyTransactionsToStaging(ctx context.Context, rowSrc pgx.CopyFromSource) (int64, error) {
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		m.staged = append(m.staged, slices.Clone(values))
 	}
-	return 0, nil
+	if err := rowSrc.Err(); err != nil {
+		return 0, err
+	}
+	return int64(len(m.staged)), nil
+}
+
+func (m *mockQuerier) InsertStagedTransactions(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+	if m.insertStagedFunc != nil {
+		return m.insertStagedFunc(ctx, importBatchID)
+	}
+	return int64(len(m.staged)), nil
+}
+
+func (m *mockQuerier) ListFundingCandidates(ctx context.Context, arg db.ListFundingCandidatesParams) ([]db.ListFundingCandidatesRow, error) {
+	if m.fundingCandidatesFunc != nil {
+		return m.fundingCandidatesFunc(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) LinkTransactions(ctx context.Context, arg db.LinkTransactionsParams) error {
+	if m.links == nil {
+		m.links = make(map[int32]int32)
+	}
+	for i, id := range arg.Ids {
+		m.links[id] = arg.LinkedTransactionIds[i]
+	}
+	return nil
+}
+
+func insertedFingerprints(fingerprints []string) []pgtype.Text {
+	rows := make([]pgtype.Text, len(fingerprints))
+	for i, f := range fingerprints {
+		rows[i] = pgtype.Text{String: f, Valid: true}
+	}
+	return rows
 }
 
 func (m *mockQuerier) WithTx(tx any) db.Querier {
 	return m
 }
 
+func (m *mockQuerier) ExecTx(ctx context.Context, fn func(db.Querier) error) error {
+	m.txCount++
+	return fn(m)
+}
+
+func (m *mockQuerier) CreateImportBatch(ctx context.Context, arg db.CreateImportBatchParams) (db.ImportBatch, error) {
+	if m.createImportBatchFunc != nil {
+		return m.createImportBatchFunc(ctx, arg)
+	}
+	return db.ImportBatch{ID: 1, FileName: arg.FileName, Bank: arg.Bank, Status: arg.Status}, nil
+}
+
+func (m *mockQuerier) CompleteImportBatch(ctx context.Context, arg db.CompleteImportBatchParams) (db.ImportBatch, error) {
+	if m.completeImportBatchFunc != nil {
+		return m.completeImportBatchFunc(ctx, arg)
+	}
+	return db.ImportBatch{ID: arg.ID}, nil
+}
+
+func (m *mockQuerier) GetImportBatchForUpdate(ctx context.Context, id int32) (db.ImportBatch, error) {
+	if m.getImportBatchFunc != nil {
+		return m.getImportBatchFunc(ctx, id)
+	}
+	return db.ImportBatch{ID: id, Status: ImportStatusCompleted}, nil
+}
+
+func (m *mockQuerier) ListImportBatches(ctx context.Context) ([]db.ImportBatch, error) {
+	return m.importBatches, m.err
+}
+
+func (m *mockQuerier) DeleteTransactionsByImportBatch(ctx context.Context, importBatchID pgtype.Int4) (int64, error) {
+	if m.deleteByImportBatchFunc != nil {
+		return m.deleteByImportBatchFunc(ctx, importBatchID)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) MarkImportBatchRolledBack(ctx context.Context, id int32) (db.ImportBatch, error) {
+	if m.markRolledBackFunc != nil {
+		return m.markRolledBackFunc(ctx, id)
+	}
+	return db.ImportBatch{ID: id, Status: ImportStatusRolledBack}, nil
+}
+
+// The account queries are exercised by the account package; the
+// transaction service never calls them.
+func (m *mockQuerier) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	return db.Account{}, nil
+}
+
+func (m *mockQuerier) GetAccount(ctx context.Context, id int32) (db.Account, error) {
+	return db.Account{}, nil
+}
+
+func (m *mockQuerier) ListAccounts(ctx context.Context) ([]db.Account, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListAccountsByInstitution(ctx context.Context, institution string) ([]db.Account, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateAccount(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
+	return db.Account{}, nil
+}
+
+func (m *mockQuerier) DeleteAccount(ctx context.Context, id int32) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ListAccountLedger(ctx context.Context, accountID pgtype.Int4) ([]db.ListAccountLedgerRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetLatestImportBalance(ctx context.Context, accountID pgtype.Int4) (db.ImportBatch, error) {
+	return db.ImportBatch{}, pgx.ErrNoRows
+}
+
+// The import job queries belong to the importjob package.
+func (m *mockQuerier) CreateImportJob(ctx context.Context, arg db.CreateImportJobParams) (db.ImportJob, error) {
+	return db.ImportJob{}, nil
+}
+
+func (m *mockQuerier) GetImportJob(ctx context.Context, id int32) (db.ImportJob, error) {
+	return db.ImportJob{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) StartImportJob(ctx context.Context, id int32) (db.ImportJob, error) {
+	return db.ImportJob{}, nil
+}
+
+func (m *mockQuerier) UpdateImportJobProgress(ctx context.Context, arg db.UpdateImportJobProgressParams) error {
+	return nil
+}
+
+func (m *mockQuerier) FinishImportJob(ctx context.Context, arg db.FinishImportJobParams) (db.ImportJob, error) {
+	return db.ImportJob{}, nil
+}
+
+func (m *mockQuerier) CancelQueuedImportJob(ctx context.Context, id int32) (db.ImportJob, error) {
+	return db.ImportJob{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) FailInterruptedImportJobs(ctx context.Context, errorMessage pgtype.Text) (int64, error) {
+	return 0, nil
+}
+
 func TestService_GetAllTransactions_EmptyList(t *testing.T) {
 	mock := &mockQuerier{
 		transactions: []db.Transaction{},
@@ -172,10 +409,10 @@ func TestService_GetAllTransactions_CategoryHandling(t *testing.T) {
 
 func TestService_AddTransactions_Success(t *testing.T) {
 	mockQuerier := &mockQuerier{
-		createTransactionsBatchFunc: func(ctx context.Context, arg []db.CreateTransactionsBatchParams) (int64, error) {
-			assert.Equal(t, 2, len(arg))
-			assert.Equal(t, "nationwide", arg[0].Bank)
-			return int64(len(arg)), nil
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			assert.Equal(t, 2, len(arg.Fingerprints))
+			assert.Equal(t, "nationwide", arg.Banks[0])
+			return insertedFingerprints(arg.Fingerprints), nil
 		},
 	}
 
@@ -185,16 +422,103 @@ func TestService_AddTransactions_Success(t *testing.T) {
 		{Bank: "nationwide", Description: "Grocery Store", Amount: money.New(5000, "GBP")},
 	}
 
-	count, err := service.AddTransactions(context.Background(), transactions)
+	result, err := service.AddTransactions(context.Background(), ImportSource{FileName: "statement.csv"}, transactions)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int64(2), count)
+	assert.Equal(t, int64(2), result.Inserted)
+	assert.Equal(t, int64(0), result.Skipped)
+}
+
+func TestService_AddTransactions_SkipsDuplicates(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			assert.Equal(t, 3, len(arg.Fingerprints))
+			return insertedFingerprints(arg.Fingerprints[:1]), nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	transactions := []Transaction{
+		{Bank: "Nationwide", Description: "Coffee", Amount: money.New(-300, "GBP")},
+		{Bank: "Nationwide", Description: "Coffee", Amount: money.New(-300, "GBP")},
+		{Bank: "Nationwide", Description: "Rent", Amount: money.New(-90000, "GBP")},
+	}
+
+	result, err := service.AddTransactions(context.Background(), ImportSource{FileName: "statement.csv"}, transactions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Inserted)
+	assert.Equal(t, int64(2), result.Skipped)
+}
+
+func TestService_FindDuplicates(t *testing.T) {
+	ref := "TX-1"
+	transactions := []Transaction{
+		{Bank: "Nationwide", Description: "Coffee", Amount: money.New(-300, "GBP")},
+		{Bank: "Nationwide", Description: "Coffee", Amount: money.New(-300, "GBP")},
+		{Bank: "Nationwide", Description: "Rent", Amount: money.New(-90000, "GBP"), ExternalID: &ref},
+		{Bank: "Nationwide", Description: "Rent again", Amount: money.New(-90000, "GBP"), ExternalID: &ref},
+	}
+	mockQuerier := &mockQuerier{
+		storedFingerprintsFunc: func(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+			assert.Len(t, fingerprints, 4)
+			return insertedFingerprints(fingerprints[1:2]), nil
+		},
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			t.Error("FindDuplicates inserted transactions")
+			return nil, nil
+		},
+	}
+
+	duplicates, err := NewService(mockQuerier).FindDuplicates(context.Background(), transactions)
+
+	assert.NoError(t, err)
+	// The second rent payment repeats the bank reference of the first.
+	assert.Equal(t, []bool{false, true, false, true}, duplicates)
+}
+
+func TestService_FindDuplicates_MatchesStoredRowsWithoutReference(t *testing.T) {
+	ref := "AT123456789"
+	accountID := int32(3)
+	transactions := []Transaction{
+		{Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Description: "RESTAURANT", Amount: money.New(-2550, "GBP"), Bank: "American Express", AccountID: &accountID, ExternalID: &ref},
+	}
+	// Stored before references were kept, the row has the content key.
+	legacy := hashKey("2026-01-15|-2550|GBP|RESTAURANT|account:3|0")
+	mockQuerier := &mockQuerier{
+		storedFingerprintsFunc: func(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+			return nil, nil
+		},
+		unreferencedFunc: func(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+			assert.Equal(t, []string{legacy}, fingerprints)
+			return insertedFingerprints(fingerprints), nil
+		},
+	}
+
+	duplicates, err := NewService(mockQuerier).FindDuplicates(context.Background(), transactions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true}, duplicates)
+}
+
+func TestService_FindDuplicates_DatabaseError(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		storedFingerprintsFunc: func(ctx context.Context, fingerprints []string) ([]pgtype.Text, error) {
+			return nil, errors.New("database connection failed")
+		},
+	}
+
+	_, err := NewService(mockQuerier).FindDuplicates(context.Background(), []Transaction{
+		{Bank: "amex", Description: "Test", Amount: money.New(-1000, "GBP")},
+	})
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
 }
 
 func TestService_AddTransactions_DatabaseError(t *testing.T) {
 	mockQuerier := &mockQuerier{
-		createTransactionsBatchFunc: func(ctx context.Context, arg []db.CreateTransactionsBatchParams) (int64, error) {
-			return 0, errors.New("database connection failed")
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			return nil, errors.New("database connection failed")
 		},
 	}
 
@@ -203,25 +527,169 @@ func TestService_AddTransactions_DatabaseError(t *testing.T) {
 		{Bank: "amex", Description: "Test", Amount: money.New(-1000, "GBP")},
 	}
 
-	count, err := service.AddTransactions(context.Background(), transactions)
+	result, err := service.AddTransactions(context.Background(), ImportSource{FileName: "statement.csv"}, transactions)
 
 	assert.Error(t, err)
-	assert.Equal(t, int64(0), count)
+	assert.Equal(t, int64(0), result.Inserted)
 	assert.ErrorIs(t, err, ErrDatabaseFailure)
 	assert.Contains(t, err.Error(), "database connection failed")
 }
 
 func TestService_AddTransactions_EmptySlice(t *testing.T) {
 	mockQuerier := &mockQuerier{
-		createTransactionsBatchFunc: func(ctx context.Context, arg []db.CreateTransactionsBatchParams) (int64, error) {
-			assert.Equal(t, 0, len(arg))
-			return 0, nil
+		createSkipDuplicatesFunc: func(ctx context.Context, arg db.CreateTransactionsSkipDuplicatesParams) ([]pgtype.Text, error) {
+			assert.Equal(t, 0, len(arg.Fingerprints))
+			return nil, nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	result, err := service.AddTransactions(context.Background(), ImportSource{FileName: "statement.csv"}, []Transaction{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Inserted)
+}
+
+func TestService_GetTransaction_Success(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		getTransactionFunc: func(ctx context.Context, id int32) (db.Transaction, error) {
+			assert.Equal(t, int32(7), id)
+			return db.Transaction{
+				ID:          7,
+				Date:        pgtype.Date{Time: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), Valid: true},
+				Description: "Coffee",
+				Amount:      -350,
+				Currency:    "GBP",
+				Bank:        "Nationwide",
+			}, nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	tx, err := service.GetTransaction(context.Background(), 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), tx.ID)
+	assert.Equal(t, int64(-350), tx.Amount.Amount())
+}
+
+func TestService_GetTransaction_NotFound(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		getTransactionFunc: func(ctx context.Context, id int32) (db.Transaction, error) {
+			return db.Transaction{}, pgx.ErrNoRows
+		},
+	}
+
+	service := NewService(mockQuerier)
+	_, err := service.GetTransaction(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_GetTransaction_DatabaseError(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		getTransactionFunc: func(ctx context.Context, id int32) (db.Transaction, error) {
+			return db.Transaction{}, errors.New("connection reset")
+		},
+	}
+
+	service := NewService(mockQuerier)
+	_, err := service.GetTransaction(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrDatabaseFailure)
+	assert.Contains(t, err.Error(), "connection reset")
+}
+
+func TestService_CreateTransaction_Success(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		createTransactionFunc: func(ctx context.Context, arg db.CreateTransactionParams) (db.Transaction, error) {
+			assert.Equal(t, "Cash", arg.Bank)
+			assert.Equal(t, int64(-1200), arg.Amount)
+			return db.Transaction{
+				ID:          3,
+				Date:        arg.Date,
+				Description: arg.Description,
+				Amount:      arg.Amount,
+				Currency:    arg.Currency,
+				Bank:        arg.Bank,
+				Category:    arg.Category,
+			}, nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	created, err := service.CreateTransaction(context.Background(), Transaction{
+		Date:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Description: "Market stall",
+		Amount:      money.New(-1200, "GBP"),
+		Bank:        "Cash",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), created.ID)
+}
+
+func TestService_CreateTransaction_ValidationError(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		createTransactionFunc: func(ctx context.Context, arg db.CreateTransactionParams) (db.Transaction, error) {
+			t.Fatal("CreateTransaction should not be called for invalid input")
+			return db.Transaction{}, nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	_, err := service.CreateTransaction(context.Background(), Transaction{
+		Date:   time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Amount: money.New(-1200, "GBP"),
+		Bank:   "Cash",
+	})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "description is required")
+}
+
+func TestService_UpdateTransaction_NotFound(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		updateTransactionFunc: func(ctx context.Context, arg db.UpdateTransactionParams) (db.Transaction, error) {
+			assert.Equal(t, int32(9), arg.ID)
+			return db.Transaction{}, pgx.ErrNoRows
 		},
 	}
 
 	service := NewService(mockQuerier)
-	count, err := service.AddTransactions(context.Background(), []Transaction{})
+	_, err := service.UpdateTransaction(context.Background(), Transaction{
+		ID:          9,
+		Date:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Description: "Rent",
+		Amount:      money.New(-95000, "GBP"),
+		Bank:        "Nationwide",
+	})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_DeleteTransaction_Success(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		deleteTransactionFunc: func(ctx context.Context, id int32) (int64, error) {
+			return 1, nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	err := service.DeleteTransaction(context.Background(), 4)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int64(0), count)
+}
+
+func TestService_DeleteTransaction_NotFound(t *testing.T) {
+	mockQuerier := &mockQuerier{
+		deleteTransactionFunc: func(ctx context.Context, id int32) (int64, error) {
+			return 0, nil
+		},
+	}
+
+	service := NewService(mockQuerier)
+	err := service.DeleteTransaction(context.Background(), 4)
+
+	assert.ErrorIs(t, err, ErrNotFound)
 }
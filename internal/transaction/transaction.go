@@ -10,9 +10,36 @@ type Transaction struct {
 	ID          int32
 	Date        time.Time
 	Description string
-	Amount      *money.Money
+	// Amount is negative for money leaving the account and positive for
+	// money coming in, regardless of how the source statement signed it.
+	Amount *money.Money
+	// RunningBalance is the account balance the statement reported after
+	// this transaction, or nil if it reported none.
+	RunningBalance *money.Money
+	// LocalAmount is what was spent in the currency of the purchase when
+	// the account was charged in another, signed like Amount.
+	LocalAmount *money.Money
 	Bank        string
 	Category    *string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// Notes and Address are free text from the bank, such as notes the
+	// account holder added in the bank's app and a merchant's address.
+	Notes      *string
+	Address    *string
+	AccountID  *int32
+	ExternalID *string
+	// LinkedExternalID is the ExternalID of the transaction on the same
+	// account that this one belongs to, such as the payment a fee was
+	// charged for.
+	LinkedExternalID *string
+	// LinkedTransactionID is the transaction on another account that
+	// funded this one, such as the card payment behind a PayPal payment.
+	// Imports set it once both have been stored.
+	LinkedTransactionID *int32
+	Fingerprint         string
+	// ContentFingerprint is, for a transaction with an ExternalID, the
+	// fingerprint it would have without one. Rows stored before the
+	// bank's reference was kept only have that, so imports look for both.
+	ContentFingerprint string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
@@ -0,0 +1,60 @@
+package transaction
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Rhymond/go-money"
+)
+
+const (
+	maxDescriptionLength = 500
+	maxBankLength        = 100
+	maxCategoryLength    = 100
+	maxNotesLength       = 1000
+	maxAddressLength     = 500
+)
+
+func (t Transaction) Validate() error {
+	if t.Date.IsZero() {
+		return fmt.Errorf("%w: date is required", ErrValidation)
+	}
+	if strings.TrimSpace(t.Description) == "" {
+		return fmt.Errorf("%w: description is required", ErrValidation)
+	}
+	if tooLong(t.Description, maxDescriptionLength) {
+		return fmt.Errorf("%w: description must be at most %d characters", ErrValidation, maxDescriptionLength)
+	}
+	if t.Amount == nil {
+		return fmt.Errorf("%w: amount is required", ErrValidation)
+	}
+	if money.GetCurrency(t.Amount.Currency().Code) == nil {
+		return fmt.Errorf("%w: unsupported currency %q", ErrValidation, t.Amount.Currency().Code)
+	}
+	if strings.TrimSpace(t.Bank) == "" {
+		return fmt.Errorf("%w: bank is required", ErrValidation)
+	}
+	if tooLong(t.Bank, maxBankLength) {
+		return fmt.Errorf("%w: bank must be at most %d characters", ErrValidation, maxBankLength)
+	}
+	if t.Category != nil && tooLong(*t.Category, maxCategoryLength) {
+		return fmt.Errorf("%w: category must be at most %d characters", ErrValidation, maxCategoryLength)
+	}
+	if t.Notes != nil && tooLong(*t.Notes, maxNotesLength) {
+		return fmt.Errorf("%w: notes must be at most %d characters", ErrValidation, maxNotesLength)
+	}
+	if t.Address != nil && tooLong(*t.Address, maxAddressLength) {
+		return fmt.Errorf("%w: address must be at most %d characters", ErrValidation, maxAddressLength)
+	}
+	if t.LocalAmount != nil && money.GetCurrency(t.LocalAmount.Currency().Code) == nil {
+		return fmt.Errorf("%w: unsupported local currency %q", ErrValidation, t.LocalAmount.Currency().Code)
+	}
+	return nil
+}
+
+// tooLong reports whether s has more than max characters. The limits are
+// those of VARCHAR columns, which count characters rather than bytes.
+func tooLong(s string, max int) bool {
+	return utf8.RuneCountInString(s) > max
+}
@@ -0,0 +1,70 @@
+package transaction
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/stretchr/testify/assert"
+)
+
+func validTransaction() Transaction {
+	return Transaction{
+		Date:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Description: "Coffee",
+		Amount:      money.New(-350, "GBP"),
+		Bank:        "Cash",
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	assert.NoError(t, validTransaction().Validate())
+}
+
+func TestValidate_MissingDate(t *testing.T) {
+	tx := validTransaction()
+	tx.Date = time.Time{}
+
+	err := tx.Validate()
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "date is required")
+}
+
+func TestValidate_DescriptionTooLong(t *testing.T) {
+	tx := validTransaction()
+	tx.Description = strings.Repeat("a", 501)
+
+	err := tx.Validate()
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "description must be at most 500 characters")
+}
+
+func TestValidate_LengthCountsCharacters(t *testing.T) {
+	tx := validTransaction()
+	tx.Description = strings.Repeat("£", 500)
+
+	assert.NoError(t, tx.Validate())
+}
+
+func TestValidate_UnsupportedCurrency(t *testing.T) {
+	tx := validTransaction()
+	tx.Amount = money.New(100, "XXY")
+
+	err := tx.Validate()
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "unsupported currency")
+}
+
+func TestValidate_MissingBank(t *testing.T) {
+	tx := validTransaction()
+	tx.Bank = " "
+
+	err := tx.Validate()
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "bank is required")
+}
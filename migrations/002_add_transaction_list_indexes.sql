@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_transactions_date_id ON transactions (date, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_amount_id ON transactions (amount, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_description_id ON transactions (description, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_bank ON transactions (bank);
+CREATE INDEX IF NOT EXISTS idx_transactions_category ON transactions (category);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_category;
+DROP INDEX IF EXISTS idx_transactions_bank;
+DROP INDEX IF EXISTS idx_transactions_description_id;
+DROP INDEX IF EXISTS idx_transactions_amount_id;
+DROP INDEX IF EXISTS idx_transactions_date_id;
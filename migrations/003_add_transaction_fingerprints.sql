@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN external_id VARCHAR(100);
+ALTER TABLE transactions ADD COLUMN fingerprint CHAR(64);
+
+-- Backfill using the content key of transaction.AssignFingerprints so that
+-- existing rows are recognised when an overlapping statement is uploaded
+-- again. No row has an external_id yet, so none gets the reference key;
+-- imports match referenced rows to these by their content key as well.
+UPDATE transactions t
+SET fingerprint = encode(sha256(convert_to(
+        to_char(f.date, 'YYYY-MM-DD') || '|' || f.amount || '|' || f.currency || '|' ||
+        f.description || '|' || f.bank || '|' || f.occurrence, 'UTF8')), 'hex')
+FROM (
+    SELECT id, date, amount, currency, description, bank,
+           row_number() OVER (PARTITION BY date, amount, currency, description, bank ORDER BY id) - 1 AS occurrence
+    FROM transactions
+) f
+WHERE t.id = f.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_fingerprint ON transactions (fingerprint);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_fingerprint;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fingerprint;
+ALTER TABLE transactions DROP COLUMN IF EXISTS external_id;
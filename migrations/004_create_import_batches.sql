@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS import_batches (
+    id SERIAL PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    bank VARCHAR(100) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    inserted_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,
+    error_message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    rolled_back_at TIMESTAMP
+);
+
+ALTER TABLE transactions ADD COLUMN import_batch_id INTEGER REFERENCES import_batches (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_import_batch_id ON transactions (import_batch_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_import_batch_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS import_batch_id;
+DROP TABLE IF EXISTS import_batches;
@@ -0,0 +1,57 @@
+-- +goose Up
+-- Amex statements sign purchases as positive. Imported Amex rows are flipped
+-- so that, like every other bank, outflows are negative. Rows entered by hand
+-- have no fingerprint and are left alone.
+CREATE TEMP TABLE amex_sign_flip ON COMMIT DROP AS
+SELECT id FROM transactions
+WHERE bank = 'American Express' AND fingerprint IS NOT NULL;
+
+-- Fingerprints without a bank reference include the amount. Clear them
+-- before flipping so a purchase and an equal refund cannot collide, then
+-- recompute with the same key as migration 003.
+UPDATE transactions
+SET fingerprint = NULL
+WHERE id IN (SELECT id FROM amex_sign_flip) AND external_id IS NULL;
+
+UPDATE transactions
+SET amount = -amount,
+    updated_at = NOW()
+WHERE id IN (SELECT id FROM amex_sign_flip);
+
+UPDATE transactions t
+SET fingerprint = encode(sha256(convert_to(
+        to_char(f.date, 'YYYY-MM-DD') || '|' || f.amount || '|' || f.currency || '|' ||
+        f.description || '|' || f.bank || '|' || f.occurrence, 'UTF8')), 'hex')
+FROM (
+    SELECT id, date, amount, currency, description, bank,
+           row_number() OVER (PARTITION BY date, amount, currency, description, bank ORDER BY id) - 1 AS occurrence
+    FROM transactions
+    WHERE id IN (SELECT id FROM amex_sign_flip) AND external_id IS NULL
+) f
+WHERE t.id = f.id;
+
+-- +goose Down
+CREATE TEMP TABLE amex_sign_flip ON COMMIT DROP AS
+SELECT id FROM transactions
+WHERE bank = 'American Express' AND fingerprint IS NOT NULL;
+
+UPDATE transactions
+SET fingerprint = NULL
+WHERE id IN (SELECT id FROM amex_sign_flip) AND external_id IS NULL;
+
+UPDATE transactions
+SET amount = -amount,
+    updated_at = NOW()
+WHERE id IN (SELECT id FROM amex_sign_flip);
+
+UPDATE transactions t
+SET fingerprint = encode(sha256(convert_to(
+        to_char(f.date, 'YYYY-MM-DD') || '|' || f.amount || '|' || f.currency || '|' ||
+        f.description || '|' || f.bank || '|' || f.occurrence, 'UTF8')), 'hex')
+FROM (
+    SELECT id, date, amount, currency, description, bank,
+           row_number() OVER (PARTITION BY date, amount, currency, description, bank ORDER BY id) - 1 AS occurrence
+    FROM transactions
+    WHERE id IN (SELECT id FROM amex_sign_flip) AND external_id IS NULL
+) f
+WHERE t.id = f.id;
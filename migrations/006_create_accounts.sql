@@ -0,0 +1,83 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS accounts (
+    id SERIAL PRIMARY KEY,
+    institution VARCHAR(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    masked_number VARCHAR(50),
+    account_type VARCHAR(20) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'GBP',
+    opening_balance BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT accounts_account_type_check CHECK (account_type IN ('current', 'credit', 'savings', 'cash'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_institution_masked_number ON accounts (institution, masked_number);
+
+ALTER TABLE transactions ADD COLUMN account_id INTEGER REFERENCES accounts (id);
+ALTER TABLE import_batches ADD COLUMN account_id INTEGER REFERENCES accounts (id);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions (account_id);
+
+-- Until now the bank name was the only notion of an account, so each bank
+-- seen so far becomes one account.
+INSERT INTO accounts (institution, name, account_type, currency)
+SELECT bank,
+       bank,
+       CASE bank WHEN 'American Express' THEN 'credit' WHEN 'Cash' THEN 'cash' ELSE 'current' END,
+       MIN(currency)
+FROM transactions
+GROUP BY bank;
+
+UPDATE transactions t SET account_id = a.id FROM accounts a WHERE a.institution = t.bank;
+UPDATE import_batches b SET account_id = a.id FROM accounts a WHERE a.institution = b.bank;
+
+-- Fingerprints are now scoped to the account rather than the bank name, so
+-- two accounts at one bank can hold identical rows. Recompute them with the
+-- key used by transaction.AssignFingerprints.
+CREATE TEMP TABLE account_refingerprint ON COMMIT DROP AS
+SELECT id FROM transactions WHERE fingerprint IS NOT NULL;
+
+UPDATE transactions SET fingerprint = NULL WHERE id IN (SELECT id FROM account_refingerprint);
+
+UPDATE transactions
+SET fingerprint = encode(sha256(convert_to('ref|account:' || account_id || '|' || external_id, 'UTF8')), 'hex')
+WHERE id IN (SELECT id FROM account_refingerprint) AND external_id IS NOT NULL;
+
+UPDATE transactions t
+SET fingerprint = encode(sha256(convert_to(
+        to_char(f.date, 'YYYY-MM-DD') || '|' || f.amount || '|' || f.currency || '|' ||
+        f.description || '|account:' || f.account_id || '|' || f.occurrence, 'UTF8')), 'hex')
+FROM (
+    SELECT id, date, amount, currency, description, account_id,
+           row_number() OVER (PARTITION BY account_id, date, amount, currency, description ORDER BY id) - 1 AS occurrence
+    FROM transactions
+    WHERE id IN (SELECT id FROM account_refingerprint) AND external_id IS NULL
+) f
+WHERE t.id = f.id;
+
+-- +goose Down
+CREATE TEMP TABLE account_refingerprint ON COMMIT DROP AS
+SELECT id FROM transactions WHERE fingerprint IS NOT NULL;
+
+UPDATE transactions SET fingerprint = NULL WHERE id IN (SELECT id FROM account_refingerprint);
+
+UPDATE transactions
+SET fingerprint = encode(sha256(convert_to('ref|' || bank || '|' || external_id, 'UTF8')), 'hex')
+WHERE id IN (SELECT id FROM account_refingerprint) AND external_id IS NOT NULL;
+
+UPDATE transactions t
+SET fingerprint = encode(sha256(convert_to(
+        to_char(f.date, 'YYYY-MM-DD') || '|' || f.amount || '|' || f.currency || '|' ||
+        f.description || '|' || f.bank || '|' || f.occurrence, 'UTF8')), 'hex')
+FROM (
+    SELECT id, date, amount, currency, description, bank,
+           row_number() OVER (PARTITION BY date, amount, currency, description, bank ORDER BY id) - 1 AS occurrence
+    FROM transactions
+    WHERE id IN (SELECT id FROM account_refingerprint) AND external_id IS NULL
+) f
+WHERE t.id = f.id;
+
+DROP INDEX IF EXISTS idx_transactions_account_id;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS account_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS account_id;
+DROP TABLE IF EXISTS accounts;
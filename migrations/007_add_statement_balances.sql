@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN running_balance BIGINT;
+
+ALTER TABLE import_batches ADD COLUMN closing_balance BIGINT;
+ALTER TABLE import_batches ADD COLUMN available_balance BIGINT;
+ALTER TABLE import_batches ADD COLUMN balance_date DATE;
+
+-- +goose Down
+ALTER TABLE import_batches DROP COLUMN IF EXISTS balance_date;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS available_balance;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS closing_balance;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS running_balance;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE import_batches ADD COLUMN opening_balance BIGINT;
+ALTER TABLE import_batches ADD COLUMN opening_balance_date DATE;
+
+-- +goose Down
+ALTER TABLE import_batches DROP COLUMN IF EXISTS opening_balance_date;
+ALTER TABLE import_batches DROP COLUMN IF EXISTS opening_balance;
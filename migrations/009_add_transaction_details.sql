@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN notes TEXT;
+ALTER TABLE transactions ADD COLUMN address TEXT;
+ALTER TABLE transactions ADD COLUMN local_amount BIGINT;
+ALTER TABLE transactions ADD COLUMN local_currency VARCHAR(3);
+
+-- +goose Down
+ALTER TABLE transactions DROP COLUMN IF EXISTS local_currency;
+ALTER TABLE transactions DROP COLUMN IF EXISTS local_amount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS address;
+ALTER TABLE transactions DROP COLUMN IF EXISTS notes;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN linked_external_id TEXT;
+
+-- +goose Down
+ALTER TABLE transactions DROP COLUMN IF EXISTS linked_external_id;
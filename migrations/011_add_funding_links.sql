@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transactions ADD COLUMN linked_transaction_id INTEGER REFERENCES transactions(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_linked_transaction_id ON transactions (linked_transaction_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_linked_transaction_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS linked_transaction_id;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id SERIAL PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    statement_count INTEGER NOT NULL DEFAULT 0,
+    statements_done INTEGER NOT NULL DEFAULT 0,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    rows_done INTEGER NOT NULL DEFAULT 0,
+    inserted_count INTEGER NOT NULL DEFAULT 0,
+    skipped_count INTEGER NOT NULL DEFAULT 0,
+    -- The import batches the job has created, one per statement.
+    import_batch_ids INTEGER[] NOT NULL DEFAULT '{}',
+    -- The rows a lenient job left out, as csvparser.RowError values.
+    rejected_rows JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS import_jobs;
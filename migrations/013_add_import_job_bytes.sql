@@ -0,0 +1,11 @@
+-- +goose Up
+-- How much of its upload a job has read, for a streamed file whose rows
+-- are only counted once they have all been read.
+ALTER TABLE import_jobs
+    ADD COLUMN byte_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN bytes_done BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE import_jobs
+    DROP COLUMN IF EXISTS byte_count,
+    DROP COLUMN IF EXISTS bytes_done;
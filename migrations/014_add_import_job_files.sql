@@ -0,0 +1,9 @@
+-- +goose Up
+-- What became of each file of a job's upload, so that one file failing
+-- does not hide how the others went.
+ALTER TABLE import_jobs
+    ADD COLUMN files JSONB NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE import_jobs
+    DROP COLUMN IF EXISTS files;